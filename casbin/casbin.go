@@ -49,10 +49,12 @@ package casbin
 
 import (
 	"errors"
+	"fmt"
 	"github.com/casbin/casbin/v2"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"net/http"
+	"reflect"
 )
 
 type (
@@ -72,6 +74,12 @@ type (
 		// Method to get the username - defaults to using basic auth
 		UserGetter func(c echo.Context) (string, error)
 
+		// DomainGetter returns domain (tenant) of the request. When set, default enforce handler enforces
+		// `(user, domain, path, method)` request so model must define domain in request definition
+		// (i.e. `r = sub, dom, obj, act`). See ParamDomainGetter and HeaderDomainGetter.
+		// Optional.
+		DomainGetter func(c echo.Context) (string, error)
+
 		// Method to handle errors
 		ErrorHandler func(c echo.Context, internal error, proposedStatus int) error
 	}
@@ -118,6 +126,15 @@ func MiddlewareWithConfig(config Config) echo.MiddlewareFunc {
 	if config.ErrorHandler == nil {
 		config.ErrorHandler = DefaultConfig.ErrorHandler
 	}
+	if config.EnforceHandler == nil && config.DomainGetter != nil {
+		config.EnforceHandler = func(c echo.Context, user string) (bool, error) {
+			domain, err := config.DomainGetter(c)
+			if err != nil {
+				return false, err
+			}
+			return config.Enforcer.Enforce(user, domain, c.Request().URL.Path, c.Request().Method)
+		}
+	}
 	if config.EnforceHandler == nil {
 		config.EnforceHandler = func(c echo.Context, user string) (bool, error) {
 			return config.Enforcer.Enforce(user, c.Request().URL.Path, c.Request().Method)
@@ -145,3 +162,72 @@ func MiddlewareWithConfig(config Config) echo.MiddlewareFunc {
 		}
 	}
 }
+
+// ParamDomainGetter returns DomainGetter reading domain from route path parameter (i.e. `tenant` for `/:tenant/*`).
+func ParamDomainGetter(name string) func(c echo.Context) (string, error) {
+	return func(c echo.Context) (string, error) {
+		return c.Param(name), nil
+	}
+}
+
+// HeaderDomainGetter returns DomainGetter reading domain from request header (i.e. `X-Tenant-ID`).
+func HeaderDomainGetter(header string) func(c echo.Context) (string, error) {
+	return func(c echo.Context) (string, error) {
+		return c.Request().Header.Get(header), nil
+	}
+}
+
+// ContextUserGetter returns UserGetter reading subject from value stored in context by authentication middleware
+// (i.e. `user` key used by echo-jwt). Value can be a string, claims map (map[string]interface{} read by claim name),
+// claims implementing `GetSubject() (string, error)` (golang-jwt/jwt/v5) or token with `Claims` field containing one of
+// the former. Claim is used only for claims maps and defaults to "sub".
+func ContextUserGetter(contextKey string, claim string) func(c echo.Context) (string, error) {
+	if claim == "" {
+		claim = "sub"
+	}
+	return func(c echo.Context) (string, error) {
+		v := c.Get(contextKey)
+		if v == nil {
+			return "", fmt.Errorf("casbin: no value for key %q in context", contextKey)
+		}
+		return subjectFrom(v, claim)
+	}
+}
+
+func subjectFrom(v interface{}, claim string) (string, error) {
+	switch t := v.(type) {
+	case string:
+		return t, nil
+	case map[string]interface{}:
+		return claimFrom(t, claim)
+	case interface{ GetSubject() (string, error) }:
+		if claim == "sub" {
+			return t.GetSubject()
+		}
+	}
+	// named claims maps (i.e. jwt.MapClaims) and token structs (i.e. *jwt.Token) are accessed by reflection to avoid
+	// depending on JWT libraries
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			m := make(map[string]interface{}, rv.Len())
+			for _, k := range rv.MapKeys() {
+				m[k.String()] = rv.MapIndex(k).Interface()
+			}
+			return claimFrom(m, claim)
+		}
+	case reflect.Struct:
+		if f := rv.FieldByName("Claims"); f.IsValid() && f.CanInterface() && !f.IsZero() {
+			return subjectFrom(f.Interface(), claim)
+		}
+	}
+	return "", fmt.Errorf("casbin: unsupported subject value of type %T", v)
+}
+
+func claimFrom(claims map[string]interface{}, claim string) (string, error) {
+	if sub, ok := claims[claim].(string); ok {
+		return sub, nil
+	}
+	return "", fmt.Errorf("casbin: claim %q is missing or not a string", claim)
+}
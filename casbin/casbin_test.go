@@ -174,3 +174,87 @@ func TestCustomSkipper(t *testing.T) {
 	testRequest(t, h, "alice", "/dataset1/resource1", echo.GET, http.StatusOK)
 	testRequest(t, h, "alice", "/dataset1/resource2", echo.POST, http.StatusForbidden)
 }
+
+func TestDomainGetter(t *testing.T) {
+	ce, err := casbin.NewEnforcer("auth_model_domain.conf", "auth_policy_domain.csv")
+	assert.NoError(t, err)
+
+	e := echo.New()
+	e.GET("/:tenant/*", func(c echo.Context) error {
+		return c.String(http.StatusOK, "test")
+	}, MiddlewareWithConfig(Config{
+		Enforcer:     ce,
+		DomainGetter: ParamDomainGetter("tenant"),
+	}))
+
+	for _, tc := range []struct {
+		user string
+		path string
+		code int
+	}{
+		{user: "alice", path: "/tenant1/data", code: http.StatusOK},
+		{user: "alice", path: "/tenant2/data", code: http.StatusForbidden},
+		{user: "bob", path: "/tenant2/data", code: http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		req.SetBasicAuth(tc.user, "secret")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, tc.code, rec.Code, tc.user+" "+tc.path)
+	}
+}
+
+func TestHeaderDomainGetter(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Tenant-ID", "tenant1")
+	domain, err := HeaderDomainGetter("X-Tenant-ID")(e.NewContext(req, nil))
+	assert.NoError(t, err)
+	assert.Equal(t, "tenant1", domain)
+}
+
+type testClaims map[string]interface{}
+
+type testToken struct {
+	Claims interface{}
+}
+
+type testSubjectClaims struct{}
+
+func (testSubjectClaims) GetSubject() (string, error) { return "from-method", nil }
+
+func TestContextUserGetter(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		givenValue  interface{}
+		givenClaim  string
+		expect      string
+		expectError string
+	}{
+		{name: "ok, string", givenValue: "alice", expect: "alice"},
+		{name: "ok, claims map", givenValue: map[string]interface{}{"sub": "alice"}, expect: "alice"},
+		{name: "ok, custom claim", givenValue: map[string]interface{}{"email": "a@example.com"}, givenClaim: "email", expect: "a@example.com"},
+		{name: "ok, named claims map", givenValue: testClaims{"sub": "alice"}, expect: "alice"},
+		{name: "ok, token with claims", givenValue: &testToken{Claims: testClaims{"sub": "alice"}}, expect: "alice"},
+		{name: "ok, GetSubject", givenValue: &testToken{Claims: testSubjectClaims{}}, expect: "from-method"},
+		{name: "nok, no value", expectError: `casbin: no value for key "user" in context`},
+		{name: "nok, missing claim", givenValue: map[string]interface{}{}, expectError: `casbin: claim "sub" is missing or not a string`},
+		{name: "nok, unsupported", givenValue: 1, expectError: "casbin: unsupported subject value of type int"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), nil)
+			if tc.givenValue != nil {
+				c.Set("user", tc.givenValue)
+			}
+			user, err := ContextUserGetter("user", tc.givenClaim)(c)
+			if tc.expectError != "" {
+				assert.EqualError(t, err, tc.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expect, user)
+		})
+	}
+}
@@ -0,0 +1,345 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+/*
+Package echoapiversion provides middleware resolving requested API version from path prefix, header or media type
+parameter, enforcing version deprecation and sunset policies and labeling metrics and traces with the version.
+
+Version is resolved by Config.Extractors in order, the first extractor finding a version wins. Leading `v` is removed
+so `/v2/users`, `API-Version: v2` and `Accept: application/json; version=2` all resolve to version `2`. Responses of
+deprecated versions get `Deprecation`, `Sunset` and `Link` headers (RFC 9745, RFC 8594) and requests to versions past
+their sunset date are rejected with `410 Gone`.
+
+Example:
+```
+package main
+
+import (
+
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo-contrib/echoapiversion"
+	"github.com/labstack/echo-contrib/echoprometheus"
+	"github.com/labstack/echo/v4"
+
+)
+
+	func main() {
+		e := echo.New()
+		e.Use(echoapiversion.MiddlewareWithConfig(echoapiversion.Config{
+			DefaultVersion: "2",
+			Versions: map[string]echoapiversion.Policy{
+				"1": {
+					Deprecated: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+					Sunset:     time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC),
+					Link:       "https://example.com/docs/migrate-to-v2",
+				},
+				"2": {},
+			},
+			AttachToTrace: true,
+		}))
+		e.Use(echoprometheus.NewMiddlewareWithConfig(echoprometheus.MiddlewareConfig{
+			LabelFuncs: map[string]echoprometheus.LabelValueFunc{"api_version": echoapiversion.LabelFunc},
+		}))
+
+		e.GET("/users", func(c echo.Context) error {
+			if echoapiversion.Version(c) == "1" {
+				return c.JSON(http.StatusOK, []string{"bob"})
+			}
+			return c.JSON(http.StatusOK, map[string]any{"users": []string{"bob"}})
+		})
+
+		e.Logger.Fatal(e.Start(":1323"))
+	}
+
+```
+*/
+package echoapiversion
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	contextKey       = "_echoapiversion_version"
+	labelContextKey  = "_echoapiversion_label"
+	defaultSubsystem = "echo_api_version"
+
+	// HeaderAPIVersion is default header the version is read from.
+	HeaderAPIVersion = "API-Version"
+	// HeaderDeprecation is response header with date version was deprecated (RFC 9745).
+	HeaderDeprecation = "Deprecation"
+	// HeaderSunset is response header with date version stops being served (RFC 8594).
+	HeaderSunset = "Sunset"
+
+	// UnknownVersionLabel is used instead of version in metrics labels and trace attributes for versions that are not
+	// in Config.Versions, so clients can not create unbounded number of label values.
+	UnknownVersionLabel = "unknown"
+)
+
+var (
+	// ErrMissingVersion is returned when request does not specify version and there is no default version.
+	ErrMissingVersion = echo.NewHTTPError(http.StatusBadRequest, "missing API version")
+	// ErrUnsupportedVersion is returned when requested version is not in Config.Versions.
+	ErrUnsupportedVersion = echo.NewHTTPError(http.StatusBadRequest, "unsupported API version")
+	// ErrVersionSunset is returned when requested version is past its sunset date.
+	ErrVersionSunset = echo.NewHTTPError(http.StatusGone, "API version is no longer available")
+)
+
+// Extractor returns API version requested by the request. Returns false when request does not specify version.
+type Extractor func(c echo.Context) (string, bool)
+
+var pathVersionRe = regexp.MustCompile(`^/[vV](\d+(?:\.\d+)*)(?:/|$)`)
+
+// FromPathPrefix returns Extractor reading version from the first path segment (i.e. `/v2/users`).
+func FromPathPrefix() Extractor {
+	return func(c echo.Context) (string, bool) {
+		m := pathVersionRe.FindStringSubmatch(c.Request().URL.Path)
+		if m == nil {
+			return "", false
+		}
+		return m[1], true
+	}
+}
+
+// FromHeader returns Extractor reading version from request header.
+func FromHeader(name string) Extractor {
+	return func(c echo.Context) (string, bool) {
+		v := normalize(c.Request().Header.Get(name))
+		return v, v != ""
+	}
+}
+
+// FromMediaType returns Extractor reading version from media type parameter of `Accept` header (i.e.
+// `application/vnd.example+json; version=2`) or `Content-Type` header when `Accept` has no such parameter.
+func FromMediaType(param string) Extractor {
+	return func(c echo.Context) (string, bool) {
+		for _, header := range []string{echo.HeaderAccept, echo.HeaderContentType} {
+			for _, mediaRange := range strings.Split(c.Request().Header.Get(header), ",") {
+				_, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+				if err != nil {
+					continue
+				}
+				if v := normalize(params[param]); v != "" {
+					return v, true
+				}
+			}
+		}
+		return "", false
+	}
+}
+
+// Policy defines lifecycle of a version.
+type Policy struct {
+	// Deprecated is date version was (or will be) deprecated. Responses get `Deprecation` header when set.
+	// Optional.
+	Deprecated time.Time
+
+	// Sunset is date version stops being served. Responses get `Sunset` header and requests after this date are
+	// rejected with `410 Gone`.
+	// Optional.
+	Sunset time.Time
+
+	// Link is URL of deprecation or migration documentation added as `Link` header with `deprecation` relation.
+	// Optional.
+	Link string
+}
+
+// Config defines the config for API version middleware.
+type Config struct {
+	// Skipper defines a function to skip middleware.
+	Skipper middleware.Skipper
+
+	// Extractors resolve requested version in order of precedence.
+	// Defaults to: FromPathPrefix(), FromHeader("API-Version"), FromMediaType("version")
+	Extractors []Extractor
+
+	// DefaultVersion is used when request does not specify version. When empty such requests are rejected with
+	// ErrMissingVersion.
+	// Optional.
+	DefaultVersion string
+
+	// Versions are supported versions with their policies. Requests for other versions are rejected with
+	// ErrUnsupportedVersion. When empty all versions are accepted, but are reported as UnknownVersionLabel in metrics
+	// and traces.
+	// Optional.
+	Versions map[string]Policy
+
+	// AttachToTrace adds `api.version` attribute to OpenTelemetry span found in request context.
+	AttachToTrace bool
+
+	// Registerer is used to register requests counter. When nil no metrics are registered.
+	// Optional.
+	Registerer prometheus.Registerer
+
+	// Namespace is components of the fully-qualified name of the Metric (created by joining Namespace,Subsystem and Name components with "_")
+	// Optional
+	Namespace string
+
+	// Subsystem is components of the fully-qualified name of the Metric (created by joining Namespace,Subsystem and Name components with "_")
+	// Defaults to: "echo_api_version"
+	Subsystem string
+
+	timeNow func() time.Time
+}
+
+// DefaultConfig is the default API version middleware config.
+var DefaultConfig = Config{
+	Skipper:    middleware.DefaultSkipper,
+	Extractors: []Extractor{FromPathPrefix(), FromHeader(HeaderAPIVersion), FromMediaType("version")},
+}
+
+// Middleware returns API version middleware with default config accepting any version and rejecting requests without
+// version.
+func Middleware() echo.MiddlewareFunc {
+	return MiddlewareWithConfig(DefaultConfig)
+}
+
+// MiddlewareWithConfig returns API version middleware with config or panics on invalid configuration.
+// See: `Middleware()`.
+func MiddlewareWithConfig(config Config) echo.MiddlewareFunc {
+	mw, err := config.ToMiddleware()
+	if err != nil {
+		panic(err)
+	}
+	return mw
+}
+
+// ToMiddleware converts configuration to middleware or returns an error.
+func (config Config) ToMiddleware() (echo.MiddlewareFunc, error) {
+	if config.Skipper == nil {
+		config.Skipper = DefaultConfig.Skipper
+	}
+	if len(config.Extractors) == 0 {
+		config.Extractors = DefaultConfig.Extractors
+	}
+	if config.Subsystem == "" {
+		config.Subsystem = defaultSubsystem
+	}
+	if config.timeNow == nil {
+		config.timeNow = time.Now
+	}
+	config.DefaultVersion = normalize(config.DefaultVersion)
+	versions := make(map[string]Policy, len(config.Versions))
+	for v, p := range config.Versions {
+		v = normalize(v)
+		if v == "" {
+			return nil, errors.New("echoapiversion: version can not be empty")
+		}
+		if !p.Deprecated.IsZero() && !p.Sunset.IsZero() && p.Sunset.Before(p.Deprecated) {
+			return nil, errors.New("echoapiversion: sunset of version " + v + " is before its deprecation")
+		}
+		versions[v] = p
+	}
+	if config.DefaultVersion != "" && len(versions) > 0 {
+		if _, ok := versions[config.DefaultVersion]; !ok {
+			return nil, errors.New("echoapiversion: default version is not in supported versions")
+		}
+	}
+
+	requests := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: config.Namespace,
+			Subsystem: config.Subsystem,
+			Name:      "requests_total",
+			Help:      "How many HTTP requests processed, partitioned by API version and deprecation.",
+		},
+		[]string{"version", "deprecated"},
+	)
+	if config.Registerer != nil {
+		if err := config.Registerer.Register(requests); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			version := ""
+			for _, extract := range config.Extractors {
+				if v, ok := extract(c); ok {
+					version = v
+					break
+				}
+			}
+			if version == "" {
+				version = config.DefaultVersion
+			}
+			if version == "" {
+				return ErrMissingVersion
+			}
+			policy, known := versions[version]
+			if len(versions) > 0 && !known {
+				return ErrUnsupportedVersion
+			}
+			label := version
+			if !known {
+				label = UnknownVersionLabel
+			}
+			c.Set(contextKey, version)
+			c.Set(labelContextKey, label)
+
+			if config.AttachToTrace {
+				if span := trace.SpanFromContext(c.Request().Context()); span.IsRecording() {
+					span.SetAttributes(attribute.String("api.version", label))
+				}
+			}
+
+			now := config.timeNow()
+			deprecated := !policy.Deprecated.IsZero() && !now.Before(policy.Deprecated)
+			requests.WithLabelValues(label, strconv.FormatBool(deprecated)).Inc()
+
+			header := c.Response().Header()
+			if !policy.Deprecated.IsZero() {
+				header.Set(HeaderDeprecation, "@"+strconv.FormatInt(policy.Deprecated.Unix(), 10))
+			}
+			if !policy.Sunset.IsZero() {
+				header.Set(HeaderSunset, policy.Sunset.UTC().Format(http.TimeFormat))
+			}
+			if policy.Link != "" {
+				header.Add("Link", "<"+policy.Link+`>; rel="deprecation"`)
+			}
+			if !policy.Sunset.IsZero() && !now.Before(policy.Sunset) {
+				return ErrVersionSunset
+			}
+			return next(c)
+		}
+	}, nil
+}
+
+// Version returns API version of the request resolved by middleware or empty string when middleware was skipped.
+func Version(c echo.Context) string {
+	v, _ := c.Get(contextKey).(string)
+	return v
+}
+
+// LabelFunc returns API version of the request or UnknownVersionLabel for versions not in Config.Versions. Can be used
+// as `echoprometheus.MiddlewareConfig.LabelFuncs` function.
+func LabelFunc(c echo.Context, err error) string {
+	v, _ := c.Get(labelContextKey).(string)
+	return v
+}
+
+func normalize(version string) string {
+	version = strings.TrimSpace(version)
+	if len(version) > 1 && (version[0] == 'v' || version[0] == 'V') && version[1] >= '0' && version[1] <= '9' {
+		return version[1:]
+	}
+	return version
+}
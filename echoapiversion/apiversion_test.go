@@ -0,0 +1,193 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echoapiversion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractors(t *testing.T) {
+	var testCases = []struct {
+		name          string
+		whenExtractor Extractor
+		whenPath      string
+		whenHeaders   map[string]string
+		expect        string
+		expectOK      bool
+	}{
+		{name: "path prefix", whenExtractor: FromPathPrefix(), whenPath: "/v2/users", expect: "2", expectOK: true},
+		{name: "path prefix only segment", whenExtractor: FromPathPrefix(), whenPath: "/V1.1", expect: "1.1", expectOK: true},
+		{name: "path prefix not version", whenExtractor: FromPathPrefix(), whenPath: "/videos/1"},
+		{
+			name:          "header",
+			whenExtractor: FromHeader(HeaderAPIVersion),
+			whenPath:      "/users",
+			whenHeaders:   map[string]string{HeaderAPIVersion: "v3"},
+			expect:        "3",
+			expectOK:      true,
+		},
+		{name: "header missing", whenExtractor: FromHeader(HeaderAPIVersion), whenPath: "/users"},
+		{
+			name:          "accept media type",
+			whenExtractor: FromMediaType("version"),
+			whenPath:      "/users",
+			whenHeaders:   map[string]string{echo.HeaderAccept: "text/html, application/vnd.example+json; version=2"},
+			expect:        "2",
+			expectOK:      true,
+		},
+		{
+			name:          "content type media type",
+			whenExtractor: FromMediaType("version"),
+			whenPath:      "/users",
+			whenHeaders:   map[string]string{echo.HeaderContentType: "application/json; version=4"},
+			expect:        "4",
+			expectOK:      true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.whenPath, nil)
+			for k, v := range tc.whenHeaders {
+				req.Header.Set(k, v)
+			}
+			c := echo.New().NewContext(req, httptest.NewRecorder())
+
+			v, ok := tc.whenExtractor(c)
+
+			assert.Equal(t, tc.expect, v)
+			assert.Equal(t, tc.expectOK, ok)
+		})
+	}
+}
+
+func TestConfig_ToMiddleware_invalidConfig(t *testing.T) {
+	_, err := Config{Versions: map[string]Policy{"": {}}}.ToMiddleware()
+	assert.EqualError(t, err, "echoapiversion: version can not be empty")
+
+	_, err = Config{DefaultVersion: "3", Versions: map[string]Policy{"1": {}, "2": {}}}.ToMiddleware()
+	assert.EqualError(t, err, "echoapiversion: default version is not in supported versions")
+
+	now := time.Now()
+	_, err = Config{Versions: map[string]Policy{"1": {Deprecated: now, Sunset: now.Add(-time.Hour)}}}.ToMiddleware()
+	assert.EqualError(t, err, "echoapiversion: sunset of version 1 is before its deprecation")
+}
+
+func TestMiddlewareWithConfig(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	reg := prometheus.NewRegistry()
+	config := Config{
+		DefaultVersion: "v2",
+		Versions: map[string]Policy{
+			"v1": {
+				Deprecated: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+				Sunset:     time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC),
+				Link:       "https://example.com/migrate",
+			},
+			"v2": {},
+			"v0": {Sunset: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		},
+		Registerer: reg,
+		timeNow:    func() time.Time { return now },
+	}
+	mw, err := config.ToMiddleware()
+	assert.NoError(t, err)
+
+	e := echo.New()
+	e.Use(mw)
+	e.GET("/*", func(c echo.Context) error {
+		return c.String(http.StatusOK, "version "+Version(c))
+	})
+
+	var testCases = []struct {
+		name              string
+		whenPath          string
+		whenVersionHeader string
+		expectStatus      int
+		expectBody        string
+		expectDeprecation string
+		expectSunset      string
+		expectLink        string
+	}{
+		{name: "default version", whenPath: "/users", expectStatus: http.StatusOK, expectBody: "version 2"},
+		{name: "path has precedence over header", whenPath: "/v2/users", whenVersionHeader: "1", expectStatus: http.StatusOK, expectBody: "version 2"},
+		{
+			name:              "deprecated version",
+			whenPath:          "/users",
+			whenVersionHeader: "1",
+			expectStatus:      http.StatusOK,
+			expectBody:        "version 1",
+			expectDeprecation: "@1735689600",
+			expectSunset:      "Wed, 31 Dec 2025 00:00:00 GMT",
+			expectLink:        `<https://example.com/migrate>; rel="deprecation"`,
+		},
+		{
+			name:         "sunset version",
+			whenPath:     "/v0/users",
+			expectStatus: http.StatusGone,
+			expectBody:   `{"message":"API version is no longer available"}` + "\n",
+			expectSunset: "Wed, 01 Jan 2025 00:00:00 GMT",
+		},
+		{
+			name:              "unsupported version",
+			whenPath:          "/users",
+			whenVersionHeader: "9",
+			expectStatus:      http.StatusBadRequest,
+			expectBody:        `{"message":"unsupported API version"}` + "\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.whenPath, nil)
+			if tc.whenVersionHeader != "" {
+				req.Header.Set(HeaderAPIVersion, tc.whenVersionHeader)
+			}
+			rec := httptest.NewRecorder()
+
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectStatus, rec.Code)
+			assert.Equal(t, tc.expectBody, rec.Body.String())
+			assert.Equal(t, tc.expectDeprecation, rec.Header().Get(HeaderDeprecation))
+			assert.Equal(t, tc.expectSunset, rec.Header().Get(HeaderSunset))
+			assert.Equal(t, tc.expectLink, rec.Header().Get("Link"))
+		})
+	}
+
+	expect := `
+# HELP echo_api_version_requests_total How many HTTP requests processed, partitioned by API version and deprecation.
+# TYPE echo_api_version_requests_total counter
+echo_api_version_requests_total{deprecated="false",version="0"} 1
+echo_api_version_requests_total{deprecated="false",version="2"} 2
+echo_api_version_requests_total{deprecated="true",version="1"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expect)))
+}
+
+func TestMiddleware_missingVersion(t *testing.T) {
+	e := echo.New()
+	e.Use(Middleware())
+	e.GET("/users", func(c echo.Context) error {
+		return c.String(http.StatusOK, Version(c)+" "+LabelFunc(c, nil))
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/users", nil)
+	req.Header.Set(echo.HeaderAccept, "application/json; version=v7")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "7 unknown", rec.Body.String()) // versions are not known when Config.Versions is empty
+}
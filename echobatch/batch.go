@@ -0,0 +1,379 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+/*
+Package echobatch provides handler that executes batch of sub-requests through the Echo router and returns combined
+responses.
+
+Request body is JSON array of sub-requests. Each sub-request is dispatched with `Echo.ServeHTTP` so it passes through
+the same middleware chain (authentication, authorization, logging etc.) as a regular request. Headers listed in
+Config.InheritHeaders (by default `Authorization` and `Cookie`) are copied from the batch request to every sub-request
+so sub-requests are executed with the auth context of the caller.
+
+Request:
+```
+
+	[
+		{"method": "GET", "path": "/users/1"},
+		{"method": "POST", "path": "/users", "headers": {"X-Request-ID": "abc"}, "body": {"name": "Jon"}}
+	]
+
+```
+
+Response:
+```
+
+	[
+		{"status": 200, "headers": {"Content-Type": "application/json"}, "body": {"id": 1, "name": "Bob"}},
+		{"status": 201, "headers": {"Content-Type": "application/json"}, "body": {"id": 2, "name": "Jon"}}
+	]
+
+```
+
+Example:
+```
+package main
+
+import (
+
+	"github.com/labstack/echo-contrib/echobatch"
+	"github.com/labstack/echo/v4"
+
+)
+
+	func main() {
+		e := echo.New()
+		e.POST("/batch", echobatch.HandlerWithConfig(echobatch.Config{
+			Echo:        e,
+			Parallelism: 8,
+		}))
+
+		e.Logger.Fatal(e.Start(":1323"))
+	}
+
+```
+*/
+package echobatch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ErrNestedBatch is returned when batch handler is called by sub-request of another batch.
+var ErrNestedBatch = echo.NewHTTPError(http.StatusBadRequest, "nested batch requests are not allowed")
+
+// subRequestKey marks context of sub-requests so batch handler can reject nested batches regardless of the path they
+// were routed by.
+type subRequestKey struct{}
+
+// isForwardingHeader reports if header carries client address or protocol set by proxies. Such headers are used by
+// IP-keyed middlewares (rate limiter, lockdown) so clients must not be able to set them for sub-requests.
+func isForwardingHeader(name string) bool {
+	name = http.CanonicalHeaderKey(name)
+	return name == echo.HeaderXRealIP || name == "Forwarded" || strings.HasPrefix(name, "X-Forwarded-")
+}
+
+// Request is single sub-request of the batch.
+type Request struct {
+	// Method is HTTP method of the sub-request. Defaults to GET.
+	Method string `json:"method"`
+	// Path is request URI (path with optional query string) of the sub-request.
+	Path string `json:"path"`
+	// Headers are sent with the sub-request. They take precedence over inherited headers. Forwarding headers
+	// (`Forwarded`, `X-Forwarded-*`, `X-Real-Ip`) are not allowed, sub-requests always get values of the batch request.
+	Headers map[string]string `json:"headers,omitempty"`
+	// Body is sent as sub-request body. JSON strings are sent as is (unquoted), other JSON values are sent as JSON.
+	Body json.RawMessage `json:"body,omitempty"`
+}
+
+// Response is result of single sub-request of the batch.
+type Response struct {
+	// Status is HTTP status code of the sub-request response.
+	Status int `json:"status"`
+	// Headers are response headers of the sub-request. Multiple values are joined with `, `.
+	Headers map[string]string `json:"headers,omitempty"`
+	// Body is JSON response body as is. Non-JSON response bodies are encoded as JSON string.
+	Body json.RawMessage `json:"body,omitempty"`
+}
+
+// Config defines the config for batch handler.
+type Config struct {
+	// Echo is instance sub-requests are dispatched to.
+	// Required.
+	Echo *echo.Echo
+
+	// MaxRequests is maximum number of sub-requests in single batch.
+	// Defaults to: 20
+	MaxRequests int
+
+	// Parallelism is maximum number of sub-requests executed concurrently.
+	// Defaults to: 4
+	Parallelism int
+
+	// InheritHeaders are headers copied from batch request to every sub-request.
+	// Defaults to: []string{"Authorization", "Cookie"}
+	InheritHeaders []string
+
+	// AllowedMethods restricts HTTP methods usable in sub-requests.
+	// Defaults to: []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"}
+	AllowedMethods []string
+}
+
+// DefaultConfig is the default batch handler config.
+var DefaultConfig = Config{
+	MaxRequests:    20,
+	Parallelism:    4,
+	InheritHeaders: []string{echo.HeaderAuthorization, echo.HeaderCookie},
+	AllowedMethods: []string{
+		http.MethodGet,
+		http.MethodHead,
+		http.MethodPost,
+		http.MethodPut,
+		http.MethodPatch,
+		http.MethodDelete,
+	},
+}
+
+// Handler returns batch handler with default config dispatching sub-requests to given Echo instance.
+func Handler(e *echo.Echo) echo.HandlerFunc {
+	c := DefaultConfig
+	c.Echo = e
+	return HandlerWithConfig(c)
+}
+
+// HandlerWithConfig returns batch handler with config or panics on invalid configuration.
+// See: `Handler()`.
+func HandlerWithConfig(config Config) echo.HandlerFunc {
+	h, err := config.ToHandler()
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
+
+// ToHandler converts configuration to handler or returns an error.
+func (config Config) ToHandler() (echo.HandlerFunc, error) {
+	if config.Echo == nil {
+		return nil, errors.New("echobatch: Echo instance is required")
+	}
+	if config.MaxRequests <= 0 {
+		config.MaxRequests = DefaultConfig.MaxRequests
+	}
+	if config.Parallelism <= 0 {
+		config.Parallelism = DefaultConfig.Parallelism
+	}
+	if config.InheritHeaders == nil {
+		config.InheritHeaders = DefaultConfig.InheritHeaders
+	}
+	if len(config.AllowedMethods) == 0 {
+		config.AllowedMethods = DefaultConfig.AllowedMethods
+	}
+	allowed := make(map[string]struct{}, len(config.AllowedMethods))
+	for _, m := range config.AllowedMethods {
+		allowed[strings.ToUpper(m)] = struct{}{}
+	}
+
+	return func(c echo.Context) error {
+		if c.Request().Context().Value(subRequestKey{}) != nil {
+			return ErrNestedBatch
+		}
+
+		var requests []Request
+		if err := json.NewDecoder(c.Request().Body).Decode(&requests); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid batch request body").SetInternal(err)
+		}
+		if len(requests) == 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "batch must contain at least one request")
+		}
+		if len(requests) > config.MaxRequests {
+			return echo.NewHTTPError(
+				http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("batch must not contain more than %d requests", config.MaxRequests),
+			)
+		}
+
+		for i := range requests {
+			r := &requests[i]
+			r.Method = strings.ToUpper(r.Method)
+			if r.Method == "" {
+				r.Method = http.MethodGet
+			}
+			if _, ok := allowed[r.Method]; !ok {
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("request %d: method %s is not allowed", i, r.Method))
+			}
+			if !strings.HasPrefix(r.Path, "/") {
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("request %d: path must start with /", i))
+			}
+			for k := range r.Headers {
+				if isForwardingHeader(k) {
+					return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("request %d: header %s is not allowed", i, k))
+				}
+			}
+		}
+
+		responses := make([]Response, len(requests))
+		sem := make(chan struct{}, config.Parallelism)
+		wg := sync.WaitGroup{}
+		for i, r := range requests {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(i int, r Request) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				responses[i] = config.execute(c, r)
+			}(i, r)
+		}
+		wg.Wait()
+
+		return c.JSON(http.StatusOK, responses)
+	}, nil
+}
+
+func (config Config) execute(c echo.Context, r Request) (resp Response) {
+	// sub-requests run in their own goroutines, so panic in handler would crash the process when Echo instance does
+	// not use Recover middleware
+	defer func() {
+		if p := recover(); p != nil {
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+			c.Logger().Errorf("echobatch: sub-request %s %s panicked: %v", r.Method, r.Path, p)
+			resp = errorResponse(http.StatusInternalServerError, errors.New(http.StatusText(http.StatusInternalServerError)))
+		}
+	}()
+	parent := c.Request()
+	ctx := context.WithValue(parent.Context(), subRequestKey{}, struct{}{})
+	req, err := http.NewRequestWithContext(ctx, r.Method, r.Path, bytes.NewReader(requestBody(r.Body)))
+	if err != nil {
+		return errorResponse(http.StatusBadRequest, err)
+	}
+	req.RemoteAddr = parent.RemoteAddr
+	req.Host = parent.Host
+	req.TLS = parent.TLS
+	for _, h := range config.InheritHeaders {
+		if v := parent.Header.Values(h); len(v) > 0 {
+			req.Header[http.CanonicalHeaderKey(h)] = v
+		}
+	}
+	for k, v := range parent.Header {
+		if isForwardingHeader(k) {
+			req.Header[k] = v
+		}
+	}
+	if len(r.Body) > 0 && r.Body[0] != '"' {
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	}
+	for k, v := range r.Headers {
+		req.Header.Set(k, v)
+	}
+
+	rec := newRecorder()
+	config.Echo.ServeHTTP(rec, req)
+
+	return Response{
+		Status:  rec.status,
+		Headers: flattenHeader(rec.header),
+		Body:    responseBody(rec.header.Get(echo.HeaderContentType), rec.body.Bytes()),
+	}
+}
+
+// requestBody unquotes JSON string bodies so non-JSON payloads (form data, plain text) can be sent.
+func requestBody(body json.RawMessage) []byte {
+	if len(body) > 0 && body[0] == '"' {
+		var s string
+		if err := json.Unmarshal(body, &s); err == nil {
+			return []byte(s)
+		}
+	}
+	return body
+}
+
+func responseBody(contentType string, body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	if strings.HasPrefix(contentType, echo.MIMEApplicationJSON) && json.Valid(body) {
+		return bytes.TrimSpace(body)
+	}
+	b, _ := json.Marshal(string(body))
+	return b
+}
+
+func errorResponse(status int, err error) Response {
+	b, _ := json.Marshal(map[string]string{"message": err.Error()})
+	return Response{
+		Status:  status,
+		Headers: map[string]string{echo.HeaderContentType: echo.MIMEApplicationJSON},
+		Body:    b,
+	}
+}
+
+func flattenHeader(h http.Header) map[string]string {
+	if len(h) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(h))
+	for k, v := range h {
+		result[k] = strings.Join(v, ", ")
+	}
+	return result
+}
+
+// recorder is minimal http.ResponseWriter capturing sub-request response.
+type recorder struct {
+	header      http.Header
+	body        bytes.Buffer
+	status      int
+	wroteHeader bool
+}
+
+func newRecorder() *recorder {
+	return &recorder{header: http.Header{}, status: http.StatusOK}
+}
+
+func (r *recorder) Header() http.Header {
+	return r.header
+}
+
+func (r *recorder) WriteHeader(code int) {
+	if r.wroteHeader {
+		return
+	}
+	r.status = code
+	r.wroteHeader = true
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.body.Write(b)
+}
+
+// FlushError implements flushing for http.ResponseController. Sub-response is buffered until handler returns so
+// flushing is no-op, this keeps streaming handlers working inside a batch.
+func (r *recorder) FlushError() error {
+	r.wroteHeader = true
+	return nil
+}
+
+// Hijack implements http.Hijacker for http.ResponseController. Sub-requests have no connection to hijack.
+func (r *recorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, http.ErrNotSupported
+}
+
+var (
+	_ http.ResponseWriter = (*recorder)(nil)
+	_ http.Hijacker       = (*recorder)(nil)
+)
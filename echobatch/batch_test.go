@@ -0,0 +1,256 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echobatch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestEcho() *echo.Echo {
+	e := echo.New()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if c.Request().Header.Get(echo.HeaderAuthorization) != "Bearer secret" {
+				return echo.ErrUnauthorized
+			}
+			return next(c)
+		}
+	})
+	e.GET("/users/:id", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, map[string]string{"id": c.Param("id")})
+	})
+	e.POST("/users", func(c echo.Context) error {
+		var u map[string]string
+		if err := c.Bind(&u); err != nil {
+			return err
+		}
+		u["request_id"] = c.Request().Header.Get(echo.HeaderXRequestID)
+		return c.JSON(http.StatusCreated, u)
+	})
+	e.POST("/echo", func(c echo.Context) error {
+		b := new(strings.Builder)
+		_, _ = b.WriteString(c.Request().Header.Get(echo.HeaderContentType) + "|")
+		buf := make([]byte, 64)
+		n, _ := c.Request().Body.Read(buf)
+		_, _ = b.Write(buf[:n])
+		return c.String(http.StatusOK, b.String())
+	})
+	return e
+}
+
+func TestHandler(t *testing.T) {
+	var testCases = []struct {
+		name             string
+		whenBody         string
+		whenAuth         string
+		expectStatus     int
+		expectBody       string
+		expectBodyPrefix string
+	}{
+		{
+			name:         "ok",
+			whenBody:     `[{"method":"get","path":"/users/1"},{"method":"POST","path":"/users","headers":{"X-Request-Id":"abc"},"body":{"name":"Jon"}}]`,
+			whenAuth:     "Bearer secret",
+			expectStatus: http.StatusOK,
+			expectBody: `[{"status":200,"headers":{"Content-Type":"application/json"},"body":{"id":"1"}},` +
+				`{"status":201,"headers":{"Content-Type":"application/json"},"body":{"name":"Jon","request_id":"abc"}}]` + "\n",
+		},
+		{
+			name:         "ok, string body is sent unquoted",
+			whenBody:     `[{"method":"POST","path":"/echo","headers":{"Content-Type":"text/plain"},"body":"hello"}]`,
+			whenAuth:     "Bearer secret",
+			expectStatus: http.StatusOK,
+			expectBody:   `[{"status":200,"headers":{"Content-Type":"text/plain; charset=UTF-8"},"body":"text/plain|hello"}]` + "\n",
+		},
+		{
+			name:         "ok, sub-requests inherit auth context",
+			whenBody:     `[{"path":"/users/1"}]`,
+			whenAuth:     "Bearer wrong",
+			expectStatus: http.StatusOK,
+			expectBody:   `[{"status":401,"headers":{"Content-Type":"application/json"},"body":{"message":"Unauthorized"}}]` + "\n",
+		},
+		{
+			name:         "ok, not found",
+			whenBody:     `[{"path":"/nope"}]`,
+			whenAuth:     "Bearer secret",
+			expectStatus: http.StatusOK,
+			expectBody:   `[{"status":404,"headers":{"Content-Type":"application/json"},"body":{"message":"Not Found"}}]` + "\n",
+		},
+		{
+			name:             "nok, invalid json",
+			whenBody:         `{`,
+			expectStatus:     http.StatusBadRequest,
+			expectBodyPrefix: `{"message":"invalid batch request body"}`,
+		},
+		{
+			name:             "nok, empty batch",
+			whenBody:         `[]`,
+			expectStatus:     http.StatusBadRequest,
+			expectBodyPrefix: `{"message":"batch must contain at least one request"}`,
+		},
+		{
+			name:             "nok, too many requests",
+			whenBody:         `[{"path":"/a"},{"path":"/b"},{"path":"/c"}]`,
+			expectStatus:     http.StatusRequestEntityTooLarge,
+			expectBodyPrefix: `{"message":"batch must not contain more than 2 requests"}`,
+		},
+		{
+			name:             "nok, method not allowed",
+			whenBody:         `[{"method":"CONNECT","path":"/a"}]`,
+			expectStatus:     http.StatusBadRequest,
+			expectBodyPrefix: `{"message":"request 0: method CONNECT is not allowed"}`,
+		},
+		{
+			name:             "nok, forwarding header",
+			whenBody:         `[{"path":"/users/1","headers":{"x-forwarded-for":"10.0.0.1"}}]`,
+			expectStatus:     http.StatusBadRequest,
+			expectBodyPrefix: `{"message":"request 0: header x-forwarded-for is not allowed"}`,
+		},
+		{
+			name:             "nok, relative path",
+			whenBody:         `[{"path":"http://example.com/a"}]`,
+			expectStatus:     http.StatusBadRequest,
+			expectBodyPrefix: `{"message":"request 0: path must start with /"}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := newTestEcho()
+			batch := echo.New()
+			batch.POST("/batch", HandlerWithConfig(Config{Echo: e, MaxRequests: 2}))
+
+			req := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(tc.whenBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			if tc.whenAuth != "" {
+				req.Header.Set(echo.HeaderAuthorization, tc.whenAuth)
+			}
+			rec := httptest.NewRecorder()
+			batch.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectStatus, rec.Code)
+			if tc.expectBodyPrefix != "" {
+				assert.True(t, strings.HasPrefix(rec.Body.String(), tc.expectBodyPrefix), rec.Body.String())
+			} else {
+				assert.Equal(t, tc.expectBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_parallelism(t *testing.T) {
+	var current, max int32
+	e := echo.New()
+	e.GET("/slow", func(c echo.Context) error {
+		n := atomic.AddInt32(&current, 1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&current, -1)
+		return c.NoContent(http.StatusNoContent)
+	})
+	e.POST("/batch", HandlerWithConfig(Config{Echo: e, Parallelism: 2}))
+
+	body := `[` + strings.Repeat(`{"path":"/slow"},`, 5) + `{"path":"/slow"}]`
+	req := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `[`+strings.Repeat(`{"status":204},`, 5)+`{"status":204}]`+"\n", rec.Body.String())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&max))
+}
+
+func TestConfig_ToHandler(t *testing.T) {
+	h, err := Config{}.ToHandler()
+	assert.EqualError(t, err, "echobatch: Echo instance is required")
+	assert.Nil(t, h)
+
+	assert.Panics(t, func() {
+		HandlerWithConfig(Config{})
+	})
+	assert.NotNil(t, Handler(echo.New()))
+}
+
+func TestHandler_nestedBatch(t *testing.T) {
+	e := echo.New()
+	e.Pre(middleware.RemoveTrailingSlash())
+	var calls atomic.Int32
+	batch := Handler(e)
+	e.POST("/batch", func(c echo.Context) error {
+		calls.Add(1)
+		return batch(c)
+	})
+
+	body := `[{"method":"POST","path":"/batch/","body":[{"method":"POST","path":"/batch"}]}]`
+	req := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `[{"status":400,"headers":{"Content-Type":"application/json"},"body":{"message":"nested batch requests are not allowed"}}]`+"\n", rec.Body.String())
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestHandler_subRequestPanicAndStreaming(t *testing.T) {
+	e := echo.New() // no Recover middleware
+	e.GET("/panic", func(c echo.Context) error {
+		panic("boom")
+	})
+	e.GET("/stream", func(c echo.Context) error {
+		c.Response().Header().Set(echo.HeaderContentType, echo.MIMETextPlain)
+		c.Response().WriteHeader(http.StatusOK)
+		_, _ = c.Response().Write([]byte("a"))
+		c.Response().Flush()
+		_, _ = c.Response().Write([]byte("b"))
+		return nil
+	})
+	e.GET("/hijack", func(c echo.Context) error {
+		_, _, err := c.Response().Hijack()
+		return err
+	})
+	e.POST("/batch", Handler(e))
+
+	body := `[{"path":"/panic"},{"path":"/stream"},{"path":"/hijack"}]`
+	req := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `[{"status":500,"headers":{"Content-Type":"application/json"},"body":{"message":"Internal Server Error"}},`+
+		`{"status":200,"headers":{"Content-Type":"text/plain"},"body":"ab"},`+
+		`{"status":500,"headers":{"Content-Type":"application/json"},"body":{"message":"Internal Server Error"}}]`+"\n", rec.Body.String())
+}
+
+func TestHandler_forwardingHeadersFromParent(t *testing.T) {
+	e := echo.New()
+	e.IPExtractor = echo.ExtractIPFromXFFHeader()
+	e.GET("/ip", func(c echo.Context) error {
+		return c.String(http.StatusOK, c.RealIP()+"|"+c.Request().Header.Get("Forwarded"))
+	})
+	e.POST("/batch", Handler(e))
+
+	req := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(`[{"path":"/ip"}]`))
+	req.RemoteAddr = "127.0.0.1:1234"
+	req.Header.Set(echo.HeaderXForwardedFor, "203.0.113.7")
+	req.Header.Set("Forwarded", "for=203.0.113.7")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `[{"status":200,"headers":{"Content-Type":"text/plain; charset=UTF-8"},"body":"203.0.113.7|for=203.0.113.7"}]`+"\n", rec.Body.String())
+}
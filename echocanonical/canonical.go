@@ -0,0 +1,269 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+/*
+Package echocanonical provides canonical URL enforcement middleware.
+
+Middleware redirects requests to canonical URL built by applying configured rules: trailing slash policy, lowercase
+path, duplicate slash collapsing, canonical host and canonical scheme. All rules are applied at once so client is
+redirected only once. GET and HEAD requests are redirected with `301 Moved Permanently` (configurable) and other
+methods with `308 Permanent Redirect` so request method and body are preserved.
+
+Middleware must be registered with `e.Pre` so routing is done with canonical path.
+
+Example:
+```
+package main
+
+import (
+
+	"github.com/labstack/echo-contrib/echocanonical"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+
+)
+
+	func main() {
+		e := echo.New()
+		e.Pre(echocanonical.MiddlewareWithConfig(echocanonical.Config{
+			TrailingSlash:   echocanonical.TrailingSlashRemove,
+			LowercasePath:   true,
+			CollapseSlashes: true,
+			Host:            "www.example.com",
+			Scheme:          "https",
+			Registerer:      prometheus.DefaultRegisterer,
+		}))
+
+		e.Logger.Fatal(e.Start(":1323"))
+	}
+
+```
+*/
+package echocanonical
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const defaultSubsystem = "echo_canonical"
+
+// Rule names used in `rule` label of redirects metric.
+const (
+	RuleTrailingSlash  = "trailing_slash"
+	RuleLowercasePath  = "lowercase_path"
+	RuleCollapseSlash  = "collapse_slashes"
+	RuleCanonicalHost  = "host"
+	RuleCanonicalProto = "scheme"
+)
+
+// TrailingSlashPolicy defines how trailing slash of the path is canonicalized.
+type TrailingSlashPolicy int
+
+const (
+	// TrailingSlashIgnore leaves trailing slash as is.
+	TrailingSlashIgnore TrailingSlashPolicy = iota
+	// TrailingSlashAdd adds trailing slash to the path.
+	TrailingSlashAdd
+	// TrailingSlashRemove removes trailing slash from the path. Root path `/` is never changed.
+	TrailingSlashRemove
+)
+
+// Config defines the config for canonical URL middleware.
+type Config struct {
+	// Skipper defines a function to skip middleware.
+	Skipper middleware.Skipper
+
+	// TrailingSlash is policy for trailing slash of the path.
+	// Defaults to: TrailingSlashIgnore
+	TrailingSlash TrailingSlashPolicy
+
+	// LowercasePath redirects paths containing upper case letters to lower case path.
+	LowercasePath bool
+
+	// CollapseSlashes redirects paths containing duplicate slashes (i.e. `/a//b`) to path with single slashes. Leading
+	// duplicate slashes are always collapsed to prevent open redirects to protocol-relative URLs.
+	CollapseSlashes bool
+
+	// Host is canonical host (with port when not default) that requests for other hosts are redirected to.
+	// Optional.
+	Host string
+
+	// Scheme is canonical scheme (`http` or `https`) that requests with other scheme are redirected to. Scheme of the
+	// request is detected with echo.Context.Scheme and respects `X-Forwarded-Proto` headers.
+	// Optional.
+	Scheme string
+
+	// RedirectCode is status code of redirects for GET and HEAD requests. Other methods are always redirected with
+	// `308 Permanent Redirect`.
+	// Defaults to: http.StatusMovedPermanently (301)
+	RedirectCode int
+
+	// Registerer is used to register redirects counter. When nil no metrics are registered.
+	// Optional.
+	Registerer prometheus.Registerer
+
+	// Namespace is components of the fully-qualified name of the Metric (created by joining Namespace,Subsystem and Name components with "_")
+	// Optional
+	Namespace string
+
+	// Subsystem is components of the fully-qualified name of the Metric (created by joining Namespace,Subsystem and Name components with "_")
+	// Defaults to: "echo_canonical"
+	Subsystem string
+}
+
+// DefaultConfig is the default canonical URL middleware config.
+var DefaultConfig = Config{
+	Skipper:       middleware.DefaultSkipper,
+	TrailingSlash: TrailingSlashRemove,
+	RedirectCode:  http.StatusMovedPermanently,
+}
+
+// Middleware returns canonical URL middleware with default config that removes trailing slashes.
+func Middleware() echo.MiddlewareFunc {
+	return MiddlewareWithConfig(DefaultConfig)
+}
+
+// MiddlewareWithConfig returns canonical URL middleware with config or panics on invalid configuration.
+// See: `Middleware()`.
+func MiddlewareWithConfig(config Config) echo.MiddlewareFunc {
+	mw, err := config.ToMiddleware()
+	if err != nil {
+		panic(err)
+	}
+	return mw
+}
+
+// ToMiddleware converts configuration to middleware or returns an error.
+func (config Config) ToMiddleware() (echo.MiddlewareFunc, error) {
+	if config.Scheme != "" && config.Scheme != "http" && config.Scheme != "https" {
+		return nil, errors.New("echocanonical: Scheme must be `http` or `https`")
+	}
+	if strings.ContainsAny(config.Host, "/?#") {
+		return nil, errors.New("echocanonical: Host must not contain path, query or fragment")
+	}
+	if config.RedirectCode != 0 && (config.RedirectCode < 300 || config.RedirectCode > 399) {
+		return nil, errors.New("echocanonical: RedirectCode must be 3xx status code")
+	}
+	if config.Skipper == nil {
+		config.Skipper = DefaultConfig.Skipper
+	}
+	if config.RedirectCode == 0 {
+		config.RedirectCode = DefaultConfig.RedirectCode
+	}
+	if config.Subsystem == "" {
+		config.Subsystem = defaultSubsystem
+	}
+
+	redirects := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: config.Namespace,
+			Subsystem: config.Subsystem,
+			Name:      "redirects_total",
+			Help:      "How many requests were redirected to canonical URL by rule.",
+		},
+		[]string{"rule"},
+	)
+	if config.Registerer != nil {
+		if err := config.Registerer.Register(redirects); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			req := c.Request()
+			path := req.URL.Path
+			rules := make([]string, 0, 5)
+
+			if p := collapseSlashes(path, config.CollapseSlashes); p != path {
+				path = p
+				rules = append(rules, RuleCollapseSlash)
+			}
+			if config.LowercasePath {
+				if p := strings.ToLower(path); p != path {
+					path = p
+					rules = append(rules, RuleLowercasePath)
+				}
+			}
+			if p := applyTrailingSlash(path, config.TrailingSlash); p != path {
+				path = p
+				rules = append(rules, RuleTrailingSlash)
+			}
+			scheme := c.Scheme()
+			if config.Scheme != "" && scheme != config.Scheme {
+				scheme = config.Scheme
+				rules = append(rules, RuleCanonicalProto)
+			}
+			host := req.Host
+			if config.Host != "" && !strings.EqualFold(host, config.Host) {
+				host = config.Host
+				rules = append(rules, RuleCanonicalHost)
+			}
+			if len(rules) == 0 {
+				return next(c)
+			}
+
+			for _, rule := range rules {
+				redirects.WithLabelValues(rule).Inc()
+			}
+			u := *req.URL
+			u.Path = path
+			u.RawPath = ""
+			location := u.RequestURI()
+			if scheme != c.Scheme() || host != req.Host {
+				location = scheme + "://" + host + location
+			}
+			code := config.RedirectCode
+			if req.Method != http.MethodGet && req.Method != http.MethodHead {
+				code = http.StatusPermanentRedirect
+			}
+			return c.Redirect(code, location)
+		}
+	}, nil
+}
+
+// collapseSlashes replaces duplicate slashes with single slash. When all is false only leading slashes are collapsed.
+func collapseSlashes(path string, all bool) string {
+	if !all {
+		if strings.HasPrefix(path, "//") {
+			return "/" + strings.TrimLeft(path, "/")
+		}
+		return path
+	}
+	if !strings.Contains(path, "//") {
+		return path
+	}
+	var b strings.Builder
+	b.Grow(len(path))
+	for i := 0; i < len(path); i++ {
+		if path[i] == '/' && i > 0 && path[i-1] == '/' {
+			continue
+		}
+		b.WriteByte(path[i])
+	}
+	return b.String()
+}
+
+func applyTrailingSlash(path string, policy TrailingSlashPolicy) string {
+	switch policy {
+	case TrailingSlashAdd:
+		if !strings.HasSuffix(path, "/") {
+			return path + "/"
+		}
+	case TrailingSlashRemove:
+		if len(path) > 1 && strings.HasSuffix(path, "/") {
+			return strings.TrimRight(path, "/")
+		}
+	}
+	return path
+}
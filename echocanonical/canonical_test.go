@@ -0,0 +1,148 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echocanonical
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddlewareWithConfig(t *testing.T) {
+	var testCases = []struct {
+		name           string
+		givenConfig    Config
+		whenMethod     string
+		whenURL        string
+		whenHeaders    map[string]string
+		expectCode     int
+		expectLocation string
+	}{
+		{
+			name:        "ok, canonical url passes",
+			givenConfig: Config{TrailingSlash: TrailingSlashRemove, LowercasePath: true},
+			whenURL:     "/users/1?a=B",
+			expectCode:  http.StatusOK,
+		},
+		{
+			name:           "ok, remove trailing slash keeps query",
+			givenConfig:    Config{TrailingSlash: TrailingSlashRemove},
+			whenURL:        "/users/?a=B",
+			expectCode:     http.StatusMovedPermanently,
+			expectLocation: "/users?a=B",
+		},
+		{
+			name:        "ok, root path is not changed",
+			givenConfig: Config{TrailingSlash: TrailingSlashRemove},
+			whenURL:     "/",
+			expectCode:  http.StatusOK,
+		},
+		{
+			name:           "ok, add trailing slash",
+			givenConfig:    Config{TrailingSlash: TrailingSlashAdd},
+			whenURL:        "/users",
+			expectCode:     http.StatusMovedPermanently,
+			expectLocation: "/users/",
+		},
+		{
+			name:           "ok, all path rules in single redirect",
+			givenConfig:    Config{TrailingSlash: TrailingSlashRemove, LowercasePath: true, CollapseSlashes: true},
+			whenURL:        "/Users//Profile/",
+			expectCode:     http.StatusMovedPermanently,
+			expectLocation: "/users/profile",
+		},
+		{
+			name:           "ok, leading slashes are always collapsed",
+			givenConfig:    Config{TrailingSlash: TrailingSlashAdd},
+			whenURL:        "//evil.com",
+			expectCode:     http.StatusMovedPermanently,
+			expectLocation: "/evil.com/",
+		},
+		{
+			name:           "ok, canonical host and scheme",
+			givenConfig:    Config{Host: "www.example.com", Scheme: "https"},
+			whenURL:        "http://example.com/a?b=c",
+			expectCode:     http.StatusMovedPermanently,
+			expectLocation: "https://www.example.com/a?b=c",
+		},
+		{
+			name:        "ok, scheme from forwarded header",
+			givenConfig: Config{Scheme: "https"},
+			whenURL:     "http://example.com/a",
+			whenHeaders: map[string]string{echo.HeaderXForwardedProto: "https"},
+			expectCode:  http.StatusOK,
+		},
+		{
+			name:           "ok, POST is redirected with 308",
+			givenConfig:    Config{TrailingSlash: TrailingSlashRemove, RedirectCode: http.StatusFound},
+			whenMethod:     http.MethodPost,
+			whenURL:        "/users/",
+			expectCode:     http.StatusPermanentRedirect,
+			expectLocation: "/users",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			e.Pre(MiddlewareWithConfig(tc.givenConfig))
+			e.Any("/*", func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			})
+
+			method := tc.whenMethod
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, tc.whenURL, nil)
+			for k, v := range tc.whenHeaders {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectCode, rec.Code)
+			assert.Equal(t, tc.expectLocation, rec.Header().Get(echo.HeaderLocation))
+		})
+	}
+}
+
+func TestMiddlewareWithConfig_metrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	e := echo.New()
+	e.Pre(MiddlewareWithConfig(Config{
+		TrailingSlash: TrailingSlashRemove,
+		LowercasePath: true,
+		Registerer:    registry,
+	}))
+
+	for _, target := range []string{"/A/", "/b/", "/C"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	expect := `
+# HELP echo_canonical_redirects_total How many requests were redirected to canonical URL by rule.
+# TYPE echo_canonical_redirects_total counter
+echo_canonical_redirects_total{rule="lowercase_path"} 2
+echo_canonical_redirects_total{rule="trailing_slash"} 2
+`
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expect)))
+}
+
+func TestConfig_ToMiddleware_invalid(t *testing.T) {
+	_, err := Config{Scheme: "ftp"}.ToMiddleware()
+	assert.EqualError(t, err, "echocanonical: Scheme must be `http` or `https`")
+
+	_, err = Config{Host: "example.com/path"}.ToMiddleware()
+	assert.EqualError(t, err, "echocanonical: Host must not contain path, query or fragment")
+
+	_, err = Config{RedirectCode: http.StatusOK}.ToMiddleware()
+	assert.EqualError(t, err, "echocanonical: RedirectCode must be 3xx status code")
+}
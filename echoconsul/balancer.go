@@ -0,0 +1,227 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echoconsul
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// BalancerConfig defines the config for Balancer.
+type BalancerConfig struct {
+	// Resolver resolves healthy instances of the service.
+	// Required.
+	Resolver Resolver
+
+	// Service is name of the upstream service.
+	// Required.
+	Service string
+
+	// Scheme is URL scheme of proxy targets.
+	// Defaults to: "http"
+	Scheme string
+
+	// RefreshInterval is interval after which targets are resolved again. Targets are refreshed lazily in background
+	// when balancer is asked for next target, only the first resolve blocks the request. Failed refresh is retried
+	// with exponential backoff starting at 1 second and capped at RefreshInterval. Refresh is not bound to request
+	// context and times out after RefreshInterval.
+	// Defaults to: 10 seconds
+	RefreshInterval time.Duration
+
+	timeNow func() time.Time
+}
+
+// DefaultBalancerConfig is the default Balancer config.
+var DefaultBalancerConfig = BalancerConfig{
+	Scheme:          "http",
+	RefreshInterval: 10 * time.Second,
+}
+
+// Balancer is round-robin `middleware.ProxyBalancer` implementation with targets resolved from service registry.
+// When refreshing targets fails previously resolved targets are kept.
+type Balancer struct {
+	config BalancerConfig
+
+	mu          sync.Mutex
+	targets     []*middleware.ProxyTarget
+	i           int
+	nextRefresh time.Time
+	failures    int
+	// refreshing is closed when refresh in progress finishes, nil when no refresh is in progress.
+	refreshing chan struct{}
+}
+
+const minRefreshBackoff = 1 * time.Second
+
+// NewBalancer creates new Balancer for the service resolved with resolver.
+func NewBalancer(resolver Resolver, service string) *Balancer {
+	c := DefaultBalancerConfig
+	c.Resolver = resolver
+	c.Service = service
+	return NewBalancerWithConfig(c)
+}
+
+// NewBalancerWithConfig creates new Balancer with config or panics on invalid configuration.
+// See: `NewBalancer()`.
+func NewBalancerWithConfig(config BalancerConfig) *Balancer {
+	b, err := config.ToBalancer()
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// ToBalancer converts configuration to Balancer or returns an error.
+func (config BalancerConfig) ToBalancer() (*Balancer, error) {
+	if config.Resolver == nil {
+		return nil, errors.New("echoconsul: balancer requires Resolver")
+	}
+	if config.Service == "" {
+		return nil, errors.New("echoconsul: balancer requires Service")
+	}
+	if config.Scheme == "" {
+		config.Scheme = DefaultBalancerConfig.Scheme
+	}
+	if config.RefreshInterval <= 0 {
+		config.RefreshInterval = DefaultBalancerConfig.RefreshInterval
+	}
+	if config.timeNow == nil {
+		config.timeNow = time.Now
+	}
+	return &Balancer{config: config}, nil
+}
+
+// Refresh resolves targets from service registry.
+func (b *Balancer) Refresh(ctx context.Context) error {
+	instances, err := b.config.Resolver.Resolve(ctx, b.config.Service)
+	if err != nil {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		backoff := b.config.RefreshInterval
+		if b.failures < 30 && minRefreshBackoff<<b.failures < backoff {
+			backoff = minRefreshBackoff << b.failures
+		}
+		b.failures++
+		b.nextRefresh = b.config.timeNow().Add(backoff)
+		return err
+	}
+
+	targets := make([]*middleware.ProxyTarget, 0, len(instances))
+	for _, instance := range instances {
+		host := instance.Address
+		if instance.Port > 0 {
+			host = net.JoinHostPort(instance.Address, strconv.Itoa(instance.Port))
+		}
+		meta := make(echo.Map, len(instance.Meta))
+		for k, v := range instance.Meta {
+			meta[k] = v
+		}
+		targets = append(targets, &middleware.ProxyTarget{
+			Name: instance.ID,
+			URL:  &url.URL{Scheme: b.config.Scheme, Host: host},
+			Meta: meta,
+		})
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.targets = targets
+	b.failures = 0
+	b.nextRefresh = b.config.timeNow().Add(b.config.RefreshInterval)
+	return nil
+}
+
+// refreshInBackground refreshes targets unless refresh is already in progress and returns channel closed when
+// refresh in progress finishes.
+func (b *Balancer) refreshInBackground(logger echo.Logger) <-chan struct{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.refreshing != nil {
+		return b.refreshing
+	}
+	done := make(chan struct{})
+	b.refreshing = done
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), b.config.RefreshInterval)
+		defer cancel()
+		if err := b.Refresh(ctx); err != nil {
+			logger.Errorf("echoconsul: failed to refresh targets: %v", err)
+		}
+		b.mu.Lock()
+		b.refreshing = nil
+		b.mu.Unlock()
+		close(done)
+	}()
+	return done
+}
+
+// AddTarget adds target to the balancer. Added target is kept until next refresh.
+func (b *Balancer) AddTarget(target *middleware.ProxyTarget) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, t := range b.targets {
+		if t.Name == target.Name {
+			return false
+		}
+	}
+	b.targets = append(b.targets, target)
+	return true
+}
+
+// RemoveTarget removes target with given name from the balancer. Removed target is added back on next refresh if it
+// is still registered.
+func (b *Balancer) RemoveTarget(name string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, t := range b.targets {
+		if t.Name == name {
+			b.targets = append(b.targets[:i], b.targets[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns next target in round-robin order or nil when there are no targets.
+func (b *Balancer) Next(c echo.Context) *middleware.ProxyTarget {
+	t, _ := b.NextTarget(c)
+	return t
+}
+
+// NextTarget returns next target in round-robin order or `503 Service Unavailable` error when there are no targets.
+func (b *Balancer) NextTarget(c echo.Context) (*middleware.ProxyTarget, error) {
+	b.mu.Lock()
+	stale := !b.config.timeNow().Before(b.nextRefresh)
+	resolved := b.targets != nil
+	b.mu.Unlock()
+	if stale {
+		done := b.refreshInBackground(c.Logger())
+		if !resolved {
+			select {
+			case <-done:
+			case <-c.Request().Context().Done():
+				return nil, c.Request().Context().Err()
+			}
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.targets) == 0 {
+		return nil, echo.NewHTTPError(http.StatusServiceUnavailable, "no healthy upstream targets")
+	}
+	b.i = b.i % len(b.targets)
+	t := b.targets[b.i]
+	b.i++
+	return t, nil
+}
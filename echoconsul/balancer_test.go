@@ -0,0 +1,214 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echoconsul
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+type staticResolver struct {
+	instances []Instance
+	err       error
+	calls     int
+	block     chan struct{}
+}
+
+func (r *staticResolver) Resolve(ctx context.Context, name string) ([]Instance, error) {
+	r.calls++
+	if r.block != nil {
+		<-r.block
+	}
+	return r.instances, r.err
+}
+
+// waitRefresh waits until refresh in progress (if any) finishes.
+func waitRefresh(b *Balancer) {
+	b.mu.Lock()
+	done := b.refreshing
+	b.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+}
+
+func newTestContext() echo.Context {
+	e := echo.New()
+	return e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+}
+
+func TestBalancer_roundRobin(t *testing.T) {
+	resolver := &staticResolver{instances: []Instance{
+		{ID: "a", Address: "10.0.0.1", Port: 8080, Meta: map[string]string{"zone": "a"}},
+		{ID: "b", Address: "10.0.0.2", Port: 8080},
+	}}
+	b := NewBalancer(resolver, "users")
+	c := newTestContext()
+
+	first := b.Next(c)
+	assert.Equal(t, "a", first.Name)
+	assert.Equal(t, "http://10.0.0.1:8080", first.URL.String())
+	assert.Equal(t, echo.Map{"zone": "a"}, first.Meta)
+	assert.Equal(t, "b", b.Next(c).Name)
+	assert.Equal(t, "a", b.Next(c).Name)
+	assert.Equal(t, 1, resolver.calls)
+}
+
+func TestBalancer_refresh(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	resolver := &staticResolver{instances: []Instance{{ID: "a", Address: "10.0.0.1", Port: 8080}}}
+	b := NewBalancerWithConfig(BalancerConfig{
+		Resolver:        resolver,
+		Service:         "users",
+		Scheme:          "https",
+		RefreshInterval: time.Minute,
+		timeNow:         func() time.Time { return now },
+	})
+	c := newTestContext()
+
+	assert.Equal(t, "https://10.0.0.1:8080", b.Next(c).URL.String())
+
+	resolver.instances = []Instance{{ID: "b", Address: "10.0.0.2", Port: 8080}}
+	assert.Equal(t, "a", b.Next(c).Name) // not refreshed yet
+
+	now = now.Add(time.Minute)
+	b.Next(c) // starts refresh in background
+	waitRefresh(b)
+	assert.Equal(t, "b", b.Next(c).Name)
+
+	resolver.err = errors.New("consul unavailable")
+	now = now.Add(time.Minute)
+	b.Next(c)
+	waitRefresh(b)
+	assert.Equal(t, "b", b.Next(c).Name) // previous targets are kept on error
+	assert.Equal(t, 3, resolver.calls)
+
+	// failed refresh is retried after backoff
+	b.Next(c)
+	waitRefresh(b)
+	assert.Equal(t, 3, resolver.calls)
+	now = now.Add(time.Second)
+	b.Next(c)
+	waitRefresh(b)
+	assert.Equal(t, 4, resolver.calls)
+	now = now.Add(time.Second)
+	b.Next(c)
+	waitRefresh(b)
+	assert.Equal(t, 4, resolver.calls) // backoff has doubled
+}
+
+func TestBalancer_singleRefreshInFlight(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	resolver := &staticResolver{instances: []Instance{{ID: "a", Address: "10.0.0.1", Port: 8080}}}
+	b := NewBalancerWithConfig(BalancerConfig{
+		Resolver:        resolver,
+		Service:         "users",
+		RefreshInterval: time.Minute,
+		timeNow:         func() time.Time { return now },
+	})
+	assert.NoError(t, b.Refresh(context.Background()))
+
+	resolver.block = make(chan struct{})
+	now = now.Add(time.Minute)
+	c := newTestContext()
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, "a", b.Next(c).Name) // requests do not wait for refresh
+	}
+	close(resolver.block)
+	waitRefresh(b)
+	assert.Equal(t, 2, resolver.calls)
+}
+
+func TestBalancer_noTargets(t *testing.T) {
+	b := NewBalancer(&staticResolver{}, "users")
+
+	target, err := b.NextTarget(newTestContext())
+	assert.Nil(t, target)
+	assert.Equal(t, echo.NewHTTPError(http.StatusServiceUnavailable, "no healthy upstream targets"), err)
+}
+
+func TestBalancer_addRemoveTarget(t *testing.T) {
+	b := NewBalancer(&staticResolver{}, "users")
+	assert.NoError(t, b.Refresh(context.Background()))
+
+	target := &middleware.ProxyTarget{Name: "static"}
+	assert.True(t, b.AddTarget(target))
+	assert.False(t, b.AddTarget(target))
+	assert.True(t, b.RemoveTarget("static"))
+	assert.False(t, b.RemoveTarget("static"))
+}
+
+func TestBalancer_proxy(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("upstream:" + r.URL.Path))
+	}))
+	defer upstream.Close()
+
+	resolver := &staticResolver{}
+	b := NewBalancer(resolver, "users")
+	assert.NoError(t, b.Refresh(context.Background()))
+	u := upstream.Listener.Addr().String()
+	b.AddTarget(&middleware.ProxyTarget{Name: "upstream", URL: mustParseURL(t, "http://"+u)})
+
+	e := echo.New()
+	e.Use(middleware.Proxy(b))
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "upstream:/users/1", rec.Body.String())
+}
+
+func TestBalancerConfig_ToBalancer(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		givenConfig BalancerConfig
+		expectErr   string
+	}{
+		{
+			name:        "ok",
+			givenConfig: BalancerConfig{Resolver: &staticResolver{}, Service: "users"},
+		},
+		{
+			name:        "nok, missing resolver",
+			givenConfig: BalancerConfig{Service: "users"},
+			expectErr:   "echoconsul: balancer requires Resolver",
+		},
+		{
+			name:        "nok, missing service",
+			givenConfig: BalancerConfig{Resolver: &staticResolver{}},
+			expectErr:   "echoconsul: balancer requires Service",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := tc.givenConfig.ToBalancer()
+			if tc.expectErr != "" {
+				assert.EqualError(t, err, tc.expectErr)
+				assert.Nil(t, b)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotNil(t, b)
+		})
+	}
+}
+
+func mustParseURL(t *testing.T, raw string) *url.URL {
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
@@ -0,0 +1,332 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+/*
+Package echoconsul provides service registration and discovery integration with Consul.
+
+Register registers Echo server in service registry and returns function deregistering it. Balancer resolves
+healthy instances of upstream service from registry and can be used as load balancer for Echo Proxy middleware.
+
+Example:
+```
+package main
+
+import (
+
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/labstack/echo-contrib/echoconsul"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+
+)
+
+	func main() {
+		e := echo.New()
+		e.GET("/health", func(c echo.Context) error {
+			return c.NoContent(http.StatusOK)
+		})
+
+		consul := echoconsul.NewClient("http://127.0.0.1:8500")
+		deregister, err := echoconsul.Register(context.Background(), consul, echoconsul.Service{
+			ID:      "api-1",
+			Name:    "api",
+			Address: "10.0.0.5",
+			Port:    8080,
+			Check: &echoconsul.Check{
+				HTTP:     "http://10.0.0.5:8080/health",
+				Interval: 10 * time.Second,
+			},
+		})
+		if err != nil {
+			e.Logger.Fatal(err)
+		}
+
+		balancer := echoconsul.NewBalancer(consul, "users")
+		e.Group("/users", middleware.Proxy(balancer))
+
+		go func() {
+			if err := e.Start(":8080"); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				e.Logger.Fatal(err)
+			}
+		}()
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		<-ctx.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := e.Shutdown(ctx); err != nil {
+			e.Logger.Error(err)
+		}
+		if err := deregister(ctx); err != nil {
+			e.Logger.Error(err)
+		}
+	}
+
+```
+*/
+package echoconsul
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// DefaultAddress is address of local Consul agent.
+	DefaultAddress = "http://127.0.0.1:8500"
+
+	headerConsulToken = "X-Consul-Token"
+)
+
+// Service describes service instance registered in service registry.
+type Service struct {
+	// ID is unique identifier of the instance. Defaults to Name when empty.
+	ID string
+	// Name is name of the service.
+	Name string
+	// Address is address the instance is reachable on. When empty registry uses address of the agent node.
+	Address string
+	// Port is port the instance is listening on.
+	Port int
+	// Tags are tags of the instance.
+	Tags []string
+	// Meta is key/value metadata of the instance.
+	Meta map[string]string
+	// Check is health check definition of the instance.
+	// Optional.
+	Check *Check
+}
+
+// Check describes HTTP health check of the service instance.
+type Check struct {
+	// HTTP is URL that registry checks periodically. Instance is healthy when check responds with 2xx status.
+	HTTP string
+	// Interval is interval between checks.
+	Interval time.Duration
+	// Timeout is timeout of single check.
+	// Optional.
+	Timeout time.Duration
+	// DeregisterCriticalServiceAfter is duration after which instance failing the check is deregistered.
+	// Optional.
+	DeregisterCriticalServiceAfter time.Duration
+}
+
+// Instance is healthy service instance resolved from service registry.
+type Instance struct {
+	// ID is unique identifier of the instance.
+	ID string
+	// Address is address of the instance.
+	Address string
+	// Port is port of the instance.
+	Port int
+	// Tags are tags of the instance.
+	Tags []string
+	// Meta is key/value metadata of the instance.
+	Meta map[string]string
+}
+
+// Registrar registers service instances in service registry.
+type Registrar interface {
+	// Register registers service instance.
+	Register(ctx context.Context, service Service) error
+	// Deregister removes service instance with given ID.
+	Deregister(ctx context.Context, serviceID string) error
+}
+
+// Resolver resolves healthy service instances from service registry.
+type Resolver interface {
+	// Resolve returns healthy instances of the service with given name.
+	Resolve(ctx context.Context, name string) ([]Instance, error)
+}
+
+// Client is Registrar and Resolver implementation using Consul HTTP API.
+type Client struct {
+	// Address is address of Consul agent (e.g. `http://127.0.0.1:8500`).
+	Address string
+	// Token is ACL token sent with every request.
+	// Optional.
+	Token string
+	// Datacenter is datacenter services are resolved from.
+	// Optional. Defaults to datacenter of the agent.
+	Datacenter string
+	// Tag limits resolved instances to instances having given tag.
+	// Optional.
+	Tag string
+	// HTTPClient is client used to send requests.
+	// Defaults to: http.DefaultClient
+	HTTPClient *http.Client
+}
+
+// NewClient creates new Consul client for agent with given address. When address is empty DefaultAddress is used.
+func NewClient(address string) *Client {
+	if address == "" {
+		address = DefaultAddress
+	}
+	return &Client{Address: address}
+}
+
+type consulService struct {
+	ID      string            `json:"ID,omitempty"`
+	Name    string            `json:"Name,omitempty"`
+	Service string            `json:"Service,omitempty"`
+	Address string            `json:"Address,omitempty"`
+	Port    int               `json:"Port,omitempty"`
+	Tags    []string          `json:"Tags,omitempty"`
+	Meta    map[string]string `json:"Meta,omitempty"`
+	Check   *consulCheck      `json:"Check,omitempty"`
+}
+
+type consulCheck struct {
+	HTTP                           string `json:"HTTP"`
+	Interval                       string `json:"Interval"`
+	Timeout                        string `json:"Timeout,omitempty"`
+	DeregisterCriticalServiceAfter string `json:"DeregisterCriticalServiceAfter,omitempty"`
+}
+
+type consulServiceEntry struct {
+	Node struct {
+		Address string `json:"Address"`
+	} `json:"Node"`
+	Service consulService `json:"Service"`
+}
+
+// Register implements Registrar.Register using `/v1/agent/service/register` endpoint.
+func (c *Client) Register(ctx context.Context, service Service) error {
+	if service.Name == "" {
+		return errors.New("echoconsul: service name is required")
+	}
+	body := consulService{
+		ID:      service.ID,
+		Name:    service.Name,
+		Address: service.Address,
+		Port:    service.Port,
+		Tags:    service.Tags,
+		Meta:    service.Meta,
+	}
+	if check := service.Check; check != nil {
+		body.Check = &consulCheck{
+			HTTP:     check.HTTP,
+			Interval: check.Interval.String(),
+		}
+		if check.Timeout > 0 {
+			body.Check.Timeout = check.Timeout.String()
+		}
+		if check.DeregisterCriticalServiceAfter > 0 {
+			body.Check.DeregisterCriticalServiceAfter = check.DeregisterCriticalServiceAfter.String()
+		}
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodPut, "/v1/agent/service/register", nil, bytes.NewReader(b), nil)
+}
+
+// Deregister implements Registrar.Deregister using `/v1/agent/service/deregister/:id` endpoint.
+func (c *Client) Deregister(ctx context.Context, serviceID string) error {
+	return c.do(ctx, http.MethodPut, "/v1/agent/service/deregister/"+url.PathEscape(serviceID), nil, nil, nil)
+}
+
+// Resolve implements Resolver.Resolve using `/v1/health/service/:name` endpoint returning only instances passing
+// health checks.
+func (c *Client) Resolve(ctx context.Context, name string) ([]Instance, error) {
+	query := url.Values{"passing": {"true"}}
+	if c.Datacenter != "" {
+		query.Set("dc", c.Datacenter)
+	}
+	if c.Tag != "" {
+		query.Set("tag", c.Tag)
+	}
+
+	var entries []consulServiceEntry
+	if err := c.do(ctx, http.MethodGet, "/v1/health/service/"+url.PathEscape(name), query, nil, &entries); err != nil {
+		return nil, err
+	}
+
+	instances := make([]Instance, 0, len(entries))
+	for _, entry := range entries {
+		address := entry.Service.Address
+		if address == "" {
+			address = entry.Node.Address
+		}
+		instances = append(instances, Instance{
+			ID:      entry.Service.ID,
+			Address: address,
+			Port:    entry.Service.Port,
+			Tags:    entry.Service.Tags,
+			Meta:    entry.Service.Meta,
+		})
+	}
+	return instances, nil
+}
+
+func (c *Client) do(ctx context.Context, method string, path string, query url.Values, body io.Reader, result interface{}) error {
+	u := strings.TrimSuffix(c.Address, "/") + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	}
+	if c.Token != "" {
+		req.Header.Set(headerConsulToken, c.Token)
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("echoconsul: request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("echoconsul: unexpected response status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("echoconsul: failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// Register registers service in registrar and returns function that deregisters it. Deregister should be called after
+// Echo server has been shut down (see `echo.Echo.Shutdown`) so instance stops receiving traffic before process exits.
+func Register(ctx context.Context, registrar Registrar, service Service) (deregister func(ctx context.Context) error, err error) {
+	if service.ID == "" {
+		service.ID = service.Name
+	}
+	if err := registrar.Register(ctx, service); err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context) error {
+		return registrar.Deregister(ctx, service.ID)
+	}, nil
+}
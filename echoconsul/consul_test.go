@@ -0,0 +1,178 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echoconsul
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type consulRequest struct {
+	Method string
+	Path   string
+	Query  string
+	Token  string
+	Body   string
+}
+
+type fakeConsul struct {
+	mu       sync.Mutex
+	requests []consulRequest
+	response string
+	status   int
+}
+
+func (f *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b, _ := io.ReadAll(r.Body)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, consulRequest{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  r.URL.RawQuery,
+		Token:  r.Header.Get(headerConsulToken),
+		Body:   string(b),
+	})
+	if f.status != 0 {
+		w.WriteHeader(f.status)
+	}
+	_, _ = io.WriteString(w, f.response)
+}
+
+func (f *fakeConsul) Requests() []consulRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]consulRequest(nil), f.requests...)
+}
+
+func TestClient_Register(t *testing.T) {
+	fake := &fakeConsul{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.Token = "acl-token"
+	err := client.Register(context.Background(), Service{
+		ID:      "api-1",
+		Name:    "api",
+		Address: "10.0.0.5",
+		Port:    8080,
+		Tags:    []string{"v1"},
+		Check: &Check{
+			HTTP:                           "http://10.0.0.5:8080/health",
+			Interval:                       10 * time.Second,
+			DeregisterCriticalServiceAfter: time.Minute,
+		},
+	})
+	assert.NoError(t, err)
+
+	requests := fake.Requests()
+	assert.Len(t, requests, 1)
+	assert.Equal(t, http.MethodPut, requests[0].Method)
+	assert.Equal(t, "/v1/agent/service/register", requests[0].Path)
+	assert.Equal(t, "acl-token", requests[0].Token)
+	assert.JSONEq(t, `{
+		"ID": "api-1",
+		"Name": "api",
+		"Address": "10.0.0.5",
+		"Port": 8080,
+		"Tags": ["v1"],
+		"Check": {"HTTP": "http://10.0.0.5:8080/health", "Interval": "10s", "DeregisterCriticalServiceAfter": "1m0s"}
+	}`, requests[0].Body)
+}
+
+func TestClient_Register_error(t *testing.T) {
+	fake := &fakeConsul{status: http.StatusForbidden, response: "ACL not found\n"}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	err := NewClient(server.URL).Register(context.Background(), Service{Name: "api"})
+	assert.EqualError(t, err, "echoconsul: unexpected response status 403: ACL not found")
+
+	err = NewClient(server.URL).Register(context.Background(), Service{})
+	assert.EqualError(t, err, "echoconsul: service name is required")
+}
+
+func TestClient_Deregister(t *testing.T) {
+	fake := &fakeConsul{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	assert.NoError(t, NewClient(server.URL).Deregister(context.Background(), "api-1"))
+
+	requests := fake.Requests()
+	assert.Len(t, requests, 1)
+	assert.Equal(t, http.MethodPut, requests[0].Method)
+	assert.Equal(t, "/v1/agent/service/deregister/api-1", requests[0].Path)
+}
+
+func TestClient_Resolve(t *testing.T) {
+	entries := []map[string]interface{}{
+		{
+			"Node":    map[string]interface{}{"Address": "10.0.0.1"},
+			"Service": map[string]interface{}{"ID": "users-1", "Service": "users", "Address": "10.0.0.5", "Port": 8080, "Meta": map[string]string{"zone": "a"}},
+		},
+		{
+			"Node":    map[string]interface{}{"Address": "10.0.0.2"},
+			"Service": map[string]interface{}{"ID": "users-2", "Service": "users", "Port": 8081},
+		},
+	}
+	b, _ := json.Marshal(entries)
+	fake := &fakeConsul{response: string(b)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.Datacenter = "dc1"
+	instances, err := client.Resolve(context.Background(), "users")
+	assert.NoError(t, err)
+	assert.Equal(t, []Instance{
+		{ID: "users-1", Address: "10.0.0.5", Port: 8080, Meta: map[string]string{"zone": "a"}},
+		{ID: "users-2", Address: "10.0.0.2", Port: 8081},
+	}, instances)
+
+	requests := fake.Requests()
+	assert.Equal(t, "/v1/health/service/users", requests[0].Path)
+	assert.Equal(t, "dc=dc1&passing=true", requests[0].Query)
+}
+
+type recordingRegistrar struct {
+	mu           sync.Mutex
+	registered   []Service
+	deregistered []string
+}
+
+func (r *recordingRegistrar) Register(ctx context.Context, service Service) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.registered = append(r.registered, service)
+	return nil
+}
+
+func (r *recordingRegistrar) Deregister(ctx context.Context, serviceID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deregistered = append(r.deregistered, serviceID)
+	return nil
+}
+
+func TestRegister(t *testing.T) {
+	registrar := &recordingRegistrar{}
+
+	deregister, err := Register(context.Background(), registrar, Service{Name: "api", Port: 8080})
+	assert.NoError(t, err)
+	assert.Equal(t, []Service{{ID: "api", Name: "api", Port: 8080}}, registrar.registered)
+	assert.Empty(t, registrar.deregistered)
+
+	assert.NoError(t, deregister(context.Background()))
+	assert.Equal(t, []string{"api"}, registrar.deregistered)
+}
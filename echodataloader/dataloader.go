@@ -0,0 +1,381 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+/*
+Package echodataloader provides per-request batching and caching of data fetches (dataloader pattern).
+
+Loader collects keys requested with Load during short wait window (or until MaxBatchSize keys are collected) and
+fetches them with a single call to batch function. Loaded values are cached for the duration of the request, so the
+same key is fetched at most once per request. Cache is stored on echo.Context and is discarded with the request.
+
+Loads issued from the same goroutine one after another wait for the window each, so sequential handler code should
+use LoadMany to fetch all keys with one batch.
+
+Example:
+```
+package main
+
+import (
+
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo-contrib/echodataloader"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+
+)
+
+	func main() {
+		e := echo.New()
+
+		users := echodataloader.MustNew(echodataloader.Config[int64, User]{
+			Name: "users",
+			Batch: func(ctx context.Context, ids []int64) (map[int64]User, error) {
+				return db.UsersByIDs(ctx, ids) // SELECT ... WHERE id IN (...)
+			},
+			MaxBatchSize: 100,
+			Registerer:   prometheus.DefaultRegisterer,
+		})
+
+		e.GET("/orders", func(c echo.Context) error {
+			orders, _ := db.Orders(c.Request().Context())
+			ids := make([]int64, 0, len(orders))
+			for _, o := range orders {
+				ids = append(ids, o.UserID)
+			}
+			byID, err := users.LoadMany(c, ids)
+			if err != nil {
+				return err
+			}
+			// ...
+			return c.JSON(http.StatusOK, orders)
+		})
+
+		e.Logger.Fatal(e.Start(":1323"))
+	}
+
+```
+*/
+package echodataloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	defaultSubsystem = "echo_dataloader"
+	defaultName      = "default"
+	defaultWait      = time.Millisecond
+)
+
+// ErrNotFound is returned by Load when batch function did not return value for the key.
+var ErrNotFound = errors.New("echodataloader: value not found")
+
+// BatchFunc fetches values for given keys. Keys are unique within single call. Keys missing from returned map result
+// in ErrNotFound. When error is returned all keys of the batch fail with that error.
+type BatchFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+// Config defines the config for Loader.
+type Config[K comparable, V any] struct {
+	// Name identifies loader in metrics `loader` label.
+	// Defaults to: "default"
+	Name string
+
+	// Batch fetches values for collected keys.
+	// Required.
+	Batch BatchFunc[K, V]
+
+	// MaxBatchSize is maximum number of keys passed to single Batch call. Zero means no limit.
+	MaxBatchSize int
+
+	// Wait is how long Load waits for other keys to be requested before batch is fetched.
+	// Defaults to: 1ms
+	Wait time.Duration
+
+	// Registerer is used to register loader metrics. When nil no metrics are registered.
+	// Optional.
+	Registerer prometheus.Registerer
+
+	// Namespace is components of the fully-qualified name of the Metric (created by joining Namespace,Subsystem and Name components with "_")
+	// Optional
+	Namespace string
+
+	// Subsystem is components of the fully-qualified name of the Metric (created by joining Namespace,Subsystem and Name components with "_")
+	// Defaults to: "echo_dataloader"
+	Subsystem string
+}
+
+// Loader batches and caches fetches of values by key for the duration of the request. Loader is safe for concurrent
+// use and should be created once and shared by all requests.
+type Loader[K comparable, V any] struct {
+	batch        BatchFunc[K, V]
+	maxBatchSize int
+	wait         time.Duration
+	contextKey   string
+
+	// mu guards creation of per-request state
+	mu sync.Mutex
+
+	batchSize     prometheus.Observer
+	batchDuration prometheus.Observer
+	batchErrors   prometheus.Counter
+	cacheHits     prometheus.Counter
+}
+
+type result[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+type batch[K comparable, V any] struct {
+	ctx     context.Context
+	keys    []K
+	results []*result[V]
+}
+
+// requestState is per-request cache and currently collected batch of the loader.
+type requestState[K comparable, V any] struct {
+	mu      sync.Mutex
+	cache   map[K]*result[V]
+	pending *batch[K, V]
+}
+
+// MustNew creates Loader with config or panics on invalid configuration.
+func MustNew[K comparable, V any](config Config[K, V]) *Loader[K, V] {
+	l, err := New(config)
+	if err != nil {
+		panic(err)
+	}
+	return l
+}
+
+// New creates Loader with config or returns an error on invalid configuration.
+func New[K comparable, V any](config Config[K, V]) (*Loader[K, V], error) {
+	if config.Batch == nil {
+		return nil, errors.New("echodataloader: Batch function is required")
+	}
+	if config.MaxBatchSize < 0 {
+		return nil, errors.New("echodataloader: MaxBatchSize must not be negative")
+	}
+	if config.Name == "" {
+		config.Name = defaultName
+	}
+	if config.Wait <= 0 {
+		config.Wait = defaultWait
+	}
+	if config.Subsystem == "" {
+		config.Subsystem = defaultSubsystem
+	}
+
+	constLabels := prometheus.Labels{"loader": config.Name}
+	batchSize := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace:   config.Namespace,
+		Subsystem:   config.Subsystem,
+		Name:        "batch_size",
+		Help:        "Number of keys fetched with single batch call.",
+		ConstLabels: constLabels,
+		Buckets:     []float64{1, 2, 5, 10, 25, 50, 100, 250, 500, 1000},
+	})
+	batchDuration := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace:   config.Namespace,
+		Subsystem:   config.Subsystem,
+		Name:        "batch_duration_seconds",
+		Help:        "Duration of batch calls.",
+		ConstLabels: constLabels,
+		Buckets:     prometheus.DefBuckets,
+	})
+	batchErrors := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace:   config.Namespace,
+		Subsystem:   config.Subsystem,
+		Name:        "batch_errors_total",
+		Help:        "How many batch calls returned an error.",
+		ConstLabels: constLabels,
+	})
+	cacheHits := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace:   config.Namespace,
+		Subsystem:   config.Subsystem,
+		Name:        "cache_hits_total",
+		Help:        "How many loads were served from per-request cache.",
+		ConstLabels: constLabels,
+	})
+	if config.Registerer != nil {
+		for _, c := range []prometheus.Collector{batchSize, batchDuration, batchErrors, cacheHits} {
+			if err := config.Registerer.Register(c); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	l := &Loader[K, V]{
+		batch:         config.Batch,
+		maxBatchSize:  config.MaxBatchSize,
+		wait:          config.Wait,
+		batchSize:     batchSize,
+		batchDuration: batchDuration,
+		batchErrors:   batchErrors,
+		cacheHits:     cacheHits,
+	}
+	l.contextKey = fmt.Sprintf("echodataloader.%p", l)
+	return l, nil
+}
+
+// Load returns value for the key. Key is fetched together with other keys requested during wait window.
+func (l *Loader[K, V]) Load(c echo.Context, key K) (V, error) {
+	ctx := c.Request().Context()
+	st := l.state(c)
+	r, full := l.enqueue(ctx, st, key)
+	if full != nil {
+		l.dispatch(st, full)
+	}
+	return l.await(ctx, r)
+}
+
+// LoadMany returns values for the keys. Keys not already cached are fetched immediately without waiting for the wait
+// window. Returns first error encountered. Keys with ErrNotFound are omitted from the map without an error.
+func (l *Loader[K, V]) LoadMany(c echo.Context, keys []K) (map[K]V, error) {
+	ctx := c.Request().Context()
+	st := l.state(c)
+
+	results := make([]*result[V], len(keys))
+	for i, key := range keys {
+		r, full := l.enqueue(ctx, st, key)
+		if full != nil {
+			go l.dispatch(st, full)
+		}
+		results[i] = r
+	}
+	st.mu.Lock()
+	pending := st.pending
+	st.pending = nil
+	st.mu.Unlock()
+	if pending != nil {
+		l.dispatch(st, pending)
+	}
+
+	values := make(map[K]V, len(keys))
+	for i, r := range results {
+		v, err := l.await(ctx, r)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		values[keys[i]] = v
+	}
+	return values, nil
+}
+
+// Prime adds value for the key to the request cache unless key is already cached or being loaded.
+func (l *Loader[K, V]) Prime(c echo.Context, key K, value V) {
+	st := l.state(c)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if _, ok := st.cache[key]; ok {
+		return
+	}
+	r := &result[V]{done: make(chan struct{}), value: value}
+	close(r.done)
+	st.cache[key] = r
+}
+
+// Clear removes the key from the request cache so next Load fetches it again.
+func (l *Loader[K, V]) Clear(c echo.Context, key K) {
+	st := l.state(c)
+	st.mu.Lock()
+	delete(st.cache, key)
+	st.mu.Unlock()
+}
+
+func (l *Loader[K, V]) state(c echo.Context) *requestState[K, V] {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if st, ok := c.Get(l.contextKey).(*requestState[K, V]); ok {
+		return st
+	}
+	st := &requestState[K, V]{cache: map[K]*result[V]{}}
+	c.Set(l.contextKey, st)
+	return st
+}
+
+// enqueue returns cached or new result for the key. When new key fills the pending batch, the batch is returned and
+// must be dispatched by the caller.
+func (l *Loader[K, V]) enqueue(ctx context.Context, st *requestState[K, V], key K) (*result[V], *batch[K, V]) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if r, ok := st.cache[key]; ok {
+		l.cacheHits.Inc()
+		return r, nil
+	}
+	r := &result[V]{done: make(chan struct{})}
+	st.cache[key] = r
+
+	b := st.pending
+	if b == nil {
+		b = &batch[K, V]{ctx: ctx}
+		st.pending = b
+		time.AfterFunc(l.wait, func() {
+			st.mu.Lock()
+			if st.pending != b {
+				st.mu.Unlock()
+				return // already dispatched
+			}
+			st.pending = nil
+			st.mu.Unlock()
+			l.dispatch(st, b)
+		})
+	}
+	b.keys = append(b.keys, key)
+	b.results = append(b.results, r)
+	if l.maxBatchSize > 0 && len(b.keys) >= l.maxBatchSize {
+		st.pending = nil
+		return r, b
+	}
+	return r, nil
+}
+
+func (l *Loader[K, V]) dispatch(st *requestState[K, V], b *batch[K, V]) {
+	l.batchSize.Observe(float64(len(b.keys)))
+	start := time.Now()
+	values, err := l.batch(b.ctx, b.keys)
+	l.batchDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		l.batchErrors.Inc()
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for i, key := range b.keys {
+		r := b.results[i]
+		if err != nil {
+			r.err = err
+		} else if v, ok := values[key]; ok {
+			r.value = v
+		} else {
+			r.err = ErrNotFound
+		}
+		if r.err != nil && st.cache[key] == r {
+			delete(st.cache, key) // failed loads are not cached so they can be retried
+		}
+		close(r.done)
+	}
+}
+
+func (l *Loader[K, V]) await(ctx context.Context, r *result[V]) (V, error) {
+	select {
+	case <-r.done:
+		return r.value, r.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
@@ -0,0 +1,142 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echodataloader
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+type batchRecorder struct {
+	mu      sync.Mutex
+	batches [][]int
+}
+
+func (r *batchRecorder) batch(ctx context.Context, keys []int) (map[int]string, error) {
+	r.mu.Lock()
+	sorted := append([]int(nil), keys...)
+	sort.Ints(sorted)
+	r.batches = append(r.batches, sorted)
+	r.mu.Unlock()
+
+	values := make(map[int]string, len(keys))
+	for _, k := range keys {
+		if k < 0 {
+			continue
+		}
+		values[k] = "v" + string(rune('0'+k))
+	}
+	return values, nil
+}
+
+func newContext() echo.Context {
+	return echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+}
+
+func TestNew_invalidConfig(t *testing.T) {
+	_, err := New(Config[int, string]{})
+	assert.EqualError(t, err, "echodataloader: Batch function is required")
+
+	_, err = New(Config[int, string]{Batch: (&batchRecorder{}).batch, MaxBatchSize: -1})
+	assert.EqualError(t, err, "echodataloader: MaxBatchSize must not be negative")
+}
+
+func TestLoader_Load_batchesConcurrentLoads(t *testing.T) {
+	rec := &batchRecorder{}
+	l := MustNew(Config[int, string]{Batch: rec.batch, Wait: 20 * time.Millisecond})
+	c := newContext()
+
+	var wg sync.WaitGroup
+	results := make([]string, 3)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			v, err := l.Load(c, i+1)
+			assert.NoError(t, err)
+			results[i] = v
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, []string{"v1", "v2", "v3"}, results)
+	assert.Equal(t, [][]int{{1, 2, 3}}, rec.batches)
+
+	// cached for the request
+	v, err := l.Load(c, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "v2", v)
+	assert.Len(t, rec.batches, 1)
+
+	// other request has its own cache
+	_, err = l.Load(newContext(), 2)
+	assert.NoError(t, err)
+	assert.Len(t, rec.batches, 2)
+}
+
+func TestLoader_LoadMany(t *testing.T) {
+	rec := &batchRecorder{}
+	registry := prometheus.NewRegistry()
+	l := MustNew(Config[int, string]{Name: "items", Batch: rec.batch, MaxBatchSize: 2, Registerer: registry})
+	c := newContext()
+	l.Prime(c, 5, "primed")
+
+	values, err := l.LoadMany(c, []int{1, 2, 3, -1, 5, 1})
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]string{1: "v1", 2: "v2", 3: "v3", 5: "primed"}, values)
+
+	sort.Slice(rec.batches, func(i, j int) bool { return rec.batches[i][0] < rec.batches[j][0] })
+	assert.Equal(t, [][]int{{-1, 3}, {1, 2}}, rec.batches)
+
+	// not found keys are not cached
+	_, err = l.Load(c, -1)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Len(t, rec.batches, 3)
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(l.cacheHits)) // primed 5 and duplicate 1
+	count, err := testutil.GatherAndCount(registry, "echo_dataloader_batch_size")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestLoader_Load_batchError(t *testing.T) {
+	calls := 0
+	expectErr := errors.New("db down")
+	l := MustNew(Config[int, string]{Batch: func(ctx context.Context, keys []int) (map[int]string, error) {
+		calls++
+		return nil, expectErr
+	}})
+	c := newContext()
+
+	_, err := l.Load(c, 1)
+	assert.ErrorIs(t, err, expectErr)
+	_, err = l.Load(c, 1)
+	assert.ErrorIs(t, err, expectErr)
+	assert.Equal(t, 2, calls) // errors are not cached
+	assert.Equal(t, 2.0, testutil.ToFloat64(l.batchErrors))
+}
+
+func TestLoader_Clear(t *testing.T) {
+	rec := &batchRecorder{}
+	l := MustNew(Config[int, string]{Batch: rec.batch})
+	c := newContext()
+
+	_, err := l.Load(c, 1)
+	assert.NoError(t, err)
+	l.Clear(c, 1)
+	_, err = l.Load(c, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]int{{1}, {1}}, rec.batches)
+}
@@ -0,0 +1,326 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+/*
+Package echodbtx provides middleware that manages per-request database/sql transaction.
+
+Transaction is started lazily when handler first asks for it with Tx function. When handler chain returns without error
+and response status is 2xx the transaction is committed, otherwise (error, non 2xx status or panic) it is rolled back.
+Transaction is committed just before response status is written, so when commit fails client receives
+`500 Internal Server Error` (with handler response body discarded) instead of success response.
+
+Example:
+```
+package main
+
+import (
+
+	"database/sql"
+	"net/http"
+
+	"github.com/labstack/echo-contrib/echodbtx"
+	"github.com/labstack/echo/v4"
+
+)
+
+	func main() {
+		db, _ := sql.Open("postgres", "...")
+
+		e := echo.New()
+		e.Use(echodbtx.Middleware(db))
+
+		e.POST("/users", func(c echo.Context) error {
+			tx, err := echodbtx.Tx(c)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(c.Request().Context(), "INSERT INTO users(name) VALUES($1)", c.FormValue("name")); err != nil {
+				return err
+			}
+			return c.NoContent(http.StatusCreated)
+		})
+
+		e.Logger.Fatal(e.Start(":1323"))
+	}
+
+```
+*/
+package echodbtx
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	contextKey = "_echodbtx_transaction"
+
+	defaultSubsystem = "echo_dbtx"
+)
+
+const (
+	resultCommit        = "commit"
+	resultCommitError   = "commit_error"
+	resultRollback      = "rollback"
+	resultRollbackError = "rollback_error"
+)
+
+// ErrMiddlewareMissing is returned from Tx when transaction middleware was not executed for the request.
+var ErrMiddlewareMissing = errors.New("echodbtx: transaction middleware is not registered for this request")
+
+// Config defines the config for transaction middleware.
+type Config struct {
+	// Skipper defines a function to skip middleware.
+	Skipper middleware.Skipper
+
+	// DB is database that transactions are started with.
+	// Required.
+	DB *sql.DB
+
+	// TxOptions are options used to start transaction.
+	// Optional.
+	TxOptions *sql.TxOptions
+
+	// CommitFunc decides if transaction should be committed. It is called just before handler writes response status
+	// (with nil error) or after handler chain has returned when nothing was written. Transaction is always rolled back
+	// on panic.
+	// Defaults to: commit when handler returned no error and response status is 2xx.
+	CommitFunc func(c echo.Context, err error) bool
+
+	// Registerer is used to register counter of committed and rolled back transactions. When nil no metrics are registered.
+	// Optional.
+	Registerer prometheus.Registerer
+
+	// Namespace is components of the fully-qualified name of the Metric (created by joining Namespace,Subsystem and Name components with "_")
+	// Optional
+	Namespace string
+
+	// Subsystem is components of the fully-qualified name of the Metric (created by joining Namespace,Subsystem and Name components with "_")
+	// Defaults to: "echo_dbtx"
+	Subsystem string
+}
+
+// DefaultConfig is the default transaction middleware config.
+var DefaultConfig = Config{
+	Skipper:    middleware.DefaultSkipper,
+	CommitFunc: defaultCommitFunc,
+}
+
+type transaction struct {
+	db         *sql.DB
+	txOptions  *sql.TxOptions
+	c          echo.Context
+	tx         *sql.Tx
+	savepoints int
+	// finished is set when transaction was committed before response was written.
+	finished  bool
+	commitErr error
+}
+
+// Middleware returns transaction middleware using given database.
+func Middleware(db *sql.DB) echo.MiddlewareFunc {
+	c := DefaultConfig
+	c.DB = db
+	return MiddlewareWithConfig(c)
+}
+
+// MiddlewareWithConfig returns transaction middleware with config or panics on invalid configuration.
+// See: `Middleware()`.
+func MiddlewareWithConfig(config Config) echo.MiddlewareFunc {
+	mw, err := config.ToMiddleware()
+	if err != nil {
+		panic(err)
+	}
+	return mw
+}
+
+// ToMiddleware converts configuration to middleware or returns an error.
+func (config Config) ToMiddleware() (echo.MiddlewareFunc, error) {
+	if config.DB == nil {
+		return nil, errors.New("echodbtx: middleware requires DB")
+	}
+	if config.Skipper == nil {
+		config.Skipper = DefaultConfig.Skipper
+	}
+	if config.CommitFunc == nil {
+		config.CommitFunc = DefaultConfig.CommitFunc
+	}
+	if config.Subsystem == "" {
+		config.Subsystem = defaultSubsystem
+	}
+
+	transactions := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: config.Namespace,
+			Subsystem: config.Subsystem,
+			Name:      "transactions_total",
+			Help:      "How many request transactions were finished, partitioned by result.",
+		},
+		[]string{"result"},
+	)
+	if config.Registerer != nil {
+		if err := config.Registerer.Register(transactions); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) (err error) {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			t := &transaction{
+				db:        config.DB,
+				txOptions: config.TxOptions,
+				c:         c,
+			}
+			c.Set(contextKey, t)
+			c.Response().Before(func() {
+				if t.tx == nil || t.finished || !config.CommitFunc(c, nil) {
+					return
+				}
+				t.finished = true
+				if cErr := t.tx.Commit(); cErr != nil {
+					transactions.WithLabelValues(resultCommitError).Inc()
+					t.commitErr = fmt.Errorf("echodbtx: failed to commit transaction: %w", cErr)
+					res := c.Response()
+					res.Status = http.StatusInternalServerError
+					res.Header().Del(echo.HeaderContentType)
+					res.Header().Del(echo.HeaderContentLength)
+					res.Writer = &discardBodyWriter{ResponseWriter: res.Writer}
+					return
+				}
+				transactions.WithLabelValues(resultCommit).Inc()
+			})
+			defer func() {
+				if r := recover(); r != nil {
+					if t.tx != nil && !t.finished {
+						transactions.WithLabelValues(rollbackResult(t.tx.Rollback())).Inc()
+					}
+					panic(r)
+				}
+			}()
+
+			err = next(c)
+			if t.tx == nil {
+				return err
+			}
+			if t.finished {
+				if t.commitErr != nil {
+					return errors.Join(err, t.commitErr)
+				}
+				return err
+			}
+
+			if !config.CommitFunc(c, err) {
+				transactions.WithLabelValues(rollbackResult(t.tx.Rollback())).Inc()
+				return err
+			}
+			if cErr := t.tx.Commit(); cErr != nil {
+				transactions.WithLabelValues(resultCommitError).Inc()
+				if err == nil {
+					err = fmt.Errorf("echodbtx: failed to commit transaction: %w", cErr)
+				}
+				return err
+			}
+			transactions.WithLabelValues(resultCommit).Inc()
+			return err
+		}
+	}, nil
+}
+
+// discardBodyWriter discards response body written by handler after transaction commit has failed.
+type discardBodyWriter struct {
+	http.ResponseWriter
+}
+
+func (w *discardBodyWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (w *discardBodyWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Tx returns transaction for the current request. Transaction is started on the first call.
+func Tx(c echo.Context) (*sql.Tx, error) {
+	t, ok := c.Get(contextKey).(*transaction)
+	if !ok {
+		return nil, ErrMiddlewareMissing
+	}
+	if t.tx != nil {
+		return t.tx, nil
+	}
+	tx, err := t.db.BeginTx(t.c.Request().Context(), t.txOptions)
+	if err != nil {
+		return nil, fmt.Errorf("echodbtx: failed to begin transaction: %w", err)
+	}
+	t.tx = tx
+	return tx, nil
+}
+
+// MustTx returns transaction for the current request or panics when transaction can not be started.
+func MustTx(c echo.Context) *sql.Tx {
+	tx, err := Tx(c)
+	if err != nil {
+		panic(err)
+	}
+	return tx
+}
+
+// Savepoint executes fn within savepoint of the request transaction. When fn returns an error or panics changes done
+// after savepoint are rolled back and outer transaction can continue. Savepoints can be nested.
+func Savepoint(c echo.Context, fn func(tx *sql.Tx) error) (err error) {
+	tx, err := Tx(c)
+	if err != nil {
+		return err
+	}
+	t := c.Get(contextKey).(*transaction)
+
+	t.savepoints++
+	name := fmt.Sprintf("echodbtx_sp_%d", t.savepoints)
+	ctx := c.Request().Context()
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("echodbtx: failed to create savepoint: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			_, _ = tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+			panic(r)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		if _, rErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); rErr != nil {
+			return errors.Join(err, fmt.Errorf("echodbtx: failed to rollback to savepoint: %w", rErr))
+		}
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("echodbtx: failed to release savepoint: %w", err)
+	}
+	return nil
+}
+
+func rollbackResult(err error) string {
+	if err != nil {
+		return resultRollbackError
+	}
+	return resultRollback
+}
+
+func defaultCommitFunc(c echo.Context, err error) bool {
+	if err != nil {
+		return false
+	}
+	status := c.Response().Status
+	return status >= 200 && status < 300
+}
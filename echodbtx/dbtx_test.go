@@ -0,0 +1,288 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echodbtx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingDriver is minimal database/sql driver that records executed statements and transaction outcomes.
+type recordingDriver struct {
+	mu         sync.Mutex
+	log        []string
+	failCommit bool
+}
+
+func (d *recordingDriver) record(s string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.log = append(d.log, s)
+}
+
+func (d *recordingDriver) Log() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.log...)
+}
+
+func (d *recordingDriver) Open(name string) (driver.Conn, error) {
+	return &recordingConn{d: d}, nil
+}
+
+type recordingConn struct {
+	d *recordingDriver
+}
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *recordingConn) Close() error { return nil }
+
+func (c *recordingConn) Begin() (driver.Tx, error) {
+	c.d.record("BEGIN")
+	return &recordingTx{d: c.d}, nil
+}
+
+func (c *recordingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.d.record(query)
+	if strings.Contains(query, "fail") {
+		return nil, errors.New("exec failed")
+	}
+	return driver.RowsAffected(1), nil
+}
+
+type recordingTx struct {
+	d *recordingDriver
+}
+
+func (t *recordingTx) Commit() error {
+	t.d.record("COMMIT")
+	if t.d.failCommit {
+		return errors.New("serialization failure")
+	}
+	return nil
+}
+
+func (t *recordingTx) Rollback() error {
+	t.d.record("ROLLBACK")
+	return nil
+}
+
+var driverCount int
+
+func openTestDB(t *testing.T) (*sql.DB, *recordingDriver) {
+	d := &recordingDriver{}
+	driverCount++
+	name := fmt.Sprintf("echodbtx_test_%d", driverCount)
+	sql.Register(name, d)
+	db, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db, d
+}
+
+func TestMiddleware(t *testing.T) {
+	var testCases = []struct {
+		name          string
+		whenHandler   echo.HandlerFunc
+		expectLog     []string
+		expectCode    int
+		expectResults map[string]float64
+	}{
+		{
+			name: "ok, commit on 2xx",
+			whenHandler: func(c echo.Context) error {
+				tx := MustTx(c)
+				if _, err := tx.Exec("INSERT 1"); err != nil {
+					return err
+				}
+				return c.String(http.StatusCreated, "OK")
+			},
+			expectLog:     []string{"BEGIN", "INSERT 1", "COMMIT"},
+			expectCode:    http.StatusCreated,
+			expectResults: map[string]float64{resultCommit: 1},
+		},
+		{
+			name: "ok, no transaction when not requested",
+			whenHandler: func(c echo.Context) error {
+				return c.String(http.StatusOK, "OK")
+			},
+			expectLog:  nil,
+			expectCode: http.StatusOK,
+		},
+		{
+			name: "ok, rollback on error",
+			whenHandler: func(c echo.Context) error {
+				tx := MustTx(c)
+				if _, err := tx.Exec("INSERT 1"); err != nil {
+					return err
+				}
+				return echo.NewHTTPError(http.StatusBadRequest, "bad")
+			},
+			expectLog:     []string{"BEGIN", "INSERT 1", "ROLLBACK"},
+			expectCode:    http.StatusBadRequest,
+			expectResults: map[string]float64{resultRollback: 1},
+		},
+		{
+			name: "ok, rollback on non 2xx status",
+			whenHandler: func(c echo.Context) error {
+				MustTx(c)
+				return c.String(http.StatusConflict, "NOK")
+			},
+			expectLog:     []string{"BEGIN", "ROLLBACK"},
+			expectCode:    http.StatusConflict,
+			expectResults: map[string]float64{resultRollback: 1},
+		},
+		{
+			name: "ok, savepoint rolled back and outer transaction committed",
+			whenHandler: func(c echo.Context) error {
+				err := Savepoint(c, func(tx *sql.Tx) error {
+					_, err := tx.Exec("INSERT fail")
+					return err
+				})
+				assert.EqualError(t, err, "exec failed")
+
+				err = Savepoint(c, func(tx *sql.Tx) error {
+					return Savepoint(c, func(tx *sql.Tx) error {
+						_, err := tx.Exec("INSERT 2")
+						return err
+					})
+				})
+				assert.NoError(t, err)
+				return c.NoContent(http.StatusNoContent)
+			},
+			expectLog: []string{
+				"BEGIN",
+				"SAVEPOINT echodbtx_sp_1",
+				"INSERT fail",
+				"ROLLBACK TO SAVEPOINT echodbtx_sp_1",
+				"SAVEPOINT echodbtx_sp_2",
+				"SAVEPOINT echodbtx_sp_3",
+				"INSERT 2",
+				"RELEASE SAVEPOINT echodbtx_sp_3",
+				"RELEASE SAVEPOINT echodbtx_sp_2",
+				"COMMIT",
+			},
+			expectCode:    http.StatusNoContent,
+			expectResults: map[string]float64{resultCommit: 1},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, d := openTestDB(t)
+			registry := prometheus.NewRegistry()
+
+			e := echo.New()
+			e.Use(MiddlewareWithConfig(Config{DB: db, Registerer: registry}))
+			e.GET("/", tc.whenHandler)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectCode, rec.Code)
+			assert.Equal(t, tc.expectLog, d.Log())
+
+			mfs, err := registry.Gather()
+			assert.NoError(t, err)
+			results := map[string]float64{}
+			for _, mf := range mfs {
+				for _, m := range mf.GetMetric() {
+					results[m.GetLabel()[0].GetValue()] = m.GetCounter().GetValue()
+				}
+			}
+			if tc.expectResults == nil {
+				tc.expectResults = map[string]float64{}
+			}
+			assert.Equal(t, tc.expectResults, results)
+		})
+	}
+}
+
+func TestMiddleware_commitFailure(t *testing.T) {
+	db, d := openTestDB(t)
+	d.failCommit = true
+	registry := prometheus.NewRegistry()
+
+	var handlerErr error
+	e := echo.New()
+	e.HTTPErrorHandler = func(err error, c echo.Context) {
+		handlerErr = err
+	}
+	e.Use(MiddlewareWithConfig(Config{DB: db, Registerer: registry, Subsystem: "app"}))
+	e.POST("/", func(c echo.Context) error {
+		if _, err := MustTx(c).Exec("INSERT 1"); err != nil {
+			return err
+		}
+		return c.JSON(http.StatusCreated, map[string]int{"id": 1})
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Empty(t, rec.Body.String())
+	assert.Empty(t, rec.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, []string{"BEGIN", "INSERT 1", "COMMIT"}, d.Log())
+	assert.EqualError(t, handlerErr, "echodbtx: failed to commit transaction: serialization failure")
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP app_transactions_total How many request transactions were finished, partitioned by result.
+# TYPE app_transactions_total counter
+app_transactions_total{result="commit_error"} 1
+`)))
+}
+
+func TestMiddleware_rollbackOnPanic(t *testing.T) {
+	db, d := openTestDB(t)
+	registry := prometheus.NewRegistry()
+
+	mw := MiddlewareWithConfig(Config{DB: db, Registerer: registry, Subsystem: "app"})
+	h := mw(func(c echo.Context) error {
+		MustTx(c)
+		panic("boom")
+	})
+
+	e := echo.New()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+
+	assert.PanicsWithValue(t, "boom", func() {
+		_ = h(c)
+	})
+	assert.Equal(t, []string{"BEGIN", "ROLLBACK"}, d.Log())
+	assert.Equal(t, 1, testutil.CollectAndCount(registry, "app_transactions_total"))
+}
+
+func TestTx_middlewareMissing(t *testing.T) {
+	e := echo.New()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+
+	tx, err := Tx(c)
+	assert.Nil(t, tx)
+	assert.ErrorIs(t, err, ErrMiddlewareMissing)
+}
+
+func TestMiddlewareWithConfig_panicsWithoutDB(t *testing.T) {
+	assert.PanicsWithError(t, "echodbtx: middleware requires DB", func() {
+		MiddlewareWithConfig(Config{})
+	})
+}
@@ -0,0 +1,313 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echoedgecache
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const defaultSubsystem = "echo_edgecache"
+
+// Purger purges CDN cache entries associated with given keys.
+type Purger interface {
+	Purge(ctx context.Context, keys []string) error
+}
+
+// PurgeError is returned by purgers when purge API responds with unexpected status code.
+type PurgeError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *PurgeError) Error() string {
+	return fmt.Sprintf("echoedgecache: purge request failed with status %d: %s", e.StatusCode, e.Body)
+}
+
+// Temporary returns true when purge request may succeed when retried (rate limited or server errors).
+func (e *PurgeError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+}
+
+// FastlyPurger purges Fastly cache by surrogate keys.
+// See: https://www.fastly.com/documentation/reference/api/purging/#bulk-purge-tag
+type FastlyPurger struct {
+	// ServiceID is Fastly service ID.
+	ServiceID string
+	// Token is Fastly API token with purge permission.
+	Token string
+	// SoftPurge marks content as stale instead of removing it.
+	SoftPurge bool
+	// BaseURL defaults to `https://api.fastly.com`.
+	BaseURL string
+	// HTTPClient defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// Purge implements Purger.
+func (p *FastlyPurger) Purge(ctx context.Context, keys []string) error {
+	baseURL := p.BaseURL
+	if baseURL == "" {
+		baseURL = "https://api.fastly.com"
+	}
+	header := http.Header{}
+	header.Set("Fastly-Key", p.Token)
+	if p.SoftPurge {
+		header.Set("Fastly-Soft-Purge", "1")
+	}
+	// Fastly accepts at most 256 keys in single request
+	return inBatches(keys, 256, func(batch []string) error {
+		return doJSON(ctx, p.HTTPClient, http.MethodPost, baseURL+"/service/"+p.ServiceID+"/purge", header,
+			map[string][]string{"surrogate_keys": batch})
+	})
+}
+
+// CloudflarePurger purges Cloudflare cache by cache tags.
+// See: https://developers.cloudflare.com/api/operations/zone-purge
+type CloudflarePurger struct {
+	// ZoneID is Cloudflare zone identifier.
+	ZoneID string
+	// Token is Cloudflare API token with cache purge permission.
+	Token string
+	// BaseURL defaults to `https://api.cloudflare.com/client/v4`.
+	BaseURL string
+	// HTTPClient defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// Purge implements Purger.
+func (p *CloudflarePurger) Purge(ctx context.Context, keys []string) error {
+	baseURL := p.BaseURL
+	if baseURL == "" {
+		baseURL = "https://api.cloudflare.com/client/v4"
+	}
+	header := http.Header{}
+	header.Set(echo.HeaderAuthorization, "Bearer "+p.Token)
+	// Cloudflare accepts at most 30 tags in single request
+	return inBatches(keys, 30, func(batch []string) error {
+		return doJSON(ctx, p.HTTPClient, http.MethodPost, baseURL+"/zones/"+p.ZoneID+"/purge_cache", header,
+			map[string][]string{"tags": batch})
+	})
+}
+
+// HTTPPurger purges cache with generic HTTP API by sending `{"keys": [...]}` JSON body.
+type HTTPPurger struct {
+	// URL is purge API endpoint.
+	URL string
+	// Method defaults to POST.
+	Method string
+	// Header is sent with every purge request, for example for authentication.
+	Header http.Header
+	// HTTPClient defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// Purge implements Purger.
+func (p *HTTPPurger) Purge(ctx context.Context, keys []string) error {
+	method := p.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	return doJSON(ctx, p.HTTPClient, method, p.URL, p.Header, map[string][]string{"keys": keys})
+}
+
+func inBatches(keys []string, size int, fn func(batch []string) error) error {
+	for start := 0; start < len(keys); start += size {
+		end := min(start+size, len(keys))
+		if err := fn(keys[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func doJSON(ctx context.Context, client *http.Client, method string, url string, header http.Header, body any) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(echo.HeaderAccept, echo.MIMEApplicationJSON)
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		resBody, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return &PurgeError{StatusCode: res.StatusCode, Body: string(resBody)}
+	}
+	_, _ = io.Copy(io.Discard, res.Body)
+	return nil
+}
+
+// PurgeConfig defines the config for PurgeClient.
+type PurgeConfig struct {
+	// Purger sends purge requests to CDN.
+	// Required.
+	Purger Purger
+
+	// Provider is used as `provider` label value of purge metrics.
+	// Defaults to: "default"
+	Provider string
+
+	// MaxRetries is number of retries of failed purge requests. Requests are retried on network errors and on
+	// PurgeError with temporary status code. Negative value disables retries.
+	// Defaults to: 3
+	MaxRetries int
+
+	// RetryBackoff is delay before the first retry. Every next retry doubles the delay.
+	// Defaults to: 200 milliseconds
+	RetryBackoff time.Duration
+
+	// Registerer is used to register purge counter and duration histogram. When nil no metrics are registered.
+	// Optional.
+	Registerer prometheus.Registerer
+
+	// Namespace is components of the fully-qualified name of the Metric (created by joining Namespace,Subsystem and Name components with "_")
+	// Optional
+	Namespace string
+
+	// Subsystem is components of the fully-qualified name of the Metric (created by joining Namespace,Subsystem and Name components with "_")
+	// Defaults to: "echo_edgecache"
+	Subsystem string
+}
+
+// DefaultPurgeConfig is the default PurgeClient config.
+var DefaultPurgeConfig = PurgeConfig{
+	Provider:     "default",
+	MaxRetries:   3,
+	RetryBackoff: 200 * time.Millisecond,
+}
+
+// PurgeClient purges keys with configured Purger retrying failed requests and recording metrics.
+type PurgeClient struct {
+	config   PurgeConfig
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// NewPurgeClient creates PurgeClient or panics on invalid configuration.
+func NewPurgeClient(config PurgeConfig) *PurgeClient {
+	c, err := config.ToPurgeClient()
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// ToPurgeClient converts configuration to PurgeClient or returns an error.
+func (config PurgeConfig) ToPurgeClient() (*PurgeClient, error) {
+	if config.Purger == nil {
+		return nil, errors.New("echoedgecache: purge config requires Purger")
+	}
+	if config.Provider == "" {
+		config.Provider = DefaultPurgeConfig.Provider
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = DefaultPurgeConfig.MaxRetries
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = DefaultPurgeConfig.RetryBackoff
+	}
+	if config.Subsystem == "" {
+		config.Subsystem = defaultSubsystem
+	}
+
+	c := &PurgeClient{
+		config: config,
+		requests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: config.Namespace,
+				Subsystem: config.Subsystem,
+				Name:      "purge_requests_total",
+				Help:      "How many purge requests were made, partitioned by provider and result.",
+			},
+			[]string{"provider", "result"},
+		),
+		duration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: config.Namespace,
+				Subsystem: config.Subsystem,
+				Name:      "purge_duration_seconds",
+				Help:      "The purge latencies in seconds including retries.",
+				Buckets:   prometheus.DefBuckets,
+			},
+			[]string{"provider"},
+		),
+	}
+	if config.Registerer != nil {
+		for _, col := range []prometheus.Collector{c.requests, c.duration} {
+			if err := config.Registerer.Register(col); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return c, nil
+}
+
+// Purge purges given keys. Empty and duplicate keys are ignored.
+func (c *PurgeClient) Purge(ctx context.Context, keys ...string) error {
+	keys = uniqueKeys(append([]string(nil), keys...))
+	if len(keys) == 0 {
+		return nil
+	}
+
+	start := time.Now()
+	defer func() {
+		c.duration.WithLabelValues(c.config.Provider).Observe(time.Since(start).Seconds())
+	}()
+
+	backoff := c.config.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := c.config.Purger.Purge(ctx, keys)
+		if err == nil {
+			c.requests.WithLabelValues(c.config.Provider, "success").Inc()
+			return nil
+		}
+		if attempt >= c.config.MaxRetries || !isRetryable(err) || ctx.Err() != nil {
+			c.requests.WithLabelValues(c.config.Provider, "failure").Inc()
+			return err
+		}
+		c.requests.WithLabelValues(c.config.Provider, "retry").Inc()
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			c.requests.WithLabelValues(c.config.Provider, "failure").Inc()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+func isRetryable(err error) bool {
+	var purgeErr *PurgeError
+	if errors.As(err, &purgeErr) {
+		return purgeErr.Temporary()
+	}
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,225 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echoedgecache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordedRequest struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   map[string][]string
+}
+
+func newPurgeServer(t *testing.T, statuses ...int) (*httptest.Server, *[]recordedRequest) {
+	var requests []recordedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string][]string
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		requests = append(requests, recordedRequest{Method: r.Method, Path: r.URL.Path, Header: r.Header, Body: body})
+
+		status := http.StatusOK
+		if len(statuses) > 0 {
+			status = statuses[0]
+			statuses = statuses[1:]
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestFastlyPurger_Purge(t *testing.T) {
+	server, requests := newPurgeServer(t)
+	p := &FastlyPurger{ServiceID: "svc", Token: "secret", SoftPurge: true, BaseURL: server.URL}
+
+	keys := make([]string, 300)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("k%d", i)
+	}
+	assert.NoError(t, p.Purge(context.Background(), keys))
+
+	assert.Len(t, *requests, 2)
+	r := (*requests)[0]
+	assert.Equal(t, http.MethodPost, r.Method)
+	assert.Equal(t, "/service/svc/purge", r.Path)
+	assert.Equal(t, "secret", r.Header.Get("Fastly-Key"))
+	assert.Equal(t, "1", r.Header.Get("Fastly-Soft-Purge"))
+	assert.Len(t, r.Body["surrogate_keys"], 256)
+	assert.Len(t, (*requests)[1].Body["surrogate_keys"], 44)
+}
+
+func TestCloudflarePurger_Purge(t *testing.T) {
+	server, requests := newPurgeServer(t)
+	p := &CloudflarePurger{ZoneID: "zone", Token: "secret", BaseURL: server.URL}
+
+	assert.NoError(t, p.Purge(context.Background(), []string{"a", "b"}))
+
+	assert.Len(t, *requests, 1)
+	r := (*requests)[0]
+	assert.Equal(t, "/zones/zone/purge_cache", r.Path)
+	assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+	assert.Equal(t, []string{"a", "b"}, r.Body["tags"])
+}
+
+func TestHTTPPurger_Purge(t *testing.T) {
+	server, requests := newPurgeServer(t, http.StatusForbidden)
+	p := &HTTPPurger{URL: server.URL + "/purge", Method: http.MethodDelete, Header: http.Header{"X-Api-Key": []string{"k"}}}
+
+	err := p.Purge(context.Background(), []string{"a"})
+
+	var purgeErr *PurgeError
+	assert.ErrorAs(t, err, &purgeErr)
+	assert.Equal(t, http.StatusForbidden, purgeErr.StatusCode)
+	assert.Equal(t, `{"status":"ok"}`, purgeErr.Body)
+	assert.False(t, purgeErr.Temporary())
+
+	r := (*requests)[0]
+	assert.Equal(t, http.MethodDelete, r.Method)
+	assert.Equal(t, "k", r.Header.Get("X-Api-Key"))
+	assert.Equal(t, []string{"a"}, r.Body["keys"])
+}
+
+type purgerFunc func(ctx context.Context, keys []string) error
+
+func (f purgerFunc) Purge(ctx context.Context, keys []string) error {
+	return f(ctx, keys)
+}
+
+func TestPurgeClient_Purge(t *testing.T) {
+	var testCases = []struct {
+		name           string
+		givenErrors    []error
+		givenRetries   int
+		whenKeys       []string
+		expectCalls    int
+		expectErr      string
+		expectResults  map[string]float64
+		expectNoMetric bool
+	}{
+		{
+			name:          "ok",
+			whenKeys:      []string{"a", "a", ""},
+			expectCalls:   1,
+			expectResults: map[string]float64{"success": 1},
+		},
+		{
+			name:           "ok, no keys",
+			whenKeys:       []string{""},
+			expectCalls:    0,
+			expectNoMetric: true,
+		},
+		{
+			name:          "ok, retried temporary errors",
+			givenErrors:   []error{&PurgeError{StatusCode: http.StatusServiceUnavailable}, errors.New("connection reset")},
+			whenKeys:      []string{"a"},
+			expectCalls:   3,
+			expectResults: map[string]float64{"retry": 2, "success": 1},
+		},
+		{
+			name:          "nok, permanent error is not retried",
+			givenErrors:   []error{&PurgeError{StatusCode: http.StatusUnauthorized, Body: "denied"}},
+			whenKeys:      []string{"a"},
+			expectCalls:   1,
+			expectErr:     "echoedgecache: purge request failed with status 401: denied",
+			expectResults: map[string]float64{"failure": 1},
+		},
+		{
+			name:          "nok, retries exhausted",
+			givenErrors:   []error{errors.New("e1"), errors.New("e2"), errors.New("e3")},
+			givenRetries:  2,
+			whenKeys:      []string{"a"},
+			expectCalls:   3,
+			expectErr:     "e3",
+			expectResults: map[string]float64{"retry": 2, "failure": 1},
+		},
+		{
+			name:          "nok, retries disabled",
+			givenErrors:   []error{errors.New("e1")},
+			givenRetries:  -1,
+			whenKeys:      []string{"a"},
+			expectCalls:   1,
+			expectErr:     "e1",
+			expectResults: map[string]float64{"failure": 1},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			errs := tc.givenErrors
+			reg := prometheus.NewRegistry()
+			client := NewPurgeClient(PurgeConfig{
+				Purger: purgerFunc(func(ctx context.Context, keys []string) error {
+					calls++
+					assert.Equal(t, []string{"a"}, keys)
+					if len(errs) > 0 {
+						err := errs[0]
+						errs = errs[1:]
+						return err
+					}
+					return nil
+				}),
+				Provider:     "test",
+				MaxRetries:   tc.givenRetries,
+				RetryBackoff: time.Millisecond,
+				Registerer:   reg,
+			})
+
+			err := client.Purge(context.Background(), tc.whenKeys...)
+			if tc.expectErr != "" {
+				assert.EqualError(t, err, tc.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expectCalls, calls)
+
+			if tc.expectNoMetric {
+				assert.Equal(t, 0, testutil.CollectAndCount(reg, "echo_edgecache_purge_requests_total"))
+				return
+			}
+			for result, expect := range tc.expectResults {
+				assert.Equal(t, expect, testutil.ToFloat64(client.requests.WithLabelValues("test", result)), result)
+			}
+			assert.Equal(t, 1, testutil.CollectAndCount(reg, "echo_edgecache_purge_duration_seconds"))
+		})
+	}
+}
+
+func TestPurgeClient_Purge_contextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	client := NewPurgeClient(PurgeConfig{
+		Purger: purgerFunc(func(ctx context.Context, keys []string) error {
+			cancel()
+			return &PurgeError{StatusCode: http.StatusBadGateway}
+		}),
+		RetryBackoff: time.Hour,
+	})
+
+	err := client.Purge(ctx, "a")
+	assert.ErrorContains(t, err, "status 502")
+}
+
+func TestPurgeConfig_ToPurgeClient(t *testing.T) {
+	c, err := PurgeConfig{}.ToPurgeClient()
+	assert.EqualError(t, err, "echoedgecache: purge config requires Purger")
+	assert.Nil(t, c)
+
+	assert.Panics(t, func() {
+		NewPurgeClient(PurgeConfig{})
+	})
+}
@@ -0,0 +1,187 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+/*
+Package echoedgecache provides middleware emitting surrogate key (cache tag) response headers and clients for purging
+CDN caches by these keys.
+
+Keys are derived per request with Config.KeysFunc and can be added by handlers with AddKeys. They are written as
+`Surrogate-Key` (Fastly, generic surrogate caches) and `Cache-Tag` (Cloudflare, Akamai) headers just before the response
+is committed.
+
+Example:
+```
+package main
+
+import (
+
+	"net/http"
+
+	"github.com/labstack/echo-contrib/echoedgecache"
+	"github.com/labstack/echo/v4"
+
+)
+
+	func main() {
+		e := echo.New()
+		e.Use(echoedgecache.MiddlewareWithConfig(echoedgecache.Config{
+			KeysFunc: func(c echo.Context) []string {
+				return []string{"route:" + c.Path()}
+			},
+		}))
+
+		purger := echoedgecache.NewPurgeClient(echoedgecache.PurgeConfig{
+			Purger: &echoedgecache.FastlyPurger{ServiceID: "SU1Z0isxPaozGVKXdv0eY", Token: "token"},
+		})
+
+		e.GET("/products/:id", func(c echo.Context) error {
+			echoedgecache.AddKeys(c, "product:"+c.Param("id"))
+			return c.String(http.StatusOK, "product")
+		})
+		e.PUT("/products/:id", func(c echo.Context) error {
+			// ... update product
+			return purger.Purge(c.Request().Context(), "product:"+c.Param("id"))
+		})
+
+		e.Logger.Fatal(e.Start(":1323"))
+	}
+
+```
+*/
+package echoedgecache
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+const keysContextKey = "_echoedgecache_keys"
+
+const (
+	// HeaderSurrogateKey is space separated list of surrogate keys used by Fastly and generic surrogate caches.
+	HeaderSurrogateKey = "Surrogate-Key"
+	// HeaderCacheTag is comma separated list of cache tags used by Cloudflare and Akamai.
+	HeaderCacheTag = "Cache-Tag"
+)
+
+// KeyHeader is response header keys are written to.
+type KeyHeader struct {
+	// Name is header name.
+	Name string
+	// Separator is used to join keys.
+	Separator string
+}
+
+// Config defines the config for surrogate key middleware.
+type Config struct {
+	// Skipper defines a function to skip middleware.
+	Skipper middleware.Skipper
+
+	// KeysFunc returns keys for the request. Keys added with AddKeys are appended to these keys.
+	// Optional.
+	KeysFunc func(c echo.Context) []string
+
+	// Headers are response headers keys are written to.
+	// Defaults to: `Surrogate-Key` separated with space and `Cache-Tag` separated with comma
+	Headers []KeyHeader
+
+	// OnlySuccessful writes headers only for responses with status code less than 400 so error responses are not
+	// associated with keys.
+	OnlySuccessful bool
+}
+
+// DefaultConfig is the default surrogate key middleware config.
+var DefaultConfig = Config{
+	Skipper: middleware.DefaultSkipper,
+	Headers: []KeyHeader{
+		{Name: HeaderSurrogateKey, Separator: " "},
+		{Name: HeaderCacheTag, Separator: ","},
+	},
+}
+
+// Middleware returns surrogate key middleware with default config. Keys are added by handlers with AddKeys.
+func Middleware() echo.MiddlewareFunc {
+	return MiddlewareWithConfig(DefaultConfig)
+}
+
+// MiddlewareWithConfig returns surrogate key middleware with config or panics on invalid configuration.
+// See: `Middleware()`.
+func MiddlewareWithConfig(config Config) echo.MiddlewareFunc {
+	mw, err := config.ToMiddleware()
+	if err != nil {
+		panic(err)
+	}
+	return mw
+}
+
+// ToMiddleware converts configuration to middleware or returns an error.
+func (config Config) ToMiddleware() (echo.MiddlewareFunc, error) {
+	if config.Skipper == nil {
+		config.Skipper = DefaultConfig.Skipper
+	}
+	if len(config.Headers) == 0 {
+		config.Headers = DefaultConfig.Headers
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			c.Response().Before(func() {
+				if config.OnlySuccessful && c.Response().Status >= http.StatusBadRequest {
+					return
+				}
+				var keys []string
+				if config.KeysFunc != nil {
+					keys = append(keys, config.KeysFunc(c)...)
+				}
+				if added, ok := c.Get(keysContextKey).([]string); ok {
+					keys = append(keys, added...)
+				}
+				keys = uniqueKeys(keys)
+				if len(keys) == 0 {
+					return
+				}
+				for _, h := range config.Headers {
+					c.Response().Header().Set(h.Name, strings.Join(keys, h.Separator))
+				}
+			})
+			return next(c)
+		}
+	}, nil
+}
+
+// AddKeys adds keys to the response of the current request. Keys are written to headers when response is committed
+// so AddKeys must be called before response body is written.
+func AddKeys(c echo.Context, keys ...string) {
+	existing, _ := c.Get(keysContextKey).([]string)
+	c.Set(keysContextKey, append(existing, keys...))
+}
+
+// Keys returns keys added to the current request with AddKeys.
+func Keys(c echo.Context) []string {
+	keys, _ := c.Get(keysContextKey).([]string)
+	return keys
+}
+
+// uniqueKeys removes empty and duplicate keys and keys containing whitespace or separators while preserving order.
+func uniqueKeys(keys []string) []string {
+	seen := make(map[string]struct{}, len(keys))
+	result := keys[:0]
+	for _, k := range keys {
+		if k == "" || strings.ContainsAny(k, " \t\r\n,") {
+			continue
+		}
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		result = append(result, k)
+	}
+	return result
+}
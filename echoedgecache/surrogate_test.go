@@ -0,0 +1,101 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echoedgecache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddlewareWithConfig(t *testing.T) {
+	var testCases = []struct {
+		name                string
+		givenConfig         Config
+		whenPath            string
+		expectSurrogateKey  string
+		expectCacheTag      string
+		expectCustomHeaders map[string]string
+	}{
+		{
+			name: "ok, route keys and handler keys",
+			givenConfig: Config{
+				KeysFunc: func(c echo.Context) []string {
+					return []string{"route:" + c.Path(), "products"}
+				},
+			},
+			whenPath:           "/products/1",
+			expectSurrogateKey: "route:/products/:id products product:1",
+			expectCacheTag:     "route:/products/:id,products,product:1",
+		},
+		{
+			name:               "ok, only handler keys, duplicates and invalid keys removed",
+			givenConfig:        Config{},
+			whenPath:           "/products/1",
+			expectSurrogateKey: "products product:1",
+			expectCacheTag:     "products,product:1",
+		},
+		{
+			name:        "ok, no keys",
+			givenConfig: Config{},
+			whenPath:    "/empty",
+		},
+		{
+			name:        "ok, only successful skips error responses",
+			givenConfig: Config{OnlySuccessful: true},
+			whenPath:    "/error",
+		},
+		{
+			name: "ok, custom headers",
+			givenConfig: Config{
+				Headers: []KeyHeader{{Name: "Edge-Cache-Tag", Separator: ","}},
+			},
+			whenPath:            "/products/1",
+			expectCustomHeaders: map[string]string{"Edge-Cache-Tag": "products,product:1"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(MiddlewareWithConfig(tc.givenConfig))
+			e.GET("/products/:id", func(c echo.Context) error {
+				AddKeys(c, "products", "product:"+c.Param("id"), "", "bad key", "products")
+				return c.String(http.StatusOK, "ok")
+			})
+			e.GET("/empty", func(c echo.Context) error {
+				return c.String(http.StatusOK, "ok")
+			})
+			e.GET("/error", func(c echo.Context) error {
+				AddKeys(c, "error")
+				return c.String(http.StatusInternalServerError, "error")
+			})
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.whenPath, nil))
+
+			if tc.expectCustomHeaders != nil {
+				for k, v := range tc.expectCustomHeaders {
+					assert.Equal(t, v, rec.Header().Get(k))
+				}
+				assert.Empty(t, rec.Header().Get(HeaderSurrogateKey))
+				return
+			}
+			assert.Equal(t, tc.expectSurrogateKey, rec.Header().Get(HeaderSurrogateKey))
+			assert.Equal(t, tc.expectCacheTag, rec.Header().Get(HeaderCacheTag))
+		})
+	}
+}
+
+func TestKeys(t *testing.T) {
+	e := echo.New()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+
+	assert.Nil(t, Keys(c))
+	AddKeys(c, "a")
+	AddKeys(c, "b", "c")
+	assert.Equal(t, []string{"a", "b", "c"}, Keys(c))
+}
@@ -0,0 +1,296 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+/*
+Package echoformtoken provides middleware issuing signed single-use form tokens to prevent duplicate form submissions
+and replay of tokens issued for other forms.
+
+Token is bound to a form ID (route path by default), expires after configured TTL and is signed with HMAC-SHA256.
+When form is submitted, token nonce is recorded in Store so the same token can not be submitted twice. Unlike CSRF
+tokens, form tokens are single-use and specific to a form.
+
+Example:
+```
+package main
+
+import (
+
+	"html/template"
+	"net/http"
+
+	"github.com/labstack/echo-contrib/echoformtoken"
+	"github.com/labstack/echo/v4"
+
+)
+
+	var form = template.Must(template.New("form").Parse(`<form method="post">{{.Token}}<button>Pay</button></form>`))
+
+	func main() {
+		e := echo.New()
+		e.Use(echoformtoken.Middleware([]byte("secret-key-of-32-bytes-or-more..")))
+
+		e.GET("/checkout", func(c echo.Context) error {
+			return form.Execute(c.Response(), map[string]any{"Token": echoformtoken.Field(c)})
+		})
+		e.POST("/checkout", func(c echo.Context) error {
+			// token was validated and consumed by middleware
+			return c.String(http.StatusOK, "paid")
+		})
+
+		e.Logger.Fatal(e.Start(":1323"))
+	}
+
+```
+*/
+package echoformtoken
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+const (
+	contextKey = "_echoformtoken_issuer"
+
+	// DefaultFieldName is default name of the hidden form field containing the token.
+	DefaultFieldName = "_form_token"
+	// HeaderFormToken is default header the token is read from when form field is missing (i.e. for AJAX submissions).
+	HeaderFormToken = "X-Form-Token"
+
+	nonceSize   = 16
+	payloadSize = 8 + nonceSize
+)
+
+var (
+	// ErrTokenMissing is returned when submitted request does not contain form token.
+	ErrTokenMissing = echo.NewHTTPError(http.StatusForbidden, "missing form token")
+	// ErrTokenInvalid is returned when form token is malformed, has invalid signature, was issued for another form or
+	// has expired.
+	ErrTokenInvalid = echo.NewHTTPError(http.StatusForbidden, "invalid form token")
+	// ErrDuplicateSubmission is returned when form token was already used.
+	ErrDuplicateSubmission = echo.NewHTTPError(http.StatusConflict, "form already submitted")
+)
+
+// Config defines the config for form token middleware.
+type Config struct {
+	// Skipper defines a function to skip middleware.
+	Skipper middleware.Skipper
+
+	// Secret is HMAC key used to sign tokens. Should be at least 32 bytes long.
+	// Required.
+	Secret []byte
+
+	// TTL is duration token is valid for after it was issued.
+	// Defaults to: 1 hour
+	TTL time.Duration
+
+	// Store records used token nonces. Use shared store (i.e. Redis backed) when application runs multiple instances.
+	// Defaults to: NewMemoryStore()
+	Store Store
+
+	// FormIDFunc returns ID of the form token is issued for or validated against. Both issuing and submitting requests
+	// must resolve to the same form ID.
+	// Defaults to: route path (`c.Path()`)
+	FormIDFunc func(c echo.Context) string
+
+	// FieldName is name of the form field the token is read from.
+	// Defaults to: "_form_token"
+	FieldName string
+
+	// HeaderName is name of the header the token is read from when form field is empty.
+	// Defaults to: "X-Form-Token"
+	HeaderName string
+
+	// ValidateMethods are HTTP methods requests with are validated. Requests with other methods are only issued tokens.
+	// Defaults to: POST, PUT, PATCH and DELETE
+	ValidateMethods []string
+
+	timeNow func() time.Time
+}
+
+// DefaultConfig is the default form token middleware config.
+var DefaultConfig = Config{
+	Skipper:         middleware.DefaultSkipper,
+	TTL:             time.Hour,
+	FieldName:       DefaultFieldName,
+	HeaderName:      HeaderFormToken,
+	ValidateMethods: []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete},
+}
+
+// Middleware returns form token middleware with given secret and default config.
+func Middleware(secret []byte) echo.MiddlewareFunc {
+	c := DefaultConfig
+	c.Secret = secret
+	return MiddlewareWithConfig(c)
+}
+
+// MiddlewareWithConfig returns form token middleware with config or panics on invalid configuration.
+// See: `Middleware()`.
+func MiddlewareWithConfig(config Config) echo.MiddlewareFunc {
+	mw, err := config.ToMiddleware()
+	if err != nil {
+		panic(err)
+	}
+	return mw
+}
+
+// ToMiddleware converts configuration to middleware or returns an error.
+func (config Config) ToMiddleware() (echo.MiddlewareFunc, error) {
+	if len(config.Secret) == 0 {
+		return nil, errors.New("echoformtoken: secret is required")
+	}
+	if config.Skipper == nil {
+		config.Skipper = DefaultConfig.Skipper
+	}
+	if config.TTL <= 0 {
+		config.TTL = DefaultConfig.TTL
+	}
+	if config.Store == nil {
+		config.Store = NewMemoryStore()
+	}
+	if config.FormIDFunc == nil {
+		config.FormIDFunc = func(c echo.Context) string {
+			return c.Path()
+		}
+	}
+	if config.FieldName == "" {
+		config.FieldName = DefaultConfig.FieldName
+	}
+	if config.HeaderName == "" {
+		config.HeaderName = DefaultConfig.HeaderName
+	}
+	if len(config.ValidateMethods) == 0 {
+		config.ValidateMethods = DefaultConfig.ValidateMethods
+	}
+	if config.timeNow == nil {
+		config.timeNow = time.Now
+	}
+	validate := make(map[string]struct{}, len(config.ValidateMethods))
+	for _, m := range config.ValidateMethods {
+		validate[m] = struct{}{}
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+			c.Set(contextKey, &issuer{config: &config, c: c})
+
+			if _, ok := validate[c.Request().Method]; !ok {
+				return next(c)
+			}
+
+			token := c.FormValue(config.FieldName)
+			if token == "" {
+				token = c.Request().Header.Get(config.HeaderName)
+			}
+			if token == "" {
+				return ErrTokenMissing
+			}
+			nonce, expiresAt, err := config.verify(token, config.FormIDFunc(c))
+			if err != nil {
+				return ErrTokenInvalid.WithInternal(err)
+			}
+			ok, err := config.Store.Use(c.Request().Context(), nonce, expiresAt)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return ErrDuplicateSubmission
+			}
+			return next(c)
+		}
+	}, nil
+}
+
+// issue creates token for given form.
+func (config *Config) issue(formID string) (string, error) {
+	payload := make([]byte, payloadSize, payloadSize+sha256.Size)
+	binary.BigEndian.PutUint64(payload, uint64(config.timeNow().Add(config.TTL).Unix()))
+	if _, err := rand.Read(payload[8:]); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(append(payload, config.sign(formID, payload)...)), nil
+}
+
+// verify checks token signature and expiry and returns token nonce and expiry time.
+func (config *Config) verify(token string, formID string) (string, time.Time, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("malformed token: %w", err)
+	}
+	if len(raw) != payloadSize+sha256.Size {
+		return "", time.Time{}, errors.New("malformed token: invalid length")
+	}
+	payload, sig := raw[:payloadSize], raw[payloadSize:]
+	if !hmac.Equal(sig, config.sign(formID, payload)) {
+		return "", time.Time{}, errors.New("token signature mismatch")
+	}
+	expiresAt := time.Unix(int64(binary.BigEndian.Uint64(payload)), 0)
+	if !config.timeNow().Before(expiresAt) {
+		return "", time.Time{}, errors.New("token expired")
+	}
+	return base64.RawURLEncoding.EncodeToString(payload[8:]), expiresAt, nil
+}
+
+func (config *Config) sign(formID string, payload []byte) []byte {
+	mac := hmac.New(sha256.New, config.Secret)
+	mac.Write([]byte(formID))
+	mac.Write([]byte{0})
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+type issuer struct {
+	config *Config
+	c      echo.Context
+	token  string
+}
+
+// Token returns form token for the form of the current request (see Config.FormIDFunc). Token is created once per
+// request. Empty string is returned when middleware was not applied to the request.
+func Token(c echo.Context) string {
+	i, ok := c.Get(contextKey).(*issuer)
+	if !ok {
+		return ""
+	}
+	if i.token == "" {
+		i.token, _ = i.config.issue(i.config.FormIDFunc(c))
+	}
+	return i.token
+}
+
+// TokenFor returns new form token for given form ID. Useful when page contains form submitted to another route.
+func TokenFor(c echo.Context, formID string) string {
+	i, ok := c.Get(contextKey).(*issuer)
+	if !ok {
+		return ""
+	}
+	token, _ := i.config.issue(formID)
+	return token
+}
+
+// Field returns hidden input element containing form token for the form of the current request.
+func Field(c echo.Context) template.HTML {
+	i, ok := c.Get(contextKey).(*issuer)
+	if !ok {
+		return ""
+	}
+	return template.HTML(fmt.Sprintf(
+		`<input type="hidden" name="%s" value="%s">`,
+		template.HTMLEscapeString(i.config.FieldName),
+		template.HTMLEscapeString(Token(c)),
+	))
+}
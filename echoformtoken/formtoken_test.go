@@ -0,0 +1,215 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echoformtoken
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func newTestEcho(config Config) *echo.Echo {
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(config))
+	e.GET("/checkout", func(c echo.Context) error {
+		return c.String(http.StatusOK, Token(c))
+	})
+	e.POST("/checkout", func(c echo.Context) error {
+		return c.String(http.StatusOK, "submitted")
+	})
+	e.GET("/other", func(c echo.Context) error {
+		return c.String(http.StatusOK, Token(c))
+	})
+	e.POST("/other", func(c echo.Context) error {
+		return c.String(http.StatusOK, "submitted")
+	})
+	return e
+}
+
+func getToken(e *echo.Echo, path string) string {
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec.Body.String()
+}
+
+func submit(e *echo.Echo, path string, token string) *httptest.ResponseRecorder {
+	form := url.Values{}
+	if token != "" {
+		form.Set(DefaultFieldName, token)
+	}
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMiddleware(t *testing.T) {
+	e := newTestEcho(Config{Secret: testSecret})
+
+	token := getToken(e, "/checkout")
+	assert.NotEmpty(t, token)
+
+	rec := submit(e, "/checkout", token)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "submitted", rec.Body.String())
+
+	rec = submit(e, "/checkout", token)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, `{"message":"form already submitted"}`+"\n", rec.Body.String())
+}
+
+func TestMiddleware_validation(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	config := Config{Secret: testSecret, TTL: time.Minute, timeNow: func() time.Time { return now }}
+	e := newTestEcho(config)
+	checkoutToken := getToken(e, "/checkout")
+	otherSecretToken := getToken(newTestEcho(Config{Secret: []byte("another secret"), timeNow: config.timeNow}), "/checkout")
+
+	var testCases = []struct {
+		name         string
+		whenPath     string
+		whenToken    string
+		whenHeader   string
+		whenNow      time.Time
+		expectStatus int
+		expectBody   string
+	}{
+		{
+			name:         "ok, token in header",
+			whenPath:     "/checkout",
+			whenHeader:   checkoutToken,
+			whenNow:      now,
+			expectStatus: http.StatusOK,
+		},
+		{
+			name:         "nok, missing token",
+			whenPath:     "/checkout",
+			whenNow:      now,
+			expectStatus: http.StatusForbidden,
+			expectBody:   `{"message":"missing form token"}`,
+		},
+		{
+			name:         "nok, token issued for another form",
+			whenPath:     "/other",
+			whenToken:    checkoutToken,
+			whenNow:      now,
+			expectStatus: http.StatusForbidden,
+			expectBody:   `{"message":"invalid form token"}`,
+		},
+		{
+			name:         "nok, expired token",
+			whenPath:     "/checkout",
+			whenToken:    checkoutToken,
+			whenNow:      now.Add(time.Minute),
+			expectStatus: http.StatusForbidden,
+			expectBody:   `{"message":"invalid form token"}`,
+		},
+		{
+			name:         "nok, malformed token",
+			whenPath:     "/checkout",
+			whenToken:    "not-a-token",
+			whenNow:      now,
+			expectStatus: http.StatusForbidden,
+			expectBody:   `{"message":"invalid form token"}`,
+		},
+		{
+			name:         "nok, token signed with another secret",
+			whenPath:     "/checkout",
+			whenToken:    otherSecretToken,
+			whenNow:      now,
+			expectStatus: http.StatusForbidden,
+			expectBody:   `{"message":"invalid form token"}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := config
+			c.timeNow = func() time.Time { return tc.whenNow }
+			e := newTestEcho(c)
+
+			form := url.Values{}
+			if tc.whenToken != "" {
+				form.Set(DefaultFieldName, tc.whenToken)
+			}
+			req := httptest.NewRequest(http.MethodPost, tc.whenPath, strings.NewReader(form.Encode()))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+			if tc.whenHeader != "" {
+				req.Header.Set(HeaderFormToken, tc.whenHeader)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectStatus, rec.Code)
+			if tc.expectBody != "" {
+				assert.Equal(t, tc.expectBody+"\n", rec.Body.String())
+			}
+		})
+	}
+}
+
+type errorStore struct{}
+
+func (errorStore) Use(ctx context.Context, nonce string, expiresAt time.Time) (bool, error) {
+	return false, errors.New("store unavailable")
+}
+
+func TestMiddleware_storeError(t *testing.T) {
+	e := newTestEcho(Config{Secret: testSecret, Store: errorStore{}})
+
+	rec := submit(e, "/checkout", getToken(e, "/checkout"))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
+
+func TestTokenFor(t *testing.T) {
+	e := echo.New()
+	e.Use(Middleware(testSecret))
+	e.GET("/cart", func(c echo.Context) error {
+		return c.String(http.StatusOK, TokenFor(c, "/checkout"))
+	})
+	e.POST("/checkout", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	rec := submit(e, "/checkout", getToken(e, "/cart"))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestField(t *testing.T) {
+	e := echo.New()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	assert.Equal(t, "", string(Field(c)))
+	assert.Equal(t, "", Token(c))
+	assert.Equal(t, "", TokenFor(c, "/x"))
+
+	var field string
+	mw := Middleware(testSecret)
+	err := mw(func(c echo.Context) error {
+		field = string(Field(c))
+		assert.Equal(t, `<input type="hidden" name="_form_token" value="`+Token(c)+`">`, field)
+		return nil
+	})(c)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, field)
+}
+
+func TestConfig_ToMiddleware(t *testing.T) {
+	mw, err := Config{}.ToMiddleware()
+	assert.EqualError(t, err, "echoformtoken: secret is required")
+	assert.Nil(t, mw)
+
+	assert.Panics(t, func() {
+		Middleware(nil)
+	})
+}
@@ -0,0 +1,68 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echoformtoken
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store keeps nonces of used tokens until they expire. Implementations must be safe for concurrent use.
+type Store interface {
+	// Use atomically marks nonce as used until expiresAt. Returns false when nonce was already used.
+	Use(ctx context.Context, nonce string, expiresAt time.Time) (bool, error)
+}
+
+// MemoryStore is in-memory Store implementation. Expired nonces are removed with DeleteExpired and periodically on Use.
+type MemoryStore struct {
+	mu      sync.Mutex
+	used    map[string]time.Time
+	uses    int
+	timeNow func() time.Time
+}
+
+// NewMemoryStore creates new in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		used:    make(map[string]time.Time),
+		timeNow: time.Now,
+	}
+}
+
+// Use implements Store.Use.
+func (s *MemoryStore) Use(ctx context.Context, nonce string, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.timeNow()
+	s.uses++
+	if s.uses%1000 == 0 {
+		s.deleteExpired(now)
+	}
+	if exp, ok := s.used[nonce]; ok && now.Before(exp) {
+		return false, nil
+	}
+	s.used[nonce] = expiresAt
+	return true, nil
+}
+
+// DeleteExpired removes expired nonces and returns number of removed nonces.
+func (s *MemoryStore) DeleteExpired() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.deleteExpired(s.timeNow())
+}
+
+func (s *MemoryStore) deleteExpired(now time.Time) int {
+	n := 0
+	for nonce, exp := range s.used {
+		if !now.Before(exp) {
+			delete(s.used, nonce)
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,36 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echoformtoken
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+	s.timeNow = func() time.Time { return now }
+	ctx := context.Background()
+
+	ok, err := s.Use(ctx, "a", now.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = s.Use(ctx, "a", now.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, _ = s.Use(ctx, "b", now.Add(time.Hour))
+	assert.True(t, ok)
+
+	now = now.Add(2 * time.Minute)
+	assert.Equal(t, 1, s.DeleteExpired())
+
+	ok, _ = s.Use(ctx, "a", now.Add(time.Minute))
+	assert.True(t, ok) // expired nonce can be used again, token itself is rejected as expired by middleware
+}
@@ -0,0 +1,87 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+/*
+Package echogrpcgateway allows serving gRPC server and Echo on the same listener and sharing Echo middlewares (auth,
+metrics, tracing, rate limiting etc.) with gRPC server so hybrid services do not duplicate policy in two stacks.
+
+Requests are multiplexed by protocol and content type: HTTP/2 requests with `application/grpc` content type are served
+by gRPC server, all other requests by Echo. Cleartext HTTP/2 (h2c) is supported so gRPC clients can connect without
+TLS.
+
+Echo middlewares are adapted to gRPC interceptors with UnaryInterceptor and StreamInterceptor. gRPC calls are presented
+to middlewares as `POST` requests to full method name (`/pkg.Service/Method`) with headers from incoming metadata.
+Middlewares that depend on request body or write response body (i.e. body dump, gzip) are not suitable for gRPC calls.
+
+Example:
+```
+package main
+
+import (
+
+	"github.com/labstack/echo-contrib/echogrpcgateway"
+	"github.com/labstack/echo-contrib/echoprometheus"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"google.golang.org/grpc"
+
+)
+
+	func main() {
+		e := echo.New()
+		shared := []echo.MiddlewareFunc{
+			middleware.RequestID(),
+			echoprometheus.NewMiddleware("myapp"),
+			middleware.KeyAuth(validateKey),
+		}
+		e.Use(shared...)
+
+		grpcServer := grpc.NewServer(
+			grpc.ChainUnaryInterceptor(echogrpcgateway.UnaryInterceptor(e, shared...)),
+			grpc.ChainStreamInterceptor(echogrpcgateway.StreamInterceptor(e, shared...)),
+		)
+		pb.RegisterGreeterServer(grpcServer, &greeter{})
+
+		e.Server.Handler = echogrpcgateway.NewHandler(e, grpcServer)
+		e.Logger.Fatal(e.StartServer(e.Server))
+	}
+
+```
+*/
+package echogrpcgateway
+
+import (
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// NewHandler returns handler serving gRPC requests with grpcHandler (i.e. *grpc.Server) and all other requests with
+// httpHandler (i.e. *echo.Echo). Cleartext HTTP/2 connections are accepted so the handler can be used with plain TCP
+// listener. For TLS listeners HTTP/2 is negotiated by http.Server and h2c upgrade is not used.
+func NewHandler(httpHandler http.Handler, grpcHandler http.Handler) http.Handler {
+	return h2c.NewHandler(Mux(httpHandler, grpcHandler), &http2.Server{})
+}
+
+// Mux returns handler dispatching gRPC requests to grpcHandler and other requests to httpHandler without h2c support.
+func Mux(httpHandler http.Handler, grpcHandler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if IsGRPCRequest(r) {
+			grpcHandler.ServeHTTP(w, r)
+			return
+		}
+		httpHandler.ServeHTTP(w, r)
+	})
+}
+
+// IsGRPCRequest returns true for HTTP/2 requests with `application/grpc` (including `application/grpc+proto` etc.)
+// content type.
+func IsGRPCRequest(r *http.Request) bool {
+	if r.ProtoMajor != 2 {
+		return false
+	}
+	ct := r.Header.Get("Content-Type")
+	return ct == "application/grpc" || strings.HasPrefix(ct, "application/grpc+") || strings.HasPrefix(ct, "application/grpc;")
+}
@@ -0,0 +1,69 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echogrpcgateway
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestNewHandler(t *testing.T) {
+	e := echo.New()
+	e.GET("/hello", func(c echo.Context) error {
+		return c.String(http.StatusOK, "hello")
+	})
+	grpcServer := grpc.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, health.NewServer())
+
+	server := httptest.NewServer(NewHandler(e, grpcServer))
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/hello")
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	assert.Equal(t, "hello", string(body))
+
+	conn, err := grpc.NewClient(strings.TrimPrefix(server.URL, "http://"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+}
+
+func TestIsGRPCRequest(t *testing.T) {
+	var testCases = []struct {
+		name      string
+		whenProto int
+		whenType  string
+		expect    bool
+	}{
+		{name: "grpc", whenProto: 2, whenType: "application/grpc", expect: true},
+		{name: "grpc+proto", whenProto: 2, whenType: "application/grpc+proto", expect: true},
+		{name: "grpc-web is not grpc", whenProto: 2, whenType: "application/grpc-web", expect: false},
+		{name: "http/1.1", whenProto: 1, whenType: "application/grpc", expect: false},
+		{name: "json", whenProto: 2, whenType: "application/json", expect: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			req.ProtoMajor = tc.whenProto
+			req.Header.Set("Content-Type", tc.whenType)
+			assert.Equal(t, tc.expect, IsGRPCRequest(req))
+		})
+	}
+}
@@ -0,0 +1,254 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echogrpcgateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// InterceptorConfig defines the config for gRPC interceptors running Echo middlewares.
+type InterceptorConfig struct {
+	// Echo is instance used to create contexts for gRPC calls. Its middlewares are NOT applied automatically.
+	// Required.
+	Echo *echo.Echo
+
+	// Middlewares are applied to every gRPC call in given order (first is outermost). Each call is presented to
+	// middlewares as `POST` request with path and route path set to full gRPC method name (`/pkg.Service/Method`) and
+	// headers set from incoming metadata. Response headers set by middlewares are sent as gRPC header metadata.
+	Middlewares []echo.MiddlewareFunc
+}
+
+// UnaryInterceptor returns gRPC unary server interceptor applying given Echo middlewares to every call.
+func UnaryInterceptor(e *echo.Echo, middlewares ...echo.MiddlewareFunc) grpc.UnaryServerInterceptor {
+	return InterceptorConfig{Echo: e, Middlewares: middlewares}.ToUnaryInterceptor()
+}
+
+// StreamInterceptor returns gRPC stream server interceptor applying given Echo middlewares to every stream.
+func StreamInterceptor(e *echo.Echo, middlewares ...echo.MiddlewareFunc) grpc.StreamServerInterceptor {
+	return InterceptorConfig{Echo: e, Middlewares: middlewares}.ToStreamInterceptor()
+}
+
+// ToUnaryInterceptor converts configuration to gRPC unary server interceptor.
+func (config InterceptorConfig) ToUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		var resp any
+		err := config.run(ctx, info.FullMethod, func(ctx context.Context) error {
+			var err error
+			resp, err = handler(ctx, req)
+			return err
+		})
+		return resp, err
+	}
+}
+
+// ToStreamInterceptor converts configuration to gRPC stream server interceptor.
+func (config InterceptorConfig) ToStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return config.run(ss.Context(), info.FullMethod, func(ctx context.Context) error {
+			return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		})
+	}
+}
+
+// run executes middlewares for gRPC call. Context of the (possibly replaced) request is passed to the call so values
+// added by middlewares (i.e. tracing spans, authenticated principals) are visible to gRPC handlers.
+func (config InterceptorConfig) run(ctx context.Context, fullMethod string, call func(ctx context.Context) error) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fullMethod, nil)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	req.RequestURI = fullMethod
+	req.ProtoMajor, req.ProtoMinor, req.Proto = 2, 0, "HTTP/2.0"
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for k, values := range md {
+			if strings.HasPrefix(k, ":") {
+				if k == ":authority" && len(values) > 0 {
+					req.Host = values[0]
+				}
+				continue
+			}
+			for _, v := range values {
+				req.Header.Add(k, v)
+			}
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		req.RemoteAddr = p.Addr.String()
+	}
+
+	rw := &responseRecorder{header: http.Header{}}
+	c := config.Echo.NewContext(req, rw)
+	c.SetPath(fullMethod)
+
+	called := false
+	var callErr error
+	h := func(c echo.Context) error {
+		called = true
+		sendHeader(c.Request().Context(), c.Response().Header())
+		callErr = call(c.Request().Context())
+		if callErr != nil {
+			st := status.Convert(callErr)
+			// middlewares (i.e. metrics, logging) see gRPC errors as HTTP errors with equivalent status code
+			return echo.NewHTTPError(HTTPStatusFromCode(st.Code()), st.Message()).WithInternal(callErr)
+		}
+		return nil
+	}
+	for i := len(config.Middlewares) - 1; i >= 0; i-- {
+		h = config.Middlewares[i](h)
+	}
+	err = h(c)
+
+	if called {
+		return callErr
+	}
+	if err != nil {
+		var he *echo.HTTPError
+		if errors.As(err, &he) {
+			msg, ok := he.Message.(string)
+			if !ok {
+				msg = http.StatusText(he.Code)
+			}
+			return status.Error(CodeFromHTTPStatus(he.Code), msg)
+		}
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+		return status.Error(codes.Internal, err.Error())
+	}
+	// middleware responded without calling next handler
+	code := rw.status
+	if code == 0 {
+		code = c.Response().Status
+	}
+	if code < http.StatusBadRequest {
+		return status.Error(codes.Internal, "request was not passed to gRPC handler")
+	}
+	msg := strings.TrimSpace(rw.body.String())
+	if msg == "" {
+		msg = http.StatusText(code)
+	}
+	return status.Error(CodeFromHTTPStatus(code), msg)
+}
+
+// sendHeader sends response headers set by middlewares as gRPC header metadata.
+func sendHeader(ctx context.Context, header http.Header) {
+	md := metadata.MD{}
+	for k, v := range header {
+		switch k {
+		case echo.HeaderContentType, echo.HeaderContentLength:
+			continue
+		}
+		md[strings.ToLower(k)] = v
+	}
+	if len(md) > 0 {
+		_ = grpc.SetHeader(ctx, md)
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// responseRecorder captures responses written by middlewares that do not call next handler.
+type responseRecorder struct {
+	header http.Header
+	body   strings.Builder
+	status int
+}
+
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.body.Len() < 1024 {
+		r.body.Write(b[:min(len(b), 1024-r.body.Len())])
+	}
+	return len(b), nil
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+}
+
+// CodeFromHTTPStatus converts HTTP status code to gRPC code.
+// See: https://github.com/grpc/grpc/blob/master/doc/http-grpc-status-mapping.md
+func CodeFromHTTPStatus(code int) codes.Code {
+	switch code {
+	case http.StatusOK:
+		return codes.OK
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.Aborted
+	case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	case http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusRequestEntityTooLarge, http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return codes.Unavailable
+	}
+	if code >= http.StatusInternalServerError {
+		return codes.Internal
+	}
+	return codes.Unknown
+}
+
+// HTTPStatusFromCode converts gRPC code to HTTP status code.
+func HTTPStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499 // client closed request
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.FailedPrecondition:
+		return http.StatusPreconditionFailed
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,205 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echogrpcgateway
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type principalKey struct{}
+
+type recordingHealthServer struct {
+	healthpb.HealthServer
+	principal any
+}
+
+func (s *recordingHealthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	s.principal = ctx.Value(principalKey{})
+	if req.GetService() == "missing" {
+		return nil, status.Error(codes.NotFound, "unknown service")
+	}
+	return s.HealthServer.Check(ctx, req)
+}
+
+func (s *recordingHealthServer) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	s.principal = stream.Context().Value(principalKey{})
+	return stream.Send(&healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING})
+}
+
+func newTestClient(t *testing.T, middlewares ...echo.MiddlewareFunc) (healthpb.HealthClient, *recordingHealthServer) {
+	e := echo.New()
+	hs := &recordingHealthServer{HealthServer: health.NewServer()}
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(UnaryInterceptor(e, middlewares...)),
+		grpc.ChainStreamInterceptor(StreamInterceptor(e, middlewares...)),
+	)
+	healthpb.RegisterHealthServer(server, hs)
+
+	lis := bufconn.Listen(1024 * 1024)
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	return healthpb.NewHealthClient(conn), hs
+}
+
+func authMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if c.Request().Header.Get(echo.HeaderAuthorization) != "Bearer secret" {
+			return echo.NewHTTPError(http.StatusUnauthorized, "invalid token")
+		}
+		ctx := context.WithValue(c.Request().Context(), principalKey{}, "alice")
+		c.SetRequest(c.Request().WithContext(ctx))
+		c.Response().Header().Set("X-Principal", "alice")
+		return next(c)
+	}
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	var seenPath, seenMethod string
+	var seenErr error
+	observer := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			err := next(c)
+			seenPath, seenMethod, seenErr = c.Path(), c.Request().Method, err
+			return err
+		}
+	}
+	client, hs := newTestClient(t, observer, authMiddleware)
+
+	var testCases = []struct {
+		name            string
+		whenToken       string
+		whenService     string
+		expectCode      codes.Code
+		expectMessage   string
+		expectPrincipal any
+		expectHeader    []string
+		expectSeenErr   string
+	}{
+		{
+			name:            "ok",
+			whenToken:       "Bearer secret",
+			expectCode:      codes.OK,
+			expectPrincipal: "alice",
+			expectHeader:    []string{"alice"},
+		},
+		{
+			name:          "nok, middleware rejects call",
+			whenToken:     "Bearer wrong",
+			expectCode:    codes.Unauthenticated,
+			expectMessage: "invalid token",
+			expectSeenErr: "code=401, message=invalid token",
+		},
+		{
+			name:            "nok, handler error is passed through and visible to middlewares as HTTP error",
+			whenToken:       "Bearer secret",
+			whenService:     "missing",
+			expectCode:      codes.NotFound,
+			expectMessage:   "unknown service",
+			expectPrincipal: "alice",
+			expectHeader:    []string{"alice"},
+			expectSeenErr:   "code=404, message=unknown service, internal=rpc error: code = NotFound desc = unknown service",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hs.principal = nil
+			ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", tc.whenToken)
+			var header metadata.MD
+
+			_, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: tc.whenService}, grpc.Header(&header))
+
+			st := status.Convert(err)
+			assert.Equal(t, tc.expectCode, st.Code())
+			if tc.expectMessage != "" {
+				assert.Equal(t, tc.expectMessage, st.Message())
+			}
+			assert.Equal(t, tc.expectPrincipal, hs.principal)
+			assert.Equal(t, tc.expectHeader, header.Get("x-principal"))
+			assert.Equal(t, "/grpc.health.v1.Health/Check", seenPath)
+			assert.Equal(t, http.MethodPost, seenMethod)
+			if tc.expectSeenErr != "" {
+				assert.EqualError(t, seenErr, tc.expectSeenErr)
+			} else {
+				assert.NoError(t, seenErr)
+			}
+		})
+	}
+}
+
+func TestUnaryInterceptor_middlewareResponds(t *testing.T) {
+	client, _ := newTestClient(t, func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			return c.String(http.StatusTooManyRequests, "slow down")
+		}
+	})
+
+	_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+
+	st := status.Convert(err)
+	assert.Equal(t, codes.ResourceExhausted, st.Code())
+	assert.Equal(t, "slow down", st.Message())
+}
+
+func TestStreamInterceptor(t *testing.T) {
+	client, hs := newTestClient(t, authMiddleware)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+	resp, err := stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+	assert.Equal(t, "alice", hs.principal)
+
+	stream, err = client.Watch(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestCodeFromHTTPStatus(t *testing.T) {
+	assert.Equal(t, codes.OK, CodeFromHTTPStatus(http.StatusOK))
+	assert.Equal(t, codes.Unauthenticated, CodeFromHTTPStatus(http.StatusUnauthorized))
+	assert.Equal(t, codes.ResourceExhausted, CodeFromHTTPStatus(http.StatusTooManyRequests))
+	assert.Equal(t, codes.Unavailable, CodeFromHTTPStatus(http.StatusServiceUnavailable))
+	assert.Equal(t, codes.Internal, CodeFromHTTPStatus(http.StatusInsufficientStorage))
+	assert.Equal(t, codes.Unknown, CodeFromHTTPStatus(http.StatusTeapot))
+}
+
+func TestHTTPStatusFromCode(t *testing.T) {
+	for _, code := range []codes.Code{codes.InvalidArgument, codes.Unauthenticated, codes.PermissionDenied, codes.NotFound,
+		codes.ResourceExhausted, codes.Unimplemented, codes.Unavailable, codes.DeadlineExceeded, codes.FailedPrecondition} {
+		assert.Equal(t, code, CodeFromHTTPStatus(HTTPStatusFromCode(code)), code.String())
+	}
+	assert.Equal(t, 499, HTTPStatusFromCode(codes.Canceled))
+	assert.Equal(t, http.StatusInternalServerError, HTTPStatusFromCode(codes.DataLoss))
+}
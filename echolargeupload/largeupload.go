@@ -0,0 +1,347 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+/*
+Package echolargeupload provides handlers implementing tus.io resumable upload protocol (version 1.0.0) with `creation`,
+`expiration` and `termination` extensions. Uploads are stored in the filesystem with FileStorage or in S3 compatible
+object storage with S3Storage.
+
+Example:
+```
+package main
+
+import (
+
+	"github.com/labstack/echo-contrib/echolargeupload"
+	"github.com/labstack/echo/v4"
+
+)
+
+	func main() {
+		storage, err := echolargeupload.NewFileStorage("/var/uploads")
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		e := echo.New()
+		echolargeupload.Register(e.Group("/files"), echolargeupload.Config{
+			Storage:    storage,
+			MaxSize:    10 << 30, // 10GB
+			Expiration: 24 * time.Hour,
+			OnComplete: func(c echo.Context, info echolargeupload.UploadInfo) error {
+				c.Logger().Infof("upload %s completed", info.ID)
+				return nil
+			},
+		})
+
+		e.Logger.Fatal(e.Start(":1323"))
+	}
+
+```
+*/
+package echolargeupload
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// TusVersion is version of tus protocol implemented by this package.
+	TusVersion = "1.0.0"
+
+	tusExtensions = "creation,expiration,termination"
+
+	headerTusResumable   = "Tus-Resumable"
+	headerTusVersion     = "Tus-Version"
+	headerTusExtension   = "Tus-Extension"
+	headerTusMaxSize     = "Tus-Max-Size"
+	headerUploadLength   = "Upload-Length"
+	headerUploadOffset   = "Upload-Offset"
+	headerUploadMetadata = "Upload-Metadata"
+	headerUploadExpires  = "Upload-Expires"
+
+	mimeOffsetOctetStream = "application/offset+octet-stream"
+)
+
+// Router is implemented by *echo.Echo and *echo.Group.
+type Router interface {
+	Add(method, path string, handler echo.HandlerFunc, middleware ...echo.MiddlewareFunc) *echo.Route
+}
+
+// Config defines the config for resumable upload handlers.
+type Config struct {
+	// Storage is backend where uploads are stored.
+	// Required.
+	Storage Storage
+
+	// MaxSize is maximum allowed upload size in bytes. Zero means no limit.
+	MaxSize int64
+
+	// Expiration is duration after creation when incomplete upload expires. Zero means uploads do not expire.
+	Expiration time.Duration
+
+	// ScanFunc is called with upload contents when upload completes and before OnComplete is called. Use it for example
+	// for virus scanning. When ScanFunc returns an error the upload is deleted and request fails with that error. Errors
+	// that are not *echo.HTTPError are responded with "422 - Unprocessable Entity" status.
+	// Optional.
+	ScanFunc func(c echo.Context, info UploadInfo, r io.Reader) error
+
+	// OnComplete is called when upload completes (and passed ScanFunc).
+	// Optional.
+	OnComplete func(c echo.Context, info UploadInfo) error
+
+	// IDGenerator generates identifiers for new uploads.
+	// Defaults to: random 16 byte hex string
+	IDGenerator func() string
+
+	timeNow func() time.Time
+}
+
+type handler struct {
+	config Config
+}
+
+// Register adds resumable upload routes to given router. Uploads are created with POST request to router root and
+// identified by `/:id` path.
+func Register(r Router, config Config) {
+	if config.Storage == nil {
+		panic("echo: largeupload requires storage")
+	}
+	if config.IDGenerator == nil {
+		config.IDGenerator = generateID
+	}
+	if config.timeNow == nil {
+		config.timeNow = time.Now
+	}
+	h := &handler{config: config}
+
+	r.Add(http.MethodOptions, "", h.options)
+	r.Add(http.MethodOptions, "/", h.options)
+	r.Add(http.MethodPost, "", h.create, h.requireTusResumable)
+	r.Add(http.MethodPost, "/", h.create, h.requireTusResumable)
+	r.Add(http.MethodHead, "/:id", h.head, h.requireTusResumable)
+	r.Add(http.MethodPatch, "/:id", h.patch, h.requireTusResumable)
+	r.Add(http.MethodDelete, "/:id", h.delete, h.requireTusResumable)
+}
+
+func (h *handler) requireTusResumable(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		res := c.Response()
+		res.Header().Set(headerTusResumable, TusVersion)
+		if c.Request().Header.Get(headerTusResumable) != TusVersion {
+			res.Header().Set(headerTusVersion, TusVersion)
+			return echo.NewHTTPError(http.StatusPreconditionFailed, "unsupported tus protocol version")
+		}
+		return next(c)
+	}
+}
+
+func (h *handler) options(c echo.Context) error {
+	header := c.Response().Header()
+	header.Set(headerTusResumable, TusVersion)
+	header.Set(headerTusVersion, TusVersion)
+	header.Set(headerTusExtension, tusExtensions)
+	if h.config.MaxSize > 0 {
+		header.Set(headerTusMaxSize, strconv.FormatInt(h.config.MaxSize, 10))
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *handler) create(c echo.Context) error {
+	size, err := strconv.ParseInt(c.Request().Header.Get(headerUploadLength), 10, 64)
+	if err != nil || size < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid Upload-Length header")
+	}
+	if h.config.MaxSize > 0 && size > h.config.MaxSize {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "upload exceeds maximum size")
+	}
+	metadata, err := parseMetadata(c.Request().Header.Get(headerUploadMetadata))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid Upload-Metadata header").SetInternal(err)
+	}
+
+	info := UploadInfo{
+		ID:       h.config.IDGenerator(),
+		Size:     size,
+		Metadata: metadata,
+	}
+	if h.config.Expiration > 0 {
+		info.ExpiresAt = h.config.timeNow().Add(h.config.Expiration).UTC()
+	}
+	if err := h.config.Storage.Create(c.Request().Context(), info); err != nil {
+		return err
+	}
+
+	header := c.Response().Header()
+	header.Set(echo.HeaderLocation, strings.TrimSuffix(c.Request().URL.Path, "/")+"/"+info.ID)
+	setExpires(header, info)
+	if size == 0 {
+		if err := h.complete(c, info); err != nil {
+			return err
+		}
+	}
+	return c.NoContent(http.StatusCreated)
+}
+
+func (h *handler) head(c echo.Context) error {
+	info, err := h.info(c)
+	if err != nil {
+		return err
+	}
+
+	header := c.Response().Header()
+	header.Set(echo.HeaderCacheControl, "no-store")
+	header.Set(headerUploadOffset, strconv.FormatInt(info.Offset, 10))
+	header.Set(headerUploadLength, strconv.FormatInt(info.Size, 10))
+	if len(info.Metadata) > 0 {
+		header.Set(headerUploadMetadata, formatMetadata(info.Metadata))
+	}
+	setExpires(header, info)
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *handler) patch(c echo.Context) error {
+	if c.Request().Header.Get(echo.HeaderContentType) != mimeOffsetOctetStream {
+		return echo.ErrUnsupportedMediaType
+	}
+	offset, err := strconv.ParseInt(c.Request().Header.Get(headerUploadOffset), 10, 64)
+	if err != nil || offset < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid Upload-Offset header")
+	}
+	info, err := h.info(c)
+	if err != nil {
+		return err
+	}
+	if info.Offset != offset {
+		return echo.NewHTTPError(http.StatusConflict, "Upload-Offset does not match current offset")
+	}
+	if c.Request().ContentLength > info.Size-offset {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "chunk exceeds Upload-Length")
+	}
+
+	n, err := h.config.Storage.WriteChunk(c.Request().Context(), info.ID, offset, c.Request().Body)
+	if errors.Is(err, ErrOffsetMismatch) {
+		return echo.NewHTTPError(http.StatusConflict, "Upload-Offset does not match current offset").SetInternal(err)
+	} else if errors.Is(err, ErrSizeExceeded) {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "chunk exceeds Upload-Length").SetInternal(err)
+	} else if err != nil {
+		return err
+	}
+	info.Offset += n
+
+	header := c.Response().Header()
+	header.Set(headerUploadOffset, strconv.FormatInt(info.Offset, 10))
+	setExpires(header, info)
+	if info.IsComplete() {
+		if err := h.complete(c, info); err != nil {
+			return err
+		}
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *handler) delete(c echo.Context) error {
+	err := h.config.Storage.Delete(c.Request().Context(), c.Param("id"))
+	if errors.Is(err, ErrUploadNotFound) {
+		return echo.ErrNotFound
+	} else if err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *handler) info(c echo.Context) (UploadInfo, error) {
+	ctx := c.Request().Context()
+	info, err := h.config.Storage.Info(ctx, c.Param("id"))
+	if errors.Is(err, ErrUploadNotFound) {
+		return UploadInfo{}, echo.ErrNotFound
+	} else if err != nil {
+		return UploadInfo{}, err
+	}
+	if info.IsExpired(h.config.timeNow()) {
+		if err := h.config.Storage.Delete(ctx, info.ID); err != nil && !errors.Is(err, ErrUploadNotFound) {
+			return UploadInfo{}, err
+		}
+		return UploadInfo{}, echo.ErrNotFound
+	}
+	return info, nil
+}
+
+func (h *handler) complete(c echo.Context, info UploadInfo) error {
+	ctx := c.Request().Context()
+	if h.config.ScanFunc != nil {
+		r, err := h.config.Storage.Reader(ctx, info.ID)
+		if err != nil {
+			return err
+		}
+		scanErr := h.config.ScanFunc(c, info, r)
+		r.Close()
+		if scanErr != nil {
+			if err := h.config.Storage.Delete(ctx, info.ID); err != nil {
+				return errors.Join(scanErr, err)
+			}
+			var httpErr *echo.HTTPError
+			if errors.As(scanErr, &httpErr) {
+				return scanErr
+			}
+			return echo.NewHTTPError(http.StatusUnprocessableEntity, "upload was rejected").SetInternal(scanErr)
+		}
+	}
+	if h.config.OnComplete != nil {
+		return h.config.OnComplete(c, info)
+	}
+	return nil
+}
+
+func setExpires(header http.Header, info UploadInfo) {
+	if !info.ExpiresAt.IsZero() && !info.IsComplete() {
+		header.Set(headerUploadExpires, info.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+}
+
+// parseMetadata parses `Upload-Metadata` header. Header consists of comma separated key-value pairs where key and
+// value are separated by space and value is base64 encoded. Value may be omitted.
+func parseMetadata(header string) (map[string]string, error) {
+	if header == "" {
+		return nil, nil
+	}
+	result := map[string]string{}
+	for _, pair := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, errors.New("metadata key is empty")
+		}
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("metadata value for key %q is not base64 encoded: %w", key, err)
+		}
+		result[key] = string(decoded)
+	}
+	return result, nil
+}
+
+func formatMetadata(metadata map[string]string) string {
+	pairs := make([]string, 0, len(metadata))
+	for k, v := range metadata {
+		pairs = append(pairs, k+" "+base64.StdEncoding.EncodeToString([]byte(v)))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func generateID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return fmt.Sprintf("%x", b)
+}
@@ -0,0 +1,275 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echolargeupload
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestServer(t *testing.T, config Config) (*echo.Echo, *FileStorage) {
+	storage, err := NewFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Storage = storage
+	if config.IDGenerator == nil {
+		config.IDGenerator = func() string { return "abc" }
+	}
+
+	e := echo.New()
+	Register(e.Group("/files"), config)
+	return e, storage
+}
+
+func doRequest(e *echo.Echo, method string, target string, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(headerTusResumable, TusVersion)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestUploadFlow(t *testing.T) {
+	var completed UploadInfo
+	var scanned string
+	e, storage := newTestServer(t, Config{
+		ScanFunc: func(c echo.Context, info UploadInfo, r io.Reader) error {
+			b, err := io.ReadAll(r)
+			scanned = string(b)
+			return err
+		},
+		OnComplete: func(c echo.Context, info UploadInfo) error {
+			completed = info
+			return nil
+		},
+	})
+
+	rec := doRequest(e, http.MethodPost, "/files", "", map[string]string{
+		headerUploadLength:   "11",
+		headerUploadMetadata: "filename d29ybGQudHh0,empty",
+	})
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "/files/abc", rec.Header().Get(echo.HeaderLocation))
+	assert.Equal(t, TusVersion, rec.Header().Get(headerTusResumable))
+
+	rec = doRequest(e, http.MethodPatch, "/files/abc", "hello", map[string]string{
+		echo.HeaderContentType: mimeOffsetOctetStream,
+		headerUploadOffset:     "0",
+	})
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "5", rec.Header().Get(headerUploadOffset))
+
+	rec = doRequest(e, http.MethodHead, "/files/abc", "", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "5", rec.Header().Get(headerUploadOffset))
+	assert.Equal(t, "11", rec.Header().Get(headerUploadLength))
+	assert.Equal(t, "empty ,filename d29ybGQudHh0", rec.Header().Get(headerUploadMetadata))
+	assert.Equal(t, "no-store", rec.Header().Get(echo.HeaderCacheControl))
+
+	rec = doRequest(e, http.MethodPatch, "/files/abc", " world", map[string]string{
+		echo.HeaderContentType: mimeOffsetOctetStream,
+		headerUploadOffset:     "5",
+	})
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "11", rec.Header().Get(headerUploadOffset))
+
+	assert.Equal(t, "hello world", scanned)
+	assert.Equal(t, UploadInfo{
+		ID:       "abc",
+		Size:     11,
+		Offset:   11,
+		Metadata: map[string]string{"filename": "world.txt", "empty": ""},
+	}, completed)
+
+	r, err := storage.Reader(context.Background(), "abc")
+	assert.NoError(t, err)
+	b, _ := io.ReadAll(r)
+	r.Close()
+	assert.Equal(t, "hello world", string(b))
+
+	rec = doRequest(e, http.MethodDelete, "/files/abc", "", nil)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = doRequest(e, http.MethodHead, "/files/abc", "", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestOptions(t *testing.T) {
+	e, _ := newTestServer(t, Config{MaxSize: 100})
+
+	req := httptest.NewRequest(http.MethodOptions, "/files", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, TusVersion, rec.Header().Get(headerTusVersion))
+	assert.Equal(t, tusExtensions, rec.Header().Get(headerTusExtension))
+	assert.Equal(t, "100", rec.Header().Get(headerTusMaxSize))
+}
+
+func TestRequestErrors(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		whenMethod  string
+		whenTarget  string
+		whenBody    string
+		whenHeaders map[string]string
+		expectCode  int
+	}{
+		{
+			name:        "nok, unsupported protocol version",
+			whenMethod:  http.MethodHead,
+			whenTarget:  "/files/abc",
+			whenHeaders: map[string]string{headerTusResumable: "0.2.2"},
+			expectCode:  http.StatusPreconditionFailed,
+		},
+		{
+			name:        "nok, missing upload length",
+			whenMethod:  http.MethodPost,
+			whenTarget:  "/files",
+			whenHeaders: map[string]string{},
+			expectCode:  http.StatusBadRequest,
+		},
+		{
+			name:        "nok, upload too large",
+			whenMethod:  http.MethodPost,
+			whenTarget:  "/files",
+			whenHeaders: map[string]string{headerUploadLength: "101"},
+			expectCode:  http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:        "nok, invalid metadata",
+			whenMethod:  http.MethodPost,
+			whenTarget:  "/files",
+			whenHeaders: map[string]string{headerUploadLength: "10", headerUploadMetadata: "filename !!!"},
+			expectCode:  http.StatusBadRequest,
+		},
+		{
+			name:        "nok, offset mismatch",
+			whenMethod:  http.MethodPatch,
+			whenTarget:  "/files/existing",
+			whenBody:    "x",
+			whenHeaders: map[string]string{echo.HeaderContentType: mimeOffsetOctetStream, headerUploadOffset: "3"},
+			expectCode:  http.StatusConflict,
+		},
+		{
+			name:        "nok, chunk exceeds upload length",
+			whenMethod:  http.MethodPatch,
+			whenTarget:  "/files/existing",
+			whenBody:    "hello world",
+			whenHeaders: map[string]string{echo.HeaderContentType: mimeOffsetOctetStream, headerUploadOffset: "0"},
+			expectCode:  http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:        "nok, invalid content type",
+			whenMethod:  http.MethodPatch,
+			whenTarget:  "/files/existing",
+			whenBody:    "x",
+			whenHeaders: map[string]string{echo.HeaderContentType: echo.MIMETextPlain, headerUploadOffset: "0"},
+			expectCode:  http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "nok, unknown upload",
+			whenMethod:  http.MethodPatch,
+			whenTarget:  "/files/unknown",
+			whenBody:    "x",
+			whenHeaders: map[string]string{echo.HeaderContentType: mimeOffsetOctetStream, headerUploadOffset: "0"},
+			expectCode:  http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e, storage := newTestServer(t, Config{MaxSize: 100})
+			err := storage.Create(context.Background(), UploadInfo{ID: "existing", Size: 10})
+			assert.NoError(t, err)
+
+			rec := doRequest(e, tc.whenMethod, tc.whenTarget, tc.whenBody, tc.whenHeaders)
+			assert.Equal(t, tc.expectCode, rec.Code)
+		})
+	}
+}
+
+func TestExpiration(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	e, _ := newTestServer(t, Config{
+		Expiration: time.Hour,
+		timeNow:    func() time.Time { return now },
+	})
+
+	rec := doRequest(e, http.MethodPost, "/files/", "", map[string]string{headerUploadLength: "10"})
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "Mon, 01 Jan 2024 13:00:00 GMT", rec.Header().Get(headerUploadExpires))
+
+	now = now.Add(2 * time.Hour)
+	rec = doRequest(e, http.MethodHead, "/files/abc", "", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestScanFuncRejectsUpload(t *testing.T) {
+	completed := false
+	e, storage := newTestServer(t, Config{
+		ScanFunc: func(c echo.Context, info UploadInfo, r io.Reader) error {
+			return errors.New("virus found")
+		},
+		OnComplete: func(c echo.Context, info UploadInfo) error {
+			completed = true
+			return nil
+		},
+	})
+
+	rec := doRequest(e, http.MethodPost, "/files", "", map[string]string{headerUploadLength: "3"})
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	rec = doRequest(e, http.MethodPatch, "/files/abc", "bad", map[string]string{
+		echo.HeaderContentType: mimeOffsetOctetStream,
+		headerUploadOffset:     "0",
+	})
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.False(t, completed)
+
+	_, err := storage.Info(context.Background(), "abc")
+	assert.ErrorIs(t, err, ErrUploadNotFound)
+}
+
+func TestStreamedChunkExceedsUploadLength(t *testing.T) {
+	completed := false
+	e, storage := newTestServer(t, Config{
+		OnComplete: func(c echo.Context, info UploadInfo) error {
+			completed = true
+			return nil
+		},
+	})
+
+	rec := doRequest(e, http.MethodPost, "/files", "", map[string]string{headerUploadLength: "5"})
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	req := httptest.NewRequest(http.MethodPatch, "/files/abc", strings.NewReader("hello world"))
+	req.ContentLength = -1 // chunked transfer encoding
+	req.Header.Set(headerTusResumable, TusVersion)
+	req.Header.Set(echo.HeaderContentType, mimeOffsetOctetStream)
+	req.Header.Set(headerUploadOffset, "0")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.False(t, completed)
+
+	info, err := storage.Info(context.Background(), "abc")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), info.Offset)
+}
@@ -0,0 +1,276 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echolargeupload
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+)
+
+// S3Client is minimal S3 client used by S3Storage. Package does not depend on any S3 client library, client is adapted
+// with this interface. For example with github.com/aws/aws-sdk-go-v2:
+//
+//	type awsS3Client struct {
+//		client   *s3.Client
+//		uploader *manager.Uploader
+//		bucket   string
+//	}
+//
+//	func (c awsS3Client) GetObject(ctx context.Context, key string) (io.ReadCloser, error) {
+//		out, err := c.client.GetObject(ctx, &s3.GetObjectInput{Bucket: &c.bucket, Key: &key})
+//		var noSuchKey *types.NoSuchKey
+//		if errors.As(err, &noSuchKey) {
+//			return nil, nil
+//		} else if err != nil {
+//			return nil, err
+//		}
+//		return out.Body, nil
+//	}
+//
+//	func (c awsS3Client) PutObject(ctx context.Context, key string, body io.Reader) error {
+//		_, err := c.uploader.Upload(ctx, &s3.PutObjectInput{Bucket: &c.bucket, Key: &key, Body: body})
+//		return err
+//	}
+//
+//	func (c awsS3Client) DeleteObject(ctx context.Context, key string) error {
+//		_, err := c.client.DeleteObject(ctx, &s3.DeleteObjectInput{Bucket: &c.bucket, Key: &key})
+//		return err
+//	}
+type S3Client interface {
+	// GetObject returns reader for object contents or nil reader when object does not exist.
+	GetObject(ctx context.Context, key string) (io.ReadCloser, error)
+	// PutObject stores object with contents read from body until EOF.
+	PutObject(ctx context.Context, key string, body io.Reader) error
+	// DeleteObject deletes the object. Deleting object that does not exist is not an error.
+	DeleteObject(ctx context.Context, key string) error
+}
+
+// S3Storage is Storage implementation keeping uploads in S3 compatible object storage. Each upload is stored as
+// `<prefix><id>.info` object with upload state in JSON format and `<prefix><id>.<offset>` object for every received
+// chunk. Reader returns chunks concatenated in order.
+//
+// Chunks are written under lock held in the process, so all requests for the same upload must be served by the same
+// instance of S3Storage. Interrupted chunk is not stored, client continues from the end of last complete chunk.
+// Expired uploads are deleted when they are accessed, use bucket lifecycle rules to remove abandoned uploads.
+type S3Storage struct {
+	client S3Client
+	prefix string
+
+	mu    sync.Mutex
+	locks map[string]*uploadLock
+}
+
+// s3UploadInfo is upload state stored in the info object.
+type s3UploadInfo struct {
+	UploadInfo
+	// Chunks contains offsets of stored chunks in order.
+	Chunks []int64 `json:"chunks,omitempty"`
+}
+
+// NewS3Storage creates new S3Storage storing uploads under keys with given prefix.
+func NewS3Storage(client S3Client, prefix string) *S3Storage {
+	return &S3Storage{
+		client: client,
+		prefix: prefix,
+		locks:  map[string]*uploadLock{},
+	}
+}
+
+// Create implements Storage.Create.
+func (s *S3Storage) Create(ctx context.Context, info UploadInfo) error {
+	if err := validateID(info.ID); err != nil {
+		return err
+	}
+	unlock := lockUpload(&s.mu, s.locks, info.ID)
+	defer unlock()
+
+	_, err := s.readInfo(ctx, info.ID)
+	if err == nil {
+		return fmt.Errorf("upload already exists: %q", info.ID)
+	} else if !errors.Is(err, ErrUploadNotFound) {
+		return err
+	}
+	return s.writeInfo(ctx, s3UploadInfo{UploadInfo: info})
+}
+
+// Info implements Storage.Info.
+func (s *S3Storage) Info(ctx context.Context, id string) (UploadInfo, error) {
+	if err := validateID(id); err != nil {
+		return UploadInfo{}, ErrUploadNotFound
+	}
+	unlock := lockUpload(&s.mu, s.locks, id)
+	defer unlock()
+
+	info, err := s.readInfo(ctx, id)
+	return info.UploadInfo, err
+}
+
+// WriteChunk implements Storage.WriteChunk.
+func (s *S3Storage) WriteChunk(ctx context.Context, id string, offset int64, r io.Reader) (int64, error) {
+	if err := validateID(id); err != nil {
+		return 0, ErrUploadNotFound
+	}
+	unlock := lockUpload(&s.mu, s.locks, id)
+	defer unlock()
+
+	info, err := s.readInfo(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	if info.Offset != offset {
+		return 0, ErrOffsetMismatch
+	}
+
+	remaining := info.Size - offset
+	cr := &countingReader{r: io.LimitReader(r, remaining+1)}
+	key := s.chunkKey(id, offset)
+	if err := s.client.PutObject(ctx, key, cr); err != nil {
+		return 0, errors.Join(err, s.client.DeleteObject(ctx, key))
+	}
+	if cr.n > remaining {
+		return 0, errors.Join(ErrSizeExceeded, s.client.DeleteObject(ctx, key))
+	}
+	if cr.n == 0 {
+		return 0, s.client.DeleteObject(ctx, key)
+	}
+
+	info.Offset += cr.n
+	info.Chunks = append(info.Chunks, offset)
+	return cr.n, s.writeInfo(ctx, info)
+}
+
+// Reader implements Storage.Reader.
+func (s *S3Storage) Reader(ctx context.Context, id string) (io.ReadCloser, error) {
+	if err := validateID(id); err != nil {
+		return nil, ErrUploadNotFound
+	}
+	unlock := lockUpload(&s.mu, s.locks, id)
+	defer unlock()
+
+	info, err := s.readInfo(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(info.Chunks))
+	for _, offset := range info.Chunks {
+		keys = append(keys, s.chunkKey(id, offset))
+	}
+	return &s3ChunksReader{ctx: ctx, client: s.client, keys: keys}, nil
+}
+
+// Delete implements Storage.Delete.
+func (s *S3Storage) Delete(ctx context.Context, id string) error {
+	if err := validateID(id); err != nil {
+		return ErrUploadNotFound
+	}
+	unlock := lockUpload(&s.mu, s.locks, id)
+	defer unlock()
+
+	info, err := s.readInfo(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, offset := range info.Chunks {
+		if err := s.client.DeleteObject(ctx, s.chunkKey(id, offset)); err != nil {
+			return err
+		}
+	}
+	return s.client.DeleteObject(ctx, s.infoKey(id))
+}
+
+func (s *S3Storage) readInfo(ctx context.Context, id string) (s3UploadInfo, error) {
+	r, err := s.client.GetObject(ctx, s.infoKey(id))
+	if err != nil {
+		return s3UploadInfo{}, err
+	}
+	if r == nil {
+		return s3UploadInfo{}, ErrUploadNotFound
+	}
+	defer r.Close()
+
+	info := s3UploadInfo{}
+	if err := json.NewDecoder(r).Decode(&info); err != nil {
+		return s3UploadInfo{}, fmt.Errorf("failed to decode upload info: %w", err)
+	}
+	return info, nil
+}
+
+func (s *S3Storage) writeInfo(ctx context.Context, info s3UploadInfo) error {
+	b, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	return s.client.PutObject(ctx, s.infoKey(info.ID), bytes.NewReader(b))
+}
+
+func (s *S3Storage) infoKey(id string) string {
+	return s.prefix + id + ".info"
+}
+
+func (s *S3Storage) chunkKey(id string, offset int64) string {
+	return s.prefix + id + "." + strconv.FormatInt(offset, 10)
+}
+
+// s3ChunksReader reads chunk objects one after another opening each of them only when previous one is exhausted.
+type s3ChunksReader struct {
+	ctx     context.Context
+	client  S3Client
+	keys    []string
+	current io.ReadCloser
+}
+
+func (r *s3ChunksReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.keys) == 0 {
+				return 0, io.EOF
+			}
+			rc, err := r.client.GetObject(r.ctx, r.keys[0])
+			if err != nil {
+				return 0, err
+			}
+			if rc == nil {
+				return 0, fmt.Errorf("upload chunk not found: %q", r.keys[0])
+			}
+			r.keys = r.keys[1:]
+			r.current = rc
+		}
+		n, err := r.current.Read(p)
+		if errors.Is(err, io.EOF) {
+			err = r.current.Close()
+			r.current = nil
+			if n > 0 || err != nil {
+				return n, err
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (r *s3ChunksReader) Close() error {
+	if r.current == nil {
+		return nil
+	}
+	err := r.current.Close()
+	r.current = nil
+	return err
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echolargeupload
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryS3Client struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (c *memoryS3Client) GetObject(ctx context.Context, key string) (io.ReadCloser, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, ok := c.objects[key]
+	if !ok {
+		return nil, nil
+	}
+	return io.NopCloser(bytes.NewReader(b)), nil
+}
+
+func (c *memoryS3Client) PutObject(ctx context.Context, key string, body io.Reader) error {
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.objects[key] = b
+	return nil
+}
+
+func (c *memoryS3Client) DeleteObject(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.objects, key)
+	return nil
+}
+
+func TestS3Storage(t *testing.T) {
+	client := &memoryS3Client{objects: map[string][]byte{}}
+	storage := NewS3Storage(client, "uploads/")
+	ctx := context.Background()
+
+	assert.NoError(t, storage.Create(ctx, UploadInfo{ID: "abc", Size: 11, Metadata: map[string]string{"filename": "a.txt"}}))
+	assert.Error(t, storage.Create(ctx, UploadInfo{ID: "abc", Size: 11}))
+
+	n, err := storage.WriteChunk(ctx, "abc", 0, strings.NewReader("hello"))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), n)
+
+	_, err = storage.WriteChunk(ctx, "abc", 0, strings.NewReader("hello"))
+	assert.ErrorIs(t, err, ErrOffsetMismatch)
+
+	n, err = storage.WriteChunk(ctx, "abc", 5, strings.NewReader(" world!"))
+	assert.ErrorIs(t, err, ErrSizeExceeded)
+	assert.Equal(t, int64(0), n)
+
+	n, err = storage.WriteChunk(ctx, "abc", 5, strings.NewReader(" world"))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(6), n)
+
+	info, err := storage.Info(ctx, "abc")
+	assert.NoError(t, err)
+	assert.Equal(t, UploadInfo{ID: "abc", Size: 11, Offset: 11, Metadata: map[string]string{"filename": "a.txt"}}, info)
+
+	r, err := storage.Reader(ctx, "abc")
+	assert.NoError(t, err)
+	b, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.NoError(t, r.Close())
+	assert.Equal(t, "hello world", string(b))
+
+	keys := make([]string, 0, len(client.objects))
+	for k := range client.objects {
+		keys = append(keys, k)
+	}
+	assert.ElementsMatch(t, []string{"uploads/abc.info", "uploads/abc.0", "uploads/abc.5"}, keys)
+
+	assert.NoError(t, storage.Delete(ctx, "abc"))
+	assert.Empty(t, client.objects)
+	assert.Empty(t, storage.locks)
+
+	_, err = storage.Info(ctx, "abc")
+	assert.ErrorIs(t, err, ErrUploadNotFound)
+	assert.ErrorIs(t, storage.Delete(ctx, "abc"), ErrUploadNotFound)
+}
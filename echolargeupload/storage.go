@@ -0,0 +1,286 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echolargeupload
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrUploadNotFound is returned by Storage when upload does not exist.
+	ErrUploadNotFound = errors.New("upload not found")
+	// ErrOffsetMismatch is returned by Storage when chunk offset does not match current upload offset.
+	ErrOffsetMismatch = errors.New("upload offset mismatch")
+	// ErrSizeExceeded is returned by Storage when chunk data goes past the declared upload size.
+	ErrSizeExceeded = errors.New("upload size exceeded")
+)
+
+// UploadInfo describes state of the single upload.
+type UploadInfo struct {
+	// ID is unique identifier of the upload.
+	ID string `json:"id"`
+	// Size is total size of the upload in bytes.
+	Size int64 `json:"size"`
+	// Offset is number of bytes received so far.
+	Offset int64 `json:"offset"`
+	// Metadata contains decoded `Upload-Metadata` header values.
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// ExpiresAt is time after which incomplete upload is considered expired. Zero value means upload does not expire.
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// IsComplete returns true when all bytes of the upload have been received.
+func (i UploadInfo) IsComplete() bool {
+	return i.Offset == i.Size
+}
+
+// IsExpired returns true when incomplete upload has expired at given time.
+func (i UploadInfo) IsExpired(now time.Time) bool {
+	return !i.IsComplete() && !i.ExpiresAt.IsZero() && now.After(i.ExpiresAt)
+}
+
+// Storage is backend where uploads are stored. Implementations must be safe for concurrent use.
+type Storage interface {
+	// Create creates new empty upload.
+	Create(ctx context.Context, info UploadInfo) error
+	// Info returns current state of the upload or ErrUploadNotFound.
+	Info(ctx context.Context, id string) (UploadInfo, error)
+	// WriteChunk appends data from reader to the upload starting at given offset and returns number of bytes written.
+	// Returns ErrOffsetMismatch when offset is not equal to current upload offset and ErrSizeExceeded, without storing
+	// anything, when reader has more data than remains until the upload size.
+	WriteChunk(ctx context.Context, id string, offset int64, r io.Reader) (int64, error)
+	// Reader returns reader for the upload contents.
+	Reader(ctx context.Context, id string) (io.ReadCloser, error)
+	// Delete removes upload and its contents.
+	Delete(ctx context.Context, id string) error
+}
+
+// FileStorage is Storage implementation keeping uploads in a directory on the filesystem. Each upload is stored as two
+// files: `<id>.bin` with contents and `<id>.info` with upload state in JSON format.
+type FileStorage struct {
+	dir string
+
+	mu    sync.Mutex
+	locks map[string]*uploadLock
+}
+
+// uploadLock is per upload mutex. It is removed from storage locks map when last holder or waiter unlocks it.
+type uploadLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// NewFileStorage creates new FileStorage using given directory. Directory is created when it does not exist.
+func NewFileStorage(dir string) (*FileStorage, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &FileStorage{
+		dir:   dir,
+		locks: map[string]*uploadLock{},
+	}, nil
+}
+
+// Create implements Storage.Create.
+func (s *FileStorage) Create(ctx context.Context, info UploadInfo) error {
+	if err := validateID(info.ID); err != nil {
+		return err
+	}
+	unlock := s.lock(info.ID)
+	defer unlock()
+
+	f, err := os.OpenFile(s.binPath(info.ID), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o640)
+	if err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return s.writeInfo(info)
+}
+
+// Info implements Storage.Info.
+func (s *FileStorage) Info(ctx context.Context, id string) (UploadInfo, error) {
+	if err := validateID(id); err != nil {
+		return UploadInfo{}, ErrUploadNotFound
+	}
+	unlock := s.lock(id)
+	defer unlock()
+
+	return s.readInfo(id)
+}
+
+// WriteChunk implements Storage.WriteChunk.
+func (s *FileStorage) WriteChunk(ctx context.Context, id string, offset int64, r io.Reader) (int64, error) {
+	if err := validateID(id); err != nil {
+		return 0, ErrUploadNotFound
+	}
+	unlock := s.lock(id)
+	defer unlock()
+
+	info, err := s.readInfo(id)
+	if err != nil {
+		return 0, err
+	}
+	if info.Offset != offset {
+		return 0, ErrOffsetMismatch
+	}
+
+	f, err := os.OpenFile(s.binPath(id), os.O_WRONLY, 0o640)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	// we store partially written data too, so client can continue from the point where connection was interrupted
+	remaining := info.Size - offset
+	n, copyErr := io.Copy(f, io.LimitReader(r, remaining+1))
+	if n > remaining {
+		if err := f.Truncate(offset); err != nil {
+			return 0, err
+		}
+		return 0, ErrSizeExceeded
+	}
+	info.Offset += n
+	if err := s.writeInfo(info); err != nil {
+		return n, err
+	}
+	return n, copyErr
+}
+
+// Reader implements Storage.Reader.
+func (s *FileStorage) Reader(ctx context.Context, id string) (io.ReadCloser, error) {
+	if err := validateID(id); err != nil {
+		return nil, ErrUploadNotFound
+	}
+	f, err := os.Open(s.binPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrUploadNotFound
+	}
+	return f, err
+}
+
+// Delete implements Storage.Delete.
+func (s *FileStorage) Delete(ctx context.Context, id string) error {
+	if err := validateID(id); err != nil {
+		return ErrUploadNotFound
+	}
+	unlock := s.lock(id)
+	defer unlock()
+
+	if _, err := s.readInfo(id); err != nil {
+		return err
+	}
+	if err := os.Remove(s.binPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return os.Remove(s.infoPath(id))
+}
+
+// DeleteExpired removes all uploads that have expired at given time and returns number of removed uploads.
+func (s *FileStorage) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".info")
+		if !ok {
+			continue
+		}
+		info, err := s.Info(ctx, id)
+		if err != nil {
+			continue
+		}
+		if !info.IsExpired(now) {
+			continue
+		}
+		if err := s.Delete(ctx, id); err != nil && !errors.Is(err, ErrUploadNotFound) {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+func (s *FileStorage) lock(id string) func() {
+	return lockUpload(&s.mu, s.locks, id)
+}
+
+// lockUpload locks upload with given id and returns function unlocking it. mu guards locks map.
+func lockUpload(mu *sync.Mutex, locks map[string]*uploadLock, id string) func() {
+	mu.Lock()
+	l, ok := locks[id]
+	if !ok {
+		l = &uploadLock{}
+		locks[id] = l
+	}
+	l.refs++
+	mu.Unlock()
+
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+		mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(locks, id)
+		}
+		mu.Unlock()
+	}
+}
+
+func (s *FileStorage) readInfo(id string) (UploadInfo, error) {
+	b, err := os.ReadFile(s.infoPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return UploadInfo{}, ErrUploadNotFound
+	} else if err != nil {
+		return UploadInfo{}, err
+	}
+	info := UploadInfo{}
+	if err := json.Unmarshal(b, &info); err != nil {
+		return UploadInfo{}, fmt.Errorf("failed to decode upload info: %w", err)
+	}
+	return info, nil
+}
+
+func (s *FileStorage) writeInfo(info UploadInfo) error {
+	b, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	tmp := s.infoPath(info.ID) + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o640); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.infoPath(info.ID))
+}
+
+func (s *FileStorage) binPath(id string) string {
+	return filepath.Join(s.dir, id+".bin")
+}
+
+func (s *FileStorage) infoPath(id string) string {
+	return filepath.Join(s.dir, id+".info")
+}
+
+func validateID(id string) error {
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return fmt.Errorf("invalid upload id: %q", id)
+	}
+	return nil
+}
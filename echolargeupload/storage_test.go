@@ -0,0 +1,92 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echolargeupload
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileStorage_locksAreReleased(t *testing.T) {
+	storage, err := NewFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	assert.NoError(t, storage.Create(ctx, UploadInfo{ID: "abc", Size: 5}))
+	n, err := storage.WriteChunk(ctx, "abc", 0, strings.NewReader("hello"))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), n)
+
+	for _, id := range []string{"unknown1", "unknown2", "unknown3"} {
+		_, err := storage.Info(ctx, id)
+		assert.ErrorIs(t, err, ErrUploadNotFound)
+		_, err = storage.WriteChunk(ctx, id, 0, strings.NewReader("x"))
+		assert.ErrorIs(t, err, ErrUploadNotFound)
+		assert.ErrorIs(t, storage.Delete(ctx, id), ErrUploadNotFound)
+	}
+
+	info, err := storage.Info(ctx, "abc")
+	assert.NoError(t, err)
+	assert.True(t, info.IsComplete())
+	assert.Empty(t, storage.locks)
+}
+
+func TestFileStorage_concurrentWrites(t *testing.T) {
+	storage, err := NewFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	assert.NoError(t, storage.Create(ctx, UploadInfo{ID: "abc", Size: 1}))
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	written := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n, err := storage.WriteChunk(ctx, "abc", 0, strings.NewReader("x"))
+			if err == nil {
+				mu.Lock()
+				written += int(n)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, written) // only one write succeeds, others get offset mismatch
+	assert.Empty(t, storage.locks)
+}
+
+func TestFileStorage_WriteChunkSizeExceeded(t *testing.T) {
+	storage, err := NewFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	assert.NoError(t, storage.Create(ctx, UploadInfo{ID: "abc", Size: 5}))
+
+	n, err := storage.WriteChunk(ctx, "abc", 0, strings.NewReader("hello world"))
+	assert.ErrorIs(t, err, ErrSizeExceeded)
+	assert.Equal(t, int64(0), n)
+
+	info, err := storage.Info(ctx, "abc")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), info.Offset)
+
+	r, err := storage.Reader(ctx, "abc")
+	assert.NoError(t, err)
+	b, _ := io.ReadAll(r)
+	r.Close()
+	assert.Empty(t, b)
+}
@@ -0,0 +1,439 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+/*
+Package echolockdown provides brute-force protection middleware for authentication endpoints.
+
+Middleware tracks failed authentication attempts per key (client IP, username or both) and locks the key out with
+exponentially growing lockout duration after too many failures. Locked requests are rejected with
+`429 Too Many Requests` and `Retry-After` header. After fewer failures clients are signaled with
+`X-Captcha-Required` response header that they should solve CAPTCHA before next attempt.
+
+Example:
+```
+package main
+
+import (
+
+	"net/http"
+
+	"github.com/labstack/echo-contrib/echolockdown"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+
+)
+
+	func main() {
+		e := echo.New()
+
+		store := echolockdown.NewMemoryStore()
+		e.POST("/login", login, echolockdown.MiddlewareWithConfig(echolockdown.Config{
+			Store:         store,
+			KeyExtractors: []echolockdown.KeyExtractor{echolockdown.IPKey, echolockdown.FormValueKey("username")},
+		}))
+
+		admin := e.Group("/admin/lockdown", middleware.BasicAuth(checkAdmin))
+		admin.GET("/:key", echolockdown.StatusHandler(store))
+		admin.DELETE("/:key", echolockdown.UnlockHandler(store))
+
+		e.Logger.Fatal(e.Start(":1323"))
+	}
+
+	func login(c echo.Context) error {
+		if echolockdown.CaptchaRequired(c) && !verifyCaptcha(c) {
+			return echo.ErrUnauthorized
+		}
+		// ... check credentials, return echo.ErrUnauthorized on failure
+		return c.NoContent(http.StatusOK)
+	}
+
+```
+*/
+package echolockdown
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// HeaderCaptchaRequired is response header set to `true` when client should solve CAPTCHA before next attempt.
+	HeaderCaptchaRequired = "X-Captcha-Required"
+
+	captchaContextKey = "_echolockdown_captcha_required"
+
+	ipKeyPrefix = "ip:"
+
+	defaultSubsystem = "echo_lockdown"
+)
+
+// KeyExtractor returns key that failed attempts are tracked by. Empty key is not tracked.
+type KeyExtractor func(c echo.Context) (string, error)
+
+// Config defines the config for lockdown middleware.
+type Config struct {
+	// Skipper defines a function to skip middleware.
+	Skipper middleware.Skipper
+
+	// Store keeps failure state of keys.
+	// Required.
+	Store Store
+
+	// KeyExtractors extract keys that failed attempts are tracked by. Request is rejected when any of the keys is locked.
+	// Defaults to: IPKey
+	KeyExtractors []KeyExtractor
+
+	// MaxFailures is number of failed attempts after which key is locked. Failures are recorded atomically, but lockout
+	// is checked before the handler is called, so requests already in flight when key gets locked are still handled and
+	// can add more failed attempts than MaxFailures (counted towards the next lockout).
+	// Defaults to: 5
+	MaxFailures int
+
+	// CaptchaAfter is number of failed attempts after which HeaderCaptchaRequired header is sent. Zero disables the header.
+	// Defaults to: 3
+	CaptchaAfter int
+
+	// LockoutDuration is duration of the first lockout. Every next lockout doubles the duration.
+	// Defaults to: 1 minute
+	LockoutDuration time.Duration
+
+	// MaxLockoutDuration caps the exponential lockout duration.
+	// Defaults to: 1 hour
+	MaxLockoutDuration time.Duration
+
+	// ResetAfter is duration after last failure (or lockout end) after which key state is forgotten.
+	// Defaults to: 15 minutes
+	ResetAfter time.Duration
+
+	// IsFailure decides if request was failed authentication attempt.
+	// Defaults to: handler returned error with status 401 or responded with status 401.
+	IsFailure func(c echo.Context, err error) bool
+
+	// ResetOnSuccess decides if state of the key is forgotten after successful (non-failure, status < 400) request.
+	// Client scoped keys (i.e. IPKey) should not be reset, otherwise attacker can clear throttling of their IP by
+	// logging into their own account. Use `func(string) bool { return false }` to never reset state.
+	// Defaults to: all keys except keys returned by IPKey are reset.
+	ResetOnSuccess func(key string) bool
+
+	// Registerer is used to register failure, lockout and blocked request counters. When nil no metrics are registered.
+	// Optional.
+	Registerer prometheus.Registerer
+
+	// Namespace is components of the fully-qualified name of the Metric (created by joining Namespace,Subsystem and Name components with "_")
+	// Optional
+	Namespace string
+
+	// Subsystem is components of the fully-qualified name of the Metric (created by joining Namespace,Subsystem and Name components with "_")
+	// Defaults to: "echo_lockdown"
+	Subsystem string
+
+	timeNow func() time.Time
+}
+
+// DefaultConfig is the default lockdown middleware config.
+var DefaultConfig = Config{
+	Skipper:            middleware.DefaultSkipper,
+	KeyExtractors:      []KeyExtractor{IPKey},
+	MaxFailures:        5,
+	CaptchaAfter:       3,
+	LockoutDuration:    time.Minute,
+	MaxLockoutDuration: time.Hour,
+	ResetAfter:         15 * time.Minute,
+	IsFailure:          defaultIsFailure,
+	ResetOnSuccess:     defaultResetOnSuccess,
+}
+
+// Middleware returns lockdown middleware tracking failed attempts by client IP in given store.
+func Middleware(store Store) echo.MiddlewareFunc {
+	c := DefaultConfig
+	c.Store = store
+	return MiddlewareWithConfig(c)
+}
+
+// MiddlewareWithConfig returns lockdown middleware with config or panics on invalid configuration.
+// See: `Middleware()`.
+func MiddlewareWithConfig(config Config) echo.MiddlewareFunc {
+	mw, err := config.ToMiddleware()
+	if err != nil {
+		panic(err)
+	}
+	return mw
+}
+
+// ToMiddleware converts configuration to middleware or returns an error.
+func (config Config) ToMiddleware() (echo.MiddlewareFunc, error) {
+	if config.Store == nil {
+		return nil, errors.New("echolockdown: middleware requires Store")
+	}
+	if config.Skipper == nil {
+		config.Skipper = DefaultConfig.Skipper
+	}
+	if len(config.KeyExtractors) == 0 {
+		config.KeyExtractors = DefaultConfig.KeyExtractors
+	}
+	if config.MaxFailures <= 0 {
+		config.MaxFailures = DefaultConfig.MaxFailures
+	}
+	if config.CaptchaAfter < 0 {
+		return nil, errors.New("echolockdown: CaptchaAfter can not be negative")
+	}
+	if config.LockoutDuration <= 0 {
+		config.LockoutDuration = DefaultConfig.LockoutDuration
+	}
+	if config.MaxLockoutDuration <= 0 {
+		config.MaxLockoutDuration = DefaultConfig.MaxLockoutDuration
+	}
+	if config.MaxLockoutDuration < config.LockoutDuration {
+		return nil, errors.New("echolockdown: MaxLockoutDuration can not be smaller than LockoutDuration")
+	}
+	if config.ResetAfter <= 0 {
+		config.ResetAfter = DefaultConfig.ResetAfter
+	}
+	if config.IsFailure == nil {
+		config.IsFailure = DefaultConfig.IsFailure
+	}
+	if config.ResetOnSuccess == nil {
+		config.ResetOnSuccess = DefaultConfig.ResetOnSuccess
+	}
+	if config.Subsystem == "" {
+		config.Subsystem = defaultSubsystem
+	}
+	if config.timeNow == nil {
+		config.timeNow = time.Now
+	}
+
+	failures := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: config.Subsystem,
+		Name:      "failures_total",
+		Help:      "How many failed authentication attempts were recorded.",
+	})
+	lockouts := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: config.Subsystem,
+		Name:      "lockouts_total",
+		Help:      "How many times keys were locked out.",
+	})
+	blocked := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: config.Subsystem,
+		Name:      "blocked_requests_total",
+		Help:      "How many requests were rejected because key was locked out.",
+	})
+	if config.Registerer != nil {
+		for _, c := range []prometheus.Collector{failures, lockouts, blocked} {
+			if err := config.Registerer.Register(c); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			keys := make([]string, 0, len(config.KeyExtractors))
+			for _, extractor := range config.KeyExtractors {
+				key, err := extractor(c)
+				if err != nil {
+					return err
+				}
+				if key != "" {
+					keys = append(keys, key)
+				}
+			}
+			if len(keys) == 0 {
+				return next(c)
+			}
+
+			ctx := c.Request().Context()
+			now := config.timeNow()
+			var lockedUntil time.Time
+			captcha := false
+			for _, key := range keys {
+				state, err := config.Store.Get(ctx, key)
+				if err != nil {
+					return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+				}
+				if state.IsLocked(now) && state.LockedUntil.After(lockedUntil) {
+					lockedUntil = state.LockedUntil
+				}
+				if config.CaptchaAfter > 0 && state.Failures >= config.CaptchaAfter {
+					captcha = true
+				}
+			}
+			if !lockedUntil.IsZero() {
+				blocked.Inc()
+				retryAfter := int(math.Ceil(lockedUntil.Sub(now).Seconds()))
+				c.Response().Header().Set(echo.HeaderRetryAfter, strconv.Itoa(retryAfter))
+				return echo.NewHTTPError(http.StatusTooManyRequests, "too many failed attempts, try again later")
+			}
+			if captcha {
+				c.Set(captchaContextKey, true)
+				c.Response().Header().Set(HeaderCaptchaRequired, "true")
+			}
+
+			err := next(c)
+
+			if config.IsFailure(c, err) {
+				failures.Inc()
+				for _, key := range keys {
+					lockedOut := false
+					state, uErr := config.Store.Update(ctx, key, func(s State) State {
+						s, lockedOut = config.recordFailure(s, now)
+						return s
+					})
+					if uErr != nil {
+						c.Logger().Errorf("echolockdown: failed to record failure: %v", uErr)
+						continue
+					}
+					if lockedOut {
+						lockouts.Inc()
+					}
+					if config.CaptchaAfter > 0 && state.Failures >= config.CaptchaAfter && !c.Response().Committed {
+						c.Response().Header().Set(HeaderCaptchaRequired, "true")
+					}
+				}
+				return err
+			}
+
+			if err == nil && c.Response().Status < http.StatusBadRequest {
+				for _, key := range keys {
+					if !config.ResetOnSuccess(key) {
+						continue
+					}
+					if dErr := config.Store.Delete(ctx, key); dErr != nil {
+						c.Logger().Errorf("echolockdown: failed to reset state: %v", dErr)
+					}
+				}
+			}
+			return err
+		}
+	}, nil
+}
+
+// recordFailure adds failed attempt to the state and returns the new state and true when key was locked out.
+func (config Config) recordFailure(s State, now time.Time) (State, bool) {
+	lockedOut := false
+	s.Failures++
+	s.LastFailure = now
+	if s.Failures >= config.MaxFailures {
+		lockedOut = true
+		s.Failures = 0
+		s.Lockouts++
+		s.LockedUntil = now.Add(config.lockoutDuration(s.Lockouts))
+	}
+	if s.LockedUntil.After(now) {
+		s.ExpiresAt = s.LockedUntil.Add(config.ResetAfter)
+	} else {
+		s.ExpiresAt = now.Add(config.ResetAfter)
+	}
+	return s, lockedOut
+}
+
+// lockoutDuration returns duration of n-th lockout.
+func (config Config) lockoutDuration(n int) time.Duration {
+	d := config.LockoutDuration
+	for i := 1; i < n && d < config.MaxLockoutDuration; i++ {
+		d *= 2
+	}
+	if d > config.MaxLockoutDuration {
+		d = config.MaxLockoutDuration
+	}
+	return d
+}
+
+func defaultIsFailure(c echo.Context, err error) bool {
+	if err == nil {
+		return c.Response().Status == http.StatusUnauthorized
+	}
+	var he *echo.HTTPError
+	return errors.As(err, &he) && he.Code == http.StatusUnauthorized
+}
+
+func defaultResetOnSuccess(key string) bool {
+	return !strings.HasPrefix(key, ipKeyPrefix)
+}
+
+// CaptchaRequired returns true when client of the current request has failed enough attempts to be required to
+// solve CAPTCHA.
+func CaptchaRequired(c echo.Context) bool {
+	required, _ := c.Get(captchaContextKey).(bool)
+	return required
+}
+
+// IPKey tracks failed attempts by client IP address (see `echo.Context.RealIP`).
+func IPKey(c echo.Context) (string, error) {
+	return ipKeyPrefix + c.RealIP(), nil
+}
+
+// FormValueKey returns KeyExtractor tracking failed attempts by form field value (e.g. username).
+func FormValueKey(name string) KeyExtractor {
+	return func(c echo.Context) (string, error) {
+		v := c.FormValue(name)
+		if v == "" {
+			return "", nil
+		}
+		return "form:" + name + ":" + v, nil
+	}
+}
+
+// HeaderKey returns KeyExtractor tracking failed attempts by request header value.
+func HeaderKey(name string) KeyExtractor {
+	return func(c echo.Context) (string, error) {
+		v := c.Request().Header.Get(name)
+		if v == "" {
+			return "", nil
+		}
+		return "header:" + name + ":" + v, nil
+	}
+}
+
+// StatusHandler returns handler responding with JSON state of the key given in `:key` route parameter. Handler must
+// be protected with authentication middleware.
+func StatusHandler(store Store) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		key, err := keyParam(c)
+		if err != nil {
+			return err
+		}
+		state, err := store.Get(c.Request().Context(), key)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+		}
+		return c.JSON(http.StatusOK, state)
+	}
+}
+
+// UnlockHandler returns handler removing state (and lockout) of the key given in `:key` route parameter. Handler must
+// be protected with authentication middleware.
+func UnlockHandler(store Store) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		key, err := keyParam(c)
+		if err != nil {
+			return err
+		}
+		if err := store.Delete(c.Request().Context(), key); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+		}
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+func keyParam(c echo.Context) (string, error) {
+	key := c.Param("key")
+	if key == "" {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "missing key")
+	}
+	return key, nil
+}
@@ -0,0 +1,252 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echolockdown
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func newTestEcho(config Config) *echo.Echo {
+	e := echo.New()
+	e.POST("/login", func(c echo.Context) error {
+		if c.FormValue("password") != "secret" {
+			return echo.ErrUnauthorized
+		}
+		return c.String(http.StatusOK, "welcome")
+	}, MiddlewareWithConfig(config))
+	return e
+}
+
+func login(e *echo.Echo, username string, password string) *httptest.ResponseRecorder {
+	form := url.Values{"username": {username}, "password": {password}}
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(form.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	req.RemoteAddr = "192.0.2.1:1234"
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMiddleware_lockout(t *testing.T) {
+	clock := &testClock{now: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)}
+	store := NewMemoryStore()
+	store.timeNow = clock.Now
+	reg := prometheus.NewRegistry()
+
+	e := newTestEcho(Config{
+		Store:           store,
+		MaxFailures:     3,
+		CaptchaAfter:    2,
+		LockoutDuration: 10 * time.Second,
+		Registerer:      reg,
+		timeNow:         clock.Now,
+	})
+
+	rec := login(e, "jon", "wrong")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, "", rec.Header().Get(HeaderCaptchaRequired))
+
+	rec = login(e, "jon", "wrong")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, "true", rec.Header().Get(HeaderCaptchaRequired))
+
+	rec = login(e, "jon", "wrong") // third failure locks key out
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = login(e, "jon", "secret")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "10", rec.Header().Get(echo.HeaderRetryAfter))
+
+	clock.now = clock.now.Add(10 * time.Second)
+	for i := 0; i < 3; i++ {
+		login(e, "jon", "wrong")
+	}
+	rec = login(e, "jon", "secret")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "20", rec.Header().Get(echo.HeaderRetryAfter)) // second lockout is twice as long
+
+	clock.now = clock.now.Add(20 * time.Second)
+	rec = login(e, "jon", "secret")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	state, err := store.Get(context.Background(), "ip:192.0.2.1")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, state.Lockouts) // successful login does not reset state of IP key
+
+	mfs, err := reg.Gather()
+	assert.NoError(t, err)
+	counters := map[string]float64{}
+	for _, mf := range mfs {
+		counters[mf.GetName()] = mf.GetMetric()[0].GetCounter().GetValue()
+	}
+	assert.Equal(t, map[string]float64{
+		"echo_lockdown_failures_total":         6,
+		"echo_lockdown_lockouts_total":         2,
+		"echo_lockdown_blocked_requests_total": 2,
+	}, counters)
+}
+
+func TestMiddleware_formValueKey(t *testing.T) {
+	store := NewMemoryStore()
+	e := newTestEcho(Config{
+		Store:         store,
+		KeyExtractors: []KeyExtractor{FormValueKey("username")},
+		MaxFailures:   2,
+	})
+
+	login(e, "jon", "wrong")
+	login(e, "jon", "wrong")
+
+	assert.Equal(t, http.StatusTooManyRequests, login(e, "jon", "secret").Code)
+	assert.Equal(t, http.StatusOK, login(e, "arya", "secret").Code)
+}
+
+func TestMiddleware_resetOnSuccess(t *testing.T) {
+	store := NewMemoryStore()
+	e := newTestEcho(Config{
+		Store:         store,
+		KeyExtractors: []KeyExtractor{IPKey, FormValueKey("username")},
+		MaxFailures:   3,
+	})
+
+	login(e, "jon", "wrong")
+	login(e, "mallory", "wrong")
+	// attacker logging into their own account from the same IP must not clear throttling of the IP
+	assert.Equal(t, http.StatusOK, login(e, "mallory", "secret").Code)
+
+	ctx := context.Background()
+	state, err := store.Get(ctx, "ip:192.0.2.1")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, state.Failures)
+	state, err = store.Get(ctx, "form:username:mallory")
+	assert.NoError(t, err)
+	assert.Equal(t, State{}, state)
+	state, err = store.Get(ctx, "form:username:jon")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, state.Failures)
+
+	login(e, "arya", "wrong") // third failure from the IP locks it out
+	assert.Equal(t, http.StatusTooManyRequests, login(e, "mallory", "secret").Code)
+}
+
+func TestMiddleware_captchaRequired(t *testing.T) {
+	store := NewMemoryStore()
+	var captcha []bool
+	e := echo.New()
+	e.POST("/login", func(c echo.Context) error {
+		captcha = append(captcha, CaptchaRequired(c))
+		return echo.ErrUnauthorized
+	}, MiddlewareWithConfig(Config{Store: store, CaptchaAfter: 1}))
+
+	login(e, "jon", "wrong")
+	login(e, "jon", "wrong")
+
+	assert.Equal(t, []bool{false, true}, captcha)
+}
+
+func TestMiddleware_lockoutExpires(t *testing.T) {
+	clock := &testClock{now: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)}
+	store := NewMemoryStore()
+	store.timeNow = clock.Now
+
+	e := newTestEcho(Config{
+		Store:       store,
+		MaxFailures: 1,
+		ResetAfter:  time.Minute,
+		timeNow:     clock.Now,
+	})
+
+	login(e, "jon", "wrong")
+	clock.now = clock.now.Add(2 * time.Minute) // lockout (1 minute) + ResetAfter
+
+	state, err := store.Get(context.Background(), "ip:192.0.2.1")
+	assert.NoError(t, err)
+	assert.Equal(t, State{}, state)
+}
+
+func TestAdminHandlers(t *testing.T) {
+	store := NewMemoryStore()
+	e := newTestEcho(Config{Store: store, MaxFailures: 1})
+	e.GET("/admin/lockdown/:key", StatusHandler(store))
+	e.DELETE("/admin/lockdown/:key", UnlockHandler(store))
+
+	login(e, "jon", "wrong")
+	assert.Equal(t, http.StatusTooManyRequests, login(e, "jon", "secret").Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/lockdown/ip:192.0.2.1", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"lockouts":1`)
+
+	req = httptest.NewRequest(http.MethodDelete, "/admin/lockdown/ip:192.0.2.1", nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	assert.Equal(t, http.StatusOK, login(e, "jon", "secret").Code)
+}
+
+func TestConfig_ToMiddleware(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		givenConfig Config
+		expectErr   string
+	}{
+		{
+			name:        "ok",
+			givenConfig: Config{Store: NewMemoryStore()},
+		},
+		{
+			name:        "nok, missing store",
+			givenConfig: Config{},
+			expectErr:   "echolockdown: middleware requires Store",
+		},
+		{
+			name:        "nok, max lockout smaller than lockout",
+			givenConfig: Config{Store: NewMemoryStore(), LockoutDuration: time.Hour, MaxLockoutDuration: time.Minute},
+			expectErr:   "echolockdown: MaxLockoutDuration can not be smaller than LockoutDuration",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mw, err := tc.givenConfig.ToMiddleware()
+			if tc.expectErr != "" {
+				assert.EqualError(t, err, tc.expectErr)
+				assert.Nil(t, mw)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotNil(t, mw)
+		})
+	}
+}
+
+func TestConfig_lockoutDuration(t *testing.T) {
+	config := Config{LockoutDuration: time.Minute, MaxLockoutDuration: 5 * time.Minute}
+
+	assert.Equal(t, time.Minute, config.lockoutDuration(1))
+	assert.Equal(t, 2*time.Minute, config.lockoutDuration(2))
+	assert.Equal(t, 4*time.Minute, config.lockoutDuration(3))
+	assert.Equal(t, 5*time.Minute, config.lockoutDuration(4))
+	assert.Equal(t, 5*time.Minute, config.lockoutDuration(100))
+}
@@ -0,0 +1,113 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echolockdown
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// State is tracked authentication failure state of the single key.
+type State struct {
+	// Failures is number of consecutive failed attempts since last lockout or reset.
+	Failures int `json:"failures"`
+	// Lockouts is number of lockouts applied to the key. Used to calculate exponential lockout duration.
+	Lockouts int `json:"lockouts"`
+	// LastFailure is time of the last failed attempt.
+	LastFailure time.Time `json:"last_failure,omitempty"`
+	// LockedUntil is time until requests for the key are rejected. Zero value means key is not locked.
+	LockedUntil time.Time `json:"locked_until,omitempty"`
+	// ExpiresAt is time after which state is forgotten.
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// IsLocked returns true when key is locked at given time.
+func (s State) IsLocked(now time.Time) bool {
+	return now.Before(s.LockedUntil)
+}
+
+// IsExpired returns true when state has expired at given time.
+func (s State) IsExpired(now time.Time) bool {
+	return !s.ExpiresAt.IsZero() && !now.Before(s.ExpiresAt)
+}
+
+// Store keeps failure state of keys. Implementations must be safe for concurrent use.
+type Store interface {
+	// Get returns state of the key. Zero State is returned for unknown or expired keys.
+	Get(ctx context.Context, key string) (State, error)
+	// Update atomically replaces state of the key with value returned by fn and returns the new state. fn receives
+	// zero State for unknown or expired keys.
+	Update(ctx context.Context, key string, fn func(s State) State) (State, error)
+	// Delete removes state of the key.
+	Delete(ctx context.Context, key string) error
+}
+
+// MemoryStore is in-memory Store implementation. Expired states are removed lazily on access and with DeleteExpired.
+type MemoryStore struct {
+	mu     sync.Mutex
+	states map[string]State
+
+	timeNow func() time.Time
+}
+
+// NewMemoryStore creates new in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		states:  make(map[string]State),
+		timeNow: time.Now,
+	}
+}
+
+// Get implements Store.Get.
+func (s *MemoryStore) Get(ctx context.Context, key string) (State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.get(key), nil
+}
+
+// Update implements Store.Update.
+func (s *MemoryStore) Update(ctx context.Context, key string, fn func(s State) State) (State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := fn(s.get(key))
+	s.states[key] = state
+	return state, nil
+}
+
+// Delete implements Store.Delete.
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.states, key)
+	return nil
+}
+
+// DeleteExpired removes all expired states. Can be called periodically to limit memory usage.
+func (s *MemoryStore) DeleteExpired() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.timeNow()
+	for key, state := range s.states {
+		if state.IsExpired(now) {
+			delete(s.states, key)
+		}
+	}
+}
+
+func (s *MemoryStore) get(key string) State {
+	state, ok := s.states[key]
+	if !ok {
+		return State{}
+	}
+	if state.IsExpired(s.timeNow()) {
+		delete(s.states, key)
+		return State{}
+	}
+	return state
+}
@@ -0,0 +1,76 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echolockdown
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore(t *testing.T) {
+	clock := &testClock{now: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)}
+	store := NewMemoryStore()
+	store.timeNow = clock.Now
+	ctx := context.Background()
+
+	state, err := store.Get(ctx, "unknown")
+	assert.NoError(t, err)
+	assert.Equal(t, State{}, state)
+
+	state, err = store.Update(ctx, "a", func(s State) State {
+		s.Failures++
+		s.ExpiresAt = clock.now.Add(time.Minute)
+		return s
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, state.Failures)
+
+	state, err = store.Update(ctx, "a", func(s State) State {
+		s.Failures++
+		return s
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, state.Failures)
+
+	assert.NoError(t, store.Delete(ctx, "a"))
+	state, err = store.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, State{}, state)
+}
+
+func TestMemoryStore_DeleteExpired(t *testing.T) {
+	clock := &testClock{now: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)}
+	store := NewMemoryStore()
+	store.timeNow = clock.Now
+	ctx := context.Background()
+
+	_, _ = store.Update(ctx, "expiring", func(s State) State {
+		s.ExpiresAt = clock.now.Add(time.Minute)
+		return s
+	})
+	_, _ = store.Update(ctx, "long", func(s State) State {
+		s.ExpiresAt = clock.now.Add(time.Hour)
+		return s
+	})
+
+	clock.now = clock.now.Add(time.Minute)
+	store.DeleteExpired()
+
+	assert.Len(t, store.states, 1)
+	assert.Contains(t, store.states, "long")
+}
+
+func TestState(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	state := State{LockedUntil: now.Add(time.Second), ExpiresAt: now.Add(time.Minute)}
+
+	assert.True(t, state.IsLocked(now))
+	assert.False(t, state.IsLocked(now.Add(time.Second)))
+	assert.False(t, state.IsExpired(now))
+	assert.True(t, state.IsExpired(now.Add(time.Minute)))
+	assert.False(t, State{}.IsExpired(now))
+}
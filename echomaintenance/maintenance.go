@@ -0,0 +1,505 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+/*
+Package echomaintenance provides middleware switching request policies (maintenance mode, read-only mode, elevated
+rate limits) on and off by cron-style schedules, so planned maintenance windows do not require somebody to flip
+toggles in the middle of the night.
+
+Policies are named middlewares applied to requests only while the policy is active. Windows activate a policy at
+times matching cron schedule (evaluated in window time zone) for given duration. Policies can also be switched
+manually with overrides. Every switch is recorded in audit history, passed to OnSwitch callback and counted in
+Prometheus metrics.
+
+Example:
+```
+package main
+
+import (
+
+	"context"
+	"time"
+
+	"github.com/labstack/echo-contrib/echomaintenance"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+
+)
+
+	func main() {
+		e := echo.New()
+		berlin, _ := time.LoadLocation("Europe/Berlin")
+
+		scheduler := echomaintenance.MustNew(echomaintenance.Config{
+			Policies: []echomaintenance.Policy{
+				{Name: "maintenance", Middleware: echomaintenance.MaintenanceMode(30 * time.Minute)},
+				{Name: "read-only", Middleware: echomaintenance.ReadOnlyMode()},
+				{Name: "strict-rate-limit", Middleware: middleware.RateLimiter(middleware.NewRateLimiterMemoryStore(5))},
+			},
+			Windows: []echomaintenance.Window{
+				// database upgrade on the first day of every month 03:00-03:30 Berlin time
+				{Name: "db-upgrade", Policy: "maintenance", Schedule: "0 3 1 * *", Duration: 30 * time.Minute, Location: berlin},
+				// nightly backup
+				{Name: "backup", Policy: "read-only", Schedule: "0 2 * * *", Duration: time.Hour, Location: berlin},
+			},
+			OnSwitch: func(s echomaintenance.Switch) {
+				e.Logger.Infof("policy %v active=%v (window: %v, manual: %v)", s.Policy, s.Active, s.Window, s.Manual)
+			},
+			Registerer: prometheus.DefaultRegisterer,
+		})
+		go scheduler.Run(context.Background(), time.Minute)
+
+		e.Use(scheduler.Middleware())
+		e.GET("/admin/maintenance", scheduler.StatusHandler(), middleware.BasicAuth(checkAdmin))
+
+		e.Logger.Fatal(e.Start(":1323"))
+	}
+
+```
+*/
+package echomaintenance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	defaultSubsystem = "echo_maintenance"
+	defaultAuditSize = 100
+)
+
+var (
+	// ErrMaintenance is returned by MaintenanceMode middleware.
+	ErrMaintenance = echo.NewHTTPError(http.StatusServiceUnavailable, "service is under maintenance")
+	// ErrReadOnly is returned by ReadOnlyMode middleware for requests with unsafe methods.
+	ErrReadOnly = echo.NewHTTPError(http.StatusServiceUnavailable, "service is in read-only mode")
+)
+
+// Policy is named middleware applied to requests while the policy is active.
+type Policy struct {
+	Name       string
+	Middleware echo.MiddlewareFunc
+}
+
+// Window activates policy at times matching Schedule for Duration.
+type Window struct {
+	// Name identifies window in audit history. Defaults to policy name.
+	Name string
+	// Policy is name of the activated policy.
+	Policy string
+	// Schedule is cron expression of window start times, see ParseSchedule.
+	Schedule string
+	// Duration is how long policy is active after each start.
+	Duration time.Duration
+	// Location is time zone Schedule is evaluated in.
+	// Defaults to: Config.Location
+	Location *time.Location
+}
+
+// Switch is audit record of policy being activated or deactivated.
+type Switch struct {
+	Time   time.Time `json:"time"`
+	Policy string    `json:"policy"`
+	Active bool      `json:"active"`
+	// Window is name of the window that activated policy. Empty for deactivations and manual switches.
+	Window string `json:"window,omitempty"`
+	// Manual is true when switch was caused by Override or ClearOverride.
+	Manual bool `json:"manual"`
+}
+
+// Config defines the config for maintenance scheduler.
+type Config struct {
+	// Skipper defines a function to skip middleware.
+	Skipper middleware.Skipper
+
+	// Policies are applied in given order while active.
+	// Required.
+	Policies []Policy
+
+	// Windows schedule activation of policies.
+	Windows []Window
+
+	// Location is default time zone of window schedules.
+	// Defaults to: time.UTC
+	Location *time.Location
+
+	// OnSwitch is called for every policy switch. Called synchronously, must not call Scheduler methods.
+	// Optional.
+	OnSwitch func(s Switch)
+
+	// AuditSize is number of the latest switches kept in history.
+	// Defaults to: 100
+	AuditSize int
+
+	// Registerer is used to register policy state and switch metrics. When nil no metrics are registered.
+	// Optional.
+	Registerer prometheus.Registerer
+
+	// Namespace is components of the fully-qualified name of the Metric (created by joining Namespace,Subsystem and Name components with "_")
+	// Optional
+	Namespace string
+
+	// Subsystem is components of the fully-qualified name of the Metric (created by joining Namespace,Subsystem and Name components with "_")
+	// Defaults to: "echo_maintenance"
+	Subsystem string
+}
+
+type window struct {
+	Window
+	schedule Schedule
+}
+
+// policyState is state of single policy. Guarded by Scheduler.mu.
+type policyState struct {
+	scheduled bool
+	window    string
+	override  *bool
+	active    bool
+}
+
+// Scheduler switches policies by schedule and applies active policies to requests.
+type Scheduler struct {
+	config  Config
+	windows []window
+	now     func() time.Time
+
+	mu      sync.Mutex
+	states  map[string]*policyState
+	history []Switch
+
+	// active holds middlewares of active policies in configuration order.
+	active atomic.Pointer[[]echo.MiddlewareFunc]
+
+	activeGauge *prometheus.GaugeVec
+	switches    *prometheus.CounterVec
+}
+
+// New returns Scheduler with all policies inactive or an error on invalid configuration. Call Evaluate or Run to
+// activate policies by schedule.
+func New(config Config) (*Scheduler, error) {
+	if len(config.Policies) == 0 {
+		return nil, errors.New("echomaintenance: at least one policy is required")
+	}
+	if config.Skipper == nil {
+		config.Skipper = middleware.DefaultSkipper
+	}
+	if config.Location == nil {
+		config.Location = time.UTC
+	}
+	if config.AuditSize <= 0 {
+		config.AuditSize = defaultAuditSize
+	}
+	if config.Subsystem == "" {
+		config.Subsystem = defaultSubsystem
+	}
+
+	states := make(map[string]*policyState, len(config.Policies))
+	for _, p := range config.Policies {
+		if p.Name == "" || p.Middleware == nil {
+			return nil, errors.New("echomaintenance: policy must have name and middleware")
+		}
+		if _, ok := states[p.Name]; ok {
+			return nil, fmt.Errorf("echomaintenance: duplicate policy `%v`", p.Name)
+		}
+		states[p.Name] = &policyState{}
+	}
+
+	windows := make([]window, 0, len(config.Windows))
+	for _, w := range config.Windows {
+		if _, ok := states[w.Policy]; !ok {
+			return nil, fmt.Errorf("echomaintenance: window `%v` refers to unknown policy `%v`", w.Name, w.Policy)
+		}
+		if w.Duration <= 0 {
+			return nil, fmt.Errorf("echomaintenance: window `%v` must have positive duration", w.Name)
+		}
+		schedule, err := ParseSchedule(w.Schedule)
+		if err != nil {
+			return nil, err
+		}
+		if w.Name == "" {
+			w.Name = w.Policy
+		}
+		if w.Location == nil {
+			w.Location = config.Location
+		}
+		windows = append(windows, window{Window: w, schedule: schedule})
+	}
+
+	s := &Scheduler{
+		config:  config,
+		windows: windows,
+		now:     time.Now,
+		states:  states,
+		activeGauge: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: config.Namespace,
+				Subsystem: config.Subsystem,
+				Name:      "policy_active",
+				Help:      "Whether policy is active (1) or not (0).",
+			},
+			[]string{"policy"},
+		),
+		switches: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: config.Namespace,
+				Subsystem: config.Subsystem,
+				Name:      "policy_switches_total",
+				Help:      "How many times policies were switched, partitioned by policy, new state and trigger.",
+			},
+			[]string{"policy", "state", "trigger"},
+		),
+	}
+	for _, p := range config.Policies {
+		s.activeGauge.WithLabelValues(p.Name).Set(0)
+	}
+	s.active.Store(&[]echo.MiddlewareFunc{})
+	if config.Registerer != nil {
+		for _, c := range []prometheus.Collector{s.activeGauge, s.switches} {
+			if err := config.Registerer.Register(c); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return s, nil
+}
+
+// MustNew returns Scheduler or panics on invalid configuration.
+func MustNew(config Config) *Scheduler {
+	s, err := New(config)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Middleware returns middleware applying active policies to requests.
+func (s *Scheduler) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if s.config.Skipper(c) {
+				return next(c)
+			}
+			h := next
+			active := *s.active.Load()
+			for i := len(active) - 1; i >= 0; i-- {
+				h = active[i](h)
+			}
+			return h(c)
+		}
+	}
+}
+
+// Run evaluates schedules immediately and then every interval until ctx is done. Interval should not be longer than a
+// minute as schedules have minute resolution.
+func (s *Scheduler) Run(ctx context.Context, interval time.Duration) error {
+	s.Evaluate()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.Evaluate()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Evaluate switches policies according to windows active at current time.
+func (s *Scheduler) Evaluate() {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, st := range s.states {
+		st.scheduled, st.window = false, ""
+	}
+	for _, w := range s.windows {
+		st := s.states[w.Policy]
+		if !st.scheduled && w.activeAt(now) {
+			st.scheduled, st.window = true, w.Name
+		}
+	}
+	s.apply(now, false)
+}
+
+// Override manually activates or deactivates policy regardless of schedule until ClearOverride is called.
+func (s *Scheduler) Override(policy string, active bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.states[policy]
+	if !ok {
+		return fmt.Errorf("echomaintenance: unknown policy `%v`", policy)
+	}
+	st.override = &active
+	s.apply(s.now(), true)
+	return nil
+}
+
+// ClearOverride removes manual override of policy so it follows schedule again.
+func (s *Scheduler) ClearOverride(policy string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.states[policy]
+	if !ok {
+		return fmt.Errorf("echomaintenance: unknown policy `%v`", policy)
+	}
+	st.override = nil
+	s.apply(s.now(), true)
+	return nil
+}
+
+// apply switches policies whose effective state changed and updates middlewares applied to requests. Must be called
+// with s.mu held.
+func (s *Scheduler) apply(now time.Time, manual bool) {
+	active := make([]echo.MiddlewareFunc, 0, len(s.config.Policies))
+	for _, p := range s.config.Policies {
+		st := s.states[p.Name]
+		want := st.scheduled
+		if st.override != nil {
+			want = *st.override
+		}
+		if want != st.active {
+			st.active = want
+			sw := Switch{Time: now, Policy: p.Name, Active: want, Manual: manual}
+			if want && !manual {
+				sw.Window = st.window
+			}
+			s.record(sw)
+		}
+		if st.active {
+			active = append(active, p.Middleware)
+		}
+	}
+	s.active.Store(&active)
+}
+
+func (s *Scheduler) record(sw Switch) {
+	s.history = append(s.history, sw)
+	if len(s.history) > s.config.AuditSize {
+		s.history = s.history[len(s.history)-s.config.AuditSize:]
+	}
+
+	state, trigger := "inactive", "schedule"
+	gauge := 0.0
+	if sw.Active {
+		state, gauge = "active", 1
+	}
+	if sw.Manual {
+		trigger = "manual"
+	}
+	s.activeGauge.WithLabelValues(sw.Policy).Set(gauge)
+	s.switches.WithLabelValues(sw.Policy, state, trigger).Inc()
+
+	if s.config.OnSwitch != nil {
+		s.config.OnSwitch(sw)
+	}
+}
+
+// PolicyStatus is state of single policy in Status.
+type PolicyStatus struct {
+	Name     string `json:"name"`
+	Active   bool   `json:"active"`
+	Window   string `json:"window,omitempty"`
+	Override *bool  `json:"override,omitempty"`
+}
+
+// WindowStatus is next start of single window in Status.
+type WindowStatus struct {
+	Name      string    `json:"name"`
+	Policy    string    `json:"policy"`
+	NextStart time.Time `json:"next_start"`
+}
+
+// Status is snapshot of scheduler state.
+type Status struct {
+	Policies []PolicyStatus `json:"policies"`
+	Windows  []WindowStatus `json:"windows"`
+	History  []Switch       `json:"history"`
+}
+
+// Status returns current state of policies, next starts of windows and audit history of switches (oldest first).
+func (s *Scheduler) Status() Status {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := Status{
+		Policies: make([]PolicyStatus, 0, len(s.config.Policies)),
+		Windows:  make([]WindowStatus, 0, len(s.windows)),
+		History:  append([]Switch(nil), s.history...),
+	}
+	for _, p := range s.config.Policies {
+		st := s.states[p.Name]
+		ps := PolicyStatus{Name: p.Name, Active: st.active, Override: st.override}
+		if st.active && st.override == nil {
+			ps.Window = st.window
+		}
+		status.Policies = append(status.Policies, ps)
+	}
+	for _, w := range s.windows {
+		status.Windows = append(status.Windows, WindowStatus{
+			Name:      w.Name,
+			Policy:    w.Policy,
+			NextStart: w.schedule.Next(now.In(w.Location)),
+		})
+	}
+	return status
+}
+
+// StatusHandler returns handler responding with JSON Status. Handler must be protected with authentication middleware.
+func (s *Scheduler) StatusHandler() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return c.JSON(http.StatusOK, s.Status())
+	}
+}
+
+// activeAt returns true when t is within Duration after any start of the window.
+func (w window) activeAt(t time.Time) bool {
+	t = t.In(w.Location)
+	for start := w.schedule.Next(t.Add(-w.Duration)); !start.IsZero() && !start.After(t); start = w.schedule.Next(start) {
+		if start.Add(w.Duration).After(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// MaintenanceMode returns policy middleware rejecting all requests with ErrMaintenance and `Retry-After` header.
+// Header is not set when retryAfter is not positive.
+func MaintenanceMode(retryAfter time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if retryAfter > 0 {
+				c.Response().Header().Set(echo.HeaderRetryAfter, strconv.Itoa(int(retryAfter.Seconds())))
+			}
+			return ErrMaintenance
+		}
+	}
+}
+
+// ReadOnlyMode returns policy middleware rejecting requests with methods other than GET, HEAD and OPTIONS with
+// ErrReadOnly.
+func ReadOnlyMode() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			switch c.Request().Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				return next(c)
+			}
+			return ErrReadOnly
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echomaintenance
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew_invalidConfig(t *testing.T) {
+	policies := []Policy{{Name: "maintenance", Middleware: MaintenanceMode(0)}}
+
+	var testCases = []struct {
+		name        string
+		whenConfig  Config
+		expectError string
+	}{
+		{
+			name:        "nok, no policies",
+			whenConfig:  Config{},
+			expectError: "echomaintenance: at least one policy is required",
+		},
+		{
+			name:        "nok, duplicate policy",
+			whenConfig:  Config{Policies: append(policies, policies...)},
+			expectError: "echomaintenance: duplicate policy `maintenance`",
+		},
+		{
+			name: "nok, unknown policy",
+			whenConfig: Config{
+				Policies: policies,
+				Windows:  []Window{{Name: "w", Policy: "read-only", Schedule: "* * * * *", Duration: time.Minute}},
+			},
+			expectError: "echomaintenance: window `w` refers to unknown policy `read-only`",
+		},
+		{
+			name: "nok, missing duration",
+			whenConfig: Config{
+				Policies: policies,
+				Windows:  []Window{{Name: "w", Policy: "maintenance", Schedule: "* * * * *"}},
+			},
+			expectError: "echomaintenance: window `w` must have positive duration",
+		},
+		{
+			name: "nok, invalid schedule",
+			whenConfig: Config{
+				Policies: policies,
+				Windows:  []Window{{Name: "w", Policy: "maintenance", Schedule: "daily", Duration: time.Minute}},
+			},
+			expectError: "echomaintenance: schedule `daily` must have 5 fields",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New(tc.whenConfig)
+			assert.EqualError(t, err, tc.expectError)
+		})
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	var switches []Switch
+	reg := prometheus.NewRegistry()
+	s := MustNew(Config{
+		Policies: []Policy{
+			{Name: "maintenance", Middleware: MaintenanceMode(10 * time.Minute)},
+			{Name: "read-only", Middleware: ReadOnlyMode()},
+		},
+		Windows: []Window{
+			{Name: "db-upgrade", Policy: "maintenance", Schedule: "0 3 * * 0", Duration: 30 * time.Minute},
+			{Name: "backup", Policy: "read-only", Schedule: "0 2 * * *", Duration: 2 * time.Hour},
+		},
+		OnSwitch:   func(sw Switch) { switches = append(switches, sw) },
+		Registerer: reg,
+	})
+	now := time.Date(2024, 5, 12, 1, 0, 0, 0, time.UTC) // Sunday
+	s.now = func() time.Time { return now }
+
+	e := echo.New()
+	e.Use(s.Middleware())
+	e.GET("/", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	e.POST("/", func(c echo.Context) error { return c.NoContent(http.StatusCreated) })
+	serve := func(method string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(method, "/", nil))
+		return rec
+	}
+
+	s.Evaluate()
+	assert.Equal(t, http.StatusCreated, serve(http.MethodPost).Code)
+	assert.Empty(t, switches)
+
+	now = time.Date(2024, 5, 12, 2, 0, 0, 0, time.UTC)
+	s.Evaluate()
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet).Code)
+	assert.Equal(t, http.StatusServiceUnavailable, serve(http.MethodPost).Code)
+
+	now = time.Date(2024, 5, 12, 3, 10, 0, 0, time.UTC)
+	s.Evaluate()
+	rec := serve(http.MethodGet)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "600", rec.Header().Get(echo.HeaderRetryAfter))
+	assert.Equal(t, 1.0, testutil.ToFloat64(s.activeGauge.WithLabelValues("maintenance")))
+
+	now = time.Date(2024, 5, 12, 4, 0, 0, 0, time.UTC)
+	s.Evaluate()
+	assert.Equal(t, http.StatusCreated, serve(http.MethodPost).Code)
+
+	assert.Equal(t, []Switch{
+		{Time: time.Date(2024, 5, 12, 2, 0, 0, 0, time.UTC), Policy: "read-only", Active: true, Window: "backup"},
+		{Time: time.Date(2024, 5, 12, 3, 10, 0, 0, time.UTC), Policy: "maintenance", Active: true, Window: "db-upgrade"},
+		{Time: time.Date(2024, 5, 12, 4, 0, 0, 0, time.UTC), Policy: "maintenance", Active: false},
+		{Time: time.Date(2024, 5, 12, 4, 0, 0, 0, time.UTC), Policy: "read-only", Active: false},
+	}, switches)
+	assert.Equal(t, switches, s.Status().History)
+	assert.Equal(t, 2.0, testutil.ToFloat64(s.switches.WithLabelValues("maintenance", "active", "schedule"))+
+		testutil.ToFloat64(s.switches.WithLabelValues("read-only", "active", "schedule")))
+}
+
+func TestScheduler_location(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip("time zone database is not available")
+	}
+	s := MustNew(Config{
+		Policies: []Policy{{Name: "maintenance", Middleware: MaintenanceMode(0)}},
+		Windows:  []Window{{Policy: "maintenance", Schedule: "0 3 * * *", Duration: time.Hour}},
+		Location: tokyo,
+	})
+	s.now = func() time.Time { return time.Date(2024, 5, 11, 18, 30, 0, 0, time.UTC) } // 03:30 in Tokyo
+
+	s.Evaluate()
+	status := s.Status()
+	assert.Equal(t, []PolicyStatus{{Name: "maintenance", Active: true, Window: "maintenance"}}, status.Policies)
+	assert.True(t, time.Date(2024, 5, 13, 3, 0, 0, 0, tokyo).Equal(status.Windows[0].NextStart))
+}
+
+func TestScheduler_Override(t *testing.T) {
+	var switches []Switch
+	s := MustNew(Config{
+		Policies: []Policy{{Name: "read-only", Middleware: ReadOnlyMode()}},
+		Windows:  []Window{{Name: "backup", Policy: "read-only", Schedule: "0 2 * * *", Duration: time.Hour}},
+		OnSwitch: func(sw Switch) { switches = append(switches, sw) },
+	})
+	now := time.Date(2024, 5, 12, 2, 30, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	s.Evaluate()
+
+	assert.EqualError(t, s.Override("unknown", true), "echomaintenance: unknown policy `unknown`")
+	assert.NoError(t, s.Override("read-only", false))
+	s.Evaluate() // override wins over schedule
+	assert.NoError(t, s.ClearOverride("read-only"))
+
+	assert.Equal(t, []Switch{
+		{Time: now, Policy: "read-only", Active: true, Window: "backup"},
+		{Time: now, Policy: "read-only", Active: false, Manual: true},
+		{Time: now, Policy: "read-only", Active: true, Manual: true},
+	}, switches)
+}
+
+func TestScheduler_StatusHandler(t *testing.T) {
+	s := MustNew(Config{
+		Policies:  []Policy{{Name: "maintenance", Middleware: MaintenanceMode(0)}},
+		AuditSize: 2,
+	})
+	assert.NoError(t, s.Override("maintenance", true))
+	assert.NoError(t, s.Override("maintenance", false))
+	assert.NoError(t, s.Override("maintenance", true))
+
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+	assert.NoError(t, s.StatusHandler()(c))
+
+	var status Status
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+	assert.Len(t, status.History, 2)
+	assert.False(t, status.History[0].Active)
+	if assert.Len(t, status.Policies, 1) {
+		assert.True(t, status.Policies[0].Active)
+		assert.True(t, *status.Policies[0].Override)
+	}
+}
@@ -0,0 +1,140 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echomaintenance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSchedule_invalid(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		whenSpec    string
+		expectError string
+	}{
+		{
+			name:        "nok, missing fields",
+			whenSpec:    "0 3 * *",
+			expectError: "echomaintenance: schedule `0 3 * *` must have 5 fields",
+		},
+		{
+			name:        "nok, out of range",
+			whenSpec:    "60 3 * * *",
+			expectError: "echomaintenance: invalid minute in schedule `60 3 * * *`: value 60 out of range 0-59",
+		},
+		{
+			name:        "nok, invalid step",
+			whenSpec:    "*/0 3 * * *",
+			expectError: "echomaintenance: invalid minute in schedule `*/0 3 * * *`: invalid step `*/0`",
+		},
+		{
+			name:        "nok, reversed range",
+			whenSpec:    "0 5-3 * * *",
+			expectError: "echomaintenance: invalid hour in schedule `0 5-3 * * *`: invalid range `5-3`",
+		},
+		{
+			name:        "nok, not a number",
+			whenSpec:    "0 3 * jan *",
+			expectError: "echomaintenance: invalid month in schedule `0 3 * jan *`: invalid value `jan`",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseSchedule(tc.whenSpec)
+			assert.EqualError(t, err, tc.expectError)
+		})
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("time zone database is not available")
+	}
+
+	var testCases = []struct {
+		name     string
+		whenSpec string
+		whenTime time.Time
+		expect   time.Time
+	}{
+		{
+			name:     "ok, every 15 minutes",
+			whenSpec: "*/15 * * * *",
+			whenTime: time.Date(2024, 5, 10, 10, 7, 30, 0, time.UTC),
+			expect:   time.Date(2024, 5, 10, 10, 15, 0, 0, time.UTC),
+		},
+		{
+			name:     "ok, exact match is skipped",
+			whenSpec: "0 3 * * *",
+			whenTime: time.Date(2024, 5, 10, 3, 0, 0, 0, time.UTC),
+			expect:   time.Date(2024, 5, 11, 3, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "ok, day of month or day of week, next Sunday",
+			whenSpec: "0 3 1 * 0",
+			whenTime: time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC), // Friday
+			expect:   time.Date(2024, 5, 12, 3, 0, 0, 0, time.UTC), // Sunday
+		},
+		{
+			name:     "ok, `1-7 * 0` is not first Sunday of month, matches every Sunday",
+			whenSpec: "0 3 1-7 * 0",
+			whenTime: time.Date(2024, 5, 13, 0, 0, 0, 0, time.UTC), // Monday
+			expect:   time.Date(2024, 5, 19, 3, 0, 0, 0, time.UTC), // third Sunday
+		},
+		{
+			name:     "ok, `1-7 * 0` is not first Sunday of month, matches days 1-7",
+			whenSpec: "0 3 1-7 * 0",
+			whenTime: time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC), // Friday
+			expect:   time.Date(2024, 6, 1, 3, 0, 0, 0, time.UTC),  // Saturday
+		},
+		{
+			name:     "ok, weekdays list and range",
+			whenSpec: "30 22 * * 1-5",
+			whenTime: time.Date(2024, 5, 10, 23, 0, 0, 0, time.UTC),  // Friday
+			expect:   time.Date(2024, 5, 13, 22, 30, 0, 0, time.UTC), // Monday
+		},
+		{
+			name:     "ok, sunday as 7",
+			whenSpec: "0 0 * * 7",
+			whenTime: time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC),
+			expect:   time.Date(2024, 5, 12, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "ok, next year",
+			whenSpec: "0 0 1 1 *",
+			whenTime: time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC),
+			expect:   time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "ok, evaluated in location",
+			whenSpec: "0 3 * * *",
+			whenTime: time.Date(2024, 5, 10, 0, 0, 0, 0, berlin),
+			expect:   time.Date(2024, 5, 10, 1, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "ok, hour skipped by daylight saving time",
+			whenSpec: "30 * * * *",
+			whenTime: time.Date(2024, 3, 31, 1, 45, 0, 0, berlin),
+			expect:   time.Date(2024, 3, 31, 3, 30, 0, 0, berlin),
+		},
+		{
+			name:     "ok, never matches",
+			whenSpec: "0 0 30 2 *",
+			whenTime: time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC),
+			expect:   time.Time{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			next := MustParseSchedule(tc.whenSpec).Next(tc.whenTime)
+			assert.True(t, tc.expect.Equal(next), "expected %v, got %v", tc.expect, next)
+		})
+	}
+}
@@ -0,0 +1,247 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+/*
+Package echomem provides diagnostics middleware that measures heap allocations made while handling sampled requests
+and flags handlers exceeding configured allocation budgets.
+
+Allocations are measured as difference of process-wide heap allocation counters (`runtime/metrics`) before and after
+the handler. Allocations of concurrently handled requests and background goroutines are included in the measurement,
+so values are most accurate under low concurrency and should be treated as an upper bound.
+
+Example:
+```
+package main
+
+import (
+
+	"github.com/labstack/echo-contrib/echomem"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+
+)
+
+	func main() {
+		e := echo.New()
+		e.Use(echomem.MiddlewareWithConfig(echomem.Config{
+			SampleRate: 0.05,
+			MaxBytes:   10 << 20, // 10 MiB
+			Registerer: prometheus.DefaultRegisterer,
+		}))
+
+		e.Logger.Fatal(e.Start(":1323"))
+	}
+
+```
+*/
+package echomem
+
+import (
+	"errors"
+	"math/rand"
+	"runtime/metrics"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	defaultSubsystem = "echo_mem"
+
+	metricAllocBytes   = "/gc/heap/allocs:bytes"
+	metricAllocObjects = "/gc/heap/allocs:objects"
+)
+
+// Usage is heap allocation usage of the single request.
+type Usage struct {
+	// Bytes is number of bytes allocated on heap.
+	Bytes uint64
+	// Objects is number of heap objects allocated.
+	Objects uint64
+}
+
+// Config defines the config for memory budget middleware.
+type Config struct {
+	// Skipper defines a function to skip middleware.
+	Skipper middleware.Skipper
+
+	// SampleRate is fraction (0.0 - 1.0) of requests that are measured.
+	// Defaults to: 0.01
+	SampleRate float64
+
+	// MaxBytes is budget of heap bytes allocated per request. Zero disables the budget.
+	MaxBytes uint64
+
+	// MaxObjects is budget of heap objects allocated per request. Zero disables the budget.
+	MaxObjects uint64
+
+	// OnExceeded is called when measured request exceeds configured budget.
+	// Defaults to: logging warning with echo.Context.Logger
+	OnExceeded func(c echo.Context, usage Usage)
+
+	// AttachToTrace adds `echo.mem.alloc_bytes` and `echo.mem.alloc_objects` attributes to OpenTelemetry span found in
+	// request context.
+	AttachToTrace bool
+
+	// Registerer is used to register allocation histograms and budget exceeded counter. When nil no metrics are registered.
+	// Optional.
+	Registerer prometheus.Registerer
+
+	// Namespace is components of the fully-qualified name of the Metric (created by joining Namespace,Subsystem and Name components with "_")
+	// Optional
+	Namespace string
+
+	// Subsystem is components of the fully-qualified name of the Metric (created by joining Namespace,Subsystem and Name components with "_")
+	// Defaults to: "echo_mem"
+	Subsystem string
+
+	random func() float64
+}
+
+// DefaultConfig is the default memory budget middleware config.
+var DefaultConfig = Config{
+	Skipper:    middleware.DefaultSkipper,
+	SampleRate: 0.01,
+	OnExceeded: defaultOnExceeded,
+}
+
+// Middleware returns memory budget middleware with default config.
+func Middleware() echo.MiddlewareFunc {
+	return MiddlewareWithConfig(DefaultConfig)
+}
+
+// MiddlewareWithConfig returns memory budget middleware with config or panics on invalid configuration.
+// See: `Middleware()`.
+func MiddlewareWithConfig(config Config) echo.MiddlewareFunc {
+	mw, err := config.ToMiddleware()
+	if err != nil {
+		panic(err)
+	}
+	return mw
+}
+
+// ToMiddleware converts configuration to middleware or returns an error.
+func (config Config) ToMiddleware() (echo.MiddlewareFunc, error) {
+	if config.SampleRate < 0 || config.SampleRate > 1 {
+		return nil, errors.New("echomem: SampleRate must be between 0 and 1")
+	}
+	if config.Skipper == nil {
+		config.Skipper = DefaultConfig.Skipper
+	}
+	if config.SampleRate == 0 {
+		config.SampleRate = DefaultConfig.SampleRate
+	}
+	if config.OnExceeded == nil {
+		config.OnExceeded = DefaultConfig.OnExceeded
+	}
+	if config.Subsystem == "" {
+		config.Subsystem = defaultSubsystem
+	}
+	if config.random == nil {
+		config.random = rand.Float64
+	}
+
+	allocBytes := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: config.Namespace,
+			Subsystem: config.Subsystem,
+			Name:      "request_alloc_bytes",
+			Help:      "Heap bytes allocated while handling sampled requests.",
+			Buckets:   prometheus.ExponentialBuckets(1024, 4, 10), // 1KiB - 256MiB
+		},
+		[]string{"method", "url"},
+	)
+	allocObjects := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: config.Namespace,
+			Subsystem: config.Subsystem,
+			Name:      "request_alloc_objects",
+			Help:      "Heap objects allocated while handling sampled requests.",
+			Buckets:   prometheus.ExponentialBuckets(16, 4, 10),
+		},
+		[]string{"method", "url"},
+	)
+	exceeded := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: config.Namespace,
+			Subsystem: config.Subsystem,
+			Name:      "budget_exceeded_total",
+			Help:      "How many sampled requests exceeded allocation budget.",
+		},
+		[]string{"method", "url"},
+	)
+	if config.Registerer != nil {
+		for _, c := range []prometheus.Collector{allocBytes, allocObjects, exceeded} {
+			if err := config.Registerer.Register(c); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) || config.random() >= config.SampleRate {
+				return next(c)
+			}
+
+			before := readUsage()
+			err := next(c)
+			after := readUsage()
+
+			usage := Usage{
+				Bytes:   after.Bytes - before.Bytes,
+				Objects: after.Objects - before.Objects,
+			}
+			method := c.Request().Method
+			url := c.Path()
+			allocBytes.WithLabelValues(method, url).Observe(float64(usage.Bytes))
+			allocObjects.WithLabelValues(method, url).Observe(float64(usage.Objects))
+
+			if config.AttachToTrace {
+				if span := trace.SpanFromContext(c.Request().Context()); span.IsRecording() {
+					span.SetAttributes(
+						attribute.Int64("echo.mem.alloc_bytes", int64(usage.Bytes)),
+						attribute.Int64("echo.mem.alloc_objects", int64(usage.Objects)),
+					)
+				}
+			}
+
+			if (config.MaxBytes > 0 && usage.Bytes > config.MaxBytes) ||
+				(config.MaxObjects > 0 && usage.Objects > config.MaxObjects) {
+				exceeded.WithLabelValues(method, url).Inc()
+				config.OnExceeded(c, usage)
+			}
+			return err
+		}
+	}, nil
+}
+
+func defaultOnExceeded(c echo.Context, usage Usage) {
+	c.Logger().Warnf(
+		"echomem: request %s %s exceeded allocation budget: %d bytes, %d objects",
+		c.Request().Method,
+		c.Path(),
+		usage.Bytes,
+		usage.Objects,
+	)
+}
+
+func readUsage() Usage {
+	samples := []metrics.Sample{
+		{Name: metricAllocBytes},
+		{Name: metricAllocObjects},
+	}
+	metrics.Read(samples)
+
+	var u Usage
+	if samples[0].Value.Kind() == metrics.KindUint64 {
+		u.Bytes = samples[0].Value.Uint64()
+	}
+	if samples[1].Value.Kind() == metrics.KindUint64 {
+		u.Objects = samples[1].Value.Uint64()
+	}
+	return u
+}
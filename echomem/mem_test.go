@@ -0,0 +1,151 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echomem
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var sink [][]byte
+
+func allocatingHandler(c echo.Context) error {
+	for i := 0; i < 64; i++ {
+		sink = append(sink, make([]byte, 64*1024))
+	}
+	sink = nil
+	return c.NoContent(http.StatusOK)
+}
+
+func TestMiddleware_budgetExceeded(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	var exceeded []Usage
+
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(Config{
+		SampleRate: 1,
+		MaxBytes:   1024 * 1024,
+		OnExceeded: func(c echo.Context, usage Usage) {
+			exceeded = append(exceeded, usage)
+		},
+		Registerer: reg,
+	}))
+	e.GET("/heavy", allocatingHandler)
+	e.GET("/light", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/heavy", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/light", nil))
+
+	assert.Len(t, exceeded, 1)
+	assert.GreaterOrEqual(t, exceeded[0].Bytes, uint64(64*64*1024))
+	assert.GreaterOrEqual(t, exceeded[0].Objects, uint64(64))
+
+	assert.Equal(t, 2, testutil.CollectAndCount(reg, "echo_mem_request_alloc_bytes"))
+	assert.Equal(t, 2, testutil.CollectAndCount(reg, "echo_mem_request_alloc_objects"))
+	assert.Equal(t, 1, testutil.CollectAndCount(reg, "echo_mem_budget_exceeded_total"))
+}
+
+func TestMiddleware_sampling(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	values := []float64{0.2, 0.05, 0.5}
+
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(Config{
+		SampleRate: 0.1,
+		Registerer: reg,
+		random: func() float64 {
+			v := values[0]
+			values = values[1:]
+			return v
+		},
+	}))
+	e.GET("/", allocatingHandler)
+
+	for i := 0; i < 3; i++ {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+
+	mfs, err := reg.Gather()
+	assert.NoError(t, err)
+	for _, mf := range mfs {
+		if mf.GetName() == "echo_mem_request_alloc_bytes" {
+			assert.Equal(t, uint64(1), mf.GetMetric()[0].GetHistogram().GetSampleCount())
+		}
+	}
+}
+
+func TestMiddleware_attachToTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	e := echo.New()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx, span := tp.Tracer("test").Start(c.Request().Context(), "request")
+			defer span.End()
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	})
+	e.Use(MiddlewareWithConfig(Config{SampleRate: 1, AttachToTrace: true}))
+	e.GET("/", allocatingHandler)
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	attrs := map[string]int64{}
+	for _, a := range spans[0].Attributes() {
+		attrs[string(a.Key)] = a.Value.AsInt64()
+	}
+	assert.GreaterOrEqual(t, attrs["echo.mem.alloc_bytes"], int64(64*64*1024))
+	assert.Greater(t, attrs["echo.mem.alloc_objects"], int64(0))
+	assert.False(t, trace.SpanFromContext(httptest.NewRequest(http.MethodGet, "/", nil).Context()).IsRecording())
+}
+
+func TestConfig_ToMiddleware(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		givenConfig Config
+		expectErr   string
+	}{
+		{
+			name:        "ok, defaults",
+			givenConfig: Config{},
+		},
+		{
+			name:        "nok, negative sample rate",
+			givenConfig: Config{SampleRate: -1},
+			expectErr:   "echomem: SampleRate must be between 0 and 1",
+		},
+		{
+			name:        "nok, sample rate over 1",
+			givenConfig: Config{SampleRate: 1.5},
+			expectErr:   "echomem: SampleRate must be between 0 and 1",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mw, err := tc.givenConfig.ToMiddleware()
+			if tc.expectErr != "" {
+				assert.EqualError(t, err, tc.expectErr)
+				assert.Nil(t, mw)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotNil(t, mw)
+		})
+	}
+}
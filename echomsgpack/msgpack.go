@@ -0,0 +1,257 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+/*
+Package echomsgpack provides MessagePack and CBOR support for binding requests and rendering responses.
+
+Example:
+```
+package main
+
+import (
+
+	"net/http"
+
+	"github.com/labstack/echo-contrib/echomsgpack"
+	"github.com/labstack/echo/v4"
+
+)
+
+	type Reading struct {
+		Sensor string  `msgpack:"sensor" cbor:"sensor"`
+		Value  float64 `msgpack:"value" cbor:"value"`
+	}
+
+	func main() {
+		e := echo.New()
+		e.Binder = echomsgpack.NewBinder(nil)
+
+		e.POST("/readings", func(c echo.Context) error {
+			r := new(Reading)
+			if err := c.Bind(r); err != nil {
+				return err
+			}
+			// responds with MessagePack, CBOR or JSON depending on request Accept header
+			return echomsgpack.Negotiate(c, http.StatusCreated, r)
+		})
+
+		e.Logger.Fatal(e.Start(":1323"))
+	}
+
+```
+*/
+package echomsgpack
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	// MIMEApplicationMsgpack is MessagePack media type.
+	MIMEApplicationMsgpack = "application/msgpack"
+	// MIMEApplicationXMsgpack is legacy MessagePack media type still used by many clients.
+	MIMEApplicationXMsgpack = "application/x-msgpack"
+	// MIMEApplicationVndMsgpack is vendor MessagePack media type.
+	MIMEApplicationVndMsgpack = "application/vnd.msgpack"
+	// MIMEApplicationCBOR is CBOR media type (RFC 8949).
+	MIMEApplicationCBOR = "application/cbor"
+)
+
+// Binder is echo.Binder implementation that decodes MessagePack and CBOR request bodies and delegates all other
+// content types to the fallback binder.
+type Binder struct {
+	// Fallback is binder used for requests that do not have MessagePack or CBOR body.
+	// Defaults to: echo.DefaultBinder
+	Fallback echo.Binder
+}
+
+// NewBinder creates new Binder. When fallback is nil echo.DefaultBinder is used.
+func NewBinder(fallback echo.Binder) *Binder {
+	if fallback == nil {
+		fallback = &echo.DefaultBinder{}
+	}
+	return &Binder{Fallback: fallback}
+}
+
+// Bind implements the `echo.Binder#Bind` function. Binding order is same as with echo.DefaultBinder: 1) path params;
+// 2) query params (only for GET/DELETE/HEAD); 3) request body.
+func (b *Binder) Bind(i interface{}, c echo.Context) error {
+	if !isMsgpack(c.Request()) && !isCBOR(c.Request()) {
+		fallback := b.Fallback
+		if fallback == nil {
+			fallback = &echo.DefaultBinder{}
+		}
+		return fallback.Bind(i, c)
+	}
+
+	db := &echo.DefaultBinder{}
+	if err := db.BindPathParams(c, i); err != nil {
+		return err
+	}
+	method := c.Request().Method
+	if method == http.MethodGet || method == http.MethodDelete || method == http.MethodHead {
+		if err := db.BindQueryParams(c, i); err != nil {
+			return err
+		}
+	}
+	return BindBody(c, i)
+}
+
+// BindBody decodes MessagePack or CBOR request body to given value. Returns echo.ErrUnsupportedMediaType for other
+// content types.
+func BindBody(c echo.Context, i interface{}) error {
+	req := c.Request()
+	if req.ContentLength == 0 {
+		return nil
+	}
+
+	var err error
+	switch {
+	case isMsgpack(req):
+		err = msgpack.NewDecoder(req.Body).Decode(i)
+	case isCBOR(req):
+		err = cbor.NewDecoder(req.Body).Decode(i)
+	default:
+		return echo.ErrUnsupportedMediaType
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+	}
+	return nil
+}
+
+// Msgpack sends a MessagePack response with status code.
+func Msgpack(c echo.Context, code int, i interface{}) error {
+	b, err := msgpack.Marshal(i)
+	if err != nil {
+		return err
+	}
+	return c.Blob(code, MIMEApplicationMsgpack, b)
+}
+
+// CBOR sends a CBOR response with status code.
+func CBOR(c echo.Context, code int, i interface{}) error {
+	b, err := cbor.Marshal(i)
+	if err != nil {
+		return err
+	}
+	return c.Blob(code, MIMEApplicationCBOR, b)
+}
+
+// negotiateOffers are media types Negotiate can respond with in order of server preference.
+var negotiateOffers = []string{
+	echo.MIMEApplicationJSON,
+	MIMEApplicationMsgpack,
+	MIMEApplicationXMsgpack,
+	MIMEApplicationVndMsgpack,
+	MIMEApplicationCBOR,
+}
+
+// Negotiate sends response encoded as MessagePack, CBOR or JSON depending on request `Accept` header. Media type with
+// the highest quality value is used, media types with `q=0` are never used. On equal quality media type listed earlier
+// in the header wins. JSON is used when header does not accept MessagePack or CBOR. Response is sent with
+// `Vary: Accept` header.
+func Negotiate(c echo.Context, code int, i interface{}) error {
+	c.Response().Header().Add(echo.HeaderVary, echo.HeaderAccept)
+	switch acceptedMediaType(c.Request().Header.Get(echo.HeaderAccept)) {
+	case MIMEApplicationMsgpack, MIMEApplicationXMsgpack, MIMEApplicationVndMsgpack:
+		return Msgpack(c, code, i)
+	case MIMEApplicationCBOR:
+		return CBOR(c, code, i)
+	}
+	return c.JSON(code, i)
+}
+
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+// acceptedMediaType returns offer with the highest quality in `Accept` header value or empty string when none of the
+// offers is acceptable. Offer takes quality of the most specific media range matching it (`*/*` < `type/*` <
+// `type/subtype`). On equal quality offer matched by media range listed earlier in the header wins and then earlier
+// offer.
+func acceptedMediaType(header string) string {
+	ranges := parseAccept(header)
+	best := ""
+	bestQ := 0.0
+	bestIndex := len(ranges)
+	for _, offer := range negotiateOffers {
+		q, index, specificity := 0.0, -1, -1
+		for i, r := range ranges {
+			if m := matchMediaRange(r.mediaType, offer); m > specificity {
+				q, index, specificity = r.q, i, m
+			}
+		}
+		if index < 0 || q <= 0 {
+			continue
+		}
+		if q > bestQ || (q == bestQ && index < bestIndex) {
+			best, bestQ, bestIndex = offer, q, index
+		}
+	}
+	return best
+}
+
+func parseAccept(header string) []acceptRange {
+	ranges := make([]acceptRange, 0, strings.Count(header, ",")+1)
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		mt := strings.ToLower(strings.TrimSpace(params[0]))
+		if mt == "" {
+			continue
+		}
+		r := acceptRange{mediaType: mt, q: 1}
+		for _, p := range params[1:] {
+			k, v, ok := strings.Cut(strings.TrimSpace(p), "=")
+			if !ok || strings.ToLower(strings.TrimSpace(k)) != "q" {
+				continue
+			}
+			if q, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil && q >= 0 && q <= 1 {
+				r.q = q
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+func matchMediaRange(mediaRange, offer string) int {
+	if mediaRange == offer {
+		return 2
+	}
+	if mediaRange == "*/*" {
+		return 0
+	}
+	if strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(offer, mediaRange[:len(mediaRange)-1]) {
+		return 1
+	}
+	return -1
+}
+
+func isMsgpack(r *http.Request) bool {
+	switch mediaType(r.Header.Get(echo.HeaderContentType)) {
+	case MIMEApplicationMsgpack, MIMEApplicationXMsgpack, MIMEApplicationVndMsgpack:
+		return true
+	}
+	return false
+}
+
+func isCBOR(r *http.Request) bool {
+	return mediaType(r.Header.Get(echo.HeaderContentType)) == MIMEApplicationCBOR
+}
+
+func mediaType(value string) string {
+	mt, _, err := mime.ParseMediaType(strings.TrimSpace(value))
+	if err != nil {
+		return ""
+	}
+	return mt
+}
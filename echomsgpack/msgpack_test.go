@@ -0,0 +1,193 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echomsgpack
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type reading struct {
+	ID     string  `param:"id" msgpack:"-" cbor:"-" json:"id"`
+	Sensor string  `msgpack:"sensor" cbor:"sensor" json:"sensor"`
+	Value  float64 `msgpack:"value" cbor:"value" json:"value"`
+}
+
+func TestBinder_Bind(t *testing.T) {
+	msgpackBody, _ := msgpack.Marshal(reading{Sensor: "temp", Value: 21.5})
+	cborBody, _ := cbor.Marshal(reading{Sensor: "temp", Value: 21.5})
+
+	var testCases = []struct {
+		name              string
+		whenContentType   string
+		whenBody          []byte
+		expect            reading
+		expectErrContains string
+	}{
+		{
+			name:            "ok, msgpack",
+			whenContentType: MIMEApplicationMsgpack,
+			whenBody:        msgpackBody,
+			expect:          reading{ID: "1", Sensor: "temp", Value: 21.5},
+		},
+		{
+			name:            "ok, legacy msgpack media type",
+			whenContentType: MIMEApplicationXMsgpack,
+			whenBody:        msgpackBody,
+			expect:          reading{ID: "1", Sensor: "temp", Value: 21.5},
+		},
+		{
+			name:            "ok, cbor",
+			whenContentType: MIMEApplicationCBOR,
+			whenBody:        cborBody,
+			expect:          reading{ID: "1", Sensor: "temp", Value: 21.5},
+		},
+		{
+			name:            "ok, fallback to json",
+			whenContentType: echo.MIMEApplicationJSON,
+			whenBody:        []byte(`{"sensor":"temp","value":21.5}`),
+			expect:          reading{ID: "1", Sensor: "temp", Value: 21.5},
+		},
+		{
+			name:              "nok, invalid msgpack",
+			whenContentType:   MIMEApplicationMsgpack,
+			whenBody:          []byte{0xc1},
+			expect:            reading{ID: "1"},
+			expectErrContains: "code=400",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/readings/1", bytes.NewReader(tc.whenBody))
+			req.Header.Set(echo.HeaderContentType, tc.whenContentType)
+			c := e.NewContext(req, httptest.NewRecorder())
+			c.SetParamNames("id")
+			c.SetParamValues("1")
+
+			result := reading{}
+			err := NewBinder(nil).Bind(&result, c)
+
+			assert.Equal(t, tc.expect, result)
+			if tc.expectErrContains != "" {
+				assert.ErrorContains(t, err, tc.expectErrContains)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	value := reading{Sensor: "temp", Value: 21.5}
+
+	var testCases = []struct {
+		name              string
+		whenAccept        string
+		expectContentType string
+		expectDecode      func(b []byte) (reading, error)
+	}{
+		{
+			name:              "ok, msgpack",
+			whenAccept:        "application/msgpack",
+			expectContentType: MIMEApplicationMsgpack,
+			expectDecode: func(b []byte) (r reading, err error) {
+				err = msgpack.Unmarshal(b, &r)
+				return r, err
+			},
+		},
+		{
+			name:              "ok, cbor preferred by quality",
+			whenAccept:        "application/cbor, application/msgpack;q=0.9",
+			expectContentType: MIMEApplicationCBOR,
+			expectDecode: func(b []byte) (r reading, err error) {
+				err = cbor.Unmarshal(b, &r)
+				return r, err
+			},
+		},
+		{
+			name:              "ok, json by default",
+			whenAccept:        "*/*",
+			expectContentType: echo.MIMEApplicationJSON,
+			expectDecode: func(b []byte) (r reading, err error) {
+				err = json.Unmarshal(b, &r)
+				return r, err
+			},
+		},
+		{
+			name:              "ok, msgpack excluded with q=0",
+			whenAccept:        "application/msgpack;q=0, application/json",
+			expectContentType: echo.MIMEApplicationJSON,
+			expectDecode: func(b []byte) (r reading, err error) {
+				err = json.Unmarshal(b, &r)
+				return r, err
+			},
+		},
+		{
+			name:              "ok, higher quality wins over order",
+			whenAccept:        "application/json;q=0.5, application/x-msgpack",
+			expectContentType: MIMEApplicationMsgpack,
+			expectDecode: func(b []byte) (r reading, err error) {
+				err = msgpack.Unmarshal(b, &r)
+				return r, err
+			},
+		},
+		{
+			name:              "ok, specific media type overrides wildcard quality",
+			whenAccept:        "*/*;q=0.1, application/cbor;q=0.8, application/json;q=0.2",
+			expectContentType: MIMEApplicationCBOR,
+			expectDecode: func(b []byte) (r reading, err error) {
+				err = cbor.Unmarshal(b, &r)
+				return r, err
+			},
+		},
+		{
+			name:              "ok, json when nothing is acceptable",
+			whenAccept:        "application/msgpack;q=0, text/html",
+			expectContentType: echo.MIMEApplicationJSON,
+			expectDecode: func(b []byte) (r reading, err error) {
+				err = json.Unmarshal(b, &r)
+				return r, err
+			},
+		},
+		{
+			name:              "ok, empty header",
+			whenAccept:        "",
+			expectContentType: echo.MIMEApplicationJSON,
+			expectDecode: func(b []byte) (r reading, err error) {
+				err = json.Unmarshal(b, &r)
+				return r, err
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(echo.HeaderAccept, tc.whenAccept)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			err := Negotiate(c, http.StatusOK, value)
+
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(rec.Header().Get(echo.HeaderContentType), tc.expectContentType))
+			assert.Equal(t, echo.HeaderAccept, rec.Header().Get(echo.HeaderVary))
+			result, err := tc.expectDecode(rec.Body.Bytes())
+			assert.NoError(t, err)
+			assert.Equal(t, value, result)
+		})
+	}
+}
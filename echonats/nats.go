@@ -0,0 +1,371 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+/*
+Package echonats provides a bridge between Echo and NATS: routes forwarding HTTP requests to NATS subjects with
+request/reply and middleware publishing handler results to subjects. Trace context (OpenTelemetry propagator) and
+request ID are propagated in message headers and requests are counted and timed per subject.
+
+The bridge does not depend on NATS client library. Connection is accessed through Conn interface which is easy to
+implement with `github.com/nats-io/nats.go`.
+
+Example:
+```
+package main
+
+import (
+
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo-contrib/echonats"
+	"github.com/labstack/echo/v4"
+	"github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus"
+
+)
+
+	type natsConn struct{ nc *nats.Conn }
+
+	func (c natsConn) Request(ctx context.Context, msg *echonats.Msg) (*echonats.Msg, error) {
+		reply, err := c.nc.RequestMsgWithContext(ctx, &nats.Msg{Subject: msg.Subject, Header: nats.Header(msg.Header), Data: msg.Data})
+		if errors.Is(err, nats.ErrNoResponders) {
+			return nil, echonats.ErrNoResponders
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &echonats.Msg{Subject: reply.Subject, Header: http.Header(reply.Header), Data: reply.Data}, nil
+	}
+
+	func (c natsConn) Publish(msg *echonats.Msg) error {
+		return c.nc.PublishMsg(&nats.Msg{Subject: msg.Subject, Header: nats.Header(msg.Header), Data: msg.Data})
+	}
+
+	func main() {
+		nc, err := nats.Connect(nats.DefaultURL)
+		if err != nil {
+			panic(err)
+		}
+		bridge := echonats.MustNew(echonats.Config{Conn: natsConn{nc}, Registerer: prometheus.DefaultRegisterer})
+
+		e := echo.New()
+		e.POST("/orders/:region", bridge.Handler("orders.{region}.create"))
+		e.PUT("/users/:id", updateUser, bridge.Publish("users.updated"))
+
+		e.Logger.Fatal(e.Start(":1323"))
+	}
+
+```
+*/
+package echonats
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+const (
+	defaultSubsystem = "echo_nats"
+
+	// HeaderServiceError is reply header with error description (as used by NATS micro services).
+	HeaderServiceError = "Nats-Service-Error"
+	// HeaderServiceErrorCode is reply header with error code used as HTTP status code of the response.
+	HeaderServiceErrorCode = "Nats-Service-Error-Code"
+)
+
+// ErrNoResponders should be returned by Conn.Request when there are no subscribers for the subject. Such requests are
+// responded with `503 Service Unavailable`.
+var ErrNoResponders = errors.New("echonats: no responders available for request")
+
+// ErrInvalidSubjectParam is returned when path parameter substituted into subject is empty or contains `.`, `*`, `>`
+// or whitespace.
+var ErrInvalidSubjectParam = echo.NewHTTPError(http.StatusBadRequest, "invalid subject parameter")
+
+// Msg is NATS message.
+type Msg struct {
+	Subject string
+	Header  http.Header
+	Data    []byte
+}
+
+// Conn is NATS connection used by the bridge.
+type Conn interface {
+	// Request sends request message and waits for reply until ctx is done.
+	Request(ctx context.Context, msg *Msg) (*Msg, error)
+	// Publish publishes message.
+	Publish(msg *Msg) error
+}
+
+// Config defines the config for NATS bridge.
+type Config struct {
+	// Conn is NATS connection.
+	// Required.
+	Conn Conn
+
+	// Timeout is maximum duration of request/reply.
+	// Defaults to: 5 seconds
+	Timeout time.Duration
+
+	// MaxRequestSize limits size of HTTP request body forwarded to NATS.
+	// Defaults to: 1MB (default NATS max payload)
+	MaxRequestSize int64
+
+	// Propagators are used to inject trace context into message headers.
+	// Defaults to: otel.GetTextMapPropagator()
+	Propagators propagation.TextMapPropagator
+
+	// Registerer is used to register requests and published messages metrics. When nil no metrics are registered.
+	// Optional.
+	Registerer prometheus.Registerer
+
+	// Namespace is components of the fully-qualified name of the Metric (created by joining Namespace,Subsystem and Name components with "_")
+	// Optional
+	Namespace string
+
+	// Subsystem is components of the fully-qualified name of the Metric (created by joining Namespace,Subsystem and Name components with "_")
+	// Defaults to: "echo_nats"
+	Subsystem string
+}
+
+// Bridge creates handlers and middlewares exchanging messages with NATS.
+type Bridge struct {
+	config Config
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	published       *prometheus.CounterVec
+}
+
+// New returns Bridge or an error on invalid configuration.
+func New(config Config) (*Bridge, error) {
+	if config.Conn == nil {
+		return nil, errors.New("echonats: Conn is required")
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 5 * time.Second
+	}
+	if config.MaxRequestSize <= 0 {
+		config.MaxRequestSize = 1 << 20
+	}
+	if config.Propagators == nil {
+		config.Propagators = otel.GetTextMapPropagator()
+	}
+	if config.Subsystem == "" {
+		config.Subsystem = defaultSubsystem
+	}
+
+	b := &Bridge{
+		config: config,
+		requests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: config.Namespace,
+				Subsystem: config.Subsystem,
+				Name:      "requests_total",
+				Help:      "How many requests were sent to NATS subjects, partitioned by subject and outcome.",
+			},
+			[]string{"subject", "outcome"},
+		),
+		requestDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: config.Namespace,
+				Subsystem: config.Subsystem,
+				Name:      "request_duration_seconds",
+				Help:      "The NATS request/reply latencies in seconds.",
+				Buckets:   prometheus.DefBuckets,
+			},
+			[]string{"subject"},
+		),
+		published: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: config.Namespace,
+				Subsystem: config.Subsystem,
+				Name:      "published_total",
+				Help:      "How many handler results were published to NATS subjects, partitioned by subject and outcome.",
+			},
+			[]string{"subject", "outcome"},
+		),
+	}
+	if config.Registerer != nil {
+		for _, c := range []prometheus.Collector{b.requests, b.requestDuration, b.published} {
+			if err := config.Registerer.Register(c); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return b, nil
+}
+
+// MustNew returns Bridge or panics on invalid configuration.
+func MustNew(config Config) *Bridge {
+	b, err := New(config)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+var subjectParamRe = regexp.MustCompile(`\{([^{}]+)}`)
+
+// Handler returns handler forwarding request body to subject with request/reply and responding with reply data.
+// Subject can contain `{name}` placeholders replaced with path parameters (i.e. `orders.{region}.create`), invalid
+// parameter values are rejected with ErrInvalidSubjectParam.
+// Metrics are labeled with subject template. Reply with `Nats-Service-Error-Code` header is responded with that
+// status code, missing responders with `503 Service Unavailable` and timeouts with `504 Gateway Timeout`.
+func (b *Bridge) Handler(subject string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		resolved, err := resolveSubject(c, subject)
+		if err != nil {
+			return err
+		}
+
+		req := c.Request()
+		data, err := io.ReadAll(io.LimitReader(req.Body, b.config.MaxRequestSize+1))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "failed to read request body").SetInternal(err)
+		}
+		if int64(len(data)) > b.config.MaxRequestSize {
+			return echo.ErrStatusRequestEntityTooLarge
+		}
+
+		msg := b.newMsg(c, resolved, data)
+		if ct := req.Header.Get(echo.HeaderContentType); ct != "" {
+			msg.Header.Set(echo.HeaderContentType, ct)
+		}
+
+		ctx, cancel := context.WithTimeout(req.Context(), b.config.Timeout)
+		defer cancel()
+		start := time.Now()
+		reply, err := b.config.Conn.Request(ctx, msg)
+		b.requestDuration.WithLabelValues(subject).Observe(time.Since(start).Seconds())
+
+		switch {
+		case errors.Is(err, ErrNoResponders):
+			b.requests.WithLabelValues(subject, "no_responders").Inc()
+			return echo.NewHTTPError(http.StatusServiceUnavailable, "no responders").SetInternal(err)
+		case errors.Is(err, context.DeadlineExceeded):
+			b.requests.WithLabelValues(subject, "timeout").Inc()
+			return echo.NewHTTPError(http.StatusGatewayTimeout).SetInternal(err)
+		case err != nil:
+			b.requests.WithLabelValues(subject, "error").Inc()
+			return echo.NewHTTPError(http.StatusBadGateway).SetInternal(err)
+		}
+
+		if code := reply.Header.Get(HeaderServiceErrorCode); code != "" {
+			b.requests.WithLabelValues(subject, "service_error").Inc()
+			status, err := strconv.Atoi(code)
+			if err != nil || status < 400 || status > 599 {
+				status = http.StatusBadGateway
+			}
+			return echo.NewHTTPError(status, reply.Header.Get(HeaderServiceError))
+		}
+		b.requests.WithLabelValues(subject, "ok").Inc()
+
+		contentType := reply.Header.Get(echo.HeaderContentType)
+		if contentType == "" {
+			contentType = echo.MIMEOctetStream
+		}
+		return c.Blob(http.StatusOK, contentType, reply.Data)
+	}
+}
+
+// Publish returns middleware publishing response body of successful (2xx) responses to subject. Subject can contain
+// `{name}` placeholders replaced with path parameters, invalid parameter values are rejected with
+// ErrInvalidSubjectParam before handler is called. Response is sent to the client before publishing and
+// publishing errors are only logged and counted.
+func (b *Bridge) Publish(subject string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			resolved, err := resolveSubject(c, subject)
+			if err != nil {
+				return err
+			}
+
+			res := c.Response()
+			buf := new(bytes.Buffer)
+			original := res.Writer
+			res.Writer = &teeWriter{ResponseWriter: original, buf: buf}
+			defer func() {
+				res.Writer = original
+			}()
+
+			if err := next(c); err != nil {
+				return err
+			}
+			if res.Status < http.StatusOK || res.Status >= http.StatusMultipleChoices {
+				return nil
+			}
+
+			msg := b.newMsg(c, resolved, buf.Bytes())
+			if ct := res.Header().Get(echo.HeaderContentType); ct != "" {
+				msg.Header.Set(echo.HeaderContentType, ct)
+			}
+			if err := b.config.Conn.Publish(msg); err != nil {
+				b.published.WithLabelValues(subject, "error").Inc()
+				c.Logger().Error(fmt.Errorf("echonats: failed to publish to %v: %w", msg.Subject, err))
+				return nil
+			}
+			b.published.WithLabelValues(subject, "ok").Inc()
+			return nil
+		}
+	}
+}
+
+// newMsg returns message with trace context and request ID headers.
+func (b *Bridge) newMsg(c echo.Context, subject string, data []byte) *Msg {
+	msg := &Msg{Subject: subject, Header: http.Header{}, Data: data}
+	b.config.Propagators.Inject(c.Request().Context(), propagation.HeaderCarrier(msg.Header))
+	requestID := c.Request().Header.Get(echo.HeaderXRequestID)
+	if requestID == "" {
+		requestID = c.Response().Header().Get(echo.HeaderXRequestID)
+	}
+	if requestID != "" {
+		msg.Header.Set(echo.HeaderXRequestID, requestID)
+	}
+	return msg
+}
+
+// resolveSubject replaces `{name}` placeholders in subject with path parameters. Parameter values that are empty or
+// contain subject token separator (`.`), wildcards (`*`, `>`) or whitespace are rejected with ErrInvalidSubjectParam
+// so clients can not address other subjects.
+func resolveSubject(c echo.Context, subject string) (string, error) {
+	var err error
+	resolved := subjectParamRe.ReplaceAllStringFunc(subject, func(m string) string {
+		name := m[1 : len(m)-1]
+		value := c.Param(name)
+		if value == "" || strings.ContainsAny(value, ".*>") || strings.IndexFunc(value, unicode.IsSpace) != -1 {
+			err = ErrInvalidSubjectParam.WithInternal(fmt.Errorf("echonats: invalid value of subject parameter %v: %q", name, value))
+		}
+		return value
+	})
+	return resolved, err
+}
+
+type teeWriter struct {
+	http.ResponseWriter
+	buf *bytes.Buffer
+}
+
+func (w *teeWriter) Write(b []byte) (int, error) {
+	w.buf.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *teeWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
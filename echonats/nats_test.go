@@ -0,0 +1,235 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echonats
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type fakeConn struct {
+	requests  []*Msg
+	published []*Msg
+	reply     func(ctx context.Context, msg *Msg) (*Msg, error)
+	pubErr    error
+}
+
+func (c *fakeConn) Request(ctx context.Context, msg *Msg) (*Msg, error) {
+	c.requests = append(c.requests, msg)
+	return c.reply(ctx, msg)
+}
+
+func (c *fakeConn) Publish(msg *Msg) error {
+	c.published = append(c.published, msg)
+	return c.pubErr
+}
+
+func TestNew_invalidConfig(t *testing.T) {
+	_, err := New(Config{})
+	assert.EqualError(t, err, "echonats: Conn is required")
+}
+
+func TestBridge_Handler(t *testing.T) {
+	conn := &fakeConn{reply: func(ctx context.Context, msg *Msg) (*Msg, error) {
+		h := http.Header{}
+		h.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		return &Msg{Header: h, Data: []byte(`{"id":1}`)}, nil
+	}}
+	reg := prometheus.NewRegistry()
+	b := MustNew(Config{Conn: conn, Registerer: reg, Propagators: propagation.TraceContext{}})
+
+	e := echo.New()
+	e.POST("/orders/:region", b.Handler("orders.{region}.create"))
+
+	spanCtx := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{2},
+		TraceFlags: trace.FlagsSampled,
+	})
+	req := httptest.NewRequest(http.MethodPost, "/orders/eu", strings.NewReader(`{"item":"a"}`))
+	req = req.WithContext(trace.ContextWithSpanContext(req.Context(), spanCtx))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(echo.HeaderXRequestID, "req-1")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `{"id":1}`, rec.Body.String())
+	assert.Equal(t, echo.MIMEApplicationJSON, rec.Header().Get(echo.HeaderContentType))
+
+	if assert.Len(t, conn.requests, 1) {
+		msg := conn.requests[0]
+		assert.Equal(t, "orders.eu.create", msg.Subject)
+		assert.Equal(t, `{"item":"a"}`, string(msg.Data))
+		assert.Equal(t, "req-1", msg.Header.Get(echo.HeaderXRequestID))
+		assert.Equal(t, echo.MIMEApplicationJSON, msg.Header.Get(echo.HeaderContentType))
+		assert.Equal(t, "00-01000000000000000000000000000000-0200000000000000-01", msg.Header.Get("traceparent"))
+	}
+	assert.Equal(t, 1.0, testutil.ToFloat64(b.requests.WithLabelValues("orders.{region}.create", "ok")))
+	assert.Equal(t, 1, testutil.CollectAndCount(b.requestDuration))
+}
+
+func TestBridge_HandlerErrors(t *testing.T) {
+	var testCases = []struct {
+		name          string
+		reply         func(ctx context.Context, msg *Msg) (*Msg, error)
+		body          string
+		expectStatus  int
+		expectOutcome string
+	}{
+		{
+			name: "no responders",
+			reply: func(ctx context.Context, msg *Msg) (*Msg, error) {
+				return nil, ErrNoResponders
+			},
+			expectStatus:  http.StatusServiceUnavailable,
+			expectOutcome: "no_responders",
+		},
+		{
+			name: "timeout",
+			reply: func(ctx context.Context, msg *Msg) (*Msg, error) {
+				return nil, context.DeadlineExceeded
+			},
+			expectStatus:  http.StatusGatewayTimeout,
+			expectOutcome: "timeout",
+		},
+		{
+			name: "connection error",
+			reply: func(ctx context.Context, msg *Msg) (*Msg, error) {
+				return nil, errors.New("connection closed")
+			},
+			expectStatus:  http.StatusBadGateway,
+			expectOutcome: "error",
+		},
+		{
+			name: "service error",
+			reply: func(ctx context.Context, msg *Msg) (*Msg, error) {
+				h := http.Header{}
+				h.Set(HeaderServiceErrorCode, "404")
+				h.Set(HeaderServiceError, "order not found")
+				return &Msg{Header: h}, nil
+			},
+			expectStatus:  http.StatusNotFound,
+			expectOutcome: "service_error",
+		},
+		{
+			name: "too large",
+			reply: func(ctx context.Context, msg *Msg) (*Msg, error) {
+				return &Msg{Header: http.Header{}}, nil
+			},
+			body:         strings.Repeat("x", 11),
+			expectStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			conn := &fakeConn{reply: tc.reply}
+			b := MustNew(Config{Conn: conn, MaxRequestSize: 10})
+
+			e := echo.New()
+			e.POST("/orders", b.Handler("orders"))
+
+			req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(tc.body))
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectStatus, rec.Code)
+			if tc.expectOutcome != "" {
+				assert.Equal(t, 1.0, testutil.ToFloat64(b.requests.WithLabelValues("orders", tc.expectOutcome)))
+			} else {
+				assert.Empty(t, conn.requests)
+			}
+		})
+	}
+}
+
+func TestBridge_Publish(t *testing.T) {
+	conn := &fakeConn{}
+	b := MustNew(Config{Conn: conn})
+
+	e := echo.New()
+	e.PUT("/users/:id", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, map[string]string{"id": c.Param("id")})
+	}, b.Publish("users.{id}.updated"))
+	e.DELETE("/users/:id", func(c echo.Context) error {
+		return echo.ErrNotFound
+	}, b.Publish("users.{id}.deleted"))
+
+	req := httptest.NewRequest(http.MethodPut, "/users/7", nil)
+	req.Header.Set(echo.HeaderXRequestID, "req-2")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "{\"id\":\"7\"}\n", rec.Body.String())
+	if assert.Len(t, conn.published, 1) {
+		msg := conn.published[0]
+		assert.Equal(t, "users.7.updated", msg.Subject)
+		assert.Equal(t, rec.Body.String(), string(msg.Data))
+		assert.Equal(t, "req-2", msg.Header.Get(echo.HeaderXRequestID))
+		assert.Equal(t, echo.MIMEApplicationJSON, msg.Header.Get(echo.HeaderContentType))
+	}
+	assert.Equal(t, 1.0, testutil.ToFloat64(b.published.WithLabelValues("users.{id}.updated", "ok")))
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/users/7", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Len(t, conn.published, 1)
+}
+
+func TestBridge_PublishError(t *testing.T) {
+	conn := &fakeConn{pubErr: errors.New("connection closed")}
+	b := MustNew(Config{Conn: conn})
+
+	e := echo.New()
+	e.POST("/events", func(c echo.Context) error {
+		return c.String(http.StatusCreated, "created")
+	}, b.Publish("events"))
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/events", nil))
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "created", rec.Body.String())
+	assert.Equal(t, 1.0, testutil.ToFloat64(b.published.WithLabelValues("events", "error")))
+}
+
+func TestBridge_invalidSubjectParam(t *testing.T) {
+	conn := &fakeConn{reply: func(ctx context.Context, msg *Msg) (*Msg, error) {
+		return &Msg{Header: http.Header{}}, nil
+	}}
+	b := MustNew(Config{Conn: conn})
+
+	e := echo.New()
+	e.GET("/orders/:id", b.Handler("orders.{id}.get"))
+	e.PUT("/users/:id", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}, b.Publish("users.{id}.updated"))
+
+	for _, id := range []string{"x.delete", "*", ">", "a%20b", "a%09b"} {
+		t.Run(id, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders/"+id, nil))
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+			rec = httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/users/"+id, nil))
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		})
+	}
+	assert.Empty(t, conn.requests)
+	assert.Empty(t, conn.published)
+}
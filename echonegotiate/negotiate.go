@@ -0,0 +1,360 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+/*
+Package echonegotiate provides HTTP content negotiation for `Accept`, `Accept-Language` and `Accept-Encoding`
+request headers (RFC 9110) with support for quality values and wildcards.
+
+Middleware negotiates representation once per request and stores the result in context. Respond encodes response as
+JSON, XML, MessagePack or HTML depending on negotiated media type.
+
+Example:
+```
+package main
+
+import (
+
+	"net/http"
+
+	"github.com/labstack/echo-contrib/echonegotiate"
+	"github.com/labstack/echo/v4"
+
+)
+
+	type User struct {
+		Name string `json:"name" xml:"name" msgpack:"name"`
+	}
+
+	func main() {
+		e := echo.New()
+		e.Use(echonegotiate.MiddlewareWithConfig(echonegotiate.Config{
+			MediaTypes: []string{echo.MIMEApplicationJSON, echo.MIMEApplicationXML},
+			Languages:  []string{"en", "de"},
+		}))
+
+		e.GET("/users/:id", func(c echo.Context) error {
+			result := echonegotiate.Get(c)
+			c.Logger().Infof("language: %v", result.Language)
+
+			return echonegotiate.Respond(c, http.StatusOK, User{Name: "Jon"})
+		})
+
+		e.Logger.Fatal(e.Start(":1323"))
+	}
+
+```
+*/
+package echonegotiate
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo-contrib/echomsgpack"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+const contextKey = "_echonegotiate_result"
+
+const (
+	// HeaderAcceptLanguage is `Accept-Language` request header name.
+	HeaderAcceptLanguage = "Accept-Language"
+
+	// EncodingIdentity is content coding that means "no encoding".
+	EncodingIdentity = "identity"
+)
+
+// Config defines the config for content negotiation middleware.
+type Config struct {
+	// Skipper defines a function to skip middleware.
+	Skipper middleware.Skipper
+
+	// MediaTypes are media types server can produce in order of preference. Supported media types for Respond are
+	// JSON, XML, MessagePack and HTML.
+	// Defaults to: JSON, XML, MessagePack
+	MediaTypes []string
+
+	// Languages are language tags server can produce in order of preference.
+	// Optional. When empty Accept-Language header is not negotiated.
+	Languages []string
+
+	// Encodings are content codings server can produce in order of preference.
+	// Optional. When empty Accept-Encoding header is not negotiated.
+	Encodings []string
+
+	// HTMLTemplate is name of the template rendered with `echo.Context.Render` when HTML media type is negotiated.
+	// Required when MediaTypes contains `text/html`.
+	HTMLTemplate string
+
+	// ErrorOnNotAcceptable makes middleware return `406 Not Acceptable` error when request headers do not accept any of
+	// offered media types, languages or encodings. Otherwise first offer is used.
+	ErrorOnNotAcceptable bool
+}
+
+// DefaultConfig is the default content negotiation middleware config.
+var DefaultConfig = Config{
+	Skipper:    middleware.DefaultSkipper,
+	MediaTypes: []string{echo.MIMEApplicationJSON, echo.MIMEApplicationXML, echomsgpack.MIMEApplicationMsgpack},
+}
+
+// Result is outcome of negotiation stored in context by middleware.
+type Result struct {
+	// MediaType is negotiated media type (e.g. `application/json`).
+	MediaType string
+	// Language is negotiated language tag. Empty when languages are not negotiated.
+	Language string
+	// Encoding is negotiated content coding. Empty when encodings are not negotiated.
+	Encoding string
+
+	htmlTemplate string
+}
+
+// Middleware returns content negotiation middleware with default config.
+func Middleware() echo.MiddlewareFunc {
+	return MiddlewareWithConfig(DefaultConfig)
+}
+
+// MiddlewareWithConfig returns content negotiation middleware with config or panics on invalid configuration.
+// See: `Middleware()`.
+func MiddlewareWithConfig(config Config) echo.MiddlewareFunc {
+	mw, err := config.ToMiddleware()
+	if err != nil {
+		panic(err)
+	}
+	return mw
+}
+
+// ToMiddleware converts configuration to middleware or returns an error.
+func (config Config) ToMiddleware() (echo.MiddlewareFunc, error) {
+	if config.Skipper == nil {
+		config.Skipper = DefaultConfig.Skipper
+	}
+	if len(config.MediaTypes) == 0 {
+		config.MediaTypes = DefaultConfig.MediaTypes
+	}
+	for _, mt := range config.MediaTypes {
+		if !isSupportedMediaType(mt) {
+			return nil, fmt.Errorf("echonegotiate: unsupported media type: %v", mt)
+		}
+		if mt == echo.MIMETextHTML && config.HTMLTemplate == "" {
+			return nil, errors.New("echonegotiate: HTML media type requires HTMLTemplate")
+		}
+	}
+
+	vary := []string{echo.HeaderAccept}
+	if len(config.Languages) > 0 {
+		vary = append(vary, HeaderAcceptLanguage)
+	}
+	if len(config.Encodings) > 0 {
+		vary = append(vary, echo.HeaderAcceptEncoding)
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			header := c.Request().Header
+			for _, v := range vary {
+				c.Response().Header().Add(echo.HeaderVary, v)
+			}
+
+			result := &Result{htmlTemplate: config.HTMLTemplate}
+			result.MediaType = MediaType(header.Get(echo.HeaderAccept), config.MediaTypes)
+			if len(config.Languages) > 0 {
+				result.Language = Language(header.Get(HeaderAcceptLanguage), config.Languages)
+			}
+			if len(config.Encodings) > 0 {
+				result.Encoding = Encoding(header.Get(echo.HeaderAcceptEncoding), config.Encodings)
+			}
+
+			if result.MediaType == "" ||
+				(len(config.Languages) > 0 && result.Language == "") ||
+				(len(config.Encodings) > 0 && result.Encoding == "") {
+				if config.ErrorOnNotAcceptable {
+					return echo.NewHTTPError(http.StatusNotAcceptable)
+				}
+				if result.MediaType == "" {
+					result.MediaType = config.MediaTypes[0]
+				}
+				if len(config.Languages) > 0 && result.Language == "" {
+					result.Language = config.Languages[0]
+				}
+				if len(config.Encodings) > 0 && result.Encoding == "" {
+					result.Encoding = EncodingIdentity
+				}
+			}
+
+			c.Set(contextKey, result)
+			return next(c)
+		}
+	}, nil
+}
+
+// Get returns negotiation result stored in context by middleware. When middleware was not executed for the request
+// media type is negotiated against DefaultConfig media types.
+func Get(c echo.Context) Result {
+	if r, ok := c.Get(contextKey).(*Result); ok && r != nil {
+		return *r
+	}
+	mt := MediaType(c.Request().Header.Get(echo.HeaderAccept), DefaultConfig.MediaTypes)
+	if mt == "" {
+		mt = DefaultConfig.MediaTypes[0]
+	}
+	return Result{MediaType: mt}
+}
+
+// Respond sends response with status code encoded in negotiated media type.
+func Respond(c echo.Context, code int, i interface{}) error {
+	result := Get(c)
+	switch result.MediaType {
+	case echo.MIMEApplicationXML:
+		return c.XML(code, i)
+	case echomsgpack.MIMEApplicationMsgpack:
+		return echomsgpack.Msgpack(c, code, i)
+	case echo.MIMETextHTML:
+		return c.Render(code, result.htmlTemplate, i)
+	default:
+		return c.JSON(code, i)
+	}
+}
+
+// MediaType returns best offer for given `Accept` header value or empty string when none of the offers is
+// acceptable. Empty header accepts any media type and first offer is returned.
+func MediaType(header string, offers []string) string {
+	return negotiate(header, offers, "*/*", matchMediaType)
+}
+
+// Language returns best offer for given `Accept-Language` header value or empty string when none of the offers is
+// acceptable. Language ranges are matched by prefix (RFC 4647 basic filtering) so `en` accepts `en-US`.
+func Language(header string, offers []string) string {
+	return negotiate(header, offers, "*", matchLanguage)
+}
+
+// Encoding returns best offer for given `Accept-Encoding` header value or empty string when none of the offers is
+// acceptable. `identity` coding is acceptable unless explicitly excluded by the header.
+func Encoding(header string, offers []string) string {
+	if strings.TrimSpace(header) == "" {
+		return EncodingIdentity
+	}
+	specs := parseHeader(header)
+	if best := bestOffer(specs, offers, matchEncoding); best != "" {
+		return best
+	}
+	if q, specificity := quality(specs, EncodingIdentity, matchEncoding); specificity < 0 || q > 0 {
+		return EncodingIdentity
+	}
+	return ""
+}
+
+type spec struct {
+	value string
+	q     float64
+}
+
+func negotiate(header string, offers []string, wildcard string, match func(spec, offer string) int) string {
+	if strings.TrimSpace(header) == "" {
+		header = wildcard
+	}
+	return bestOffer(parseHeader(header), offers, match)
+}
+
+// bestOffer returns offer with highest quality. On equal quality earlier offer wins (server preference).
+func bestOffer(specs []spec, offers []string, match func(spec, offer string) int) string {
+	best := ""
+	bestQ := 0.0
+	for _, offer := range offers {
+		q, _ := quality(specs, offer, match)
+		if q > bestQ {
+			best = offer
+			bestQ = q
+		}
+	}
+	return best
+}
+
+// quality returns quality of most specific spec matching offer and its specificity. Specificity is -1 when no spec
+// matches.
+func quality(specs []spec, offer string, match func(spec, offer string) int) (float64, int) {
+	q := 0.0
+	specificity := -1
+	for _, s := range specs {
+		if m := match(s.value, offer); m > specificity {
+			q = s.q
+			specificity = m
+		}
+	}
+	return q, specificity
+}
+
+func parseHeader(header string) []spec {
+	specs := make([]spec, 0, strings.Count(header, ",")+1)
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		value := strings.ToLower(strings.TrimSpace(params[0]))
+		if value == "" {
+			continue
+		}
+		s := spec{value: value, q: 1}
+		for _, p := range params[1:] {
+			k, v, ok := strings.Cut(strings.TrimSpace(p), "=")
+			if !ok || strings.ToLower(strings.TrimSpace(k)) != "q" {
+				continue
+			}
+			if q, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil && q >= 0 && q <= 1 {
+				s.q = q
+			}
+		}
+		specs = append(specs, s)
+	}
+	return specs
+}
+
+func matchMediaType(spec, offer string) int {
+	offer = strings.ToLower(offer)
+	if spec == offer {
+		return 2
+	}
+	if spec == "*/*" {
+		return 0
+	}
+	if strings.HasSuffix(spec, "/*") && strings.HasPrefix(offer, spec[:len(spec)-1]) {
+		return 1
+	}
+	return -1
+}
+
+func matchLanguage(spec, offer string) int {
+	offer = strings.ToLower(offer)
+	if spec == "*" {
+		return 0
+	}
+	if spec == offer || strings.HasPrefix(offer, spec+"-") {
+		return len(spec)
+	}
+	return -1
+}
+
+func matchEncoding(spec, offer string) int {
+	offer = strings.ToLower(offer)
+	if spec == offer {
+		return 1
+	}
+	if spec == "*" {
+		return 0
+	}
+	return -1
+}
+
+func isSupportedMediaType(mt string) bool {
+	switch mt {
+	case echo.MIMEApplicationJSON, echo.MIMEApplicationXML, echomsgpack.MIMEApplicationMsgpack, echo.MIMETextHTML:
+		return true
+	}
+	return false
+}
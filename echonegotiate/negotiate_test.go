@@ -0,0 +1,277 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echonegotiate
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo-contrib/echomsgpack"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type user struct {
+	Name string `json:"name" xml:"name" msgpack:"name"`
+}
+
+func TestMediaType(t *testing.T) {
+	offers := []string{echo.MIMEApplicationJSON, echo.MIMEApplicationXML, echo.MIMETextHTML}
+
+	var testCases = []struct {
+		name       string
+		whenHeader string
+		expect     string
+	}{
+		{name: "empty header accepts first offer", whenHeader: "", expect: echo.MIMEApplicationJSON},
+		{name: "exact match", whenHeader: "application/xml", expect: echo.MIMEApplicationXML},
+		{name: "highest quality wins", whenHeader: "application/json;q=0.5, text/html;q=0.9", expect: echo.MIMETextHTML},
+		{name: "equal quality uses server preference", whenHeader: "text/html, application/xml", expect: echo.MIMEApplicationXML},
+		{name: "subtype wildcard", whenHeader: "text/*", expect: echo.MIMETextHTML},
+		{name: "more specific range overrides wildcard", whenHeader: "*/*;q=0.8, application/json;q=0", expect: echo.MIMEApplicationXML},
+		{name: "case insensitive", whenHeader: "Application/XML", expect: echo.MIMEApplicationXML},
+		{name: "invalid quality is ignored", whenHeader: "application/xml;q=abc", expect: echo.MIMEApplicationXML},
+		{name: "not acceptable", whenHeader: "image/png", expect: ""},
+		{name: "all rejected", whenHeader: "*/*;q=0", expect: ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, MediaType(tc.whenHeader, offers))
+		})
+	}
+}
+
+func TestLanguage(t *testing.T) {
+	offers := []string{"en-US", "de", "fr-CA"}
+
+	var testCases = []struct {
+		name       string
+		whenHeader string
+		expect     string
+	}{
+		{name: "empty header accepts first offer", whenHeader: "", expect: "en-US"},
+		{name: "exact match", whenHeader: "de", expect: "de"},
+		{name: "prefix match", whenHeader: "fr;q=0.9, en;q=0.8", expect: "fr-CA"},
+		{name: "range longer than offer does not match", whenHeader: "de-AT", expect: ""},
+		{name: "wildcard", whenHeader: "es, *;q=0.1", expect: "en-US"},
+		{name: "specific rejection overrides wildcard", whenHeader: "*, en;q=0", expect: "de"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, Language(tc.whenHeader, offers))
+		})
+	}
+}
+
+func TestEncoding(t *testing.T) {
+	offers := []string{"br", "gzip"}
+
+	var testCases = []struct {
+		name       string
+		whenHeader string
+		expect     string
+	}{
+		{name: "empty header means identity", whenHeader: "", expect: EncodingIdentity},
+		{name: "highest quality wins", whenHeader: "gzip, br;q=0.5", expect: "gzip"},
+		{name: "wildcard uses server preference", whenHeader: "*", expect: "br"},
+		{name: "unknown encoding falls back to identity", whenHeader: "deflate", expect: EncodingIdentity},
+		{name: "identity rejected", whenHeader: "deflate, identity;q=0", expect: ""},
+		{name: "wildcard rejection excludes identity", whenHeader: "*;q=0", expect: ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, Encoding(tc.whenHeader, offers))
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var testCases = []struct {
+		name              string
+		givenConfig       Config
+		whenAccept        string
+		whenLanguage      string
+		expectResult      Result
+		expectStatus      int
+		expectContentType string
+	}{
+		{
+			name:              "json by default",
+			givenConfig:       DefaultConfig,
+			expectResult:      Result{MediaType: echo.MIMEApplicationJSON},
+			expectStatus:      http.StatusOK,
+			expectContentType: echo.MIMEApplicationJSON,
+		},
+		{
+			name:              "xml",
+			givenConfig:       DefaultConfig,
+			whenAccept:        "application/xml",
+			expectResult:      Result{MediaType: echo.MIMEApplicationXML},
+			expectStatus:      http.StatusOK,
+			expectContentType: echo.MIMEApplicationXMLCharsetUTF8,
+		},
+		{
+			name:              "msgpack",
+			givenConfig:       DefaultConfig,
+			whenAccept:        "application/json;q=0.1, application/msgpack",
+			expectResult:      Result{MediaType: echomsgpack.MIMEApplicationMsgpack},
+			expectStatus:      http.StatusOK,
+			expectContentType: echomsgpack.MIMEApplicationMsgpack,
+		},
+		{
+			name:              "not acceptable falls back to first offer",
+			givenConfig:       Config{Languages: []string{"en", "de"}},
+			whenAccept:        "image/png",
+			whenLanguage:      "fr",
+			expectResult:      Result{MediaType: echo.MIMEApplicationJSON, Language: "en"},
+			expectStatus:      http.StatusOK,
+			expectContentType: echo.MIMEApplicationJSON,
+		},
+		{
+			name:         "not acceptable error",
+			givenConfig:  Config{ErrorOnNotAcceptable: true},
+			whenAccept:   "image/png",
+			expectStatus: http.StatusNotAcceptable,
+		},
+		{
+			name:         "not acceptable language error",
+			givenConfig:  Config{Languages: []string{"en"}, ErrorOnNotAcceptable: true},
+			whenLanguage: "fr",
+			expectStatus: http.StatusNotAcceptable,
+		},
+		{
+			name:              "language",
+			givenConfig:       Config{Languages: []string{"en", "de"}},
+			whenLanguage:      "de-DE, de;q=0.9, en;q=0.5",
+			expectResult:      Result{MediaType: echo.MIMEApplicationJSON, Language: "de"},
+			expectStatus:      http.StatusOK,
+			expectContentType: echo.MIMEApplicationJSON,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(MiddlewareWithConfig(tc.givenConfig))
+
+			var result Result
+			e.GET("/", func(c echo.Context) error {
+				result = Get(c)
+				return Respond(c, http.StatusOK, user{Name: "Jon"})
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.whenAccept != "" {
+				req.Header.Set(echo.HeaderAccept, tc.whenAccept)
+			}
+			if tc.whenLanguage != "" {
+				req.Header.Set(HeaderAcceptLanguage, tc.whenLanguage)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectStatus, rec.Code)
+			if tc.expectStatus != http.StatusOK {
+				return
+			}
+			assert.Equal(t, tc.expectResult.MediaType, result.MediaType)
+			assert.Equal(t, tc.expectResult.Language, result.Language)
+			assert.Equal(t, tc.expectContentType, rec.Header().Get(echo.HeaderContentType))
+			assert.Contains(t, rec.Header().Values(echo.HeaderVary), echo.HeaderAccept)
+		})
+	}
+}
+
+func TestRespond_msgpackBody(t *testing.T) {
+	e := echo.New()
+	e.Use(Middleware())
+	e.GET("/", func(c echo.Context) error {
+		return Respond(c, http.StatusCreated, user{Name: "Jon"})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(echo.HeaderAccept, echomsgpack.MIMEApplicationMsgpack)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	var u user
+	assert.NoError(t, msgpack.Unmarshal(rec.Body.Bytes(), &u))
+	assert.Equal(t, "Jon", u.Name)
+}
+
+type templateRenderer struct{}
+
+func (templateRenderer) Render(w io.Writer, name string, data interface{}, c echo.Context) error {
+	_, err := io.WriteString(w, "<p>"+name+":"+data.(user).Name+"</p>")
+	return err
+}
+
+func TestRespond_html(t *testing.T) {
+	e := echo.New()
+	e.Renderer = templateRenderer{}
+	e.Use(MiddlewareWithConfig(Config{
+		MediaTypes:   []string{echo.MIMEApplicationJSON, echo.MIMETextHTML},
+		HTMLTemplate: "user",
+	}))
+	e.GET("/", func(c echo.Context) error {
+		return Respond(c, http.StatusOK, user{Name: "Jon"})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(echo.HeaderAccept, "text/html,application/xhtml+xml,*/*;q=0.8")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "<p>user:Jon</p>", rec.Body.String())
+}
+
+func TestRespond_withoutMiddleware(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(echo.HeaderAccept, echo.MIMEApplicationXML)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	assert.NoError(t, Respond(c, http.StatusOK, user{Name: "Jon"}))
+	assert.Equal(t, echo.MIMEApplicationXMLCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+}
+
+func TestConfig_ToMiddleware(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		givenConfig Config
+		expectErr   string
+	}{
+		{
+			name:        "ok, defaults",
+			givenConfig: Config{},
+		},
+		{
+			name:        "nok, unsupported media type",
+			givenConfig: Config{MediaTypes: []string{"image/png"}},
+			expectErr:   "echonegotiate: unsupported media type: image/png",
+		},
+		{
+			name:        "nok, html without template",
+			givenConfig: Config{MediaTypes: []string{echo.MIMETextHTML}},
+			expectErr:   "echonegotiate: HTML media type requires HTMLTemplate",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mw, err := tc.givenConfig.ToMiddleware()
+			if tc.expectErr != "" {
+				assert.EqualError(t, err, tc.expectErr)
+				assert.Nil(t, mw)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotNil(t, mw)
+		})
+	}
+}
@@ -0,0 +1,301 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+/*
+Package echopagination provides helpers binding limit/offset and cursor pagination parameters, writing RFC 5988 `Link`
+headers and standard JSON response envelopes so all services share the same pagination contract.
+
+Limit defaults to Config.DefaultLimit and is capped to Config.MaxLimit. Invalid parameters are rejected with
+`400 Bad Request`.
+
+Example:
+```
+package main
+
+import (
+
+	"context"
+
+	"github.com/labstack/echo-contrib/echopagination"
+	"github.com/labstack/echo/v4"
+
+)
+
+	func main() {
+		e := echo.New()
+
+		e.GET("/users", func(c echo.Context) error {
+			page, err := echopagination.Bind(c)
+			if err != nil {
+				return err
+			}
+			users, err := store.ListUsers(c.Request().Context(), page.Limit, page.Offset)
+			if err != nil {
+				return err
+			}
+			return echopagination.JSON(c, page, users, func(ctx context.Context) (int64, error) {
+				return store.CountUsers(ctx)
+			})
+		})
+
+		e.GET("/events", func(c echo.Context) error {
+			page, err := echopagination.Bind(c)
+			if err != nil {
+				return err
+			}
+			events, next, err := store.ListEvents(c.Request().Context(), page.Cursor, page.Limit)
+			if err != nil {
+				return err
+			}
+			return echopagination.CursorJSON(c, page, events, next)
+		})
+
+		e.Logger.Fatal(e.Start(":1323"))
+	}
+
+```
+*/
+package echopagination
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HeaderTotalCount is default header total number of items is written to.
+const HeaderTotalCount = "X-Total-Count"
+
+// Config defines the config for pagination parameters and links.
+type Config struct {
+	// DefaultLimit is page size used when limit parameter is missing.
+	// Defaults to: 20
+	DefaultLimit int
+
+	// MaxLimit caps requested page size.
+	// Defaults to: 100
+	MaxLimit int
+
+	// LimitParam is name of the query parameter with page size.
+	// Defaults to: "limit"
+	LimitParam string
+
+	// OffsetParam is name of the query parameter with offset of the first item.
+	// Defaults to: "offset"
+	OffsetParam string
+
+	// CursorParam is name of the query parameter with opaque cursor of the page.
+	// Defaults to: "cursor"
+	CursorParam string
+
+	// TotalCountHeader is name of the response header total number of items is written to. Use "-" to disable header.
+	// Defaults to: "X-Total-Count"
+	TotalCountHeader string
+}
+
+// DefaultConfig is the default pagination config.
+var DefaultConfig = Config{
+	DefaultLimit:     20,
+	MaxLimit:         100,
+	LimitParam:       "limit",
+	OffsetParam:      "offset",
+	CursorParam:      "cursor",
+	TotalCountHeader: HeaderTotalCount,
+}
+
+// Page is requested page bound from query parameters.
+type Page struct {
+	// Limit is page size, always between 1 and MaxLimit.
+	Limit int
+	// Offset is offset of the first item for limit/offset pagination.
+	Offset int
+	// Cursor is opaque cursor for cursor pagination. Empty for the first page.
+	Cursor string
+
+	config Config
+}
+
+// TotalFunc returns total number of items. It is called only when response is written so counting can be skipped
+// when it is not needed.
+type TotalFunc func(ctx context.Context) (int64, error)
+
+// Meta describes returned page in response envelope.
+type Meta struct {
+	Limit      int    `json:"limit"`
+	Offset     *int   `json:"offset,omitempty"`
+	Total      *int64 `json:"total,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// Envelope is standard paginated JSON response.
+type Envelope[T any] struct {
+	Data       []T  `json:"data"`
+	Pagination Meta `json:"pagination"`
+}
+
+// Bind binds pagination parameters with default config.
+func Bind(c echo.Context) (Page, error) {
+	return DefaultConfig.Bind(c)
+}
+
+// Bind binds pagination parameters from query string. Returns `400 Bad Request` error for malformed parameters or
+// when both offset and cursor are given.
+func (config Config) Bind(c echo.Context) (Page, error) {
+	config = config.withDefaults()
+	p := Page{Limit: config.DefaultLimit, config: config}
+
+	if v := c.QueryParam(config.LimitParam); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			return Page{}, invalidParam(config.LimitParam)
+		}
+		p.Limit = min(limit, config.MaxLimit)
+	}
+	if v := c.QueryParam(config.OffsetParam); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return Page{}, invalidParam(config.OffsetParam)
+		}
+		p.Offset = offset
+	}
+	p.Cursor = c.QueryParam(config.CursorParam)
+	if p.Cursor != "" && p.Offset > 0 {
+		return Page{}, echo.NewHTTPError(http.StatusBadRequest,
+			config.OffsetParam+" and "+config.CursorParam+" parameters can not be combined")
+	}
+	return p, nil
+}
+
+func (config Config) withDefaults() Config {
+	if config.DefaultLimit <= 0 {
+		config.DefaultLimit = DefaultConfig.DefaultLimit
+	}
+	if config.MaxLimit <= 0 {
+		config.MaxLimit = DefaultConfig.MaxLimit
+	}
+	config.DefaultLimit = min(config.DefaultLimit, config.MaxLimit)
+	if config.LimitParam == "" {
+		config.LimitParam = DefaultConfig.LimitParam
+	}
+	if config.OffsetParam == "" {
+		config.OffsetParam = DefaultConfig.OffsetParam
+	}
+	if config.CursorParam == "" {
+		config.CursorParam = DefaultConfig.CursorParam
+	}
+	if config.TotalCountHeader == "" {
+		config.TotalCountHeader = DefaultConfig.TotalCountHeader
+	}
+	return config
+}
+
+func invalidParam(name string) *echo.HTTPError {
+	return echo.NewHTTPError(http.StatusBadRequest, "invalid "+name+" parameter")
+}
+
+// SetOffsetLinks sets `Link` header with `first`, `prev`, `next` and `last` links for limit/offset pagination and
+// total count header. count is number of items on the current page. When total is negative (unknown) `last` link is
+// omitted and `next` link is set when the page is full. Returns true when there are more items after the page.
+func (p Page) SetOffsetLinks(c echo.Context, count int, total int64) bool {
+	config := p.withConfig()
+	hasMore := count >= p.Limit
+	if total >= 0 {
+		hasMore = int64(p.Offset+p.Limit) < total
+		if config.TotalCountHeader != "-" {
+			c.Response().Header().Set(config.TotalCountHeader, strconv.FormatInt(total, 10))
+		}
+	}
+
+	links := []string{p.link(c, "first", map[string]string{config.OffsetParam: "0"})}
+	if p.Offset > 0 {
+		prev := max(p.Offset-p.Limit, 0)
+		links = append(links, p.link(c, "prev", map[string]string{config.OffsetParam: strconv.Itoa(prev)}))
+	}
+	if hasMore {
+		links = append(links, p.link(c, "next", map[string]string{config.OffsetParam: strconv.Itoa(p.Offset + p.Limit)}))
+	}
+	if total >= 0 {
+		last := int64(0)
+		if total > 0 {
+			last = (total - 1) / int64(p.Limit) * int64(p.Limit)
+		}
+		links = append(links, p.link(c, "last", map[string]string{config.OffsetParam: strconv.FormatInt(last, 10)}))
+	}
+	c.Response().Header().Set("Link", strings.Join(links, ", "))
+	return hasMore
+}
+
+// SetCursorLinks sets `Link` header with `first` and `next` links for cursor pagination. `next` link is omitted when
+// nextCursor is empty (last page).
+func (p Page) SetCursorLinks(c echo.Context, nextCursor string) {
+	config := p.withConfig()
+	links := []string{p.link(c, "first", map[string]string{config.CursorParam: ""})}
+	if nextCursor != "" {
+		links = append(links, p.link(c, "next", map[string]string{config.CursorParam: nextCursor}))
+	}
+	c.Response().Header().Set("Link", strings.Join(links, ", "))
+}
+
+func (p Page) withConfig() Config {
+	if p.config.LimitParam == "" {
+		return DefaultConfig.withDefaults()
+	}
+	return p.config
+}
+
+// link returns link to the current request URL with limit and given query parameters set. Parameters with empty
+// value are removed.
+func (p Page) link(c echo.Context, rel string, params map[string]string) string {
+	config := p.withConfig()
+	req := c.Request()
+	query := req.URL.Query()
+	query.Set(config.LimitParam, strconv.Itoa(p.Limit))
+	for k, v := range params {
+		if v == "" {
+			query.Del(k)
+			continue
+		}
+		query.Set(k, v)
+	}
+	u := url.URL{Scheme: c.Scheme(), Host: req.Host, Path: req.URL.Path, RawQuery: query.Encode()}
+	return "<" + u.String() + `>; rel="` + rel + `"`
+}
+
+// JSON writes items as Envelope for limit/offset pagination and sets links. total is called to count all items when
+// not nil.
+func JSON[T any](c echo.Context, p Page, items []T, total TotalFunc) error {
+	t := int64(-1)
+	if total != nil {
+		var err error
+		if t, err = total(c.Request().Context()); err != nil {
+			return err
+		}
+	}
+	hasMore := p.SetOffsetLinks(c, len(items), t)
+
+	offset := p.Offset
+	meta := Meta{Limit: p.Limit, Offset: &offset, HasMore: hasMore}
+	if t >= 0 {
+		meta.Total = &t
+	}
+	return c.JSON(http.StatusOK, Envelope[T]{Data: nonNil(items), Pagination: meta})
+}
+
+// CursorJSON writes items as Envelope for cursor pagination and sets links. nextCursor is empty on the last page.
+func CursorJSON[T any](c echo.Context, p Page, items []T, nextCursor string) error {
+	p.SetCursorLinks(c, nextCursor)
+	meta := Meta{Limit: p.Limit, NextCursor: nextCursor, HasMore: nextCursor != ""}
+	return c.JSON(http.StatusOK, Envelope[T]{Data: nonNil(items), Pagination: meta})
+}
+
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
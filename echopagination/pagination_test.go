@@ -0,0 +1,170 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echopagination
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func newContext(target string) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	return e.NewContext(httptest.NewRequest(http.MethodGet, target, nil), rec), rec
+}
+
+func TestBind(t *testing.T) {
+	var testCases = []struct {
+		name         string
+		whenURL      string
+		whenConfig   Config
+		expectLimit  int
+		expectOffset int
+		expectCursor string
+		expectErr    string
+	}{
+		{name: "defaults", whenURL: "/users", expectLimit: 20},
+		{name: "limit and offset", whenURL: "/users?limit=10&offset=30", expectLimit: 10, expectOffset: 30},
+		{name: "limit is capped", whenURL: "/users?limit=1000", expectLimit: 100},
+		{name: "cursor", whenURL: "/users?cursor=abc", expectLimit: 20, expectCursor: "abc"},
+		{
+			name:        "custom config",
+			whenURL:     "/users?per_page=80",
+			whenConfig:  Config{LimitParam: "per_page", DefaultLimit: 5, MaxLimit: 50},
+			expectLimit: 50,
+		},
+		{name: "invalid limit", whenURL: "/users?limit=abc", expectErr: "code=400, message=invalid limit parameter"},
+		{name: "zero limit", whenURL: "/users?limit=0", expectErr: "code=400, message=invalid limit parameter"},
+		{name: "negative offset", whenURL: "/users?offset=-1", expectErr: "code=400, message=invalid offset parameter"},
+		{
+			name:      "offset with cursor",
+			whenURL:   "/users?offset=10&cursor=abc",
+			expectErr: "code=400, message=offset and cursor parameters can not be combined",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, _ := newContext(tc.whenURL)
+
+			p, err := tc.whenConfig.Bind(c)
+
+			if tc.expectErr != "" {
+				assert.EqualError(t, err, tc.expectErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectLimit, p.Limit)
+			assert.Equal(t, tc.expectOffset, p.Offset)
+			assert.Equal(t, tc.expectCursor, p.Cursor)
+		})
+	}
+}
+
+func TestPage_SetOffsetLinks(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		whenURL     string
+		whenCount   int
+		whenTotal   int64
+		expectLink  string
+		expectTotal string
+		expectMore  bool
+	}{
+		{
+			name:      "first page",
+			whenURL:   "/users?limit=10&sort=name",
+			whenCount: 10,
+			whenTotal: 25,
+			expectLink: `<http://example.com/users?limit=10&offset=0&sort=name>; rel="first", ` +
+				`<http://example.com/users?limit=10&offset=10&sort=name>; rel="next", ` +
+				`<http://example.com/users?limit=10&offset=20&sort=name>; rel="last"`,
+			expectTotal: "25",
+			expectMore:  true,
+		},
+		{
+			name:      "last page",
+			whenURL:   "/users?limit=10&offset=20",
+			whenCount: 5,
+			whenTotal: 25,
+			expectLink: `<http://example.com/users?limit=10&offset=0>; rel="first", ` +
+				`<http://example.com/users?limit=10&offset=10>; rel="prev", ` +
+				`<http://example.com/users?limit=10&offset=20>; rel="last"`,
+			expectTotal: "25",
+		},
+		{
+			name:      "unknown total with full page",
+			whenURL:   "/users?limit=10&offset=5",
+			whenCount: 10,
+			whenTotal: -1,
+			expectLink: `<http://example.com/users?limit=10&offset=0>; rel="first", ` +
+				`<http://example.com/users?limit=10&offset=0>; rel="prev", ` +
+				`<http://example.com/users?limit=10&offset=15>; rel="next"`,
+			expectMore: true,
+		},
+		{
+			name:        "empty result",
+			whenURL:     "/users",
+			whenCount:   0,
+			whenTotal:   0,
+			expectLink:  `<http://example.com/users?limit=20&offset=0>; rel="first", <http://example.com/users?limit=20&offset=0>; rel="last"`,
+			expectTotal: "0",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, rec := newContext(tc.whenURL)
+			p, err := Bind(c)
+			assert.NoError(t, err)
+
+			hasMore := p.SetOffsetLinks(c, tc.whenCount, tc.whenTotal)
+
+			assert.Equal(t, tc.expectMore, hasMore)
+			assert.Equal(t, tc.expectLink, rec.Header().Get("Link"))
+			assert.Equal(t, tc.expectTotal, rec.Header().Get(HeaderTotalCount))
+		})
+	}
+}
+
+func TestJSON(t *testing.T) {
+	c, rec := newContext("/users?limit=2&offset=2")
+	p, err := Bind(c)
+	assert.NoError(t, err)
+
+	err = JSON(c, p, []string{"carol", "dave"}, func(ctx context.Context) (int64, error) { return 5, nil })
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"data":["carol","dave"],"pagination":{"limit":2,"offset":2,"total":5,"has_more":true}}`, rec.Body.String())
+	assert.Equal(t, "5", rec.Header().Get(HeaderTotalCount))
+}
+
+func TestJSON_totalError(t *testing.T) {
+	c, rec := newContext("/users")
+	p, _ := Bind(c)
+
+	err := JSON(c, p, []string{}, func(ctx context.Context) (int64, error) { return 0, errors.New("db down") })
+
+	assert.EqualError(t, err, "db down")
+	assert.False(t, c.Response().Committed)
+	assert.Empty(t, rec.Header().Get("Link"))
+}
+
+func TestCursorJSON(t *testing.T) {
+	c, rec := newContext("/events?cursor=abc&limit=2&type=click")
+	p, err := Bind(c)
+	assert.NoError(t, err)
+
+	err = CursorJSON[string](c, p, nil, "def")
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"data":[],"pagination":{"limit":2,"next_cursor":"def","has_more":true}}`, rec.Body.String())
+	assert.Equal(t, `<http://example.com/events?limit=2&type=click>; rel="first", `+
+		`<http://example.com/events?cursor=def&limit=2&type=click>; rel="next"`, rec.Header().Get("Link"))
+}
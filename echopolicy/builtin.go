@@ -0,0 +1,215 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echopolicy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo-contrib/echolockdown"
+	"github.com/labstack/echo-contrib/echomem"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/labstack/gommon/bytes"
+	"golang.org/x/time/rate"
+)
+
+// RegisterBuiltins registers factories for built-in middlewares:
+//
+//   - `request_id` - echo RequestID middleware. No options.
+//   - `secure` - echo Secure middleware with default config. No options.
+//   - `gzip` - echo Gzip middleware. Options: `level`.
+//   - `body_limit` - echo BodyLimit middleware. Options: `limit` (required, e.g. `2M`).
+//   - `timeout` - echo ContextTimeout middleware. Options: `timeout` (required).
+//   - `cors` - echo CORS middleware. Options: `allow_origins`, `allow_methods`, `allow_headers`, `expose_headers`,
+//     `allow_credentials`, `max_age` (seconds).
+//   - `rate_limit` - echo RateLimiter middleware with in-memory store limiting by client IP. Options: `rate`
+//     (required, requests per second), `burst`, `expires_in`.
+//   - `cache_control` - sets `Cache-Control` response header. Options: `max_age`, `public`, `private`, `no_store`.
+//   - `lockdown` - echolockdown middleware with in-memory store. Options: `max_failures`, `captcha_after`,
+//     `lockout_duration`, `max_lockout_duration`, `reset_after`.
+//   - `mem_budget` - echomem middleware. Options: `sample_rate`, `max_bytes`, `max_objects`.
+func RegisterBuiltins(r *Registry) {
+	r.Register("request_id", noOptions(middleware.RequestID))
+	r.Register("secure", noOptions(middleware.Secure))
+	r.Register("gzip", gzipFactory)
+	r.Register("body_limit", bodyLimitFactory)
+	r.Register("timeout", timeoutFactory)
+	r.Register("cors", corsFactory)
+	r.Register("rate_limit", rateLimitFactory)
+	r.Register("cache_control", cacheControlFactory)
+	r.Register("lockdown", lockdownFactory)
+	r.Register("mem_budget", memBudgetFactory)
+}
+
+func noOptions(fn func() echo.MiddlewareFunc) Factory {
+	return func(options Options) (echo.MiddlewareFunc, error) {
+		if len(options) > 0 {
+			return nil, errors.New("middleware does not accept options")
+		}
+		return fn(), nil
+	}
+}
+
+func gzipFactory(options Options) (echo.MiddlewareFunc, error) {
+	var o struct {
+		Level int `json:"level"`
+	}
+	if err := options.Decode(&o); err != nil {
+		return nil, err
+	}
+	if o.Level < -2 || o.Level > 9 {
+		return nil, errors.New("level must be between -2 and 9")
+	}
+	return middleware.GzipWithConfig(middleware.GzipConfig{Level: o.Level}), nil
+}
+
+func bodyLimitFactory(options Options) (echo.MiddlewareFunc, error) {
+	var o struct {
+		Limit string `json:"limit"`
+	}
+	if err := options.Decode(&o); err != nil {
+		return nil, err
+	}
+	if o.Limit == "" {
+		return nil, errors.New("limit is required")
+	}
+	if _, err := bytes.Parse(o.Limit); err != nil {
+		return nil, fmt.Errorf("invalid limit: %w", err)
+	}
+	return middleware.BodyLimit(o.Limit), nil
+}
+
+func timeoutFactory(options Options) (echo.MiddlewareFunc, error) {
+	var o struct {
+		Timeout Duration `json:"timeout"`
+	}
+	if err := options.Decode(&o); err != nil {
+		return nil, err
+	}
+	if o.Timeout <= 0 {
+		return nil, errors.New("timeout is required")
+	}
+	return middleware.ContextTimeoutConfig{Timeout: time.Duration(o.Timeout)}.ToMiddleware()
+}
+
+func corsFactory(options Options) (echo.MiddlewareFunc, error) {
+	var o struct {
+		AllowOrigins     []string `json:"allow_origins"`
+		AllowMethods     []string `json:"allow_methods"`
+		AllowHeaders     []string `json:"allow_headers"`
+		ExposeHeaders    []string `json:"expose_headers"`
+		AllowCredentials bool     `json:"allow_credentials"`
+		MaxAge           int      `json:"max_age"`
+	}
+	if err := options.Decode(&o); err != nil {
+		return nil, err
+	}
+	return middleware.CORSWithConfig(middleware.CORSConfig{
+		AllowOrigins:     o.AllowOrigins,
+		AllowMethods:     o.AllowMethods,
+		AllowHeaders:     o.AllowHeaders,
+		ExposeHeaders:    o.ExposeHeaders,
+		AllowCredentials: o.AllowCredentials,
+		MaxAge:           o.MaxAge,
+	}), nil
+}
+
+func rateLimitFactory(options Options) (echo.MiddlewareFunc, error) {
+	var o struct {
+		Rate      float64  `json:"rate"`
+		Burst     int      `json:"burst"`
+		ExpiresIn Duration `json:"expires_in"`
+	}
+	if err := options.Decode(&o); err != nil {
+		return nil, err
+	}
+	if o.Rate <= 0 {
+		return nil, errors.New("rate must be greater than 0")
+	}
+	store := middleware.NewRateLimiterMemoryStoreWithConfig(middleware.RateLimiterMemoryStoreConfig{
+		Rate:      rate.Limit(o.Rate),
+		Burst:     o.Burst,
+		ExpiresIn: time.Duration(o.ExpiresIn),
+	})
+	return middleware.RateLimiterWithConfig(middleware.RateLimiterConfig{Store: store}), nil
+}
+
+func cacheControlFactory(options Options) (echo.MiddlewareFunc, error) {
+	var o struct {
+		MaxAge  Duration `json:"max_age"`
+		Public  bool     `json:"public"`
+		Private bool     `json:"private"`
+		NoStore bool     `json:"no_store"`
+	}
+	if err := options.Decode(&o); err != nil {
+		return nil, err
+	}
+	if o.Public && o.Private {
+		return nil, errors.New("public and private are mutually exclusive")
+	}
+
+	var directives []string
+	switch {
+	case o.NoStore:
+		directives = append(directives, "no-store")
+	case o.MaxAge <= 0:
+		directives = append(directives, "no-cache")
+	default:
+		directives = append(directives, fmt.Sprintf("max-age=%d", int64(time.Duration(o.MaxAge).Seconds())))
+	}
+	if o.Public {
+		directives = append(directives, "public")
+	}
+	if o.Private {
+		directives = append(directives, "private")
+	}
+	value := strings.Join(directives, ", ")
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Response().Header().Set(echo.HeaderCacheControl, value)
+			return next(c)
+		}
+	}, nil
+}
+
+func lockdownFactory(options Options) (echo.MiddlewareFunc, error) {
+	var o struct {
+		MaxFailures        int      `json:"max_failures"`
+		CaptchaAfter       int      `json:"captcha_after"`
+		LockoutDuration    Duration `json:"lockout_duration"`
+		MaxLockoutDuration Duration `json:"max_lockout_duration"`
+		ResetAfter         Duration `json:"reset_after"`
+	}
+	if err := options.Decode(&o); err != nil {
+		return nil, err
+	}
+	return echolockdown.Config{
+		Store:              echolockdown.NewMemoryStore(),
+		MaxFailures:        o.MaxFailures,
+		CaptchaAfter:       o.CaptchaAfter,
+		LockoutDuration:    time.Duration(o.LockoutDuration),
+		MaxLockoutDuration: time.Duration(o.MaxLockoutDuration),
+		ResetAfter:         time.Duration(o.ResetAfter),
+	}.ToMiddleware()
+}
+
+func memBudgetFactory(options Options) (echo.MiddlewareFunc, error) {
+	var o struct {
+		SampleRate float64 `json:"sample_rate"`
+		MaxBytes   uint64  `json:"max_bytes"`
+		MaxObjects uint64  `json:"max_objects"`
+	}
+	if err := options.Decode(&o); err != nil {
+		return nil, err
+	}
+	return echomem.Config{
+		SampleRate: o.SampleRate,
+		MaxBytes:   o.MaxBytes,
+		MaxObjects: o.MaxObjects,
+	}.ToMiddleware()
+}
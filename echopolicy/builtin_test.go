@@ -0,0 +1,119 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echopolicy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuiltins(t *testing.T) {
+	var testCases = []struct {
+		name      string
+		whenRef   MiddlewareRef
+		expectErr string
+	}{
+		{name: "ok, request_id", whenRef: MiddlewareRef{Name: "request_id"}},
+		{name: "nok, request_id with options", whenRef: MiddlewareRef{Name: "request_id", Options: Options{"x": 1}}, expectErr: "middleware does not accept options"},
+		{name: "ok, secure", whenRef: MiddlewareRef{Name: "secure"}},
+		{name: "ok, gzip", whenRef: MiddlewareRef{Name: "gzip", Options: Options{"level": 5}}},
+		{name: "nok, gzip level", whenRef: MiddlewareRef{Name: "gzip", Options: Options{"level": 10}}, expectErr: "level must be between -2 and 9"},
+		{name: "ok, body_limit", whenRef: MiddlewareRef{Name: "body_limit", Options: Options{"limit": "2M"}}},
+		{name: "nok, body_limit missing", whenRef: MiddlewareRef{Name: "body_limit"}, expectErr: "limit is required"},
+		{name: "nok, body_limit invalid", whenRef: MiddlewareRef{Name: "body_limit", Options: Options{"limit": "lots"}}, expectErr: "invalid limit: error parsing value=lots"},
+		{name: "ok, timeout", whenRef: MiddlewareRef{Name: "timeout", Options: Options{"timeout": "5s"}}},
+		{name: "nok, timeout missing", whenRef: MiddlewareRef{Name: "timeout"}, expectErr: "timeout is required"},
+		{name: "ok, cors", whenRef: MiddlewareRef{Name: "cors", Options: Options{"allow_origins": []string{"https://example.com"}, "max_age": 60}}},
+		{name: "ok, rate_limit", whenRef: MiddlewareRef{Name: "rate_limit", Options: Options{"rate": 10, "burst": 20, "expires_in": "1m"}}},
+		{name: "nok, rate_limit missing rate", whenRef: MiddlewareRef{Name: "rate_limit"}, expectErr: "rate must be greater than 0"},
+		{name: "ok, cache_control", whenRef: MiddlewareRef{Name: "cache_control", Options: Options{"max_age": "1h"}}},
+		{name: "nok, cache_control public and private", whenRef: MiddlewareRef{Name: "cache_control", Options: Options{"public": true, "private": true}}, expectErr: "public and private are mutually exclusive"},
+		{name: "ok, lockdown", whenRef: MiddlewareRef{Name: "lockdown", Options: Options{"max_failures": 3, "lockout_duration": "30s"}}},
+		{name: "ok, mem_budget", whenRef: MiddlewareRef{Name: "mem_budget", Options: Options{"sample_rate": 0.5, "max_bytes": 1024}}},
+		{name: "nok, mem_budget sample rate", whenRef: MiddlewareRef{Name: "mem_budget", Options: Options{"sample_rate": 2}}, expectErr: "echomem: SampleRate must be between 0 and 1"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := NewRegistry().Build(Document{Groups: []Group{{Prefix: "/", Middlewares: []MiddlewareRef{tc.whenRef}}}})
+			if tc.expectErr != "" {
+				assert.ErrorContains(t, err, tc.expectErr)
+				assert.Nil(t, p)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotNil(t, p)
+		})
+	}
+}
+
+func TestCacheControl(t *testing.T) {
+	var testCases = []struct {
+		name         string
+		whenOptions  Options
+		expectHeader string
+	}{
+		{
+			name:         "max age",
+			whenOptions:  Options{"max_age": "1h", "public": true},
+			expectHeader: "max-age=3600, public",
+		},
+		{
+			name:         "no cache by default",
+			whenOptions:  nil,
+			expectHeader: "no-cache",
+		},
+		{
+			name:         "no store",
+			whenOptions:  Options{"no_store": true, "private": true},
+			expectHeader: "no-store, private",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mw, err := cacheControlFactory(tc.whenOptions)
+			assert.NoError(t, err)
+
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+			err = mw(func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			})(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectHeader, rec.Header().Get(echo.HeaderCacheControl))
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	policy, err := NewRegistry().BuildYAML([]byte(`
+groups:
+  - prefix: /api
+    middlewares:
+      - name: rate_limit
+        options:
+          rate: 1
+          burst: 1
+`))
+	assert.NoError(t, err)
+
+	e := echo.New()
+	e.Use(policy.Middleware())
+	e.GET("/api/users", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	codes := make([]int, 0, 2)
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/users", nil))
+		codes = append(codes, rec.Code)
+	}
+	assert.Equal(t, []int{http.StatusOK, http.StatusTooManyRequests}, codes)
+}
@@ -0,0 +1,308 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+/*
+Package echopolicy builds middleware chains for route groups from a declarative policy document (JSON or YAML).
+
+Document references middlewares by name with their options. Names are resolved with Registry which contains built-in
+factories (see `NewRegistry`) and application specific factories (for example authentication) registered with
+`Registry.Register`. All groups, names and options are validated when policy is built so configuration errors are
+reported at startup instead of at request time.
+
+Policy document:
+```
+
+	groups:
+	  - prefix: /api
+	    middlewares:
+	      - name: request_id
+	      - name: rate_limit
+	        options:
+	          rate: 10
+	          burst: 20
+	      - name: require_auth
+	  - prefix: /static
+	    middlewares:
+	      - name: cache_control
+	        options:
+	          max_age: 1h
+
+```
+
+Example:
+```
+package main
+
+import (
+
+	"os"
+
+	"github.com/labstack/echo-contrib/echopolicy"
+	"github.com/labstack/echo/v4"
+
+)
+
+	func main() {
+		registry := echopolicy.NewRegistry()
+		registry.Register("require_auth", func(o echopolicy.Options) (echo.MiddlewareFunc, error) {
+			return myAuthMiddleware(), nil
+		})
+
+		doc, err := os.ReadFile("policy.yaml")
+		if err != nil {
+			panic(err)
+		}
+		policy, err := registry.BuildYAML(doc)
+		if err != nil {
+			panic(err)
+		}
+
+		e := echo.New()
+		e.Use(policy.Middleware())
+
+		e.Logger.Fatal(e.Start(":1323"))
+	}
+
+```
+*/
+package echopolicy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"gopkg.in/yaml.v3"
+)
+
+// Document is declarative policy document.
+type Document struct {
+	// Groups are route groups with their middleware chains.
+	Groups []Group `json:"groups" yaml:"groups"`
+}
+
+// Group is middleware chain applied to requests with path matching Prefix.
+type Group struct {
+	// Name identifies group for `Policy.Group`. Defaults to Prefix.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Prefix is path prefix matched at path segment boundary (`/api` matches `/api` and `/api/users` but not `/apis`).
+	Prefix string `json:"prefix" yaml:"prefix"`
+	// Middlewares are middlewares applied in given order (first is outermost).
+	Middlewares []MiddlewareRef `json:"middlewares" yaml:"middlewares"`
+}
+
+// MiddlewareRef references registered middleware factory by name with its options.
+type MiddlewareRef struct {
+	// Name is name of the factory in Registry.
+	Name string `json:"name" yaml:"name"`
+	// Options are passed to the factory.
+	Options Options `json:"options,omitempty" yaml:"options,omitempty"`
+}
+
+// Options are middleware options from policy document.
+type Options map[string]any
+
+// Decode decodes options into struct v (using its `json` tags). Unknown options result in an error.
+func (o Options) Decode(v any) error {
+	b, err := json.Marshal(o)
+	if err != nil {
+		return err
+	}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.DisallowUnknownFields()
+	return d.Decode(v)
+}
+
+// Duration is time.Duration decodable from options as string (`1m30s`) or as number of seconds.
+type Duration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	switch value := v.(type) {
+	case float64:
+		*d = Duration(value * float64(time.Second))
+	case string:
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*d = Duration(parsed)
+	default:
+		return fmt.Errorf("invalid duration: %s", b)
+	}
+	return nil
+}
+
+// Factory creates middleware from options or returns an error when options are invalid.
+type Factory func(options Options) (echo.MiddlewareFunc, error)
+
+// Registry maps middleware names to factories.
+type Registry struct {
+	mu        sync.RWMutex
+	factories map[string]Factory
+}
+
+// NewRegistry creates registry with built-in factories registered. See `RegisterBuiltins`.
+func NewRegistry() *Registry {
+	r := &Registry{factories: map[string]Factory{}}
+	RegisterBuiltins(r)
+	return r
+}
+
+// Register adds factory with given name. Existing factory with the same name is replaced.
+func (r *Registry) Register(name string, factory Factory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.factories == nil {
+		r.factories = map[string]Factory{}
+	}
+	r.factories[name] = factory
+}
+
+// Names returns sorted names of registered factories.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.factories))
+	for n := range r.factories {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BuildJSON parses JSON policy document and builds policy from it.
+func (r *Registry) BuildJSON(data []byte) (*Policy, error) {
+	var doc Document
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.DisallowUnknownFields()
+	if err := d.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("echopolicy: invalid JSON document: %w", err)
+	}
+	return r.Build(doc)
+}
+
+// BuildYAML parses YAML policy document and builds policy from it.
+func (r *Registry) BuildYAML(data []byte) (*Policy, error) {
+	var doc Document
+	d := yaml.NewDecoder(bytes.NewReader(data))
+	d.KnownFields(true)
+	if err := d.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("echopolicy: invalid YAML document: %w", err)
+	}
+	return r.Build(doc)
+}
+
+// Build validates document and creates middlewares for all groups. All validation errors are returned joined.
+func (r *Registry) Build(doc Document) (*Policy, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p := &Policy{byName: map[string]*group{}}
+	var errs []error
+	for i, g := range doc.Groups {
+		name := g.Name
+		if name == "" {
+			name = g.Prefix
+		}
+		if !strings.HasPrefix(g.Prefix, "/") {
+			errs = append(errs, fmt.Errorf("echopolicy: group %d: prefix must start with /", i))
+			continue
+		}
+		if _, ok := p.byName[name]; ok {
+			errs = append(errs, fmt.Errorf("echopolicy: group %d: duplicate group name %q", i, name))
+			continue
+		}
+
+		built := &group{prefix: strings.TrimSuffix(g.Prefix, "/")}
+		for j, ref := range g.Middlewares {
+			factory, ok := r.factories[ref.Name]
+			if !ok {
+				errs = append(errs, fmt.Errorf("echopolicy: group %q middleware %d: unknown middleware %q", name, j, ref.Name))
+				continue
+			}
+			mw, err := factory(ref.Options)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("echopolicy: group %q middleware %q: %w", name, ref.Name, err))
+				continue
+			}
+			built.middlewares = append(built.middlewares, mw)
+		}
+		p.byName[name] = built
+		p.groups = append(p.groups, built)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	// longest prefix is matched first
+	sort.SliceStable(p.groups, func(i, j int) bool {
+		return len(p.groups[i].prefix) > len(p.groups[j].prefix)
+	})
+	return p, nil
+}
+
+// Policy is built and validated policy document.
+type Policy struct {
+	groups []*group
+	byName map[string]*group
+}
+
+type group struct {
+	prefix      string
+	middlewares []echo.MiddlewareFunc
+}
+
+func (g *group) matches(path string) bool {
+	if g.prefix == "" {
+		return true
+	}
+	return strings.HasPrefix(path, g.prefix) && (len(path) == len(g.prefix) || path[len(g.prefix)] == '/')
+}
+
+// Middleware returns middleware that applies chain of the group with the longest prefix matching request path.
+// Requests not matching any group are passed through.
+func (p *Policy) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		chains := make([]echo.HandlerFunc, len(p.groups))
+		for i, g := range p.groups {
+			chains[i] = applyMiddlewares(next, g.middlewares)
+		}
+		return func(c echo.Context) error {
+			path := c.Request().URL.Path
+			for i, g := range p.groups {
+				if g.matches(path) {
+					return chains[i](c)
+				}
+			}
+			return next(c)
+		}
+	}
+}
+
+// Group returns middlewares of the named group in order, for example to be passed to `e.Group(prefix, middlewares...)`
+// instead of using `Policy.Middleware`.
+func (p *Policy) Group(name string) ([]echo.MiddlewareFunc, bool) {
+	g, ok := p.byName[name]
+	if !ok {
+		return nil, false
+	}
+	return g.middlewares, true
+}
+
+func applyMiddlewares(h echo.HandlerFunc, middlewares []echo.MiddlewareFunc) echo.HandlerFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
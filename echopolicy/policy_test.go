@@ -0,0 +1,218 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echopolicy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func headerFactory(options Options) (echo.MiddlewareFunc, error) {
+	var o struct {
+		Value string `json:"value"`
+	}
+	if err := options.Decode(&o); err != nil {
+		return nil, err
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Response().Header().Add("X-Policy", o.Value)
+			return next(c)
+		}
+	}, nil
+}
+
+func newTestRegistry() *Registry {
+	r := &Registry{}
+	r.Register("header", headerFactory)
+	return r
+}
+
+func TestPolicy_Middleware(t *testing.T) {
+	policy, err := newTestRegistry().BuildYAML([]byte(`
+groups:
+  - prefix: /api
+    middlewares:
+      - name: header
+        options:
+          value: api
+  - prefix: /api/admin/
+    middlewares:
+      - name: header
+        options:
+          value: admin-1
+      - name: header
+        options:
+          value: admin-2
+`))
+	assert.NoError(t, err)
+
+	var testCases = []struct {
+		name         string
+		whenPath     string
+		expectHeader []string
+	}{
+		{
+			name:         "ok, exact prefix",
+			whenPath:     "/api",
+			expectHeader: []string{"api"},
+		},
+		{
+			name:         "ok, nested path",
+			whenPath:     "/api/users",
+			expectHeader: []string{"api"},
+		},
+		{
+			name:         "ok, longest prefix wins and order is kept",
+			whenPath:     "/api/admin/users",
+			expectHeader: []string{"admin-1", "admin-2"},
+		},
+		{
+			name:         "ok, prefix matches only at segment boundary",
+			whenPath:     "/apis",
+			expectHeader: nil,
+		},
+		{
+			name:         "ok, no group",
+			whenPath:     "/",
+			expectHeader: nil,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(policy.Middleware())
+			e.Any("/*", func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			})
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.whenPath, nil))
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tc.expectHeader, rec.Header().Values("X-Policy"))
+		})
+	}
+}
+
+func TestPolicy_Group(t *testing.T) {
+	policy, err := newTestRegistry().BuildJSON([]byte(`{"groups":[
+		{"name":"api","prefix":"/api","middlewares":[{"name":"header","options":{"value":"x"}}]}
+	]}`))
+	assert.NoError(t, err)
+
+	mws, ok := policy.Group("api")
+	assert.True(t, ok)
+	assert.Len(t, mws, 1)
+
+	e := echo.New()
+	e.Group("/v1", mws...).GET("/users", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/users", nil))
+	assert.Equal(t, "x", rec.Header().Get("X-Policy"))
+
+	_, ok = policy.Group("missing")
+	assert.False(t, ok)
+}
+
+func TestRegistry_Build(t *testing.T) {
+	var testCases = []struct {
+		name      string
+		whenJSON  string
+		expectErr string
+	}{
+		{
+			name:     "ok",
+			whenJSON: `{"groups":[{"prefix":"/","middlewares":[{"name":"header"}]}]}`,
+		},
+		{
+			name:      "nok, unknown document field",
+			whenJSON:  `{"groupz":[]}`,
+			expectErr: `echopolicy: invalid JSON document: json: unknown field "groupz"`,
+		},
+		{
+			name: "nok, all errors are reported",
+			whenJSON: `{"groups":[
+				{"prefix":"api"},
+				{"prefix":"/a","middlewares":[{"name":"nope"},{"name":"header","options":{"valeu":"x"}}]},
+				{"prefix":"/a"}
+			]}`,
+			expectErr: "echopolicy: group 0: prefix must start with /\n" +
+				"echopolicy: group \"/a\" middleware 0: unknown middleware \"nope\"\n" +
+				"echopolicy: group \"/a\" middleware \"header\": json: unknown field \"valeu\"\n" +
+				"echopolicy: group 2: duplicate group name \"/a\"",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := newTestRegistry().BuildJSON([]byte(tc.whenJSON))
+			if tc.expectErr != "" {
+				assert.EqualError(t, err, tc.expectErr)
+				assert.Nil(t, p)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotNil(t, p)
+		})
+	}
+}
+
+func TestRegistry_BuildYAML_unknownField(t *testing.T) {
+	_, err := newTestRegistry().BuildYAML([]byte("groups:\n  - prefx: /api\n"))
+	assert.ErrorContains(t, err, "echopolicy: invalid YAML document")
+}
+
+func TestRegistry_Names(t *testing.T) {
+	assert.Equal(t, []string{"header"}, newTestRegistry().Names())
+	assert.Contains(t, NewRegistry().Names(), "rate_limit")
+}
+
+func TestDuration_UnmarshalJSON(t *testing.T) {
+	var testCases = []struct {
+		name      string
+		whenJSON  string
+		expect    Duration
+		expectErr string
+	}{
+		{
+			name:     "ok, string",
+			whenJSON: `"1m30s"`,
+			expect:   Duration(90 * time.Second),
+		},
+		{
+			name:     "ok, seconds",
+			whenJSON: `2.5`,
+			expect:   Duration(2500 * time.Millisecond),
+		},
+		{
+			name:      "nok, invalid string",
+			whenJSON:  `"soon"`,
+			expectErr: `time: invalid duration "soon"`,
+		},
+		{
+			name:      "nok, invalid type",
+			whenJSON:  `true`,
+			expectErr: `invalid duration: true`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var d Duration
+			err := d.UnmarshalJSON([]byte(tc.whenJSON))
+			if tc.expectErr != "" {
+				assert.EqualError(t, err, tc.expectErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expect, d)
+		})
+	}
+}
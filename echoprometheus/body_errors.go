@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echoprometheus
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+)
+
+const reasonLabel = "reason"
+
+// Reasons used as `reason` label value of request body read and response write error counters.
+const (
+	ReasonClientAborted   = "client_aborted"
+	ReasonTimeout         = "timeout"
+	ReasonBrokenPipe      = "broken_pipe"
+	ReasonConnectionReset = "connection_reset"
+	ReasonUnexpectedEOF   = "unexpected_eof"
+	ReasonTooLarge        = "too_large"
+	ReasonOther           = "other"
+)
+
+// ErrorReason classifies request body read or response write error to `reason` label value.
+func ErrorReason(err error) string {
+	var maxBytesErr *http.MaxBytesError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ReasonClientAborted
+	case errors.Is(err, syscall.EPIPE):
+		return ReasonBrokenPipe
+	case errors.Is(err, syscall.ECONNRESET):
+		return ReasonConnectionReset
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded):
+		return ReasonTimeout
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return ReasonTimeout
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return ReasonUnexpectedEOF
+	case errors.As(err, &maxBytesErr):
+		return ReasonTooLarge
+	}
+	return ReasonOther
+}
+
+// errorTrackingBody wraps request body and remembers the first read error other than io.EOF.
+type errorTrackingBody struct {
+	io.ReadCloser
+	err error
+}
+
+func (b *errorTrackingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF && b.err == nil {
+		b.err = err
+	}
+	return n, err
+}
+
+// errorTrackingWriter wraps http.ResponseWriter and remembers the first write or flush error.
+type errorTrackingWriter struct {
+	http.ResponseWriter
+	err error
+}
+
+func (w *errorTrackingWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	if err != nil && w.err == nil {
+		w.err = err
+	}
+	return n, err
+}
+
+func (w *errorTrackingWriter) FlushError() error {
+	err := http.NewResponseController(w.ResponseWriter).Flush()
+	if err != nil && !errors.Is(err, http.ErrNotSupported) && w.err == nil {
+		w.err = err
+	}
+	return err
+}
+
+func (w *errorTrackingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
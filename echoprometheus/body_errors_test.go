@@ -0,0 +1,110 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echoprometheus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"syscall"
+	"testing"
+	"testing/iotest"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+type failingResponseWriter struct {
+	http.ResponseWriter
+	err error
+}
+
+func (w *failingResponseWriter) Write(b []byte) (int, error) {
+	return 0, w.err
+}
+
+func TestMiddlewareConfig_EnableBodyErrorMetrics(t *testing.T) {
+	e := echo.New()
+	customRegistry := prometheus.NewRegistry()
+	e.Use(NewMiddlewareWithConfig(MiddlewareConfig{
+		EnableBodyErrorMetrics: true,
+		Registerer:             customRegistry,
+	}))
+	e.POST("/upload", func(c echo.Context) error {
+		if _, err := io.ReadAll(c.Request().Body); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest).SetInternal(err)
+		}
+		return c.NoContent(http.StatusNoContent)
+	})
+	e.GET("/download", func(c echo.Context) error {
+		return c.String(http.StatusOK, "data")
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", io.NopCloser(iotest.ErrReader(syscall.ECONNRESET)))
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	req = httptest.NewRequest(http.MethodPost, "/upload", bytes.NewReader([]byte("ok")))
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	rec := &failingResponseWriter{ResponseWriter: httptest.NewRecorder(), err: fmt.Errorf("write tcp: %w", syscall.EPIPE)}
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/download", nil))
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, WriteGatheredMetrics(out, customRegistry))
+	body := out.String()
+
+	assert.Contains(t, body, `echo_request_body_read_errors_total{code="400",host="example.com",method="POST",reason="connection_reset",url="/upload"} 1`)
+	assert.Contains(t, body, `echo_response_write_errors_total{code="500",host="example.com",method="GET",reason="broken_pipe",url="/download"} 1`)
+	assert.Contains(t, body, `echo_requests_total{code="204",host="example.com",method="POST",url="/upload"} 1`)
+}
+
+func TestMiddlewareConfig_EnableBodyErrorMetrics_restoresWrappers(t *testing.T) {
+	e := echo.New()
+	e.Use(NewMiddlewareWithConfig(MiddlewareConfig{
+		EnableBodyErrorMetrics: true,
+		Registerer:             prometheus.NewRegistry(),
+	}))
+	e.POST("/", func(c echo.Context) error {
+		_, isBodyWrapped := c.Request().Body.(*errorTrackingBody)
+		_, isWriterWrapped := c.Response().Writer.(*errorTrackingWriter)
+		assert.True(t, isBodyWrapped)
+		assert.True(t, isWriterWrapped)
+		return c.NoContent(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte("x")))
+	originalBody := req.Body
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, originalBody, req.Body)
+}
+
+func TestErrorReason(t *testing.T) {
+	var testCases = []struct {
+		name   string
+		when   error
+		expect string
+	}{
+		{name: "client aborted", when: context.Canceled, expect: ReasonClientAborted},
+		{name: "broken pipe", when: fmt.Errorf("write: %w", syscall.EPIPE), expect: ReasonBrokenPipe},
+		{name: "connection reset", when: syscall.ECONNRESET, expect: ReasonConnectionReset},
+		{name: "deadline exceeded", when: os.ErrDeadlineExceeded, expect: ReasonTimeout},
+		{name: "context deadline exceeded", when: context.DeadlineExceeded, expect: ReasonTimeout},
+		{name: "unexpected eof", when: io.ErrUnexpectedEOF, expect: ReasonUnexpectedEOF},
+		{name: "too large", when: &http.MaxBytesError{Limit: 10}, expect: ReasonTooLarge},
+		{name: "other", when: errors.New("boom"), expect: ReasonOther},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, ErrorReason(tc.when))
+		})
+	}
+}
@@ -0,0 +1,76 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echoprometheus
+
+import (
+	"net"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// OtherHost is `host` label value used by CanonicalHosts for hosts not in the canonical set.
+const OtherHost = "other"
+
+// HostNormalizer transforms `host` label value.
+type HostNormalizer func(host string) string
+
+// NormalizeHost creates function usable as MiddlewareConfig.HostLabelFunc that applies given normalizers in order.
+//
+// Example:
+//
+//	e.Use(echoprometheus.NewMiddlewareWithConfig(echoprometheus.MiddlewareConfig{
+//		HostLabelFunc: echoprometheus.NormalizeHost(
+//			echoprometheus.StripPort,
+//			echoprometheus.LowercaseHost,
+//			echoprometheus.CanonicalHosts("api.example.com", "*.example.com"),
+//		),
+//	}))
+func NormalizeHost(normalizers ...HostNormalizer) func(c echo.Context, host string) string {
+	return func(c echo.Context, host string) string {
+		for _, n := range normalizers {
+			host = n(host)
+		}
+		return host
+	}
+}
+
+// StripPort removes port from host (`example.com:8080` becomes `example.com`, `[::1]:8080` becomes `::1`).
+func StripPort(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
+
+// LowercaseHost converts host to lower case.
+func LowercaseHost(host string) string {
+	return strings.ToLower(host)
+}
+
+// CanonicalHosts creates normalizer that keeps only hosts from the given canonical set and replaces all other hosts
+// (direct IP access, per-pod hostnames etc.) with OtherHost. Entries starting with `*.` match any subdomain and host
+// is replaced with the entry itself. Matching is exact so hosts should be stripped of port and lower cased first.
+func CanonicalHosts(hosts ...string) HostNormalizer {
+	exact := make(map[string]struct{}, len(hosts))
+	var wildcards []string
+	for _, h := range hosts {
+		if strings.HasPrefix(h, "*.") {
+			wildcards = append(wildcards, h)
+			continue
+		}
+		exact[h] = struct{}{}
+	}
+	return func(host string) string {
+		if _, ok := exact[host]; ok {
+			return host
+		}
+		for _, w := range wildcards {
+			if strings.HasSuffix(host, w[1:]) {
+				return w
+			}
+		}
+		return OtherHost
+	}
+}
@@ -0,0 +1,103 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echoprometheus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHostNormalizers(t *testing.T) {
+	var testCases = []struct {
+		name       string
+		normalizer HostNormalizer
+		whenHost   string
+		expect     string
+	}{
+		{
+			name:       "ok, strip port",
+			normalizer: StripPort,
+			whenHost:   "example.com:8080",
+			expect:     "example.com",
+		},
+		{
+			name:       "ok, strip port from ipv6",
+			normalizer: StripPort,
+			whenHost:   "[::1]:8080",
+			expect:     "::1",
+		},
+		{
+			name:       "ok, strip port when missing",
+			normalizer: StripPort,
+			whenHost:   "example.com",
+			expect:     "example.com",
+		},
+		{
+			name:       "ok, lowercase",
+			normalizer: LowercaseHost,
+			whenHost:   "API.Example.COM",
+			expect:     "api.example.com",
+		},
+		{
+			name:       "ok, canonical host",
+			normalizer: CanonicalHosts("api.example.com", "*.example.org"),
+			whenHost:   "api.example.com",
+			expect:     "api.example.com",
+		},
+		{
+			name:       "ok, canonical wildcard host",
+			normalizer: CanonicalHosts("api.example.com", "*.example.org"),
+			whenHost:   "pod-1.example.org",
+			expect:     "*.example.org",
+		},
+		{
+			name:       "ok, not canonical host",
+			normalizer: CanonicalHosts("api.example.com", "*.example.org"),
+			whenHost:   "10.0.0.1",
+			expect:     OtherHost,
+		},
+		{
+			name:       "ok, wildcard does not match parent domain",
+			normalizer: CanonicalHosts("*.example.org"),
+			whenHost:   "example.org",
+			expect:     OtherHost,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, tc.normalizer(tc.whenHost))
+		})
+	}
+}
+
+func TestMiddlewareConfig_HostLabelFunc(t *testing.T) {
+	e := echo.New()
+
+	customRegistry := prometheus.NewRegistry()
+	e.Use(NewMiddlewareWithConfig(MiddlewareConfig{
+		Registerer:    customRegistry,
+		HostLabelFunc: NormalizeHost(StripPort, LowercaseHost, CanonicalHosts("example.com")),
+	}))
+	e.GET("/ok", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+	e.GET("/metrics", NewHandlerWithConfig(HandlerConfig{Gatherer: customRegistry}))
+
+	for _, host := range []string{"Example.com:8080", "example.com", "10.0.0.1:80", "pod-1"} {
+		req := httptest.NewRequest(http.MethodGet, "/ok", nil)
+		req.Host = host
+		e.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	body, code := requestBody(e, "/metrics")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `echo_requests_total{code="200",host="example.com",method="GET",url="/ok"} 2`)
+	assert.Contains(t, body, `echo_requests_total{code="200",host="other",method="GET",url="/ok"} 2`)
+}
@@ -0,0 +1,119 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echoprometheus
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// MaxLabelValueLength is maximum length (in bytes) of label values returned by label helpers. Longer values are
+	// truncated.
+	MaxLabelValueLength = 128
+
+	// UnknownTenant is `tenant` label value used by TenantLabelFunc for requests without tenant header.
+	UnknownTenant = "unknown"
+	// OtherTenant is `tenant` label value used by TenantLabelFunc for tenants not in the known set.
+	OtherTenant = "other"
+)
+
+// SanitizeLabelValue replaces invalid UTF-8 sequences with U+FFFD replacement character and truncates value to
+// maxLength bytes without splitting multibyte characters. Value is not truncated when maxLength <= 0.
+func SanitizeLabelValue(value string, maxLength int) string {
+	value = strings.ToValidUTF8(value, string(utf8.RuneError))
+	if maxLength <= 0 || len(value) <= maxLength {
+		return value
+	}
+	cut := maxLength
+	for cut > 0 && !utf8.RuneStart(value[cut]) {
+		cut--
+	}
+	return value[:cut]
+}
+
+// LabelFromHeader creates LabelValueFunc returning value of the request header or defaultValue when header is missing
+// or empty. Value is sanitized with SanitizeLabelValue and MaxLabelValueLength.
+//
+// Example:
+//
+//	e.Use(echoprometheus.NewMiddlewareWithConfig(echoprometheus.MiddlewareConfig{
+//		LabelFuncs: map[string]echoprometheus.LabelValueFunc{
+//			"client": echoprometheus.LabelFromHeader("X-Client-Name", "unknown"),
+//		},
+//	}))
+//
+// Note: header values are controlled by clients so use only headers with bounded set of values or normalize them
+// (see TenantLabelFunc) to keep metrics cardinality low.
+func LabelFromHeader(header string, defaultValue string) LabelValueFunc {
+	return func(c echo.Context, err error) string {
+		return valueOrDefault(c.Request().Header.Get(header), defaultValue)
+	}
+}
+
+// LabelFromResponseHeader creates LabelValueFunc returning value of the response header set by handler (i.e. cache
+// status) or defaultValue when header is missing or empty. Value is sanitized with SanitizeLabelValue and
+// MaxLabelValueLength.
+func LabelFromResponseHeader(header string, defaultValue string) LabelValueFunc {
+	return func(c echo.Context, err error) string {
+		return valueOrDefault(c.Response().Header().Get(header), defaultValue)
+	}
+}
+
+// LabelFromContextValue creates LabelValueFunc returning value stored in echo.Context under key (`c.Set(key, value)`)
+// or empty string when value is not set. Values that are not strings are formatted with fmt.Sprint. Value is
+// sanitized with SanitizeLabelValue and MaxLabelValueLength.
+func LabelFromContextValue(key string) LabelValueFunc {
+	return func(c echo.Context, err error) string {
+		switch v := c.Get(key).(type) {
+		case nil:
+			return ""
+		case string:
+			return SanitizeLabelValue(v, MaxLabelValueLength)
+		default:
+			return SanitizeLabelValue(fmt.Sprint(v), MaxLabelValueLength)
+		}
+	}
+}
+
+// TenantLabelFunc creates LabelValueFunc returning tenant ID from the request header. Requests without the header are
+// labeled with UnknownTenant. When knownTenants are given, tenants not in the set are labeled with OtherTenant so
+// clients can not create new series by sending arbitrary tenant IDs.
+//
+// Example:
+//
+//	e.Use(echoprometheus.NewMiddlewareWithConfig(echoprometheus.MiddlewareConfig{
+//		LabelFuncs: map[string]echoprometheus.LabelValueFunc{
+//			"tenant": echoprometheus.TenantLabelFunc("X-Tenant-ID", "acme", "globex"),
+//		},
+//	}))
+func TenantLabelFunc(header string, knownTenants ...string) LabelValueFunc {
+	known := make(map[string]struct{}, len(knownTenants))
+	for _, t := range knownTenants {
+		known[t] = struct{}{}
+	}
+	return func(c echo.Context, err error) string {
+		tenant := c.Request().Header.Get(header)
+		if tenant == "" {
+			return UnknownTenant
+		}
+		if len(known) == 0 {
+			return SanitizeLabelValue(tenant, MaxLabelValueLength)
+		}
+		if _, ok := known[tenant]; ok {
+			return tenant
+		}
+		return OtherTenant
+	}
+}
+
+func valueOrDefault(value string, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return SanitizeLabelValue(value, MaxLabelValueLength)
+}
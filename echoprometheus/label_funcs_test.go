@@ -0,0 +1,110 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echoprometheus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeLabelValue(t *testing.T) {
+	var testCases = []struct {
+		name          string
+		whenValue     string
+		whenMaxLength int
+		expect        string
+	}{
+		{
+			name:          "ok, valid value",
+			whenValue:     "acme",
+			whenMaxLength: 10,
+			expect:        "acme",
+		},
+		{
+			name:          "ok, invalid utf-8 replaced",
+			whenValue:     "ac\xffme",
+			whenMaxLength: 0,
+			expect:        "ac�me",
+		},
+		{
+			name:          "ok, truncated",
+			whenValue:     "abcdefgh",
+			whenMaxLength: 4,
+			expect:        "abcd",
+		},
+		{
+			name:          "ok, truncated at rune boundary",
+			whenValue:     "abcé",
+			whenMaxLength: 4,
+			expect:        "abc",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, SanitizeLabelValue(tc.whenValue, tc.whenMaxLength))
+		})
+	}
+}
+
+func TestLabelFuncs(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Client-Name", "mobile")
+	req.Header.Set("X-Tenant-ID", "initech")
+	req.Header.Set("X-Long", strings.Repeat("x", MaxLabelValueLength+10))
+	rec := httptest.NewRecorder()
+	rec.Header().Set("X-Cache", "HIT")
+	c := e.NewContext(req, rec)
+	c.Set("plan", "pro")
+	c.Set("shard", 3)
+
+	assert.Equal(t, "mobile", LabelFromHeader("X-Client-Name", "unknown")(c, nil))
+	assert.Equal(t, "unknown", LabelFromHeader("X-Missing", "unknown")(c, nil))
+	assert.Len(t, LabelFromHeader("X-Long", "")(c, nil), MaxLabelValueLength)
+	assert.Equal(t, "HIT", LabelFromResponseHeader("X-Cache", "none")(c, nil))
+	assert.Equal(t, "none", LabelFromResponseHeader("X-Missing", "none")(c, nil))
+	assert.Equal(t, "pro", LabelFromContextValue("plan")(c, nil))
+	assert.Equal(t, "3", LabelFromContextValue("shard")(c, nil))
+	assert.Equal(t, "", LabelFromContextValue("missing")(c, nil))
+
+	assert.Equal(t, "initech", TenantLabelFunc("X-Tenant-ID")(c, nil))
+	assert.Equal(t, OtherTenant, TenantLabelFunc("X-Tenant-ID", "acme")(c, nil))
+	assert.Equal(t, "initech", TenantLabelFunc("X-Tenant-ID", "acme", "initech")(c, nil))
+	assert.Equal(t, UnknownTenant, TenantLabelFunc("X-Missing", "acme")(c, nil))
+}
+
+func TestMiddlewareConfig_TenantLabelFunc(t *testing.T) {
+	e := echo.New()
+
+	customRegistry := prometheus.NewRegistry()
+	e.Use(NewMiddlewareWithConfig(MiddlewareConfig{
+		Registerer: customRegistry,
+		LabelFuncs: map[string]LabelValueFunc{
+			"tenant": TenantLabelFunc("X-Tenant-ID", "acme"),
+		},
+	}))
+	e.GET("/ok", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+	e.GET("/metrics", NewHandlerWithConfig(HandlerConfig{Gatherer: customRegistry}))
+
+	for _, tenant := range []string{"acme", "globex", ""} {
+		req := httptest.NewRequest(http.MethodGet, "/ok", nil)
+		req.Header.Set("X-Tenant-ID", tenant)
+		e.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	body, code := requestBody(e, "/metrics")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `echo_requests_total{code="200",host="example.com",method="GET",tenant="acme",url="/ok"} 1`)
+	assert.Contains(t, body, `echo_requests_total{code="200",host="example.com",method="GET",tenant="other",url="/ok"} 1`)
+	assert.Contains(t, body, `echo_requests_total{code="200",host="example.com",method="GET",tenant="unknown",url="/ok"} 1`)
+}
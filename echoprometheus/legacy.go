@@ -0,0 +1,61 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echoprometheus
+
+import (
+	"fmt"
+
+	legacy "github.com/labstack/echo-contrib/prometheus"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// NewMiddlewareFromLegacyMetrics creates new instance of middleware using Prometheus default registry and additionally
+// registers custom metrics defined for the deprecated `prometheus.NewPrometheus`. This helps to migrate from old
+// middleware without rewriting custom metric definitions. Collectors are created the same way as old middleware did
+// and are assigned to `Metric.MetricCollector` field so existing code using these collectors keeps working.
+//
+// Example:
+//
+//	customMetrics := []*prometheus.Metric{{ID: "jobs", Name: "jobs_total", Description: "Jobs processed", Type: "counter_vec", Args: []string{"kind"}}}
+//	e.Use(echoprometheus.NewMiddlewareFromLegacyMetrics("myapp", customMetrics))
+//	e.GET("/metrics", echoprometheus.NewHandler())
+//	// ...
+//	customMetrics[0].MetricCollector.(*prom.CounterVec).WithLabelValues("email").Inc()
+func NewMiddlewareFromLegacyMetrics(subsystem string, metrics []*legacy.Metric) echo.MiddlewareFunc {
+	config := MiddlewareConfig{Subsystem: subsystem}
+	if err := RegisterLegacyMetrics(prometheus.DefaultRegisterer, config.Subsystem, metrics); err != nil {
+		panic(err)
+	}
+	return NewMiddlewareWithConfig(config)
+}
+
+// RegisterLegacyMetrics creates collectors for metric definitions of the deprecated `prometheus.NewPrometheus` and
+// registers them with given registerer. Created collectors are assigned to `Metric.MetricCollector` field. When any of
+// the collectors can not be registered, already registered collectors are unregistered and no field is assigned.
+func RegisterLegacyMetrics(reg prometheus.Registerer, subsystem string, metrics []*legacy.Metric) error {
+	if subsystem == "" {
+		subsystem = defaultSubsystem
+	}
+	collectors := make([]prometheus.Collector, 0, len(metrics))
+	for _, m := range metrics {
+		collector := legacy.NewMetric(m, subsystem)
+		if collector == nil {
+			return fmt.Errorf("echoprometheus: unknown legacy metric type %q for metric %q", m.Type, m.Name)
+		}
+		collectors = append(collectors, collector)
+	}
+	for i, collector := range collectors {
+		if err := reg.Register(collector); err != nil {
+			for _, registered := range collectors[:i] {
+				reg.Unregister(registered)
+			}
+			return fmt.Errorf("echoprometheus: failed to register legacy metric %q: %w", metrics[i].Name, err)
+		}
+	}
+	for i, m := range metrics {
+		m.MetricCollector = collectors[i]
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echoprometheus
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	legacy "github.com/labstack/echo-contrib/prometheus"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterLegacyMetrics(t *testing.T) {
+	customRegistry := prometheus.NewRegistry()
+	metrics := []*legacy.Metric{
+		{ID: "jobs", Name: "jobs_total", Description: "Jobs processed", Type: "counter_vec", Args: []string{"kind"}},
+		{ID: "queue", Name: "queue_size", Description: "Queue size", Type: "gauge"},
+	}
+
+	err := RegisterLegacyMetrics(customRegistry, "myapp", metrics)
+	assert.NoError(t, err)
+
+	metrics[0].MetricCollector.(*prometheus.CounterVec).WithLabelValues("email").Inc()
+	metrics[1].MetricCollector.(prometheus.Gauge).Set(5)
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, WriteGatheredMetrics(out, customRegistry))
+	assert.Contains(t, out.String(), `myapp_jobs_total{kind="email"} 1`)
+	assert.Contains(t, out.String(), `myapp_queue_size 5`)
+}
+
+func TestRegisterLegacyMetrics_unknownType(t *testing.T) {
+	err := RegisterLegacyMetrics(prometheus.NewRegistry(), "", []*legacy.Metric{{Name: "x", Type: "unknown"}})
+	assert.EqualError(t, err, `echoprometheus: unknown legacy metric type "unknown" for metric "x"`)
+}
+
+func TestRegisterLegacyMetrics_unregistersOnFailure(t *testing.T) {
+	customRegistry := prometheus.NewRegistry()
+	metrics := []*legacy.Metric{
+		{ID: "jobs", Name: "jobs_total", Description: "Jobs processed", Type: "counter"},
+		{ID: "jobs2", Name: "jobs_total", Description: "Jobs processed", Type: "counter"},
+	}
+
+	err := RegisterLegacyMetrics(customRegistry, "myapp", metrics)
+	assert.ErrorContains(t, err, `echoprometheus: failed to register legacy metric "jobs_total"`)
+	assert.Nil(t, metrics[0].MetricCollector)
+
+	// registering again after fixing definitions succeeds as nothing was left registered
+	err = RegisterLegacyMetrics(customRegistry, "myapp", metrics[:1])
+	assert.NoError(t, err)
+}
+
+func TestNewMiddlewareFromLegacyMetrics(t *testing.T) {
+	e := echo.New()
+
+	metrics := []*legacy.Metric{
+		{ID: "jobs", Name: "legacy_jobs_total", Description: "Jobs processed", Type: "counter"},
+	}
+	e.Use(NewMiddlewareFromLegacyMetrics("legacy", metrics))
+	e.GET("/metrics", NewHandler())
+	e.GET("/ok", func(c echo.Context) error {
+		metrics[0].MetricCollector.(prometheus.Counter).Inc()
+		return c.String(http.StatusOK, "OK")
+	})
+
+	assert.Equal(t, http.StatusOK, request(e, "/ok"))
+
+	body, code := requestBody(e, "/metrics")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `legacy_legacy_jobs_total 1`)
+	assert.Contains(t, body, `legacy_requests_total{code="200",host="example.com",method="GET",url="/ok"} 1`)
+
+	prometheus.DefaultRegisterer.Unregister(metrics[0].MetricCollector)
+	unregisterDefaults("legacy")
+}
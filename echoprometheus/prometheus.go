@@ -15,18 +15,21 @@ import (
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/labstack/gommon/log"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/prometheus/common/expfmt"
 	"io"
 	"net/http"
 	"sort"
-	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
 const (
 	defaultSubsystem = "echo"
+
+	routeNameLabel = "route_name"
 )
 
 const (
@@ -38,6 +41,23 @@ const (
 // sizeBuckets is the buckets for request/response size. Here we define a spectrum from 1KB through 1NB up to 10MB.
 var sizeBuckets = []float64{1.0 * bKB, 2.0 * bKB, 5.0 * bKB, 10.0 * bKB, 100 * bKB, 500 * bKB, 1.0 * bMB, 2.5 * bMB, 5.0 * bMB, 10.0 * bMB}
 
+// DefaultMethodAllowList contains HTTP methods commonly used by APIs. Can be used as MiddlewareConfig.MethodLabelAllowList.
+var DefaultMethodAllowList = []string{
+	http.MethodGet,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodHead,
+	http.MethodOptions,
+}
+
+// otherMethodLabel is `method` label value for methods not in MiddlewareConfig.MethodLabelAllowList.
+const otherMethodLabel = "OTHER"
+
+// defaultSummaryObjectives are quantile objectives for latency summary: median, 90th and 99th percentile.
+var defaultSummaryObjectives = map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001}
+
 // MiddlewareConfig contains the configuration for creating prometheus middleware collecting several default metrics.
 type MiddlewareConfig struct {
 	// Skipper defines a function to skip middleware.
@@ -55,9 +75,58 @@ type MiddlewareConfig struct {
 	// it replaces default one.
 	LabelFuncs map[string]LabelValueFunc
 
-	// HistogramOptsFunc allows to change options for metrics of type histogram before metric is registered to Registerer
+	// DurationBuckets sets buckets for `request_duration_seconds` histogram.
+	// Defaults to: prometheus.DefBuckets
+	DurationBuckets []float64
+
+	// RequestSizeBuckets sets buckets for `request_size_bytes` histogram.
+	// Defaults to: 1KB through 10MB spectrum
+	RequestSizeBuckets []float64
+
+	// ResponseSizeBuckets sets buckets for `response_size_bytes` histogram.
+	// Defaults to: 1KB through 10MB spectrum
+	ResponseSizeBuckets []float64
+
+	// NativeHistogramBucketFactor enables native (sparse) histograms for all histogram metrics when set to value
+	// greater than 1. See prometheus.HistogramOpts.NativeHistogramBucketFactor for details.
+	NativeHistogramBucketFactor float64
+
+	// NativeHistogramMaxBucketNumber limits number of buckets native histograms may have.
+	// See prometheus.HistogramOpts.NativeHistogramMaxBucketNumber for details.
+	NativeHistogramMaxBucketNumber uint32
+
+	// NativeHistogramMinResetDuration is minimal duration between native histogram bucket resets.
+	// See prometheus.HistogramOpts.NativeHistogramMinResetDuration for details.
+	NativeHistogramMinResetDuration time.Duration
+
+	// HistogramOptsFunc allows to change options for metrics of type histogram before metric is registered to Registerer.
+	// It is called after bucket related fields are applied so it can still override them.
 	HistogramOptsFunc func(opts prometheus.HistogramOpts) prometheus.HistogramOpts
 
+	// DisableCounter disables registering and collecting `requests_total` counter.
+	DisableCounter bool
+
+	// DisableDurationMetric disables registering and collecting `request_duration_seconds` histogram. Can be used together
+	// with EnableLatencySummary to record latencies only as summary.
+	DisableDurationMetric bool
+
+	// DisableRequestSizeMetric disables registering and collecting `request_size_bytes` histogram.
+	DisableRequestSizeMetric bool
+
+	// DisableResponseSizeMetric disables registering and collecting `response_size_bytes` histogram.
+	DisableResponseSizeMetric bool
+
+	// EnableLatencySummary registers `request_duration_summary_seconds` summary in addition to request duration histogram.
+	// Useful for consumers that can not aggregate histograms and need quantiles calculated by the server.
+	EnableLatencySummary bool
+
+	// SummaryObjectives sets quantile rank objectives (with their absolute allowed errors) for latency summary.
+	// Defaults to: {0.5: 0.05, 0.9: 0.01, 0.99: 0.001}
+	SummaryObjectives map[float64]float64
+
+	// SummaryOptsFunc allows to change options for metrics of type summary before metric is registered to Registerer
+	SummaryOptsFunc func(opts prometheus.SummaryOpts) prometheus.SummaryOpts
+
 	// CounterOptsFunc allows to change options for metrics of type counter before metric is registered to Registerer
 	CounterOptsFunc func(opts prometheus.CounterOpts) prometheus.CounterOpts
 
@@ -65,6 +134,10 @@ type MiddlewareConfig struct {
 	// Defaults to: prometheus.DefaultRegisterer
 	Registerer prometheus.Registerer
 
+	// RegisterRuntimeMetrics registers Go runtime and process collectors to Registerer. Useful with custom registries as
+	// prometheus.DefaultRegisterer already has these collectors registered. See RegisterDefaultCollectors.
+	RegisterRuntimeMetrics bool
+
 	// BeforeNext is callback that is executed before next middleware/handler is called. Useful for case when you have own
 	// metrics that need data to be stored for AfterNext.
 	BeforeNext func(c echo.Context)
@@ -73,11 +146,60 @@ type MiddlewareConfig struct {
 	// metrics that need incremented/observed.
 	AfterNext func(c echo.Context, err error)
 
-	timeNow func() time.Time
+	// TimeNow returns current time used to measure request durations. Useful in tests to make `request_duration_seconds`
+	// observations deterministic.
+	// Defaults to: time.Now
+	TimeNow func() time.Time
 
 	// If DoNotUseRequestPathFor404 is true, all 404 responses (due to non-matching route) will have the same `url` label and
 	// thus won't generate new metrics.
 	DoNotUseRequestPathFor404 bool
+
+	// RouteNameLabel adds `route_name` label containing name of the matched route (`e.GET(path, h).Name = "checkout"`).
+	// Note: Echo uses handler function name as route name when name is not set explicitly.
+	RouteNameLabel bool
+
+	// EnableStageMetrics registers `request_stage_duration_seconds` histogram with additional `stage` label. Durations of
+	// request handling stages recorded with RecordStage, StartStage or MeasureStage are observed to this histogram.
+	EnableStageMetrics bool
+
+	// SeparateStreamingMetrics enables detection of hijacked (i.e. WebSocket) and streamed (flushed or Server-Sent Events)
+	// responses. Durations and sizes of these responses are observed to `connection_duration_seconds` histogram and
+	// `streamed_bytes_total` counter instead of request duration and response size histograms so long-lived connections
+	// do not pollute them.
+	SeparateStreamingMetrics bool
+
+	// ConnectionDurationBuckets sets buckets for `connection_duration_seconds` histogram.
+	// Defaults to: 100ms through 1 hour spectrum
+	ConnectionDurationBuckets []float64
+
+	// URLLabelFunc allows to normalize `url` label value to keep metrics cardinality low. Argument `url` is value chosen by
+	// middleware (route path or request path for 404 responses). See NormalizeURL for built-in normalizers.
+	// Note: `url` in LabelFuncs still takes precedence over this function.
+	URLLabelFunc func(c echo.Context, url string) string
+
+	// EnableBodyErrorMetrics registers `request_body_read_errors_total` and `response_write_errors_total` counters with
+	// additional `reason` label (see ErrorReason). Request body and response writer are wrapped to count failed reads
+	// (client aborts, timeouts, too large bodies) and failed writes (broken pipes, connection resets).
+	EnableBodyErrorMetrics bool
+
+	// HostLabelFunc allows to normalize `host` label value to keep metrics cardinality low. Argument `host` is value of
+	// request Host header. See NormalizeHost for built-in normalizers.
+	// Note: `host` in LabelFuncs still takes precedence over this function.
+	HostLabelFunc func(c echo.Context, host string) string
+
+	// MethodLabelAllowList restricts `method` label values to listed HTTP methods. Requests with other methods (i.e. from
+	// WebDAV scanners or fuzzers) are labeled with `OTHER` so they do not create new series. See DefaultMethodAllowList.
+	// Defaults to: nil (all methods are used as is)
+	// Note: `method` in LabelFuncs still takes precedence over this list.
+	MethodLabelAllowList []string
+
+	// StatusLabelMode defines whether response status is reported as exact code in `code` label, as status class in
+	// `code_class` label (`2xx`, `3xx`, `4xx`, `5xx`) or both. Reporting class only keeps cardinality low for APIs
+	// returning many distinct status codes (i.e. gRPC-gateway mapped statuses).
+	// Defaults to: StatusLabelExact
+	// Note: labels in LabelFuncs still take precedence over status labels.
+	StatusLabelMode StatusLabelMode
 }
 
 type LabelValueFunc func(c echo.Context, err error) string
@@ -148,8 +270,25 @@ func NewMiddlewareWithConfig(config MiddlewareConfig) echo.MiddlewareFunc {
 
 // ToMiddleware converts configuration to middleware or returns an error.
 func (conf MiddlewareConfig) ToMiddleware() (echo.MiddlewareFunc, error) {
-	if conf.timeNow == nil {
-		conf.timeNow = time.Now
+	conf = conf.withDefaults()
+	if conf.RegisterRuntimeMetrics {
+		if err := RegisterDefaultCollectors(conf.Registerer); err != nil {
+			return nil, err
+		}
+	}
+
+	labelNames, customValuers := createLabels(conf.StatusLabelMode, conf.LabelFuncs)
+	m := conf.newMetrics(labelNames)
+	if err := m.register(conf.Registerer); err != nil {
+		return nil, err
+	}
+	return conf.newMiddleware(m, labelNames, customValuers), nil
+}
+
+// withDefaults returns configuration with defaults applied to unset fields.
+func (conf MiddlewareConfig) withDefaults() MiddlewareConfig {
+	if conf.TimeNow == nil {
+		conf.TimeNow = time.Now
 	}
 	if conf.Subsystem == "" {
 		conf.Subsystem = defaultSubsystem
@@ -167,68 +306,243 @@ func (conf MiddlewareConfig) ToMiddleware() (echo.MiddlewareFunc, error) {
 			return opts
 		}
 	}
+	if conf.SummaryOptsFunc == nil {
+		conf.SummaryOptsFunc = func(opts prometheus.SummaryOpts) prometheus.SummaryOpts {
+			return opts
+		}
+	}
+	if conf.SummaryObjectives == nil {
+		conf.SummaryObjectives = defaultSummaryObjectives
+	}
+	if conf.DurationBuckets == nil {
+		// Here, we use the prometheus defaults which are for ~10s request length max: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+		conf.DurationBuckets = prometheus.DefBuckets
+	}
+	if conf.ConnectionDurationBuckets == nil {
+		conf.ConnectionDurationBuckets = connectionDurationBuckets
+	}
+	if conf.RequestSizeBuckets == nil {
+		conf.RequestSizeBuckets = sizeBuckets
+	}
+	if conf.ResponseSizeBuckets == nil {
+		conf.ResponseSizeBuckets = sizeBuckets
+	}
+	if conf.RouteNameLabel {
+		conf.LabelFuncs = withRouteNameLabelFunc(conf.LabelFuncs)
+	}
+	return conf
+}
+
+// withRouteNameLabelFunc returns copy of labelFuncs with `route_name` label function added unless labelFuncs already
+// has one.
+func withRouteNameLabelFunc(labelFuncs map[string]LabelValueFunc) map[string]LabelValueFunc {
+	if _, ok := labelFuncs[routeNameLabel]; ok {
+		return labelFuncs
+	}
+	result := make(map[string]LabelValueFunc, len(labelFuncs)+1)
+	for k, v := range labelFuncs {
+		result[k] = v
+	}
+	result[routeNameLabel] = newRouteNameLabelFunc()
+	return result
+}
 
-	labelNames, customValuers := createLabels(conf.LabelFuncs)
+// middlewareMetrics holds collectors of the middleware. Collectors of disabled metrics are nil.
+type middlewareMetrics struct {
+	requestCount           *prometheus.CounterVec
+	requestDuration        *prometheus.HistogramVec
+	responseSize           *prometheus.HistogramVec
+	requestSize            *prometheus.HistogramVec
+	requestDurationSummary *prometheus.SummaryVec
+	connectionDuration     *prometheus.HistogramVec
+	streamedBytes          *prometheus.CounterVec
+	stageDuration          *prometheus.HistogramVec
+	bodyReadErrors         *prometheus.CounterVec
+	responseWriteErrors    *prometheus.CounterVec
+}
 
-	requestCount := prometheus.NewCounterVec(
-		conf.CounterOptsFunc(prometheus.CounterOpts{
-			Namespace: conf.Namespace,
-			Subsystem: conf.Subsystem,
-			Name:      "requests_total",
-			Help:      "How many HTTP requests processed, partitioned by status code and HTTP method.",
-		}),
-		labelNames,
-	)
-	// we do not allow skipping or replacing default collector but developer can use `conf.CounterOptsFunc` to rename
-	// this middleware default collector, so they can have own collector with that same name.
-	// and we treat all register errors as returnable failures
-	if err := conf.Registerer.Register(requestCount); err != nil {
-		return nil, err
+// newMetrics creates (but does not register) collectors of enabled metrics.
+func (conf MiddlewareConfig) newMetrics(labelNames []string) *middlewareMetrics {
+	m := &middlewareMetrics{}
+	if !conf.DisableCounter {
+		m.requestCount = prometheus.NewCounterVec(
+			conf.CounterOptsFunc(prometheus.CounterOpts{
+				Namespace: conf.Namespace,
+				Subsystem: conf.Subsystem,
+				Name:      "requests_total",
+				Help:      "How many HTTP requests processed, partitioned by status code and HTTP method.",
+			}),
+			labelNames,
+		)
 	}
 
-	requestDuration := prometheus.NewHistogramVec(
-		conf.HistogramOptsFunc(prometheus.HistogramOpts{
-			Namespace: conf.Namespace,
-			Subsystem: conf.Subsystem,
-			Name:      "request_duration_seconds",
-			Help:      "The HTTP request latencies in seconds.",
-			// Here, we use the prometheus defaults which are for ~10s request length max: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
-			Buckets: prometheus.DefBuckets,
-		}),
-		labelNames,
-	)
-	if err := conf.Registerer.Register(requestDuration); err != nil {
-		return nil, err
+	if !conf.DisableDurationMetric {
+		m.requestDuration = prometheus.NewHistogramVec(
+			conf.HistogramOptsFunc(conf.histogramOpts(prometheus.HistogramOpts{
+				Namespace: conf.Namespace,
+				Subsystem: conf.Subsystem,
+				Name:      "request_duration_seconds",
+				Help:      "The HTTP request latencies in seconds.",
+				Buckets:   conf.DurationBuckets,
+			})),
+			labelNames,
+		)
 	}
 
-	responseSize := prometheus.NewHistogramVec(
-		conf.HistogramOptsFunc(prometheus.HistogramOpts{
-			Namespace: conf.Namespace,
-			Subsystem: conf.Subsystem,
-			Name:      "response_size_bytes",
-			Help:      "The HTTP response sizes in bytes.",
-			Buckets:   sizeBuckets,
-		}),
-		labelNames,
-	)
-	if err := conf.Registerer.Register(responseSize); err != nil {
-		return nil, err
+	if !conf.DisableResponseSizeMetric {
+		m.responseSize = prometheus.NewHistogramVec(
+			conf.HistogramOptsFunc(conf.histogramOpts(prometheus.HistogramOpts{
+				Namespace: conf.Namespace,
+				Subsystem: conf.Subsystem,
+				Name:      "response_size_bytes",
+				Help:      "The HTTP response sizes in bytes.",
+				Buckets:   conf.ResponseSizeBuckets,
+			})),
+			labelNames,
+		)
 	}
 
-	requestSize := prometheus.NewHistogramVec(
-		conf.HistogramOptsFunc(prometheus.HistogramOpts{
-			Namespace: conf.Namespace,
-			Subsystem: conf.Subsystem,
-			Name:      "request_size_bytes",
-			Help:      "The HTTP request sizes in bytes.",
-			Buckets:   sizeBuckets,
-		}),
-		labelNames,
-	)
-	if err := conf.Registerer.Register(requestSize); err != nil {
-		return nil, err
+	if !conf.DisableRequestSizeMetric {
+		m.requestSize = prometheus.NewHistogramVec(
+			conf.HistogramOptsFunc(conf.histogramOpts(prometheus.HistogramOpts{
+				Namespace: conf.Namespace,
+				Subsystem: conf.Subsystem,
+				Name:      "request_size_bytes",
+				Help:      "The HTTP request sizes in bytes.",
+				Buckets:   conf.RequestSizeBuckets,
+			})),
+			labelNames,
+		)
+	}
+
+	if conf.EnableLatencySummary {
+		m.requestDurationSummary = prometheus.NewSummaryVec(
+			conf.SummaryOptsFunc(prometheus.SummaryOpts{
+				Namespace:  conf.Namespace,
+				Subsystem:  conf.Subsystem,
+				Name:       "request_duration_summary_seconds",
+				Help:       "The HTTP request latencies in seconds.",
+				Objectives: conf.SummaryObjectives,
+			}),
+			labelNames,
+		)
+	}
+
+	if conf.SeparateStreamingMetrics {
+		m.connectionDuration = prometheus.NewHistogramVec(
+			conf.HistogramOptsFunc(conf.histogramOpts(prometheus.HistogramOpts{
+				Namespace: conf.Namespace,
+				Subsystem: conf.Subsystem,
+				Name:      "connection_duration_seconds",
+				Help:      "The durations of hijacked and streamed HTTP connections in seconds.",
+				Buckets:   conf.ConnectionDurationBuckets,
+			})),
+			labelNames,
+		)
+		m.streamedBytes = prometheus.NewCounterVec(
+			conf.CounterOptsFunc(prometheus.CounterOpts{
+				Namespace: conf.Namespace,
+				Subsystem: conf.Subsystem,
+				Name:      "streamed_bytes_total",
+				Help:      "How many bytes were sent over hijacked and streamed HTTP connections.",
+			}),
+			labelNames,
+		)
+	}
+
+	if conf.EnableStageMetrics {
+		m.stageDuration = prometheus.NewHistogramVec(
+			conf.HistogramOptsFunc(conf.histogramOpts(prometheus.HistogramOpts{
+				Namespace: conf.Namespace,
+				Subsystem: conf.Subsystem,
+				Name:      "request_stage_duration_seconds",
+				Help:      "The HTTP request handling stage latencies in seconds.",
+				Buckets:   conf.DurationBuckets,
+			})),
+			append(append([]string{}, labelNames...), stageLabel),
+		)
+	}
+
+	if conf.EnableBodyErrorMetrics {
+		errorLabelNames := append(append([]string{}, labelNames...), reasonLabel)
+		m.bodyReadErrors = prometheus.NewCounterVec(
+			conf.CounterOptsFunc(prometheus.CounterOpts{
+				Namespace: conf.Namespace,
+				Subsystem: conf.Subsystem,
+				Name:      "request_body_read_errors_total",
+				Help:      "How many HTTP requests failed reading request body, partitioned by reason.",
+			}),
+			errorLabelNames,
+		)
+		m.responseWriteErrors = prometheus.NewCounterVec(
+			conf.CounterOptsFunc(prometheus.CounterOpts{
+				Namespace: conf.Namespace,
+				Subsystem: conf.Subsystem,
+				Name:      "response_write_errors_total",
+				Help:      "How many HTTP requests failed writing response, partitioned by reason.",
+			}),
+			errorLabelNames,
+		)
+	}
+	return m
+}
+
+func (m *middlewareMetrics) collectors() []prometheus.Collector {
+	var result []prometheus.Collector
+	// nil pointers of disabled metrics must not end up in the slice as non-nil interfaces
+	if m.requestCount != nil {
+		result = append(result, m.requestCount)
+	}
+	if m.requestDuration != nil {
+		result = append(result, m.requestDuration)
+	}
+	if m.responseSize != nil {
+		result = append(result, m.responseSize)
+	}
+	if m.requestSize != nil {
+		result = append(result, m.requestSize)
+	}
+	if m.requestDurationSummary != nil {
+		result = append(result, m.requestDurationSummary)
+	}
+	if m.connectionDuration != nil {
+		result = append(result, m.connectionDuration, m.streamedBytes)
+	}
+	if m.stageDuration != nil {
+		result = append(result, m.stageDuration)
+	}
+	if m.bodyReadErrors != nil {
+		result = append(result, m.bodyReadErrors, m.responseWriteErrors)
+	}
+	return result
+}
+
+// register registers all collectors. When registration of any collector fails already registered collectors are
+// unregistered.
+func (m *middlewareMetrics) register(reg prometheus.Registerer) error {
+	// we do not allow replacing default collector but developer can use `conf.CounterOptsFunc` to rename
+	// this middleware default collector, so they can have own collector with that same name.
+	// and we treat all register errors as returnable failures
+	cs := m.collectors()
+	for i, c := range cs {
+		if err := reg.Register(c); err != nil {
+			for _, registered := range cs[:i] {
+				reg.Unregister(registered)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *middlewareMetrics) unregister(reg prometheus.Registerer) {
+	for _, c := range m.collectors() {
+		reg.Unregister(c)
 	}
+}
 
+// newMiddleware returns middleware observing requests to given collectors.
+func (conf MiddlewareConfig) newMiddleware(m *middlewareMetrics, labelNames []string, customValuers []customLabelValuer) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			// NB: we do not skip metrics handler path by default. This can be added with custom Skipper but for default
@@ -242,9 +556,39 @@ func (conf MiddlewareConfig) ToMiddleware() (echo.MiddlewareFunc, error) {
 			}
 			reqSz := computeApproximateRequestSize(c.Request())
 
-			start := conf.timeNow()
+			var streamTracker *streamTrackingWriter
+			if conf.SeparateStreamingMetrics {
+				original := c.Response().Writer
+				streamTracker = &streamTrackingWriter{ResponseWriter: original}
+				c.Response().Writer = streamTracker
+				defer func() {
+					c.Response().Writer = original
+				}()
+			}
+
+			var bodyTracker *errorTrackingBody
+			var writeTracker *errorTrackingWriter
+			if conf.EnableBodyErrorMetrics {
+				req := c.Request()
+				if req.Body != nil && req.Body != http.NoBody {
+					originalBody := req.Body
+					bodyTracker = &errorTrackingBody{ReadCloser: originalBody}
+					req.Body = bodyTracker
+					defer func() {
+						req.Body = originalBody
+					}()
+				}
+				original := c.Response().Writer
+				writeTracker = &errorTrackingWriter{ResponseWriter: original}
+				c.Response().Writer = writeTracker
+				defer func() {
+					c.Response().Writer = original
+				}()
+			}
+
+			start := conf.TimeNow()
 			err := next(c)
-			elapsed := float64(conf.timeNow().Sub(start)) / float64(time.Second)
+			elapsed := float64(conf.TimeNow().Sub(start)) / float64(time.Second)
 
 			if conf.AfterNext != nil {
 				conf.AfterNext(c, err)
@@ -257,6 +601,10 @@ func (conf MiddlewareConfig) ToMiddleware() (echo.MiddlewareFunc, error) {
 				url = c.Request().URL.Path
 			}
 
+			if conf.URLLabelFunc != nil {
+				url = conf.URLLabelFunc(c, url)
+			}
+
 			status := c.Response().Status
 			if err != nil {
 				var httpError *echo.HTTPError
@@ -269,37 +617,152 @@ func (conf MiddlewareConfig) ToMiddleware() (echo.MiddlewareFunc, error) {
 			}
 
 			values := make([]string, len(labelNames))
-			values[0] = strconv.Itoa(status)
+			conf.StatusLabelMode.setStatusValues(values, status)
 			values[1] = c.Request().Method
-			values[2] = c.Request().Host
+			if conf.MethodLabelAllowList != nil && containsAt(conf.MethodLabelAllowList, values[1]) == -1 {
+				values[1] = otherMethodLabel
+			}
+			host := c.Request().Host
+			if conf.HostLabelFunc != nil {
+				host = conf.HostLabelFunc(c, host)
+			}
+			values[2] = host
 			values[3] = strings.ToValidUTF8(url, "\uFFFD") // \uFFFD is � https://en.wikipedia.org/wiki/Specials_(Unicode_block)#Replacement_character
 			for _, cv := range customValuers {
 				values[cv.index] = cv.valueFunc(c, err)
 			}
-			if obs, err := requestDuration.GetMetricWithLabelValues(values...); err == nil {
-				obs.Observe(elapsed)
-			} else {
-				return fmt.Errorf("failed to label request duration metric with values, err: %w", err)
+			streamed := streamTracker != nil && streamTracker.isStreamed()
+			if streamed {
+				if obs, err := m.connectionDuration.GetMetricWithLabelValues(values...); err == nil {
+					obs.Observe(elapsed)
+				} else {
+					return fmt.Errorf("failed to label connection duration metric with values, err: %w", err)
+				}
+				if obs, err := m.streamedBytes.GetMetricWithLabelValues(values...); err == nil {
+					obs.Add(float64(c.Response().Size + streamTracker.hijackedSize.Load()))
+				} else {
+					return fmt.Errorf("failed to label streamed bytes metric with values, err: %w", err)
+				}
+			}
+			if m.requestDuration != nil && !streamed {
+				if obs, err := m.requestDuration.GetMetricWithLabelValues(values...); err == nil {
+					obs.Observe(elapsed)
+				} else {
+					return fmt.Errorf("failed to label request duration metric with values, err: %w", err)
+				}
+			}
+			if m.requestDurationSummary != nil && !streamed {
+				if obs, err := m.requestDurationSummary.GetMetricWithLabelValues(values...); err == nil {
+					obs.Observe(elapsed)
+				} else {
+					return fmt.Errorf("failed to label request duration summary metric with values, err: %w", err)
+				}
 			}
-			if obs, err := requestCount.GetMetricWithLabelValues(values...); err == nil {
-				obs.Inc()
-			} else {
-				return fmt.Errorf("failed to label request count metric with values, err: %w", err)
+			if m.requestCount != nil {
+				if obs, err := m.requestCount.GetMetricWithLabelValues(values...); err == nil {
+					obs.Inc()
+				} else {
+					return fmt.Errorf("failed to label request count metric with values, err: %w", err)
+				}
 			}
-			if obs, err := requestSize.GetMetricWithLabelValues(values...); err == nil {
-				obs.Observe(float64(reqSz))
-			} else {
-				return fmt.Errorf("failed to label request size metric with values, err: %w", err)
+			if m.requestSize != nil {
+				if obs, err := m.requestSize.GetMetricWithLabelValues(values...); err == nil {
+					obs.Observe(float64(reqSz))
+				} else {
+					return fmt.Errorf("failed to label request size metric with values, err: %w", err)
+				}
 			}
-			if obs, err := responseSize.GetMetricWithLabelValues(values...); err == nil {
-				obs.Observe(float64(c.Response().Size))
-			} else {
-				return fmt.Errorf("failed to label response size metric with values, err: %w", err)
+			if m.responseSize != nil && !streamed {
+				if obs, err := m.responseSize.GetMetricWithLabelValues(values...); err == nil {
+					obs.Observe(float64(c.Response().Size))
+				} else {
+					return fmt.Errorf("failed to label response size metric with values, err: %w", err)
+				}
+			}
+			if bodyTracker != nil && bodyTracker.err != nil {
+				if obs, err := m.bodyReadErrors.GetMetricWithLabelValues(append(values, ErrorReason(bodyTracker.err))...); err == nil {
+					obs.Inc()
+				} else {
+					return fmt.Errorf("failed to label request body read errors metric with values, err: %w", err)
+				}
+			}
+			if writeTracker != nil && writeTracker.err != nil {
+				if obs, err := m.responseWriteErrors.GetMetricWithLabelValues(append(values, ErrorReason(writeTracker.err))...); err == nil {
+					obs.Inc()
+				} else {
+					return fmt.Errorf("failed to label response write errors metric with values, err: %w", err)
+				}
+			}
+			if m.stageDuration != nil {
+				if stages, ok := c.Get(stagesContextKey).(*stageTimings); ok {
+					var stageErr error
+					stages.each(func(stage string, d time.Duration) {
+						obs, err := m.stageDuration.GetMetricWithLabelValues(append(values, stage)...)
+						if err != nil {
+							stageErr = fmt.Errorf("failed to label request stage duration metric with values, err: %w", err)
+							return
+						}
+						obs.Observe(d.Seconds())
+					})
+					if stageErr != nil {
+						return stageErr
+					}
+				}
 			}
 
 			return err
 		}
-	}, nil
+	}
+}
+
+// histogramOpts applies native histogram options from configuration to given histogram options.
+func (conf MiddlewareConfig) histogramOpts(opts prometheus.HistogramOpts) prometheus.HistogramOpts {
+	opts.NativeHistogramBucketFactor = conf.NativeHistogramBucketFactor
+	opts.NativeHistogramMaxBucketNumber = conf.NativeHistogramMaxBucketNumber
+	opts.NativeHistogramMinResetDuration = conf.NativeHistogramMinResetDuration
+	return opts
+}
+
+// RegisterDefaultCollectors registers standard Go runtime and process collectors with given registerer. Collectors that
+// are already registered are ignored.
+func RegisterDefaultCollectors(reg prometheus.Registerer) error {
+	defaultCollectors := []prometheus.Collector{
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	}
+	for _, c := range defaultCollectors {
+		if err := reg.Register(c); err != nil {
+			var arErr prometheus.AlreadyRegisteredError
+			if errors.As(err, &arErr) {
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// newRouteNameLabelFunc creates label function that resolves name of the matched route. Resolved routes are cached by
+// method and path. Routes are cached as pointers so names changed after the first request are still reflected.
+func newRouteNameLabelFunc() LabelValueFunc {
+	cache := sync.Map{}
+	return func(c echo.Context, err error) string {
+		path := c.Path()
+		if path == "" {
+			return ""
+		}
+		key := c.Request().Method + " " + path
+		if r, ok := cache.Load(key); ok {
+			return r.(*echo.Route).Name
+		}
+		for _, r := range c.Echo().Routes() {
+			if r.Path == path && r.Method == c.Request().Method {
+				cache.Store(key, r)
+				return r.Name
+			}
+		}
+		return ""
+	}
 }
 
 type customLabelValuer struct {
@@ -308,8 +771,8 @@ type customLabelValuer struct {
 	valueFunc LabelValueFunc
 }
 
-func createLabels(customLabelFuncs map[string]LabelValueFunc) ([]string, []customLabelValuer) {
-	labelNames := []string{"code", "method", "host", "url"}
+func createLabels(mode StatusLabelMode, customLabelFuncs map[string]LabelValueFunc) ([]string, []customLabelValuer) {
+	labelNames := mode.baseLabelNames()
 	if len(customLabelFuncs) == 0 {
 		return labelNames, nil
 	}
@@ -414,28 +877,8 @@ func RunPushGatewayGatherer(ctx context.Context, config PushGatewayConfig) error
 		select {
 		case <-ticker.C:
 			out.Reset()
-			err := WriteGatheredMetrics(out, config.Gatherer)
-			if err != nil {
-				if hErr := config.ErrorHandler(fmt.Errorf("failed to create metrics: %w", err)); hErr != nil {
-					return hErr
-				}
-				continue
-			}
-			req, err := http.NewRequestWithContext(ctx, http.MethodPost, config.PushGatewayURL, out)
-			if err != nil {
-				if hErr := config.ErrorHandler(fmt.Errorf("failed to create push gateway request: %w", err)); hErr != nil {
-					return hErr
-				}
-				continue
-			}
-			res, err := client.Do(req)
-			if err != nil {
-				if hErr := config.ErrorHandler(fmt.Errorf("error sending to push gateway: %w", err)); hErr != nil {
-					return hErr
-				}
-			}
-			if res.StatusCode != http.StatusOK {
-				if hErr := config.ErrorHandler(echo.NewHTTPError(res.StatusCode, "post metrics request did not succeed")); hErr != nil {
+			if err := push(ctx, client, out, config); err != nil {
+				if hErr := config.ErrorHandler(err); hErr != nil {
 					return hErr
 				}
 			}
@@ -445,6 +888,41 @@ func RunPushGatewayGatherer(ctx context.Context, config PushGatewayConfig) error
 	}
 }
 
+// PushNow gathers collected metrics and pushes them to the push gateway once. Useful for pushing metrics before
+// application exits or for triggering push in tests without waiting for RunPushGatewayGatherer ticker. PushInterval
+// and ErrorHandler are not used.
+func PushNow(ctx context.Context, config PushGatewayConfig) error {
+	if config.PushGatewayURL == "" {
+		return errors.New("push gateway URL is missing")
+	}
+	if config.Gatherer == nil {
+		config.Gatherer = prometheus.DefaultGatherer
+	}
+	client := &http.Client{
+		Transport: config.ClientTransport,
+	}
+	return push(ctx, client, &bytes.Buffer{}, config)
+}
+
+func push(ctx context.Context, client *http.Client, out *bytes.Buffer, config PushGatewayConfig) error {
+	if err := WriteGatheredMetrics(out, config.Gatherer); err != nil {
+		return fmt.Errorf("failed to create metrics: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, config.PushGatewayURL, out)
+	if err != nil {
+		return fmt.Errorf("failed to create push gateway request: %w", err)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending to push gateway: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return echo.NewHTTPError(res.StatusCode, "post metrics request did not succeed")
+	}
+	return nil
+}
+
 // WriteGatheredMetrics gathers collected metrics and writes them to given writer
 func WriteGatheredMetrics(writer io.Writer, gatherer prometheus.Gatherer) error {
 	metricFamilies, err := gatherer.Gather()
@@ -11,6 +11,7 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -66,6 +67,30 @@ func TestPrometheus_Buckets(t *testing.T) {
 	assert.NotContains(t, body, `echo_response_size_bytes_bucket{code="404",host="example.com",method="GET",url="/ping",le="0.005"}`, "response size should NOT have time bucket (like, 0.005s)")
 }
 
+func TestMiddlewareConfig_Buckets(t *testing.T) {
+	e := echo.New()
+
+	customRegistry := prometheus.NewRegistry()
+	e.Use(NewMiddlewareWithConfig(MiddlewareConfig{
+		Registerer:          customRegistry,
+		DurationBuckets:     []float64{0.001, 0.002},
+		RequestSizeBuckets:  []float64{10},
+		ResponseSizeBuckets: []float64{20},
+	}))
+	e.GET("/metrics", NewHandlerWithConfig(HandlerConfig{Gatherer: customRegistry}))
+
+	assert.Equal(t, http.StatusNotFound, request(e, "/ping"))
+
+	body, code := requestBody(e, "/metrics")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `echo_request_duration_seconds_bucket{code="404",host="example.com",method="GET",url="/ping",le="0.002"}`)
+	assert.NotContains(t, body, `echo_request_duration_seconds_bucket{code="404",host="example.com",method="GET",url="/ping",le="0.005"}`)
+	assert.Contains(t, body, `echo_request_size_bytes_bucket{code="404",host="example.com",method="GET",url="/ping",le="10"}`)
+	assert.NotContains(t, body, `echo_request_size_bytes_bucket{code="404",host="example.com",method="GET",url="/ping",le="1024"}`)
+	assert.Contains(t, body, `echo_response_size_bytes_bucket{code="404",host="example.com",method="GET",url="/ping",le="20"}`)
+	assert.NotContains(t, body, `echo_response_size_bytes_bucket{code="404",host="example.com",method="GET",url="/ping",le="1024"}`)
+}
+
 func TestMiddlewareConfig_Skipper(t *testing.T) {
 	e := echo.New()
 
@@ -161,6 +186,31 @@ func TestMiddlewareConfig_LabelFuncs(t *testing.T) {
 	assert.Contains(t, body, `echo_request_duration_seconds_count{code="200",host="example.com",method="overridden_GET",scheme="http",url="/ok"} 1`)
 }
 
+func TestMiddlewareConfig_RouteNameLabel(t *testing.T) {
+	e := echo.New()
+	customRegistry := prometheus.NewRegistry()
+	e.Use(NewMiddlewareWithConfig(MiddlewareConfig{
+		RouteNameLabel: true,
+		Registerer:     customRegistry,
+	}))
+	e.GET("/metrics", NewHandlerWithConfig(HandlerConfig{Gatherer: customRegistry}))
+
+	e.POST("/checkout/:id", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, "OK")
+	}).Name = "checkout"
+
+	req := httptest.NewRequest(http.MethodPost, "/checkout/1", nil)
+	e.ServeHTTP(httptest.NewRecorder(), req)
+	req = httptest.NewRequest(http.MethodPost, "/checkout/2", nil)
+	e.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, http.StatusNotFound, request(e, "/nope"))
+
+	body, code := requestBody(e, "/metrics")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `echo_requests_total{code="200",host="example.com",method="POST",route_name="checkout",url="/checkout/:id"} 2`)
+	assert.Contains(t, body, `echo_requests_total{code="404",host="example.com",method="GET",route_name="",url="/nope"} 1`)
+}
+
 func TestMiddlewareConfig_HistogramOptsFunc(t *testing.T) {
 	e := echo.New()
 	customRegistry := prometheus.NewRegistry()
@@ -190,6 +240,100 @@ func TestMiddlewareConfig_HistogramOptsFunc(t *testing.T) {
 	assert.Contains(t, body, `echo_request_size_bytes_count{code="200",host="example.com",method="GET",url="/ok"} 1`)
 }
 
+func TestMiddlewareConfig_EnableLatencySummary(t *testing.T) {
+	e := echo.New()
+	customRegistry := prometheus.NewRegistry()
+	e.Use(NewMiddlewareWithConfig(MiddlewareConfig{
+		EnableLatencySummary: true,
+		SummaryObjectives:    map[float64]float64{0.95: 0.005},
+		SummaryOptsFunc: func(opts prometheus.SummaryOpts) prometheus.SummaryOpts {
+			opts.ConstLabels = prometheus.Labels{"my_const": "123"}
+			return opts
+		},
+		Registerer: customRegistry,
+	}))
+	e.GET("/metrics", NewHandlerWithConfig(HandlerConfig{Gatherer: customRegistry}))
+
+	e.GET("/ok", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, "OK")
+	})
+
+	assert.Equal(t, http.StatusOK, request(e, "/ok"))
+
+	body, code := requestBody(e, "/metrics")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `echo_request_duration_summary_seconds{code="200",host="example.com",method="GET",my_const="123",url="/ok",quantile="0.95"}`)
+	assert.Contains(t, body, `echo_request_duration_summary_seconds_count{code="200",host="example.com",method="GET",my_const="123",url="/ok"} 1`)
+	assert.Contains(t, body, `echo_request_duration_seconds_count{code="200",host="example.com",method="GET",url="/ok"} 1`)
+}
+
+func TestMiddlewareConfig_DisableMetrics(t *testing.T) {
+	e := echo.New()
+	customRegistry := prometheus.NewRegistry()
+	e.Use(NewMiddlewareWithConfig(MiddlewareConfig{
+		DisableCounter:            true,
+		DisableRequestSizeMetric:  true,
+		DisableResponseSizeMetric: true,
+		Registerer:                customRegistry,
+	}))
+	e.GET("/metrics", NewHandlerWithConfig(HandlerConfig{Gatherer: customRegistry}))
+
+	e.GET("/ok", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, "OK")
+	})
+
+	assert.Equal(t, http.StatusOK, request(e, "/ok"))
+
+	body, code := requestBody(e, "/metrics")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `echo_request_duration_seconds_count{code="200",host="example.com",method="GET",url="/ok"} 1`)
+	assert.NotContains(t, body, `echo_requests_total`)
+	assert.NotContains(t, body, `echo_request_size_bytes`)
+	assert.NotContains(t, body, `echo_response_size_bytes`)
+}
+
+func TestMiddlewareConfig_DisableDurationMetric(t *testing.T) {
+	e := echo.New()
+	customRegistry := prometheus.NewRegistry()
+	e.Use(NewMiddlewareWithConfig(MiddlewareConfig{
+		DisableDurationMetric: true,
+		EnableLatencySummary:  true,
+		Registerer:            customRegistry,
+	}))
+	e.GET("/metrics", NewHandlerWithConfig(HandlerConfig{Gatherer: customRegistry}))
+
+	assert.Equal(t, http.StatusNotFound, request(e, "/ok"))
+
+	body, code := requestBody(e, "/metrics")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `echo_request_duration_summary_seconds_count{code="404",host="example.com",method="GET",url="/ok"} 1`)
+	assert.NotContains(t, body, `echo_request_duration_seconds_bucket`)
+}
+
+func TestMiddlewareConfig_MethodLabelAllowList(t *testing.T) {
+	e := echo.New()
+	customRegistry := prometheus.NewRegistry()
+	e.Use(NewMiddlewareWithConfig(MiddlewareConfig{
+		Registerer:           customRegistry,
+		MethodLabelAllowList: DefaultMethodAllowList,
+	}))
+	e.Any("/ok", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+	e.GET("/metrics", NewHandlerWithConfig(HandlerConfig{Gatherer: customRegistry}))
+
+	for _, method := range []string{http.MethodGet, "PROPFIND", "REPORT", http.MethodDelete} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/ok", nil))
+	}
+
+	body, code := requestBody(e, "/metrics")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `echo_requests_total{code="200",host="example.com",method="GET",url="/ok"} 1`)
+	assert.Contains(t, body, `echo_requests_total{code="200",host="example.com",method="DELETE",url="/ok"} 1`)
+	assert.Contains(t, body, `echo_requests_total{code="200",host="example.com",method="OTHER",url="/ok"} 2`)
+	assert.NotContains(t, body, `method="PROPFIND"`)
+}
+
 func TestMiddlewareConfig_CounterOptsFunc(t *testing.T) {
 	e := echo.New()
 	customRegistry := prometheus.NewRegistry()
@@ -252,6 +396,32 @@ func TestMiddlewareConfig_AfterNextFuncs(t *testing.T) {
 	assert.Contains(t, body, `custom_requests_total 1`)
 }
 
+func TestMiddlewareConfig_RegisterRuntimeMetrics(t *testing.T) {
+	e := echo.New()
+
+	customRegistry := prometheus.NewRegistry()
+	e.Use(NewMiddlewareWithConfig(MiddlewareConfig{
+		Registerer:             customRegistry,
+		RegisterRuntimeMetrics: true,
+	}))
+	e.GET("/metrics", NewHandlerWithConfig(HandlerConfig{Gatherer: customRegistry}))
+
+	body, code := requestBody(e, "/metrics")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `go_goroutines`)
+}
+
+func TestRegisterDefaultCollectors(t *testing.T) {
+	customRegistry := prometheus.NewRegistry()
+
+	assert.NoError(t, RegisterDefaultCollectors(customRegistry))
+	assert.NoError(t, RegisterDefaultCollectors(customRegistry)) // already registered collectors are ignored
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, WriteGatheredMetrics(out, customRegistry))
+	assert.Contains(t, out.String(), `go_goroutines`)
+}
+
 func TestRunPushGatewayGatherer(t *testing.T) {
 	receivedMetrics := false
 	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -278,6 +448,65 @@ func TestRunPushGatewayGatherer(t *testing.T) {
 	unregisterDefaults("myapp")
 }
 
+func TestPushNow(t *testing.T) {
+	var body string
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer svr.Close()
+
+	customRegistry := prometheus.NewRegistry()
+	counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "pushed_total", Help: "test counter"})
+	customRegistry.MustRegister(counter)
+	counter.Inc()
+
+	err := PushNow(context.Background(), PushGatewayConfig{PushGatewayURL: svr.URL, Gatherer: customRegistry})
+
+	assert.NoError(t, err)
+	assert.Contains(t, body, "pushed_total 1")
+}
+
+func TestPushNow_errors(t *testing.T) {
+	err := PushNow(context.Background(), PushGatewayConfig{})
+	assert.EqualError(t, err, "push gateway URL is missing")
+
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	err = PushNow(context.Background(), PushGatewayConfig{PushGatewayURL: svr.URL, Gatherer: prometheus.NewRegistry()})
+	assert.EqualError(t, err, "code=502, message=post metrics request did not succeed")
+
+	svr.Close()
+	err = PushNow(context.Background(), PushGatewayConfig{PushGatewayURL: svr.URL, Gatherer: prometheus.NewRegistry()})
+	assert.ErrorContains(t, err, "error sending to push gateway")
+}
+
+func TestMiddlewareConfig_TimeNow(t *testing.T) {
+	e := echo.New()
+	customRegistry := prometheus.NewRegistry()
+
+	now := time.Unix(1_700_000_000, 0)
+	e.Use(NewMiddlewareWithConfig(MiddlewareConfig{
+		Registerer: customRegistry,
+		TimeNow: func() time.Time {
+			now = now.Add(250 * time.Millisecond)
+			return now
+		},
+	}))
+	e.GET("/ping", func(c echo.Context) error {
+		return c.String(http.StatusOK, "OK")
+	})
+	e.GET("/metrics", NewHandlerWithConfig(HandlerConfig{Gatherer: customRegistry}))
+
+	assert.Equal(t, http.StatusOK, request(e, "/ping"))
+
+	s, code := requestBody(e, "/metrics")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, s, `echo_request_duration_seconds_sum{code="200",host="example.com",method="GET",url="/ping"} 0.25`)
+}
+
 // TestSetPathFor404NoMatchingRoute tests that the url is not included in the metric when
 // the 404 response is due to no matching route
 func TestSetPathFor404NoMatchingRoute(t *testing.T) {
@@ -0,0 +1,106 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echoprometheus
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// SharedMiddleware creates several middleware instances (i.e. one per route group) from one configuration. All instances
+// observe requests to the same collectors, which are registered when the first instance is created and unregistered
+// when the last instance is released, so instances do not collide in the registry or duplicate series.
+type SharedMiddleware struct {
+	config MiddlewareConfig
+
+	mu      sync.Mutex
+	refs    int
+	metrics *middlewareMetrics
+}
+
+// InstanceConfig contains per-instance overrides of SharedMiddleware configuration.
+type InstanceConfig struct {
+	// Skipper defines a function to skip middleware instance.
+	// Defaults to: Skipper of shared configuration
+	Skipper middleware.Skipper
+
+	// LabelFuncs replaces functions of labels with the same key in shared configuration LabelFuncs. Keys that are not
+	// labels of shared configuration are not allowed as all instances observe to the same collectors.
+	// Optional.
+	LabelFuncs map[string]LabelValueFunc
+
+	// URLLabelFunc replaces URLLabelFunc of shared configuration.
+	// Optional.
+	URLLabelFunc func(c echo.Context, url string) string
+}
+
+// NewSharedMiddleware creates SharedMiddleware for configuration. Collectors are not registered until the first
+// middleware instance is created.
+func NewSharedMiddleware(config MiddlewareConfig) *SharedMiddleware {
+	return &SharedMiddleware{config: config.withDefaults()}
+}
+
+// Middleware returns new middleware instance or an error when instance configuration is invalid or collectors can not
+// be registered. Every successful call must be paired with Release when the instance is no longer used. Safe for
+// concurrent use.
+func (s *SharedMiddleware) Middleware(instance InstanceConfig) (echo.MiddlewareFunc, error) {
+	conf := s.config
+	labelNames, _ := createLabels(conf.StatusLabelMode, conf.LabelFuncs)
+	if len(instance.LabelFuncs) > 0 {
+		labelFuncs := make(map[string]LabelValueFunc, len(conf.LabelFuncs))
+		for k, v := range conf.LabelFuncs {
+			labelFuncs[k] = v
+		}
+		for k, v := range instance.LabelFuncs {
+			if containsAt(labelNames, k) == -1 {
+				return nil, fmt.Errorf("echoprometheus: instance label `%v` is not a label of shared configuration", k)
+			}
+			labelFuncs[k] = v
+		}
+		conf.LabelFuncs = labelFuncs
+	}
+	if instance.Skipper != nil {
+		conf.Skipper = instance.Skipper
+	}
+	if instance.URLLabelFunc != nil {
+		conf.URLLabelFunc = instance.URLLabelFunc
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.refs == 0 {
+		if conf.RegisterRuntimeMetrics {
+			if err := RegisterDefaultCollectors(conf.Registerer); err != nil {
+				return nil, err
+			}
+		}
+		m := conf.newMetrics(labelNames)
+		if err := m.register(conf.Registerer); err != nil {
+			return nil, err
+		}
+		s.metrics = m
+	}
+	s.refs++
+
+	names, customValuers := createLabels(conf.StatusLabelMode, conf.LabelFuncs)
+	return conf.newMiddleware(s.metrics, names, customValuers), nil
+}
+
+// Release releases one middleware instance. Collectors are unregistered when the last instance is released and will
+// be registered again (with reset values) when a new instance is created.
+func (s *SharedMiddleware) Release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.refs == 0 {
+		return
+	}
+	s.refs--
+	if s.refs == 0 {
+		s.metrics.unregister(s.config.Registerer)
+		s.metrics = nil
+	}
+}
@@ -0,0 +1,123 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echoprometheus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSharedMiddleware(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	shared := NewSharedMiddleware(MiddlewareConfig{
+		Registerer:                reg,
+		DisableDurationMetric:     true,
+		DisableRequestSizeMetric:  true,
+		DisableResponseSizeMetric: true,
+		LabelFuncs: map[string]LabelValueFunc{
+			"group": func(c echo.Context, err error) string { return "" },
+		},
+	})
+
+	e := echo.New()
+	api := e.Group("/api")
+	apiMW, err := shared.Middleware(InstanceConfig{
+		LabelFuncs: map[string]LabelValueFunc{
+			"group": func(c echo.Context, err error) string { return "api" },
+		},
+	})
+	assert.NoError(t, err)
+	api.Use(apiMW)
+	api.GET("/users", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	api.GET("/health", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+
+	admin := e.Group("/admin")
+	adminMW, err := shared.Middleware(InstanceConfig{
+		Skipper: func(c echo.Context) bool { return c.Path() == "/admin/ping" },
+		LabelFuncs: map[string]LabelValueFunc{
+			"group": func(c echo.Context, err error) string { return "admin" },
+		},
+	})
+	assert.NoError(t, err)
+	admin.Use(adminMW)
+	admin.GET("/ping", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	admin.GET("/stats", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+
+	for _, path := range []string{"/api/users", "/api/health", "/admin/ping", "/admin/stats"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	expect := `
+# HELP echo_requests_total How many HTTP requests processed, partitioned by status code and HTTP method.
+# TYPE echo_requests_total counter
+echo_requests_total{code="200",group="admin",host="example.com",method="GET",url="/admin/stats"} 1
+echo_requests_total{code="200",group="api",host="example.com",method="GET",url="/api/health"} 1
+echo_requests_total{code="200",group="api",host="example.com",method="GET",url="/api/users"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expect), "echo_requests_total"))
+
+	shared.Release()
+	count, err := testutil.GatherAndCount(reg)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	shared.Release()
+	count, err = testutil.GatherAndCount(reg)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	// collectors can be registered again after all instances were released
+	_, err = shared.Middleware(InstanceConfig{})
+	assert.NoError(t, err)
+}
+
+func TestSharedMiddleware_unknownLabel(t *testing.T) {
+	shared := NewSharedMiddleware(MiddlewareConfig{Registerer: prometheus.NewRegistry()})
+
+	_, err := shared.Middleware(InstanceConfig{
+		LabelFuncs: map[string]LabelValueFunc{
+			"tenant": func(c echo.Context, err error) string { return "acme" },
+		},
+	})
+
+	assert.EqualError(t, err, "echoprometheus: instance label `tenant` is not a label of shared configuration")
+}
+
+func TestSharedMiddleware_concurrent(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	shared := NewSharedMiddleware(MiddlewareConfig{Registerer: reg})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := shared.Middleware(InstanceConfig{})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 10, shared.refs)
+}
+
+func TestMiddlewareConfig_ToMiddleware_unregistersOnError(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(prometheus.NewCounter(prometheus.CounterOpts{Name: "echo_request_size_bytes", Help: "taken"}))
+
+	_, err := MiddlewareConfig{Registerer: reg}.ToMiddleware()
+	assert.Error(t, err)
+
+	// request counter registered before failure was unregistered so configuration can be retried
+	_, err = MiddlewareConfig{Registerer: reg, DisableRequestSizeMetric: true}.ToMiddleware()
+	assert.NoError(t, err)
+}
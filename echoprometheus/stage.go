@@ -0,0 +1,106 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echoprometheus
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	stagesContextKey = "_echoprometheus_stages"
+
+	stageLabel = "stage"
+)
+
+var measureStageCount atomic.Uint64
+
+// stageTimings is per-request scratchpad where durations of request handling stages are recorded.
+type stageTimings struct {
+	mu      sync.Mutex
+	stages  []string
+	timings map[string]time.Duration
+}
+
+func (s *stageTimings) add(stage string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.timings[stage]; !ok {
+		s.stages = append(s.stages, stage)
+	}
+	s.timings[stage] += d
+}
+
+func (s *stageTimings) each(fn func(stage string, d time.Duration)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, stage := range s.stages {
+		fn(stage, s.timings[stage])
+	}
+}
+
+func getStageTimings(c echo.Context) *stageTimings {
+	if s, ok := c.Get(stagesContextKey).(*stageTimings); ok {
+		return s
+	}
+	s := &stageTimings{timings: map[string]time.Duration{}}
+	c.Set(stagesContextKey, s)
+	return s
+}
+
+// RecordStage records duration of request handling stage. Recorded durations are observed by the middleware as
+// `request_stage_duration_seconds` histogram when MiddlewareConfig.EnableStageMetrics is set. Durations recorded
+// multiple times for the same stage are summed.
+func RecordStage(c echo.Context, stage string, d time.Duration) {
+	getStageTimings(c).add(stage, d)
+}
+
+// StartStage starts measuring request handling stage. Returned function must be called when stage ends.
+//
+// Example:
+//
+//	defer echoprometheus.StartStage(c, "load_user")()
+func StartStage(c echo.Context, stage string) func() {
+	start := time.Now()
+	return func() {
+		RecordStage(c, stage, time.Since(start))
+	}
+}
+
+// MeasureStage wraps middleware and records time spent in that middleware as given stage. Time spent in the next
+// middlewares/handler is excluded so only middleware own work (e.g. session loading, authorization) is measured.
+//
+// Example:
+//
+//	e.Use(echoprometheus.NewMiddlewareWithConfig(echoprometheus.MiddlewareConfig{EnableStageMetrics: true}))
+//	e.Use(echoprometheus.MeasureStage("session", session.Middleware(store)))
+//	e.Use(echoprometheus.MeasureStage("casbin", casbin_mw.Middleware(enforcer)))
+func MeasureStage(stage string, mw echo.MiddlewareFunc) echo.MiddlewareFunc {
+	nestedKey := "_echoprometheus_stage_nested_" + strconv.FormatUint(measureStageCount.Add(1), 10)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		wrapped := mw(func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+			if nested, ok := c.Get(nestedKey).(*time.Duration); ok {
+				*nested += time.Since(start)
+			}
+			return err
+		})
+
+		return func(c echo.Context) error {
+			nested := new(time.Duration)
+			c.Set(nestedKey, nested)
+
+			start := time.Now()
+			err := wrapped(c)
+			RecordStage(c, stage, time.Since(start)-*nested)
+			return err
+		}
+	}
+}
@@ -0,0 +1,66 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echoprometheus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddlewareConfig_EnableStageMetrics(t *testing.T) {
+	e := echo.New()
+	customRegistry := prometheus.NewRegistry()
+	e.Use(NewMiddlewareWithConfig(MiddlewareConfig{
+		EnableStageMetrics: true,
+		Registerer:         customRegistry,
+	}))
+	e.GET("/metrics", NewHandlerWithConfig(HandlerConfig{Gatherer: customRegistry}))
+
+	e.GET("/ok", func(c echo.Context) error {
+		RecordStage(c, "db", 10*time.Millisecond)
+		RecordStage(c, "db", 20*time.Millisecond)
+		func() {
+			defer StartStage(c, "render")()
+		}()
+		return c.JSON(http.StatusOK, "OK")
+	})
+
+	assert.Equal(t, http.StatusOK, request(e, "/ok"))
+
+	body, code := requestBody(e, "/metrics")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `echo_request_stage_duration_seconds_sum{code="200",host="example.com",method="GET",stage="db",url="/ok"} 0.03`)
+	assert.Contains(t, body, `echo_request_stage_duration_seconds_count{code="200",host="example.com",method="GET",stage="render",url="/ok"} 1`)
+	assert.NotContains(t, body, `echo_request_stage_duration_seconds_count{code="200",host="example.com",method="GET",stage="db",url="/metrics"}`)
+}
+
+func TestMeasureStage(t *testing.T) {
+	slowMiddleware := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			time.Sleep(5 * time.Millisecond)
+			return next(c)
+		}
+	}
+
+	mw := MeasureStage("slow", slowMiddleware)
+	h := mw(func(c echo.Context) error {
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	})
+
+	e := echo.New()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	assert.NoError(t, h(c))
+
+	stages := c.Get(stagesContextKey).(*stageTimings)
+	d := stages.timings["slow"]
+	assert.GreaterOrEqual(t, d, 5*time.Millisecond)
+	assert.Less(t, d, 50*time.Millisecond) // handler time is excluded
+}
@@ -0,0 +1,51 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echoprometheus
+
+import "strconv"
+
+const codeClassLabel = "code_class"
+
+// StatusLabelMode defines how response status is reported in metric labels.
+type StatusLabelMode int
+
+const (
+	// StatusLabelExact reports exact status code in `code` label (i.e. `404`).
+	StatusLabelExact StatusLabelMode = iota
+	// StatusLabelClass reports status class in `code_class` label (i.e. `4xx`) instead of `code` label.
+	StatusLabelClass
+	// StatusLabelBoth reports both `code` and `code_class` labels.
+	StatusLabelBoth
+)
+
+// baseLabelNames returns default label names for the mode. Status label is always the first label and `code_class`
+// label in StatusLabelBoth mode is the last default label.
+func (m StatusLabelMode) baseLabelNames() []string {
+	switch m {
+	case StatusLabelClass:
+		return []string{codeClassLabel, "method", "host", "url"}
+	case StatusLabelBoth:
+		return []string{"code", "method", "host", "url", codeClassLabel}
+	default:
+		return []string{"code", "method", "host", "url"}
+	}
+}
+
+// setStatusValues sets status label values for the mode to values created for baseLabelNames.
+func (m StatusLabelMode) setStatusValues(values []string, status int) {
+	switch m {
+	case StatusLabelClass:
+		values[0] = statusClass(status)
+	case StatusLabelBoth:
+		values[0] = strconv.Itoa(status)
+		values[4] = statusClass(status)
+	default:
+		values[0] = strconv.Itoa(status)
+	}
+}
+
+// statusClass returns class of status code (`200` becomes `2xx`).
+func statusClass(status int) string {
+	return strconv.Itoa(status/100) + "xx"
+}
@@ -0,0 +1,94 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echoprometheus
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddlewareConfig_StatusLabelMode(t *testing.T) {
+	var testCases = []struct {
+		name         string
+		whenMode     StatusLabelMode
+		expect       []string
+		expectAbsent string
+	}{
+		{
+			name:     "ok, exact",
+			whenMode: StatusLabelExact,
+			expect: []string{
+				`echo_requests_total{code="200",host="example.com",method="GET",url="/ok"} 1`,
+				`echo_requests_total{code="404",host="example.com",method="GET",url="/missing"} 1`,
+			},
+			expectAbsent: "code_class",
+		},
+		{
+			name:     "ok, class",
+			whenMode: StatusLabelClass,
+			expect: []string{
+				`echo_requests_total{code_class="2xx",host="example.com",method="GET",url="/ok"} 1`,
+				`echo_requests_total{code_class="4xx",host="example.com",method="GET",url="/missing"} 1`,
+				`echo_requests_total{code_class="5xx",host="example.com",method="GET",url="/error"} 1`,
+			},
+			expectAbsent: `echo_requests_total{code="`,
+		},
+		{
+			name:     "ok, both",
+			whenMode: StatusLabelBoth,
+			expect: []string{
+				`echo_requests_total{code="200",code_class="2xx",host="example.com",method="GET",url="/ok"} 1`,
+				`echo_request_duration_seconds_count{code="503",code_class="5xx",host="example.com",method="GET",url="/error"} 1`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			customRegistry := prometheus.NewRegistry()
+			e.Use(NewMiddlewareWithConfig(MiddlewareConfig{
+				Registerer:      customRegistry,
+				StatusLabelMode: tc.whenMode,
+				Skipper: func(c echo.Context) bool {
+					return c.Path() == "/metrics"
+				},
+			}))
+			e.GET("/ok", func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			})
+			e.GET("/error", func(c echo.Context) error {
+				return echo.ErrServiceUnavailable
+			})
+			e.GET("/metrics", NewHandlerWithConfig(HandlerConfig{Gatherer: customRegistry}))
+
+			assert.Equal(t, http.StatusOK, request(e, "/ok"))
+			assert.Equal(t, http.StatusNotFound, request(e, "/missing"))
+			assert.Equal(t, http.StatusServiceUnavailable, request(e, "/error"))
+
+			body, code := requestBody(e, "/metrics")
+			assert.Equal(t, http.StatusOK, code)
+			for _, line := range tc.expect {
+				assert.Contains(t, body, line)
+			}
+			if tc.expectAbsent != "" {
+				assert.NotContains(t, body, tc.expectAbsent)
+			}
+		})
+	}
+}
+
+func TestVerify_statusLabelClass(t *testing.T) {
+	e := echo.New()
+	registry := prometheus.NewRegistry()
+	cfg := MiddlewareConfig{Registerer: registry, StatusLabelMode: StatusLabelClass}
+	e.Use(NewMiddlewareWithConfig(cfg))
+	e.GET("/metrics", NewHandlerWithConfig(HandlerConfig{Gatherer: registry}))
+
+	assert.NoError(t, Verify(e, cfg))
+}
@@ -0,0 +1,65 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echoprometheus
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// connectionDurationBuckets are default buckets for long-lived connections: from 100ms up to 1 hour.
+var connectionDurationBuckets = []float64{0.1, 0.5, 1, 5, 15, 30, 60, 300, 900, 1800, 3600}
+
+// streamTrackingWriter wraps http.ResponseWriter to detect hijacked connections (i.e. WebSockets) and flushed
+// (streamed) responses.
+type streamTrackingWriter struct {
+	http.ResponseWriter
+
+	flushed      bool
+	hijacked     bool
+	hijackedSize atomic.Int64
+}
+
+func (w *streamTrackingWriter) FlushError() error {
+	w.flushed = true
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *streamTrackingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err != nil {
+		return conn, rw, err
+	}
+	w.hijacked = true
+	cc := &countingConn{Conn: conn, written: &w.hijackedSize}
+	// writer returned by Hijack has empty buffer so it is safe to replace it with one writing through our counting conn
+	rw.Writer = bufio.NewWriterSize(cc, rw.Writer.Size())
+	return cc, rw, nil
+}
+
+func (w *streamTrackingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// isStreamed returns true when response was hijacked, flushed or is Server-Sent Events stream.
+func (w *streamTrackingWriter) isStreamed() bool {
+	if w.hijacked || w.flushed {
+		return true
+	}
+	return strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream")
+}
+
+type countingConn struct {
+	net.Conn
+	written *atomic.Int64
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.written.Add(int64(n))
+	return n, err
+}
@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echoprometheus
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+const hijackedResponse = "HTTP/1.1 101 Switching Protocols\r\nConnection: close\r\n\r\nhello"
+
+func TestMiddlewareConfig_SeparateStreamingMetrics(t *testing.T) {
+	e := echo.New()
+	customRegistry := prometheus.NewRegistry()
+	e.Use(NewMiddlewareWithConfig(MiddlewareConfig{
+		SeparateStreamingMetrics: true,
+		Registerer:               customRegistry,
+	}))
+
+	e.GET("/sse", func(c echo.Context) error {
+		c.Response().Header().Set(echo.HeaderContentType, "text/event-stream")
+		c.Response().WriteHeader(http.StatusOK)
+		_, err := c.Response().Write([]byte("data: hello\n\n"))
+		c.Response().Flush()
+		return err
+	})
+	e.GET("/ws", func(c echo.Context) error {
+		conn, rw, err := c.Response().Hijack()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		rw.WriteString(hijackedResponse)
+		return rw.Flush()
+	})
+	e.GET("/ok", func(c echo.Context) error {
+		return c.String(http.StatusOK, "OK")
+	})
+
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	for _, path := range []string{"/sse", "/ws", "/ok"} {
+		res, err := http.Get(server.URL + path)
+		if assert.NoError(t, err) {
+			io.ReadAll(res.Body)
+			res.Body.Close()
+		}
+	}
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, WriteGatheredMetrics(out, customRegistry))
+	body := out.String()
+
+	host := server.Listener.Addr().String()
+	assert.Contains(t, body, `echo_connection_duration_seconds_count{code="200",host="`+host+`",method="GET",url="/sse"} 1`)
+	assert.Contains(t, body, `echo_streamed_bytes_total{code="200",host="`+host+`",method="GET",url="/sse"} 13`)
+	assert.Contains(t, body, `echo_streamed_bytes_total{code="200",host="`+host+`",method="GET",url="/ws"} `+strconv.Itoa(len(hijackedResponse)))
+	assert.Contains(t, body, `echo_requests_total{code="200",host="`+host+`",method="GET",url="/ws"} 1`)
+	assert.NotContains(t, body, `echo_request_duration_seconds_count{code="200",host="`+host+`",method="GET",url="/sse"}`)
+	assert.NotContains(t, body, `echo_response_size_bytes_count{code="200",host="`+host+`",method="GET",url="/ws"}`)
+	assert.Contains(t, body, `echo_request_duration_seconds_count{code="200",host="`+host+`",method="GET",url="/ok"} 1`)
+}
@@ -0,0 +1,91 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echoprometheus
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+)
+
+var uuidRegexp = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// URLNormalizer transforms `url` label value.
+type URLNormalizer func(url string) string
+
+// NormalizeURL creates function usable as MiddlewareConfig.URLLabelFunc that applies given normalizers in order.
+//
+// Example:
+//
+//	e.Use(echoprometheus.NewMiddlewareWithConfig(echoprometheus.MiddlewareConfig{
+//		URLLabelFunc: echoprometheus.NormalizeURL(
+//			echoprometheus.StripQueryString,
+//			echoprometheus.CollapseNumericIDs,
+//			echoprometheus.CollapseUUIDs,
+//			echoprometheus.LimitLength(64),
+//		),
+//	}))
+func NormalizeURL(normalizers ...URLNormalizer) func(c echo.Context, url string) string {
+	return func(c echo.Context, url string) string {
+		for _, n := range normalizers {
+			url = n(url)
+		}
+		return url
+	}
+}
+
+// StripQueryString removes query string (everything starting from `?`) from url.
+func StripQueryString(url string) string {
+	if i := strings.IndexByte(url, '?'); i != -1 {
+		return url[:i]
+	}
+	return url
+}
+
+// CollapseNumericIDs replaces path segments consisting only of digits with `:id` placeholder.
+func CollapseNumericIDs(url string) string {
+	return replaceSegments(url, ":id", func(segment string) bool {
+		for _, r := range segment {
+			if r < '0' || r > '9' {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// CollapseUUIDs replaces path segments that are UUIDs with `:uuid` placeholder.
+func CollapseUUIDs(url string) string {
+	return replaceSegments(url, ":uuid", uuidRegexp.MatchString)
+}
+
+// LimitLength creates normalizer that truncates url to given maximum length in bytes. Url is never cut in the middle
+// of UTF-8 encoded rune so the result can be shorter. Panics when maxLength is negative.
+func LimitLength(maxLength int) URLNormalizer {
+	if maxLength < 0 {
+		panic("echoprometheus: LimitLength maxLength must not be negative")
+	}
+	return func(url string) string {
+		if len(url) <= maxLength {
+			return url
+		}
+		cut := maxLength
+		for cut > 0 && !utf8.RuneStart(url[cut]) {
+			cut--
+		}
+		return url[:cut]
+	}
+}
+
+func replaceSegments(url string, placeholder string, match func(segment string) bool) string {
+	segments := strings.Split(url, "/")
+	for i, segment := range segments {
+		if segment != "" && match(segment) {
+			segments[i] = placeholder
+		}
+	}
+	return strings.Join(segments, "/")
+}
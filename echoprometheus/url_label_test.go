@@ -0,0 +1,103 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echoprometheus
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizers(t *testing.T) {
+	var testCases = []struct {
+		name       string
+		normalizer URLNormalizer
+		whenURL    string
+		expect     string
+	}{
+		{
+			name:       "ok, strip query string",
+			normalizer: StripQueryString,
+			whenURL:    "/users?id=1",
+			expect:     "/users",
+		},
+		{
+			name:       "ok, strip query string when missing",
+			normalizer: StripQueryString,
+			whenURL:    "/users",
+			expect:     "/users",
+		},
+		{
+			name:       "ok, collapse numeric ids",
+			normalizer: CollapseNumericIDs,
+			whenURL:    "/users/123/posts/4a/5",
+			expect:     "/users/:id/posts/4a/:id",
+		},
+		{
+			name:       "ok, collapse uuids",
+			normalizer: CollapseUUIDs,
+			whenURL:    "/users/6ba7b810-9dad-11d1-80b4-00c04fd430c8/posts",
+			expect:     "/users/:uuid/posts",
+		},
+		{
+			name:       "ok, limit length",
+			normalizer: LimitLength(5),
+			whenURL:    "/users/123",
+			expect:     "/user",
+		},
+		{
+			name:       "ok, limit length when shorter",
+			normalizer: LimitLength(50),
+			whenURL:    "/users/123",
+			expect:     "/users/123",
+		},
+		{
+			name:       "ok, limit length does not cut rune",
+			normalizer: LimitLength(8),
+			whenURL:    "/files/ärm",
+			expect:     "/files/",
+		},
+		{
+			name:       "ok, limit length to zero",
+			normalizer: LimitLength(0),
+			whenURL:    "/users/123",
+			expect:     "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, tc.normalizer(tc.whenURL))
+		})
+	}
+}
+
+func TestLimitLength_negative(t *testing.T) {
+	assert.PanicsWithValue(t, "echoprometheus: LimitLength maxLength must not be negative", func() {
+		LimitLength(-1)
+	})
+}
+
+func TestMiddlewareConfig_URLLabelFunc(t *testing.T) {
+	e := echo.New()
+
+	customRegistry := prometheus.NewRegistry()
+	e.Use(NewMiddlewareWithConfig(MiddlewareConfig{
+		Registerer:   customRegistry,
+		URLLabelFunc: NormalizeURL(CollapseNumericIDs, CollapseUUIDs),
+	}))
+	e.GET("/metrics", NewHandlerWithConfig(HandlerConfig{Gatherer: customRegistry}))
+
+	assert.Equal(t, http.StatusNotFound, request(e, "/users/123"))
+	assert.Equal(t, http.StatusNotFound, request(e, "/users/456"))
+	assert.Equal(t, http.StatusNotFound, request(e, "/files/6ba7b810-9dad-11d1-80b4-00c04fd430c8"))
+
+	body, code := requestBody(e, "/metrics")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `echo_requests_total{code="404",host="example.com",method="GET",url="/users/:id"} 2`)
+	assert.Contains(t, body, `echo_requests_total{code="404",host="example.com",method="GET",url="/files/:uuid"} 1`)
+}
@@ -0,0 +1,221 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echoprometheus
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// handlerNamePrefix is prefix of the route name Echo assigns to handlers created by NewHandler and NewHandlerWithConfig.
+const handlerNamePrefix = "github.com/labstack/echo-contrib/echoprometheus.NewHandler"
+
+// Verify checks at startup that metrics are wired correctly for given Echo instance and middleware configuration.
+// Configuration must be the same that was used to create the middleware. Verify:
+//   - finds GET route serving metrics handler (created by NewHandler or NewHandlerWithConfig, or route with `/metrics` path),
+//   - sends a probe request to that route and checks that it responds with 200,
+//   - checks that the probe request was recorded by the middleware with expected labels, which fails when the
+//     middleware is not registered with `e.Use` or is registered to a different Registerer,
+//   - gathers the registry and reports errors like duplicate metric names or inconsistent label names.
+//
+// Configured Skipper must not skip the metrics path, otherwise the probe request is not recorded and Verify fails. Verify must
+// be called after routes and middlewares have been added and before the server is started. Probe request increments
+// request metrics for the metrics path once.
+//
+// Example:
+// ```
+//
+//	mwConfig := echoprometheus.MiddlewareConfig{Subsystem: "myapp"}
+//	e.Use(echoprometheus.NewMiddlewareWithConfig(mwConfig))
+//	e.GET("/metrics", echoprometheus.NewHandler())
+//	if err := echoprometheus.Verify(e, mwConfig); err != nil {
+//		e.Logger.Fatal(err)
+//	}
+//
+// ```
+func Verify(e *echo.Echo, cfg MiddlewareConfig) error {
+	if cfg.Subsystem == "" {
+		cfg.Subsystem = defaultSubsystem
+	}
+	if cfg.Registerer == nil {
+		cfg.Registerer = prometheus.DefaultRegisterer
+	}
+	gatherer, ok := cfg.Registerer.(prometheus.Gatherer)
+	if !ok {
+		return errors.New("echoprometheus: registerer does not implement prometheus.Gatherer and can not be verified")
+	}
+
+	metricsPath := findMetricsPath(e)
+	if metricsPath == "" {
+		return errors.New("echoprometheus: no GET route serving metrics handler found, register it with `e.GET(\"/metrics\", echoprometheus.NewHandler())`")
+	}
+	if strings.ContainsAny(metricsPath, ":*") {
+		return fmt.Errorf("echoprometheus: metrics handler route `%v` must not contain path parameters", metricsPath)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, metricsPath, nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		return fmt.Errorf("echoprometheus: metrics handler at `%v` responded with status %d, check that it is not protected or blocked by other middlewares", metricsPath, rec.Code)
+	}
+	expectedMethod, expectedURL := cfg.expectedProbeLabelValues(e, metricsPath)
+
+	families, err := gatherer.Gather()
+	if err != nil {
+		return fmt.Errorf("echoprometheus: gathering metrics failed: %w", err)
+	}
+
+	expected := cfg.expectedMetricNames()
+	if len(expected) == 0 {
+		return nil
+	}
+	labelNames := cfg.expectedLabelNames()
+	_, codeOverridden := cfg.LabelFuncs["code"]
+	recorded := false
+	for _, mf := range families {
+		if !slices.Contains(expected, mf.GetName()) {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			names := metricLabelNames(m)
+			if !slices.Equal(names, labelNames) {
+				return fmt.Errorf("echoprometheus: metric `%v` has labels %v but middleware configuration expects %v", mf.GetName(), names, labelNames)
+			}
+			if !codeOverridden && labelValue(m, "code") != "200" && labelValue(m, codeClassLabel) != "2xx" {
+				continue
+			}
+			if labelValue(m, "method") == expectedMethod && labelValue(m, "url") == expectedURL {
+				recorded = true
+			}
+		}
+	}
+	if !recorded {
+		return fmt.Errorf("echoprometheus: probe request to `%v` was not recorded in any of metrics %v, check that middleware is registered with `e.Use` and uses the same Registerer", metricsPath, expected)
+	}
+	return nil
+}
+
+// expectedProbeLabelValues returns `method` and `url` label values the middleware records for the probe request. Values
+// are computed with configured URLLabelFunc, MethodLabelAllowList and LabelFuncs the same way as the middleware does.
+func (conf MiddlewareConfig) expectedProbeLabelValues(e *echo.Echo, metricsPath string) (string, string) {
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, metricsPath, nil), httptest.NewRecorder())
+	e.Router().Find(http.MethodGet, metricsPath, c)
+	c.Response().Status = http.StatusOK
+
+	method := http.MethodGet
+	if conf.MethodLabelAllowList != nil && !slices.Contains(conf.MethodLabelAllowList, method) {
+		method = otherMethodLabel
+	}
+	if f, ok := conf.LabelFuncs["method"]; ok {
+		method = f(c, nil)
+	}
+	url := c.Path()
+	if conf.URLLabelFunc != nil {
+		url = conf.URLLabelFunc(c, url)
+	}
+	url = strings.ToValidUTF8(url, "\uFFFD")
+	if f, ok := conf.LabelFuncs["url"]; ok {
+		url = f(c, nil)
+	}
+	return method, url
+}
+
+// findMetricsPath returns path of the GET route serving metrics handler. Routes created with NewHandler are preferred over
+// routes with `/metrics` path.
+func findMetricsPath(e *echo.Echo) string {
+	fallback := ""
+	for _, r := range e.Routes() {
+		if r.Method != http.MethodGet {
+			continue
+		}
+		if strings.HasPrefix(r.Name, handlerNamePrefix) {
+			return r.Path
+		}
+		if r.Path == "/metrics" {
+			fallback = r.Path
+		}
+	}
+	return fallback
+}
+
+// expectedMetricNames returns fully-qualified names of request metrics enabled by configuration. Option functions are
+// applied so renamed metrics are found as well.
+func (conf MiddlewareConfig) expectedMetricNames() []string {
+	names := make([]string, 0, 5)
+	if !conf.DisableCounter {
+		opts := prometheus.CounterOpts{Namespace: conf.Namespace, Subsystem: conf.Subsystem, Name: "requests_total"}
+		if conf.CounterOptsFunc != nil {
+			opts = conf.CounterOptsFunc(opts)
+		}
+		names = append(names, prometheus.BuildFQName(opts.Namespace, opts.Subsystem, opts.Name))
+	}
+	histograms := make([]string, 0, 3)
+	if !conf.DisableDurationMetric {
+		histograms = append(histograms, "request_duration_seconds")
+	}
+	if !conf.DisableResponseSizeMetric {
+		histograms = append(histograms, "response_size_bytes")
+	}
+	if !conf.DisableRequestSizeMetric {
+		histograms = append(histograms, "request_size_bytes")
+	}
+	for _, name := range histograms {
+		opts := prometheus.HistogramOpts{Namespace: conf.Namespace, Subsystem: conf.Subsystem, Name: name}
+		if conf.HistogramOptsFunc != nil {
+			opts = conf.HistogramOptsFunc(opts)
+		}
+		names = append(names, prometheus.BuildFQName(opts.Namespace, opts.Subsystem, opts.Name))
+	}
+	if conf.EnableLatencySummary {
+		opts := prometheus.SummaryOpts{Namespace: conf.Namespace, Subsystem: conf.Subsystem, Name: "request_duration_summary_seconds"}
+		if conf.SummaryOptsFunc != nil {
+			opts = conf.SummaryOptsFunc(opts)
+		}
+		names = append(names, prometheus.BuildFQName(opts.Namespace, opts.Subsystem, opts.Name))
+	}
+	return names
+}
+
+// expectedLabelNames returns sorted label names of request metrics for configuration.
+func (conf MiddlewareConfig) expectedLabelNames() []string {
+	labelFuncs := conf.LabelFuncs
+	if conf.RouteNameLabel {
+		labelFuncs = make(map[string]LabelValueFunc, len(conf.LabelFuncs)+1)
+		for k, v := range conf.LabelFuncs {
+			labelFuncs[k] = v
+		}
+		labelFuncs[routeNameLabel] = nil
+	}
+	labelNames, _ := createLabels(conf.StatusLabelMode, labelFuncs)
+	sort.Strings(labelNames)
+	return labelNames
+}
+
+func metricLabelNames(m *dto.Metric) []string {
+	names := make([]string, 0, len(m.GetLabel()))
+	for _, l := range m.GetLabel() {
+		names = append(names, l.GetName())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func labelValue(m *dto.Metric, name string) string {
+	for _, l := range m.GetLabel() {
+		if l.GetName() == name {
+			return l.GetValue()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,126 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echoprometheus
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerify(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		givenConfig MiddlewareConfig
+		givenSetup  func(e *echo.Echo, cfg MiddlewareConfig, registry *prometheus.Registry)
+		expectError string
+	}{
+		{
+			name: "ok",
+			givenSetup: func(e *echo.Echo, cfg MiddlewareConfig, registry *prometheus.Registry) {
+				e.Use(NewMiddlewareWithConfig(cfg))
+				e.GET("/metrics", NewHandlerWithConfig(HandlerConfig{Gatherer: registry}))
+			},
+		},
+		{
+			name: "ok, custom metrics path",
+			givenSetup: func(e *echo.Echo, cfg MiddlewareConfig, registry *prometheus.Registry) {
+				e.Use(NewMiddlewareWithConfig(cfg))
+				e.GET("/internal/prom", NewHandlerWithConfig(HandlerConfig{Gatherer: registry}))
+			},
+		},
+		{
+			name: "ok, url label normalized with URLLabelFunc",
+			givenConfig: MiddlewareConfig{
+				URLLabelFunc: func(c echo.Context, url string) string { return "api:" + url },
+			},
+			givenSetup: func(e *echo.Echo, cfg MiddlewareConfig, registry *prometheus.Registry) {
+				e.Use(NewMiddlewareWithConfig(cfg))
+				e.GET("/metrics", NewHandlerWithConfig(HandlerConfig{Gatherer: registry}))
+			},
+		},
+		{
+			name: "ok, custom method label",
+			givenConfig: MiddlewareConfig{
+				LabelFuncs: map[string]LabelValueFunc{
+					"method": func(c echo.Context, err error) string { return strings.ToLower(c.Request().Method) },
+				},
+			},
+			givenSetup: func(e *echo.Echo, cfg MiddlewareConfig, registry *prometheus.Registry) {
+				e.Use(NewMiddlewareWithConfig(cfg))
+				e.GET("/metrics", NewHandlerWithConfig(HandlerConfig{Gatherer: registry}))
+			},
+		},
+		{
+			name:        "ok, method not in allow list",
+			givenConfig: MiddlewareConfig{MethodLabelAllowList: []string{http.MethodPost}},
+			givenSetup: func(e *echo.Echo, cfg MiddlewareConfig, registry *prometheus.Registry) {
+				e.Use(NewMiddlewareWithConfig(cfg))
+				e.GET("/metrics", NewHandlerWithConfig(HandlerConfig{Gatherer: registry}))
+			},
+		},
+		{
+			name: "nok, no metrics handler",
+			givenSetup: func(e *echo.Echo, cfg MiddlewareConfig, registry *prometheus.Registry) {
+				e.Use(NewMiddlewareWithConfig(cfg))
+			},
+			expectError: "echoprometheus: no GET route serving metrics handler found",
+		},
+		{
+			name: "nok, middleware not registered",
+			givenSetup: func(e *echo.Echo, cfg MiddlewareConfig, registry *prometheus.Registry) {
+				e.GET("/metrics", NewHandlerWithConfig(HandlerConfig{Gatherer: registry}))
+			},
+			expectError: "echoprometheus: probe request to `/metrics` was not recorded",
+		},
+		{
+			name: "nok, metrics handler blocked",
+			givenSetup: func(e *echo.Echo, cfg MiddlewareConfig, registry *prometheus.Registry) {
+				e.Use(NewMiddlewareWithConfig(cfg))
+				e.GET("/metrics", NewHandlerWithConfig(HandlerConfig{Gatherer: registry}), func(next echo.HandlerFunc) echo.HandlerFunc {
+					return func(c echo.Context) error {
+						return echo.ErrUnauthorized
+					}
+				})
+			},
+			expectError: "echoprometheus: metrics handler at `/metrics` responded with status 401",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			registry := prometheus.NewRegistry()
+			cfg := tc.givenConfig
+			cfg.Registerer = registry
+			tc.givenSetup(e, cfg, registry)
+
+			err := Verify(e, cfg)
+			if tc.expectError != "" {
+				assert.ErrorContains(t, err, tc.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestVerify_labelMismatch(t *testing.T) {
+	e := echo.New()
+	registry := prometheus.NewRegistry()
+	e.Use(NewMiddlewareWithConfig(MiddlewareConfig{
+		Registerer: registry,
+		LabelFuncs: map[string]LabelValueFunc{
+			"tenant": func(c echo.Context, err error) string { return "t1" },
+		},
+	}))
+	e.GET("/metrics", NewHandlerWithConfig(HandlerConfig{Gatherer: registry}))
+
+	err := Verify(e, MiddlewareConfig{Registerer: registry})
+	assert.ErrorContains(t, err, "but middleware configuration expects [code host method url]")
+}
@@ -0,0 +1,198 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+/*
+Package echoquicstats provides middleware exposing protocol and connection level statistics of requests (HTTP
+protocol version, TLS version and cipher suite, negotiated ALPN protocol and connection reuse) as Prometheus metrics
+and OpenTelemetry span attributes to track HTTP/3 and HTTP/2 rollout from the application side.
+
+Connection reuse is detected only when server is configured with ConnContext, which works for `net/http` server and
+HTTP/3 servers with the same hook (i.e. quic-go `http3.Server.ConnContext`). Without it requests are reported with
+`reused="unknown"`.
+
+Example:
+```
+package main
+
+import (
+
+	"net/http"
+
+	"github.com/labstack/echo-contrib/echoquicstats"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+
+)
+
+	func main() {
+		e := echo.New()
+		e.Use(echoquicstats.MiddlewareWithConfig(echoquicstats.Config{
+			Registerer:    prometheus.DefaultRegisterer,
+			AttachToTrace: true,
+		}))
+
+		s := &http.Server{
+			Addr:        ":8443",
+			Handler:     e,
+			ConnContext: echoquicstats.ConnContext,
+		}
+		e.Logger.Fatal(s.ListenAndServeTLS("cert.pem", "key.pem"))
+	}
+
+```
+*/
+package echoquicstats
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const defaultSubsystem = "echo_conn"
+
+// connStats is protocol and connection information of the request.
+type connStats struct {
+	// Protocol is HTTP protocol of the request (i.e. `HTTP/1.1`, `HTTP/2.0`, `HTTP/3.0`).
+	Protocol string
+	// TLSVersion is negotiated TLS version (i.e. `TLS 1.3`) or empty string for plain text connections.
+	TLSVersion string
+	// CipherSuite is negotiated TLS cipher suite name or empty string for plain text connections.
+	CipherSuite string
+	// ALPN is negotiated application protocol (i.e. `h3`, `h2`, `http/1.1`) or empty string when ALPN was not used.
+	ALPN string
+	// Reused is `true` when request is not the first request on the connection, `false` for the first request and
+	// `unknown` when server is not configured with ConnContext.
+	Reused string
+}
+
+type connStateKey struct{}
+
+type connState struct {
+	requests atomic.Int64
+}
+
+// ConnContext is hook for http.Server.ConnContext (or HTTP/3 server equivalent) that enables connection reuse
+// detection.
+func ConnContext(ctx context.Context, _ net.Conn) context.Context {
+	return context.WithValue(ctx, connStateKey{}, &connState{})
+}
+
+// Config defines the config for connection stats middleware.
+type Config struct {
+	// Skipper defines a function to skip middleware.
+	Skipper middleware.Skipper
+
+	// AttachToTrace adds `network.protocol.version`, `tls.protocol.version`, `tls.cipher`, `tls.next_protocol` and
+	// `http.connection.reused` attributes to OpenTelemetry span found in request context.
+	AttachToTrace bool
+
+	// Registerer is used to register requests counter. When nil no metrics are registered.
+	// Optional.
+	Registerer prometheus.Registerer
+
+	// Namespace is components of the fully-qualified name of the Metric (created by joining Namespace,Subsystem and Name components with "_")
+	// Optional
+	Namespace string
+
+	// Subsystem is components of the fully-qualified name of the Metric (created by joining Namespace,Subsystem and Name components with "_")
+	// Defaults to: "echo_conn"
+	Subsystem string
+}
+
+// DefaultConfig is the default connection stats middleware config.
+var DefaultConfig = Config{
+	Skipper:    middleware.DefaultSkipper,
+	Registerer: prometheus.DefaultRegisterer,
+}
+
+// Middleware returns connection stats middleware with default config registering metrics to default registerer.
+func Middleware() echo.MiddlewareFunc {
+	return MiddlewareWithConfig(DefaultConfig)
+}
+
+// MiddlewareWithConfig returns connection stats middleware with config or panics on invalid configuration.
+// See: `Middleware()`.
+func MiddlewareWithConfig(config Config) echo.MiddlewareFunc {
+	mw, err := config.ToMiddleware()
+	if err != nil {
+		panic(err)
+	}
+	return mw
+}
+
+// ToMiddleware converts configuration to middleware or returns an error.
+func (config Config) ToMiddleware() (echo.MiddlewareFunc, error) {
+	if config.Skipper == nil {
+		config.Skipper = DefaultConfig.Skipper
+	}
+	if config.Subsystem == "" {
+		config.Subsystem = defaultSubsystem
+	}
+
+	requests := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: config.Namespace,
+			Subsystem: config.Subsystem,
+			Name:      "requests_total",
+			Help:      "How many HTTP requests processed, partitioned by protocol, TLS version, cipher suite, ALPN and connection reuse.",
+		},
+		[]string{"proto", "tls_version", "cipher", "alpn", "reused"},
+	)
+	if config.Registerer != nil {
+		if err := config.Registerer.Register(requests); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			s := statsFromRequest(c)
+			requests.WithLabelValues(s.Protocol, s.TLSVersion, s.CipherSuite, s.ALPN, s.Reused).Inc()
+
+			if config.AttachToTrace {
+				if span := trace.SpanFromContext(c.Request().Context()); span.IsRecording() {
+					span.SetAttributes(
+						attribute.String("network.protocol.version", s.Protocol),
+						attribute.String("tls.protocol.version", s.TLSVersion),
+						attribute.String("tls.cipher", s.CipherSuite),
+						attribute.String("tls.next_protocol", s.ALPN),
+						attribute.String("http.connection.reused", s.Reused),
+					)
+				}
+			}
+			return next(c)
+		}
+	}, nil
+}
+
+// statsFromRequest returns protocol and connection information of the request. Every call counts as request on the
+// connection for reuse detection.
+func statsFromRequest(c echo.Context) connStats {
+	req := c.Request()
+	s := connStats{
+		Protocol: req.Proto,
+		Reused:   "unknown",
+	}
+	if req.TLS != nil {
+		s.TLSVersion = tls.VersionName(req.TLS.Version)
+		s.CipherSuite = tls.CipherSuiteName(req.TLS.CipherSuite)
+		s.ALPN = req.TLS.NegotiatedProtocol
+	}
+	if st, ok := req.Context().Value(connStateKey{}).(*connState); ok {
+		s.Reused = strconv.FormatBool(st.requests.Add(1) > 1)
+	}
+	return s
+}
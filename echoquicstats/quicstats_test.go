@@ -0,0 +1,103 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echoquicstats
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestMiddleware_TLSConnectionReuse(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(Config{Registerer: reg}))
+	e.GET("/", func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	})
+
+	server := httptest.NewUnstartedServer(e)
+	server.EnableHTTP2 = true
+	server.Config.ConnContext = ConnContext
+	server.StartTLS()
+	defer server.Close()
+
+	client := server.Client()
+	for i := 0; i < 2; i++ {
+		res, err := client.Get(server.URL)
+		assert.NoError(t, err)
+		_, _ = io.Copy(io.Discard, res.Body)
+		res.Body.Close()
+	}
+
+	families, err := reg.Gather()
+	assert.NoError(t, err)
+	assert.Len(t, families, 1)
+	reused := map[string]float64{}
+	for _, m := range families[0].GetMetric() {
+		labels := map[string]string{}
+		for _, l := range m.GetLabel() {
+			labels[l.GetName()] = l.GetValue()
+		}
+		assert.Equal(t, "HTTP/2.0", labels["proto"])
+		assert.Equal(t, "h2", labels["alpn"])
+		assert.Equal(t, "TLS 1.3", labels["tls_version"])
+		assert.NotEmpty(t, labels["cipher"]) // depends on hardware AES support
+		reused[labels["reused"]] = m.GetCounter().GetValue()
+	}
+	assert.Equal(t, map[string]float64{"false": 1, "true": 1}, reused)
+}
+
+func TestMiddleware_withoutConnContext(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(Config{Registerer: reg}))
+	e.GET("/", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	expect := `
+# HELP echo_conn_requests_total How many HTTP requests processed, partitioned by protocol, TLS version, cipher suite, ALPN and connection reuse.
+# TYPE echo_conn_requests_total counter
+echo_conn_requests_total{alpn="",cipher="",proto="HTTP/1.1",reused="unknown",tls_version=""} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expect)))
+}
+
+func TestMiddleware_attachToTrace(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(Config{AttachToTrace: true}))
+	e.GET("/", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "request")
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ConnContext(ctx, nil))
+	e.ServeHTTP(httptest.NewRecorder(), req)
+	span.End()
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 1)
+	attrs := map[string]string{}
+	for _, a := range spans[0].Attributes {
+		attrs[string(a.Key)] = a.Value.AsString()
+	}
+	assert.Equal(t, "HTTP/1.1", attrs["network.protocol.version"])
+	assert.Equal(t, "false", attrs["http.connection.reused"])
+}
@@ -0,0 +1,397 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+/*
+Package echorequestsizebreakdown provides middleware enforcing per-field and per-file size limits and part count limit
+for `multipart/form-data` requests and exposing size breakdown of the request to handlers and Prometheus metrics.
+
+Unlike body limit middleware, which only limits total body size, limits can distinguish a large file field from abuse
+of a text field. Request body is read and validated before the handler is called and is spooled (in memory or to a
+temporary file when larger than Config.SpoolMemory) so handlers can parse the form as usual with `c.FormFile` or
+`c.MultipartForm`. Total size of spooled body is limited by Config.MaxBodySize.
+
+Example:
+```
+package main
+
+import (
+
+	"net/http"
+
+	"github.com/labstack/echo-contrib/echorequestsizebreakdown"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+
+)
+
+	func main() {
+		e := echo.New()
+		e.Use(echorequestsizebreakdown.MiddlewareWithConfig(echorequestsizebreakdown.Config{
+			MaxBodySize:  20 << 20, // 20MB
+			MaxParts:     20,
+			MaxFieldSize: 64 << 10, // 64KB
+			MaxFileSize:  5 << 20,  // 5MB
+			PartLimits: map[string]int64{
+				"video": 15 << 20, // 15MB
+			},
+			Registerer: prometheus.DefaultRegisterer,
+		}))
+
+		e.POST("/upload", func(c echo.Context) error {
+			b := echorequestsizebreakdown.FromContext(c)
+			c.Logger().Infof("received %d parts, %d bytes in files", len(b.Parts), b.FileBytes)
+			file, err := c.FormFile("video")
+			if err != nil {
+				return err
+			}
+			return c.String(http.StatusOK, file.Filename)
+		})
+
+		e.Logger.Fatal(e.Start(":1323"))
+	}
+
+```
+*/
+package echorequestsizebreakdown
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	contextKey       = "_echorequestsizebreakdown"
+	defaultSubsystem = "echo_multipart"
+)
+
+var (
+	// ErrTooManyParts is returned when request has more parts than Config.MaxParts.
+	ErrTooManyParts = echo.NewHTTPError(http.StatusRequestEntityTooLarge, "too many multipart parts")
+	// ErrMalformedMultipart is returned when request body is not valid multipart content.
+	ErrMalformedMultipart = echo.NewHTTPError(http.StatusBadRequest, "malformed multipart body")
+)
+
+// PartSize is size of single multipart part.
+type PartSize struct {
+	// Name is form field name of the part.
+	Name string
+	// Filename is file name of file parts and empty for value fields.
+	Filename string
+	// Size is size of part content in bytes.
+	Size int64
+}
+
+// Breakdown is size breakdown of multipart request.
+type Breakdown struct {
+	// Parts are sizes of parts in order of appearance.
+	Parts []PartSize
+	// FieldBytes is total size of value fields in bytes.
+	FieldBytes int64
+	// FileBytes is total size of files in bytes.
+	FileBytes int64
+}
+
+// Config defines the config for multipart size breakdown middleware.
+type Config struct {
+	// Skipper defines a function to skip middleware.
+	Skipper middleware.Skipper
+
+	// MaxParts limits number of parts of the request.
+	// Defaults to: 1000
+	MaxParts int
+
+	// MaxBodySize limits total size of multipart request body in bytes. Body is spooled to temporary file when larger
+	// than SpoolMemory, so this limit also caps disk usage of single request. Negative value disables the limit.
+	// Defaults to: 32MB
+	MaxBodySize int64
+
+	// MaxFieldSize limits size of single value (non-file) field in bytes. Negative value disables the limit.
+	// Defaults to: 1MB
+	MaxFieldSize int64
+
+	// MaxFileSize limits size of single file in bytes. Zero means no limit other than MaxBodySize.
+	// Optional.
+	MaxFileSize int64
+
+	// PartLimits override MaxFieldSize or MaxFileSize for parts with given field name.
+	// Optional.
+	PartLimits map[string]int64
+
+	// SpoolMemory is size of request body kept in memory for handler. Larger bodies are spooled to temporary file that
+	// is removed when request completes.
+	// Defaults to: 10MB
+	SpoolMemory int64
+
+	// Registerer is used to register part size histogram, parts per request histogram and rejected requests counter.
+	// When nil no metrics are registered.
+	// Optional.
+	Registerer prometheus.Registerer
+
+	// Namespace is components of the fully-qualified name of the Metric (created by joining Namespace,Subsystem and Name components with "_")
+	// Optional
+	Namespace string
+
+	// Subsystem is components of the fully-qualified name of the Metric (created by joining Namespace,Subsystem and Name components with "_")
+	// Defaults to: "echo_multipart"
+	Subsystem string
+}
+
+// DefaultConfig is the default multipart size breakdown middleware config.
+var DefaultConfig = Config{
+	Skipper:      middleware.DefaultSkipper,
+	MaxParts:     1000,
+	MaxBodySize:  32 << 20,
+	MaxFieldSize: 1 << 20,
+	SpoolMemory:  10 << 20,
+}
+
+// Middleware returns multipart size breakdown middleware with default config.
+func Middleware() echo.MiddlewareFunc {
+	return MiddlewareWithConfig(DefaultConfig)
+}
+
+// MiddlewareWithConfig returns multipart size breakdown middleware with config or panics on invalid configuration.
+// See: `Middleware()`.
+func MiddlewareWithConfig(config Config) echo.MiddlewareFunc {
+	mw, err := config.ToMiddleware()
+	if err != nil {
+		panic(err)
+	}
+	return mw
+}
+
+// ToMiddleware converts configuration to middleware or returns an error.
+func (config Config) ToMiddleware() (echo.MiddlewareFunc, error) {
+	if config.Skipper == nil {
+		config.Skipper = DefaultConfig.Skipper
+	}
+	if config.MaxParts == 0 {
+		config.MaxParts = DefaultConfig.MaxParts
+	}
+	if config.MaxBodySize == 0 {
+		config.MaxBodySize = DefaultConfig.MaxBodySize
+	}
+	if config.MaxFieldSize == 0 {
+		config.MaxFieldSize = DefaultConfig.MaxFieldSize
+	}
+	if config.SpoolMemory == 0 {
+		config.SpoolMemory = DefaultConfig.SpoolMemory
+	}
+	if config.Subsystem == "" {
+		config.Subsystem = defaultSubsystem
+	}
+	if config.MaxParts < 0 || config.MaxFileSize < 0 || config.SpoolMemory < 0 {
+		return nil, errors.New("echorequestsizebreakdown: limits can not be negative")
+	}
+	for name, limit := range config.PartLimits {
+		if limit <= 0 {
+			return nil, fmt.Errorf("echorequestsizebreakdown: limit of part `%v` must be positive", name)
+		}
+	}
+
+	partSize := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: config.Namespace,
+			Subsystem: config.Subsystem,
+			Name:      "part_size_bytes",
+			Help:      "The multipart part sizes in bytes, partitioned by kind (field or file).",
+			Buckets:   prometheus.ExponentialBuckets(64, 4, 10), // 64B to 16MB
+		},
+		[]string{"kind"},
+	)
+	parts := prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: config.Namespace,
+			Subsystem: config.Subsystem,
+			Name:      "parts_per_request",
+			Help:      "The number of parts of multipart requests.",
+			Buckets:   []float64{1, 2, 5, 10, 20, 50, 100, 500, 1000},
+		},
+	)
+	rejected := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: config.Namespace,
+			Subsystem: config.Subsystem,
+			Name:      "rejected_total",
+			Help:      "How many multipart requests were rejected, partitioned by exceeded limit.",
+		},
+		[]string{"limit"},
+	)
+	if config.Registerer != nil {
+		for _, c := range []prometheus.Collector{partSize, parts, rejected} {
+			if err := config.Registerer.Register(c); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+			req := c.Request()
+			mediaType, params, err := mime.ParseMediaType(req.Header.Get(echo.HeaderContentType))
+			if err != nil || mediaType != echo.MIMEMultipartForm || req.Body == nil {
+				return next(c)
+			}
+
+			s := &spool{memory: config.SpoolMemory}
+			defer s.close()
+			src := io.Reader(req.Body)
+			var limited *io.LimitedReader
+			if config.MaxBodySize > 0 {
+				limited = &io.LimitedReader{R: req.Body, N: config.MaxBodySize + 1}
+				src = limited
+			}
+			bodyTooLarge := func() error {
+				rejected.WithLabelValues("body").Inc()
+				return echo.NewHTTPError(http.StatusRequestEntityTooLarge,
+					fmt.Sprintf("multipart body exceeds %d bytes", config.MaxBodySize))
+			}
+
+			b, limit, err := config.breakdown(multipart.NewReader(io.TeeReader(src, s), params["boundary"]))
+			if limited != nil && limited.N == 0 {
+				return bodyTooLarge()
+			}
+			if err != nil {
+				if limit != "" {
+					rejected.WithLabelValues(limit).Inc()
+				}
+				return err
+			}
+			// multipart reader stops at the closing boundary, the rest of the body is kept for the handler
+			if _, err := io.Copy(s, src); err != nil {
+				return ErrMalformedMultipart.WithInternal(err)
+			}
+			if limited != nil && limited.N == 0 {
+				return bodyTooLarge()
+			}
+			body, err := s.reader()
+			if err != nil {
+				return err
+			}
+
+			parts.Observe(float64(len(b.Parts)))
+			for _, p := range b.Parts {
+				kind := "field"
+				if p.Filename != "" {
+					kind = "file"
+				}
+				partSize.WithLabelValues(kind).Observe(float64(p.Size))
+			}
+
+			originalBody := req.Body
+			req.Body = io.NopCloser(body)
+			defer func() {
+				req.Body = originalBody
+			}()
+			c.Set(contextKey, b)
+			return next(c)
+		}
+	}, nil
+}
+
+// breakdown reads all parts and checks limits. Returns name of exceeded limit (`parts`, `field` or `file`) with error.
+// Exceeded MaxBodySize (`body`) is checked by the caller.
+func (config Config) breakdown(mr *multipart.Reader) (*Breakdown, string, error) {
+	b := &Breakdown{}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, "", ErrMalformedMultipart.WithInternal(err)
+		}
+		if len(b.Parts) >= config.MaxParts {
+			return nil, "parts", ErrTooManyParts
+		}
+
+		ps := PartSize{Name: part.FormName(), Filename: part.FileName()}
+		limitName, limit := "field", config.MaxFieldSize
+		if ps.Filename != "" {
+			limitName, limit = "file", config.MaxFileSize
+		}
+		if l, ok := config.PartLimits[ps.Name]; ok {
+			limit = l
+		}
+		r := io.Reader(part)
+		if limit > 0 {
+			r = io.LimitReader(part, limit+1)
+		}
+		ps.Size, err = io.Copy(io.Discard, r)
+		if err != nil {
+			return nil, "", ErrMalformedMultipart.WithInternal(err)
+		}
+		if limit > 0 && ps.Size > limit {
+			return nil, limitName, echo.NewHTTPError(http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("multipart %s `%s` exceeds %d bytes", limitName, ps.Name, limit))
+		}
+
+		b.Parts = append(b.Parts, ps)
+		if ps.Filename != "" {
+			b.FileBytes += ps.Size
+		} else {
+			b.FieldBytes += ps.Size
+		}
+	}
+	return b, "", nil
+}
+
+// FromContext returns size breakdown of the request or nil when request is not multipart or middleware was skipped.
+func FromContext(c echo.Context) *Breakdown {
+	b, _ := c.Get(contextKey).(*Breakdown)
+	return b
+}
+
+// spool buffers written data in memory and moves it to temporary file when it grows larger than memory.
+type spool struct {
+	memory int64
+	buf    bytes.Buffer
+	file   *os.File
+}
+
+func (s *spool) Write(p []byte) (int, error) {
+	if s.file == nil && int64(s.buf.Len()+len(p)) > s.memory {
+		f, err := os.CreateTemp("", "echorequestsizebreakdown-")
+		if err != nil {
+			return 0, err
+		}
+		s.file = f
+		if _, err := s.buf.WriteTo(f); err != nil {
+			return 0, err
+		}
+	}
+	if s.file != nil {
+		return s.file.Write(p)
+	}
+	return s.buf.Write(p)
+}
+
+func (s *spool) reader() (io.Reader, error) {
+	if s.file == nil {
+		return &s.buf, nil
+	}
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return s.file, nil
+}
+
+func (s *spool) close() {
+	if s.file != nil {
+		s.file.Close()
+		os.Remove(s.file.Name())
+	}
+}
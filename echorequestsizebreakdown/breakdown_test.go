@@ -0,0 +1,191 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echorequestsizebreakdown
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+type part struct {
+	name     string
+	filename string
+	content  string
+}
+
+func newMultipartRequest(parts ...part) *http.Request {
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	for _, p := range parts {
+		if p.filename != "" {
+			fw, _ := w.CreateFormFile(p.name, p.filename)
+			fw.Write([]byte(p.content))
+		} else {
+			w.WriteField(p.name, p.content)
+		}
+	}
+	w.Close()
+	req := httptest.NewRequest(http.MethodPost, "/upload", body)
+	req.Header.Set(echo.HeaderContentType, w.FormDataContentType())
+	return req
+}
+
+func TestConfig_ToMiddleware_invalidConfig(t *testing.T) {
+	_, err := Config{MaxFileSize: -1}.ToMiddleware()
+	assert.EqualError(t, err, "echorequestsizebreakdown: limits can not be negative")
+
+	_, err = Config{PartLimits: map[string]int64{"avatar": 0}}.ToMiddleware()
+	assert.EqualError(t, err, "echorequestsizebreakdown: limit of part `avatar` must be positive")
+}
+
+func TestMiddlewareWithConfig(t *testing.T) {
+	var testCases = []struct {
+		name         string
+		whenConfig   Config
+		whenParts    []part
+		whenSpool    int64
+		expectStatus int
+		expectBody   string
+	}{
+		{
+			name:         "ok",
+			whenParts:    []part{{name: "title", content: "cat"}, {name: "photo", filename: "cat.jpg", content: "0123456789"}},
+			expectStatus: http.StatusOK,
+			expectBody:   "title=cat photo=cat.jpg parts=2 fields=3 files=10",
+		},
+		{
+			name:         "ok, spooled to file",
+			whenConfig:   Config{SpoolMemory: 16},
+			whenParts:    []part{{name: "title", content: "cat"}, {name: "photo", filename: "cat.jpg", content: strings.Repeat("x", 100)}},
+			expectStatus: http.StatusOK,
+			expectBody:   "title=cat photo=cat.jpg parts=2 fields=3 files=100",
+		},
+		{
+			name:         "nok, field too large",
+			whenConfig:   Config{MaxFieldSize: 2},
+			whenParts:    []part{{name: "title", content: "cat"}},
+			expectStatus: http.StatusRequestEntityTooLarge,
+			expectBody:   `{"message":"multipart field ` + "`title`" + ` exceeds 2 bytes"}` + "\n",
+		},
+		{
+			name:         "nok, file too large",
+			whenConfig:   Config{MaxFileSize: 5},
+			whenParts:    []part{{name: "photo", filename: "cat.jpg", content: "0123456789"}},
+			expectStatus: http.StatusRequestEntityTooLarge,
+			expectBody:   `{"message":"multipart file ` + "`photo`" + ` exceeds 5 bytes"}` + "\n",
+		},
+		{
+			name:         "ok, part limit overrides file limit",
+			whenConfig:   Config{MaxFileSize: 5, PartLimits: map[string]int64{"photo": 10}},
+			whenParts:    []part{{name: "title", content: "cat"}, {name: "photo", filename: "cat.jpg", content: "0123456789"}},
+			expectStatus: http.StatusOK,
+			expectBody:   "title=cat photo=cat.jpg parts=2 fields=3 files=10",
+		},
+		{
+			name:         "ok, field limit disabled",
+			whenConfig:   Config{MaxFieldSize: -1},
+			whenParts:    []part{{name: "note", content: strings.Repeat("x", 2<<20)}, {name: "photo", filename: "cat.jpg", content: "0123456789"}},
+			expectStatus: http.StatusOK,
+			expectBody:   "title= photo=cat.jpg parts=2 fields=2097152 files=10",
+		},
+		{
+			name:         "nok, body too large",
+			whenConfig:   Config{MaxBodySize: 100},
+			whenParts:    []part{{name: "photo", filename: "cat.jpg", content: strings.Repeat("x", 200)}},
+			expectStatus: http.StatusRequestEntityTooLarge,
+			expectBody:   `{"message":"multipart body exceeds 100 bytes"}` + "\n",
+		},
+		{
+			name:         "nok, too many parts",
+			whenConfig:   Config{MaxParts: 1},
+			whenParts:    []part{{name: "title", content: "cat"}, {name: "photo", filename: "cat.jpg", content: "0123456789"}},
+			expectStatus: http.StatusRequestEntityTooLarge,
+			expectBody:   `{"message":"too many multipart parts"}` + "\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(MiddlewareWithConfig(tc.whenConfig))
+			e.POST("/upload", func(c echo.Context) error {
+				file, err := c.FormFile("photo")
+				if err != nil {
+					return err
+				}
+				b := FromContext(c)
+				return c.String(http.StatusOK, fmt.Sprintf("title=%s photo=%s parts=%d fields=%d files=%d",
+					c.FormValue("title"), file.Filename, len(b.Parts), b.FieldBytes, b.FileBytes))
+			})
+			rec := httptest.NewRecorder()
+
+			e.ServeHTTP(rec, newMultipartRequest(tc.whenParts...))
+
+			assert.Equal(t, tc.expectStatus, rec.Code)
+			assert.Equal(t, tc.expectBody, rec.Body.String())
+		})
+	}
+}
+
+func TestMiddleware_notMultipart(t *testing.T) {
+	e := echo.New()
+	e.Use(Middleware())
+	e.POST("/upload", func(c echo.Context) error {
+		body, _ := io.ReadAll(c.Request().Body)
+		assert.Nil(t, FromContext(c))
+		return c.String(http.StatusOK, string(body))
+	})
+	req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(`{"a":1}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, `{"a":1}`, rec.Body.String())
+}
+
+func TestMiddleware_malformed(t *testing.T) {
+	e := echo.New()
+	e.Use(Middleware())
+	e.POST("/upload", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+	req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("--xyz\r\nbroken"))
+	req.Header.Set(echo.HeaderContentType, "multipart/form-data; boundary=xyz")
+	rec := httptest.NewRecorder()
+
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestMiddlewareWithConfig_metrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(Config{MaxFieldSize: 5, Registerer: reg}))
+	e.POST("/upload", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), newMultipartRequest(part{name: "title", content: "cat"}, part{name: "photo", filename: "a.jpg", content: "1234"}))
+	e.ServeHTTP(httptest.NewRecorder(), newMultipartRequest(part{name: "title", content: "too long"}))
+
+	expect := `
+# HELP echo_multipart_rejected_total How many multipart requests were rejected, partitioned by exceeded limit.
+# TYPE echo_multipart_rejected_total counter
+echo_multipart_rejected_total{limit="field"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expect), "echo_multipart_rejected_total"))
+	assert.Equal(t, 2, testutil.CollectAndCount(reg, "echo_multipart_part_size_bytes"))
+}
@@ -0,0 +1,309 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+/*
+Package echosecretsredact provides redaction of sensitive data (credentials, tokens, PII) in headers, bodies and free
+text so sensitive data handling is configured once and shared by middlewares that record request data (body dumps in
+tracing, audit logs, panic reports).
+
+Redactor methods have the same signatures as hooks of tracing middlewares, i.e. `Redactor.Header` can be used as
+`jaegertracing.TraceConfig.HeaderTagRedactor` and `Redactor.Body` as `BodySanitizer` of `jaegertracing` and
+`oteltracing` configs.
+
+Example:
+```
+package main
+
+import (
+
+	"regexp"
+
+	"github.com/labstack/echo-contrib/echosecretsredact"
+	"github.com/labstack/echo-contrib/oteltracing"
+	"github.com/labstack/echo/v4"
+
+)
+
+	func main() {
+		e := echo.New()
+		redactor := echosecretsredact.MustNew(echosecretsredact.Config{
+			Fields:   []string{"password", "user.ssn", "cards.*.number"},
+			Patterns: []*regexp.Regexp{regexp.MustCompile(`\b\d{13,19}\b`)},
+		})
+
+		e.Use(oteltracing.TraceWithConfig(oteltracing.TraceConfig{
+			IsBodyDump:    true,
+			BodySanitizer: redactor.Body,
+		}))
+
+		e.Logger.Fatal(e.Start(":1323"))
+	}
+
+```
+*/
+package echosecretsredact
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// DefaultReplacement replaces redacted values.
+const DefaultReplacement = "[REDACTED]"
+
+const truncatedSuffix = "...[TRUNCATED]"
+
+// DefaultHeaders are headers redacted by default.
+var DefaultHeaders = []string{
+	echo.HeaderAuthorization,
+	echo.HeaderCookie,
+	echo.HeaderSetCookie,
+	"Proxy-Authorization",
+	"X-Api-Key",
+	echo.HeaderXCSRFToken,
+}
+
+// Config defines the config for Redactor.
+type Config struct {
+	// Headers is deny-list of header names (case-insensitive) whose values are replaced entirely.
+	// Defaults to: DefaultHeaders
+	Headers []string
+
+	// Fields are JSON and form field names redacted at any nesting level (i.e. `password`) or dot separated JSON paths
+	// from the document root (i.e. `user.ssn`). Path segment `*` matches any object key, arrays are traversed
+	// transparently so `cards.*.number` matches `{"cards":[{"number":"..."}]}`. Names are case-insensitive.
+	// Optional.
+	Fields []string
+
+	// Patterns are regular expressions whose matches are replaced in header values, string values of JSON and form
+	// bodies, bodies of any other content type and free text.
+	// Optional.
+	Patterns []*regexp.Regexp
+
+	// MaxValueLength truncates longer header values, JSON and form string values and free text.
+	// Optional. Zero means no limit.
+	MaxValueLength int
+
+	// MaxBodyLength truncates longer bodies after redaction.
+	// Optional. Zero means no limit.
+	MaxBodyLength int
+
+	// Replacement replaces redacted values and pattern matches.
+	// Defaults to: DefaultReplacement
+	Replacement string
+}
+
+// Redactor redacts sensitive data according to configuration. Redactor is safe for concurrent use.
+type Redactor struct {
+	headers        map[string]struct{}
+	names          map[string]struct{}
+	paths          [][]string
+	patterns       []*regexp.Regexp
+	maxValueLength int
+	maxBodyLength  int
+	replacement    string
+}
+
+// New returns Redactor or an error on invalid configuration.
+func New(config Config) (*Redactor, error) {
+	if config.Headers == nil {
+		config.Headers = DefaultHeaders
+	}
+	if config.Replacement == "" {
+		config.Replacement = DefaultReplacement
+	}
+	if config.MaxValueLength < 0 || config.MaxBodyLength < 0 {
+		return nil, errors.New("echosecretsredact: max length can not be negative")
+	}
+
+	r := &Redactor{
+		headers:        make(map[string]struct{}, len(config.Headers)),
+		names:          make(map[string]struct{}),
+		maxValueLength: config.MaxValueLength,
+		maxBodyLength:  config.MaxBodyLength,
+		replacement:    config.Replacement,
+	}
+	for _, h := range config.Headers {
+		r.headers[http.CanonicalHeaderKey(h)] = struct{}{}
+	}
+	for _, f := range config.Fields {
+		f = strings.ToLower(strings.TrimSpace(f))
+		if f == "" {
+			return nil, errors.New("echosecretsredact: field can not be empty")
+		}
+		if !strings.Contains(f, ".") {
+			r.names[f] = struct{}{}
+			continue
+		}
+		path := strings.Split(f, ".")
+		for _, segment := range path {
+			if segment == "" {
+				return nil, errors.New("echosecretsredact: invalid field path: " + f)
+			}
+		}
+		r.paths = append(r.paths, path)
+	}
+	for _, p := range config.Patterns {
+		if p == nil {
+			return nil, errors.New("echosecretsredact: pattern can not be nil")
+		}
+		r.patterns = append(r.patterns, p)
+	}
+	return r, nil
+}
+
+// MustNew returns Redactor or panics on invalid configuration.
+func MustNew(config Config) *Redactor {
+	r, err := New(config)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Header returns redacted value of header. Values of deny-listed headers are replaced entirely.
+func (r *Redactor) Header(name string, value string) string {
+	if _, ok := r.headers[http.CanonicalHeaderKey(name)]; ok {
+		return r.replacement
+	}
+	return r.String(value)
+}
+
+// Headers returns copy of headers with redacted values.
+func (r *Redactor) Headers(h http.Header) http.Header {
+	result := make(http.Header, len(h))
+	for name, values := range h {
+		redacted := make([]string, len(values))
+		for i, v := range values {
+			redacted[i] = r.Header(name, v)
+		}
+		result[name] = redacted
+	}
+	return result
+}
+
+// String returns text with pattern matches replaced and truncated to MaxValueLength. Can be used for panic messages,
+// error messages and log lines.
+func (r *Redactor) String(s string) string {
+	for _, p := range r.patterns {
+		s = p.ReplaceAllLiteralString(s, r.replacement)
+	}
+	return truncate(s, r.maxValueLength)
+}
+
+// Body returns redacted body of given content type. Fields are redacted in JSON and form-urlencoded bodies, patterns
+// are applied to bodies of all other content types (including unknown or missing content type). JSON body that can not
+// be parsed is replaced entirely.
+func (r *Redactor) Body(contentType string, body []byte) []byte {
+	if len(body) == 0 {
+		return body
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == echo.MIMEApplicationJSON || strings.HasSuffix(mediaType, "+json"):
+		body = r.json(body)
+	case mediaType == echo.MIMEApplicationForm:
+		body = r.form(body)
+	default:
+		for _, p := range r.patterns {
+			body = p.ReplaceAllLiteral(body, []byte(r.replacement))
+		}
+	}
+	if r.maxBodyLength > 0 && len(body) > r.maxBodyLength {
+		return append(body[:r.maxBodyLength:r.maxBodyLength], truncatedSuffix...)
+	}
+	return body
+}
+
+func (r *Redactor) json(body []byte) []byte {
+	d := json.NewDecoder(bytes.NewReader(body))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return []byte(r.replacement)
+	}
+	b, err := json.Marshal(r.value(v, nil))
+	if err != nil {
+		return []byte(r.replacement)
+	}
+	return b
+}
+
+// value redacts v found at path (lower cased object keys from document root).
+func (r *Redactor) value(v interface{}, path []string) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, fv := range t {
+			p := append(path[:len(path):len(path)], strings.ToLower(k))
+			if r.isRedactedField(p) {
+				t[k] = r.replacement
+				continue
+			}
+			t[k] = r.value(fv, p)
+		}
+	case []interface{}:
+		for i, iv := range t {
+			t[i] = r.value(iv, path)
+		}
+	case string:
+		return r.String(t)
+	}
+	return v
+}
+
+func (r *Redactor) isRedactedField(path []string) bool {
+	if _, ok := r.names[path[len(path)-1]]; ok {
+		return true
+	}
+	for _, p := range r.paths {
+		if matchPath(p, path) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchPath(pattern []string, path []string) bool {
+	if len(pattern) != len(path) {
+		return false
+	}
+	for i, segment := range pattern {
+		if segment != "*" && segment != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Redactor) form(body []byte) []byte {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return []byte(r.replacement)
+	}
+	for k, vs := range values {
+		_, redacted := r.names[strings.ToLower(k)]
+		for i, v := range vs {
+			if redacted {
+				vs[i] = r.replacement
+			} else {
+				vs[i] = r.String(v)
+			}
+		}
+	}
+	return []byte(values.Encode())
+}
+
+func truncate(s string, max int) string {
+	if max <= 0 || len(s) <= max {
+		return s
+	}
+	return s[:max] + truncatedSuffix
+}
@@ -0,0 +1,152 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echosecretsredact
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+var cardNumber = regexp.MustCompile(`\b\d{13,19}\b`)
+
+func TestNew_invalidConfig(t *testing.T) {
+	var testCases = []struct {
+		name      string
+		when      Config
+		expectErr string
+	}{
+		{name: "empty field", when: Config{Fields: []string{" "}}, expectErr: "echosecretsredact: field can not be empty"},
+		{name: "invalid path", when: Config{Fields: []string{"user..ssn"}}, expectErr: "echosecretsredact: invalid field path: user..ssn"},
+		{name: "nil pattern", when: Config{Patterns: []*regexp.Regexp{nil}}, expectErr: "echosecretsredact: pattern can not be nil"},
+		{name: "negative length", when: Config{MaxBodyLength: -1}, expectErr: "echosecretsredact: max length can not be negative"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New(tc.when)
+			assert.EqualError(t, err, tc.expectErr)
+		})
+	}
+}
+
+func TestRedactor_Header(t *testing.T) {
+	r := MustNew(Config{Patterns: []*regexp.Regexp{cardNumber}, MaxValueLength: 20})
+
+	assert.Equal(t, DefaultReplacement, r.Header("authorization", "Bearer token"))
+	assert.Equal(t, "card [REDACTED]", r.Header("X-Card", "card 4111111111111111"))
+	assert.Equal(t, "Mozilla/5.0 (X11; Li...[TRUNCATED]", r.Header("User-Agent", "Mozilla/5.0 (X11; Linux x86_64)"))
+
+	h := http.Header{"Cookie": {"session=abc"}, "Accept": {"*/*"}}
+	assert.Equal(t, http.Header{"Cookie": {DefaultReplacement}, "Accept": {"*/*"}}, r.Headers(h))
+	assert.Equal(t, "session=abc", h.Get("Cookie"))
+}
+
+func TestRedactor_Body(t *testing.T) {
+	r := MustNew(Config{
+		Fields:      []string{"Password", "user.ssn", "cards.*.number"},
+		Patterns:    []*regexp.Regexp{cardNumber},
+		Replacement: "***",
+	})
+
+	var testCases = []struct {
+		name        string
+		contentType string
+		body        string
+		expect      string
+	}{
+		{
+			name:        "json field at any level",
+			contentType: echo.MIMEApplicationJSONCharsetUTF8,
+			body:        `{"login":"bob","auth":{"PASSWORD":"secret"}}`,
+			expect:      `{"auth":{"PASSWORD":"***"},"login":"bob"}`,
+		},
+		{
+			name:        "json path from root",
+			contentType: echo.MIMEApplicationJSON,
+			body:        `{"user":{"ssn":"123","name":"bob"},"ssn":"456"}`,
+			expect:      `{"ssn":"456","user":{"name":"bob","ssn":"***"}}`,
+		},
+		{
+			name:        "json path with wildcard through arrays",
+			contentType: "application/vnd.api+json",
+			body:        `{"cards":{"main":[{"number":"x","brand":"visa"}]}}`,
+			expect:      `{"cards":{"main":[{"brand":"visa","number":"***"}]}}`,
+		},
+		{
+			name:        "json pattern in string values",
+			contentType: echo.MIMEApplicationJSON,
+			body:        `{"note":"pay with 4111111111111111","amount":10}`,
+			expect:      `{"amount":10,"note":"pay with ***"}`,
+		},
+		{
+			name:        "invalid json",
+			contentType: echo.MIMEApplicationJSON,
+			body:        `{"password":`,
+			expect:      `***`,
+		},
+		{
+			name:        "form",
+			contentType: echo.MIMEApplicationForm,
+			body:        `password=secret&note=4111111111111111&login=bob`,
+			expect:      `login=bob&note=%2A%2A%2A&password=%2A%2A%2A`,
+		},
+		{
+			name:        "text",
+			contentType: echo.MIMETextPlain,
+			body:        `card 4111111111111111`,
+			expect:      `card ***`,
+		},
+		{
+			name:        "xml",
+			contentType: echo.MIMEApplicationXMLCharsetUTF8,
+			body:        `<card>4111111111111111</card>`,
+			expect:      `<card>***</card>`,
+		},
+		{
+			name:        "ndjson",
+			contentType: "application/x-ndjson",
+			body:        "{\"card\":\"4111111111111111\"}\n",
+			expect:      "{\"card\":\"***\"}\n",
+		},
+		{
+			name:        "multipart",
+			contentType: echo.MIMEMultipartForm + "; boundary=x",
+			body:        "--x\r\n\r\n4111111111111111\r\n--x--",
+			expect:      "--x\r\n\r\n***\r\n--x--",
+		},
+		{
+			name:        "missing content type",
+			contentType: "",
+			body:        `card 4111111111111111`,
+			expect:      `card ***`,
+		},
+		{
+			name:        "binary",
+			contentType: echo.MIMEOctetStream,
+			body:        `4111111111111111`,
+			expect:      `***`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, string(r.Body(tc.contentType, []byte(tc.body))))
+		})
+	}
+}
+
+func TestRedactor_Body_maxBodyLength(t *testing.T) {
+	r := MustNew(Config{MaxBodyLength: 5})
+
+	assert.Equal(t, "01234...[TRUNCATED]", string(r.Body(echo.MIMEOctetStream, []byte("0123456789"))))
+	assert.Equal(t, "01234", string(r.Body(echo.MIMEOctetStream, []byte("01234"))))
+}
+
+func TestRedactor_String(t *testing.T) {
+	r := MustNew(Config{Patterns: []*regexp.Regexp{cardNumber}})
+
+	assert.Equal(t, "panic: invalid card [REDACTED]", r.String("panic: invalid card 4111111111111111"))
+}
@@ -0,0 +1,255 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+/*
+Package echoserverpush provides middleware and helpers sending `103 Early Hints` informational responses with `Link`
+preload and preconnect headers so browsers can start fetching page resources while the handler is still rendering.
+
+Hints are configured globally, per route or derived per request, and can be sent by handlers with SendEarlyHints.
+Links are also added to the final response for clients and proxies ignoring informational responses. Early hints are
+sent only when response is written by `net/http` server (HTTP/1.1 and HTTP/2), for other writers (i.e. test
+recorders) only `Link` headers of the final response are set.
+
+Example:
+```
+package main
+
+import (
+
+	"net/http"
+
+	"github.com/labstack/echo-contrib/echoserverpush"
+	"github.com/labstack/echo/v4"
+
+)
+
+	func main() {
+		e := echo.New()
+		e.Use(echoserverpush.MiddlewareWithConfig(echoserverpush.Config{
+			Hints: []echoserverpush.Hint{
+				echoserverpush.Preload("/static/app.css", "style"),
+				echoserverpush.Preconnect("https://fonts.gstatic.com"),
+			},
+			RouteHints: map[string][]echoserverpush.Hint{
+				"/products/:id": {echoserverpush.Preload("/static/product.js", "script")},
+			},
+		}))
+
+		e.GET("/products/:id", func(c echo.Context) error {
+			echoserverpush.SendEarlyHints(c, echoserverpush.Preload("/img/"+c.Param("id")+".webp", "image"))
+			// ... slow rendering
+			return c.HTML(http.StatusOK, "<html>...</html>")
+		})
+
+		e.Logger.Fatal(e.Start(":1323"))
+	}
+
+```
+*/
+package echoserverpush
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// Hint is single `Link` header value sent with early hints.
+type Hint struct {
+	// URL is URL of the resource or origin.
+	URL string
+	// Rel is link relation type (i.e. `preload`, `preconnect`, `modulepreload`).
+	// Defaults to: "preload"
+	Rel string
+	// As is destination of preloaded resource (i.e. `style`, `script`, `font`, `image`).
+	// Optional.
+	As string
+	// Type is MIME type of preloaded resource (i.e. `font/woff2`).
+	// Optional.
+	Type string
+	// CrossOrigin adds `crossorigin` attribute, required for fonts and other CORS fetched resources.
+	CrossOrigin bool
+}
+
+// Preload returns hint preloading resource at url as given destination.
+func Preload(url string, as string) Hint {
+	return Hint{URL: url, Rel: "preload", As: as}
+}
+
+// Preconnect returns hint opening connection to origin.
+func Preconnect(origin string) Hint {
+	return Hint{URL: origin, Rel: "preconnect"}
+}
+
+// String returns hint formatted as `Link` header value.
+func (h Hint) String() string {
+	rel := h.Rel
+	if rel == "" {
+		rel = "preload"
+	}
+	var sb strings.Builder
+	sb.WriteString("<" + h.URL + ">; rel=" + rel)
+	if h.As != "" {
+		sb.WriteString("; as=" + h.As)
+	}
+	if h.Type != "" {
+		sb.WriteString(`; type="` + h.Type + `"`)
+	}
+	if h.CrossOrigin {
+		sb.WriteString("; crossorigin")
+	}
+	return sb.String()
+}
+
+func (h Hint) validate() error {
+	if h.URL == "" {
+		return errors.New("echoserverpush: hint URL is required")
+	}
+	if strings.ContainsAny(h.URL+h.Rel+h.As+h.Type, "<>\"\r\n") {
+		return errors.New("echoserverpush: hint contains invalid characters")
+	}
+	return nil
+}
+
+// Config defines the config for early hints middleware.
+type Config struct {
+	// Skipper defines a function to skip middleware.
+	Skipper middleware.Skipper
+
+	// Hints are sent for every request.
+	// Optional.
+	Hints []Hint
+
+	// RouteHints are sent for requests matching route path (i.e. `/products/:id`) in addition to Hints.
+	// Optional.
+	RouteHints map[string][]Hint
+
+	// HintsFunc returns request specific hints in addition to Hints and RouteHints.
+	// Optional.
+	HintsFunc func(c echo.Context) []Hint
+
+	// DisableEarlyHints only adds `Link` headers to the final response without sending `103 Early Hints`.
+	DisableEarlyHints bool
+}
+
+// DefaultConfig is the default early hints middleware config.
+var DefaultConfig = Config{
+	Skipper: middleware.DefaultSkipper,
+}
+
+// MiddlewareWithConfig returns early hints middleware with config or panics on invalid configuration.
+func MiddlewareWithConfig(config Config) echo.MiddlewareFunc {
+	mw, err := config.ToMiddleware()
+	if err != nil {
+		panic(err)
+	}
+	return mw
+}
+
+// ToMiddleware converts configuration to middleware or returns an error.
+func (config Config) ToMiddleware() (echo.MiddlewareFunc, error) {
+	if config.Skipper == nil {
+		config.Skipper = DefaultConfig.Skipper
+	}
+	for _, h := range config.Hints {
+		if err := h.validate(); err != nil {
+			return nil, err
+		}
+	}
+	for _, hints := range config.RouteHints {
+		for _, h := range hints {
+			if err := h.validate(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			hints := append([]Hint{}, config.Hints...)
+			hints = append(hints, config.RouteHints[c.Path()]...)
+			if config.HintsFunc != nil {
+				for _, h := range config.HintsFunc(c) {
+					if h.validate() == nil {
+						hints = append(hints, h)
+					}
+				}
+			}
+			if len(hints) > 0 {
+				if config.DisableEarlyHints {
+					addLinks(c, hints)
+				} else {
+					SendEarlyHints(c, hints...)
+				}
+			}
+			return next(c)
+		}
+	}, nil
+}
+
+// SendEarlyHints adds hints as `Link` headers to the response and sends them with `103 Early Hints` informational
+// response. Returns false when early hints could not be sent because response is already committed, request is
+// HTTP/1.0 or response writer does not support informational responses. Invalid hints are ignored.
+func SendEarlyHints(c echo.Context, hints ...Hint) bool {
+	res := c.Response()
+	if res.Committed {
+		return false
+	}
+	if !addLinks(c, hints) {
+		return false
+	}
+	if !c.Request().ProtoAtLeast(1, 1) {
+		return false
+	}
+	w := informationalWriter(res.Writer)
+	if w == nil {
+		return false
+	}
+	// echo.Response.WriteHeader would mark response as committed so informational response is written directly
+	// to the innermost writer which keeps headers for the final response. Wrapping writers (i.e. by other middlewares)
+	// are bypassed as they could take the 103 status for the final one.
+	w.WriteHeader(http.StatusEarlyHints)
+	return true
+}
+
+// addLinks adds valid hints as `Link` headers and returns true when any was added.
+func addLinks(c echo.Context, hints []Hint) bool {
+	header := c.Response().Header()
+	added := false
+	for _, h := range hints {
+		if h.validate() != nil {
+			continue
+		}
+		header.Add("Link", h.String())
+		added = true
+	}
+	return added
+}
+
+// informationalWriter returns innermost response writer when it is implemented by `net/http` server which supports
+// writing 1xx responses since Go 1.19. Returns nil otherwise.
+func informationalWriter(w http.ResponseWriter) http.ResponseWriter {
+	for {
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			break
+		}
+		w = u.Unwrap()
+	}
+	t := reflect.TypeOf(w)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.PkgPath() != "net/http" {
+		return nil
+	}
+	return w
+}
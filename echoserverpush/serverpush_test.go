@@ -0,0 +1,203 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package echoserverpush
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
+	"sync"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHint_String(t *testing.T) {
+	var testCases = []struct {
+		name   string
+		when   Hint
+		expect string
+	}{
+		{name: "preload", when: Preload("/app.css", "style"), expect: "</app.css>; rel=preload; as=style"},
+		{name: "preconnect", when: Preconnect("https://cdn.example.com"), expect: "<https://cdn.example.com>; rel=preconnect"},
+		{name: "default rel", when: Hint{URL: "/app.js"}, expect: "</app.js>; rel=preload"},
+		{
+			name:   "font",
+			when:   Hint{URL: "/font.woff2", As: "font", Type: "font/woff2", CrossOrigin: true},
+			expect: `</font.woff2>; rel=preload; as=font; type="font/woff2"; crossorigin`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, tc.when.String())
+		})
+	}
+}
+
+func TestConfig_ToMiddleware_invalidHint(t *testing.T) {
+	_, err := Config{Hints: []Hint{{As: "style"}}}.ToMiddleware()
+	assert.EqualError(t, err, "echoserverpush: hint URL is required")
+
+	_, err = Config{RouteHints: map[string][]Hint{"/": {Preload("/a>b", "style")}}}.ToMiddleware()
+	assert.EqualError(t, err, "echoserverpush: hint contains invalid characters")
+}
+
+func TestMiddlewareWithConfig_earlyHints(t *testing.T) {
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(Config{
+		Hints:      []Hint{Preload("/app.css", "style")},
+		RouteHints: map[string][]Hint{"/products/:id": {Preload("/product.js", "script")}},
+		HintsFunc: func(c echo.Context) []Hint {
+			return []Hint{Preload("/img/"+c.Param("id")+".webp", "image")}
+		},
+	}))
+	e.GET("/products/:id", func(c echo.Context) error {
+		return c.String(http.StatusOK, "product")
+	})
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+
+	var mu sync.Mutex
+	var informational []int
+	var earlyLinks []string
+	trace := &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			mu.Lock()
+			defer mu.Unlock()
+			informational = append(informational, code)
+			earlyLinks = header.Values("Link")
+			return nil
+		},
+	}
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/products/42", nil)
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	res, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+
+	expectLinks := []string{
+		"</app.css>; rel=preload; as=style",
+		"</product.js>; rel=preload; as=script",
+		"</img/42.webp>; rel=preload; as=image",
+	}
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, expectLinks, res.Header.Values("Link"))
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []int{http.StatusEarlyHints}, informational)
+	assert.Equal(t, expectLinks, earlyLinks)
+}
+
+type statusRecordingWriter struct {
+	http.ResponseWriter
+	statuses []int
+}
+
+func (w *statusRecordingWriter) WriteHeader(code int) {
+	w.statuses = append(w.statuses, code)
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusRecordingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func TestMiddlewareWithConfig_earlyHintsBypassWrappingWriter(t *testing.T) {
+	e := echo.New()
+	var wrapper *statusRecordingWriter
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			wrapper = &statusRecordingWriter{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = wrapper
+			return next(c)
+		}
+	})
+	e.Use(MiddlewareWithConfig(Config{Hints: []Hint{Preload("/app.css", "style")}}))
+	e.GET("/", func(c echo.Context) error {
+		return c.String(http.StatusOK, "index")
+	})
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+
+	var mu sync.Mutex
+	var informational []int
+	trace := &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			mu.Lock()
+			defer mu.Unlock()
+			informational = append(informational, code)
+			return nil
+		},
+	}
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/", nil)
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	res, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, []int{http.StatusOK}, wrapper.statuses)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []int{http.StatusEarlyHints}, informational)
+}
+
+func TestMiddlewareWithConfig_unsupportedWriter(t *testing.T) {
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(Config{Hints: []Hint{Preload("/app.css", "style")}}))
+	var sent bool
+	e.GET("/", func(c echo.Context) error {
+		sent = SendEarlyHints(c, Preload("/app.js", "script"))
+		return c.String(http.StatusOK, "index")
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.False(t, sent)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{"</app.css>; rel=preload; as=style", "</app.js>; rel=preload; as=script"}, rec.Header().Values("Link"))
+}
+
+func TestMiddlewareWithConfig_disableEarlyHints(t *testing.T) {
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(Config{
+		Hints:             []Hint{Preconnect("https://cdn.example.com")},
+		DisableEarlyHints: true,
+	}))
+	e.GET("/", func(c echo.Context) error {
+		return c.String(http.StatusOK, "index")
+	})
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+
+	var informational int
+	trace := &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			informational++
+			return nil
+		},
+	}
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	res, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+
+	assert.Equal(t, 0, informational)
+	assert.Equal(t, "<https://cdn.example.com>; rel=preconnect", res.Header.Get("Link"))
+}
+
+func TestSendEarlyHints_committed(t *testing.T) {
+	e := echo.New()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	assert.NoError(t, c.NoContent(http.StatusOK))
+
+	assert.False(t, SendEarlyHints(c, Preload("/app.css", "style")))
+}
@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type checkFunc struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+func (c checkFunc) Name() string                    { return c.name }
+func (c checkFunc) Check(ctx context.Context) error { return c.fn(ctx) }
+
+// CheckFunc returns Checker calling fn.
+func CheckFunc(name string, fn func(ctx context.Context) error) Checker {
+	return checkFunc{name: name, fn: fn}
+}
+
+// Pinger is implemented by clients able to check their connectivity (i.e. *sql.DB).
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// PingCheck returns Checker pinging database or cache backend client.
+func PingCheck(name string, p Pinger) Checker {
+	return CheckFunc(name, p.PingContext)
+}
+
+// HTTPCheck returns Checker sending GET request to url and failing for non-2xx responses. When client is nil
+// http.DefaultClient is used.
+func HTTPCheck(name string, url string, client *http.Client) Checker {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return CheckFunc(name, func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		if res.StatusCode < 200 || res.StatusCode > 299 {
+			return fmt.Errorf("unexpected status code %d", res.StatusCode)
+		}
+		return nil
+	})
+}
+
+type timeoutCheck struct {
+	Checker
+	timeout time.Duration
+}
+
+func (c timeoutCheck) Check(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	return c.Checker.Check(ctx)
+}
+
+// WithTimeout returns Checker limiting duration of check to timeout.
+func WithTimeout(check Checker, timeout time.Duration) Checker {
+	return timeoutCheck{Checker: check, timeout: timeout}
+}
+
+type cachedCheck struct {
+	Checker
+	ttl time.Duration
+	now func() time.Time
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	err       error
+}
+
+// Cached returns Checker caching result of check for ttl. Concurrent calls wait for the running check.
+func Cached(check Checker, ttl time.Duration) Checker {
+	return &cachedCheck{Checker: check, ttl: ttl, now: time.Now}
+}
+
+func (c *cachedCheck) Check(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.checkedAt.IsZero() && c.now().Sub(c.checkedAt) < c.ttl {
+		return c.err
+	}
+	c.err = c.Checker.Check(ctx)
+	c.checkedAt = c.now()
+	return c.err
+}
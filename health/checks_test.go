@@ -0,0 +1,68 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type pingerFunc func(ctx context.Context) error
+
+func (f pingerFunc) PingContext(ctx context.Context) error { return f(ctx) }
+
+func TestPingCheck(t *testing.T) {
+	check := PingCheck("db", pingerFunc(func(ctx context.Context) error { return errors.New("bad connection") }))
+
+	assert.Equal(t, "db", check.Name())
+	assert.EqualError(t, check.Check(context.Background()), "bad connection")
+}
+
+func TestHTTPCheck(t *testing.T) {
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+	check := HTTPCheck("search", srv.URL, nil)
+
+	assert.NoError(t, check.Check(context.Background()))
+
+	status = http.StatusInternalServerError
+	assert.EqualError(t, check.Check(context.Background()), "unexpected status code 500")
+}
+
+func TestWithTimeout(t *testing.T) {
+	check := WithTimeout(CheckFunc("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}), 10*time.Millisecond)
+
+	assert.Equal(t, "slow", check.Name())
+	assert.ErrorIs(t, check.Check(context.Background()), context.DeadlineExceeded)
+}
+
+func TestCached(t *testing.T) {
+	calls := 0
+	check := Cached(CheckFunc("db", func(ctx context.Context) error {
+		calls++
+		return errors.New("down")
+	}), time.Minute).(*cachedCheck)
+	now := time.Unix(1_700_000_000, 0)
+	check.now = func() time.Time { return now }
+
+	assert.EqualError(t, check.Check(context.Background()), "down")
+	assert.EqualError(t, check.Check(context.Background()), "down")
+	assert.Equal(t, 1, calls)
+
+	now = now.Add(time.Minute)
+	assert.EqualError(t, check.Check(context.Background()), "down")
+	assert.Equal(t, 2, calls)
+}
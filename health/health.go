@@ -0,0 +1,199 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+/*
+Package health provides handlers for liveness, readiness and startup probes with JSON output.
+
+Checks are run concurrently, every check with its own timeout. Results of expensive checks can be cached with Cached
+so frequent probes do not overload dependencies. Handler responds with `200 OK` when all checks pass and with
+`503 Service Unavailable` otherwise.
+
+Example:
+```
+package main
+
+import (
+
+	"database/sql"
+	"time"
+
+	"github.com/labstack/echo-contrib/health"
+	"github.com/labstack/echo/v4"
+
+)
+
+	func main() {
+		e := echo.New()
+		db, _ := sql.Open("pgx", "postgres://localhost/app")
+
+		e.GET("/livez", health.NewHandler())
+		e.GET("/readyz", health.NewHandler(
+			health.Cached(health.PingCheck("db", db), 5*time.Second),
+			health.HTTPCheck("search", "http://search:9200/_cluster/health", nil),
+		))
+		e.GET("/startupz", health.NewHandlerWithConfig(health.HandlerConfig{
+			Checks:       []health.Checker{health.PingCheck("db", db)},
+			LatchSuccess: true,
+		}))
+
+		e.Logger.Fatal(e.Start(":1323"))
+	}
+
+```
+*/
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// StatusOK is status of passed check and handler response when all checks passed.
+	StatusOK = "ok"
+	// StatusFail is status of failed check and handler response when any check failed.
+	StatusFail = "fail"
+
+	defaultTimeout = 5 * time.Second
+)
+
+// Checker checks health of single dependency or component.
+type Checker interface {
+	// Name returns name the check result is reported under.
+	Name() string
+	// Check returns an error when component is not healthy.
+	Check(ctx context.Context) error
+}
+
+// CheckResult is result of single check in handler response.
+type CheckResult struct {
+	Status   string `json:"status"`
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Response is JSON body of handler response.
+type Response struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// HandlerConfig defines the config for health handler.
+type HandlerConfig struct {
+	// Checks are run for every request. Handler without checks always responds with `200 OK` (liveness probe).
+	Checks []Checker
+
+	// Timeout is maximum duration of single check. Checks with own timeout (see WithTimeout) use the shorter one.
+	// Defaults to: 5 seconds
+	Timeout time.Duration
+
+	// LatchSuccess makes handler respond with `200 OK` without running checks once all checks have passed (startup
+	// probe).
+	LatchSuccess bool
+
+	// HideErrors omits check error messages from response so internal details are not exposed.
+	HideErrors bool
+}
+
+// NewHandler returns handler running given checks.
+func NewHandler(checks ...Checker) echo.HandlerFunc {
+	return NewHandlerWithConfig(HandlerConfig{Checks: checks})
+}
+
+// NewHandlerWithConfig returns handler with config.
+// See `NewHandler()`.
+func NewHandlerWithConfig(config HandlerConfig) echo.HandlerFunc {
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+	var started atomic.Bool
+
+	return func(c echo.Context) error {
+		if config.LatchSuccess && started.Load() {
+			return c.JSON(http.StatusOK, Response{Status: StatusOK})
+		}
+
+		res := Run(c.Request().Context(), config.Timeout, config.Checks...)
+		if config.HideErrors {
+			for name, r := range res.Checks {
+				r.Error = ""
+				res.Checks[name] = r
+			}
+		}
+		if res.Status != StatusOK {
+			return c.JSON(http.StatusServiceUnavailable, res)
+		}
+		if config.LatchSuccess {
+			started.Store(true)
+		}
+		return c.JSON(http.StatusOK, res)
+	}
+}
+
+// Run runs checks concurrently, each limited by timeout, and returns combined result.
+func Run(ctx context.Context, timeout time.Duration, checks ...Checker) Response {
+	res := Response{Status: StatusOK, Checks: make(map[string]CheckResult, len(checks))}
+	if len(checks) == 0 {
+		return res
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range checks {
+		wg.Add(1)
+		go func(check Checker) {
+			defer wg.Done()
+			cr := runCheck(ctx, timeout, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			res.Checks[check.Name()] = cr
+			if cr.Status != StatusOK {
+				res.Status = StatusFail
+			}
+		}(check)
+	}
+	wg.Wait()
+	return res
+}
+
+func runCheck(ctx context.Context, timeout time.Duration, check Checker) (result CheckResult) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	defer func() {
+		result.Duration = time.Since(start).String()
+	}()
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("check panicked: %v", r)
+			}
+		}()
+		done <- check.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("check timed out after %v", timeout)
+	}
+	if err != nil {
+		return CheckResult{Status: StatusFail, Error: err.Error()}
+	}
+	return CheckResult{Status: StatusOK}
+}
@@ -0,0 +1,101 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func serve(h echo.HandlerFunc) *httptest.ResponseRecorder {
+	e := echo.New()
+	e.GET("/health", h)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	return rec
+}
+
+func TestNewHandler_liveness(t *testing.T) {
+	rec := serve(NewHandler())
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, rec.Body.String())
+}
+
+func TestNewHandler_readiness(t *testing.T) {
+	ok := CheckFunc("db", func(ctx context.Context) error { return nil })
+	failing := CheckFunc("cache", func(ctx context.Context) error { return errors.New("connection refused") })
+
+	rec := serve(NewHandler(ok))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"db":{"status":"ok"`)
+
+	rec = serve(NewHandler(ok, failing))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"status":"fail"`)
+	assert.Contains(t, rec.Body.String(), `"error":"connection refused"`)
+}
+
+func TestNewHandlerWithConfig_hideErrors(t *testing.T) {
+	failing := CheckFunc("cache", func(ctx context.Context) error { return errors.New("dial tcp 10.0.0.1:6379") })
+
+	rec := serve(NewHandlerWithConfig(HandlerConfig{Checks: []Checker{failing}, HideErrors: true}))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "10.0.0.1")
+}
+
+func TestNewHandlerWithConfig_latchSuccess(t *testing.T) {
+	var calls atomic.Int32
+	var healthy atomic.Bool
+	check := CheckFunc("migrations", func(ctx context.Context) error {
+		calls.Add(1)
+		if !healthy.Load() {
+			return errors.New("pending")
+		}
+		return nil
+	})
+	h := NewHandlerWithConfig(HandlerConfig{Checks: []Checker{check}, LatchSuccess: true})
+
+	assert.Equal(t, http.StatusServiceUnavailable, serve(h).Code)
+	healthy.Store(true)
+	assert.Equal(t, http.StatusOK, serve(h).Code)
+	healthy.Store(false)
+	assert.Equal(t, http.StatusOK, serve(h).Code)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestRun_timeout(t *testing.T) {
+	slow := CheckFunc("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	ignoresContext := CheckFunc("stuck", func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	res := Run(context.Background(), 20*time.Millisecond, slow, ignoresContext)
+
+	assert.Equal(t, StatusFail, res.Status)
+	assert.Equal(t, "check timed out after 20ms", res.Checks["slow"].Error)
+	assert.Equal(t, "check timed out after 20ms", res.Checks["stuck"].Error)
+}
+
+func TestRun_panic(t *testing.T) {
+	check := CheckFunc("panics", func(ctx context.Context) error { panic("boom") })
+
+	res := Run(context.Background(), time.Second, check)
+
+	assert.Equal(t, StatusFail, res.Status)
+	assert.Equal(t, "check panicked: boom", res.Checks["panics"].Error)
+}
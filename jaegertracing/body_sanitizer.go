@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package jaegertracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/url"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// RedactedValue replaces values of redacted fields in dumped bodies.
+const RedactedValue = "[REDACTED]"
+
+// RedactFields returns BodySanitizer that replaces values of given fields (case-insensitive) in JSON and
+// form-urlencoded bodies with RedactedValue. JSON fields are redacted at any nesting level. JSON body that can not be
+// parsed is replaced entirely with RedactedValue. Bodies of other content types are returned unchanged.
+func RedactFields(fields ...string) func(contentType string, body []byte) []byte {
+	redacted := make(map[string]struct{}, len(fields))
+	for _, f := range fields {
+		redacted[strings.ToLower(f)] = struct{}{}
+	}
+
+	return func(contentType string, body []byte) []byte {
+		if len(body) == 0 {
+			return body
+		}
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		switch {
+		case mediaType == echo.MIMEApplicationJSON || strings.HasSuffix(mediaType, "+json"):
+			return redactJSON(body, redacted)
+		case mediaType == echo.MIMEApplicationForm:
+			return redactForm(body, redacted)
+		}
+		return body
+	}
+}
+
+func redactJSON(body []byte, fields map[string]struct{}) []byte {
+	d := json.NewDecoder(bytes.NewReader(body))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return []byte(RedactedValue)
+	}
+	b, err := json.Marshal(redactValue(v, fields))
+	if err != nil {
+		return []byte(RedactedValue)
+	}
+	return b
+}
+
+func redactValue(v interface{}, fields map[string]struct{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, fv := range t {
+			if _, ok := fields[strings.ToLower(k)]; ok {
+				t[k] = RedactedValue
+				continue
+			}
+			t[k] = redactValue(fv, fields)
+		}
+	case []interface{}:
+		for i, iv := range t {
+			t[i] = redactValue(iv, fields)
+		}
+	}
+	return v
+}
+
+func redactForm(body []byte, fields map[string]struct{}) []byte {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return []byte(RedactedValue)
+	}
+	for k, vs := range values {
+		if _, ok := fields[strings.ToLower(k)]; ok {
+			for i := range vs {
+				vs[i] = RedactedValue
+			}
+		}
+	}
+	return []byte(values.Encode())
+}
@@ -0,0 +1,58 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package jaegertracing
+
+import (
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactFields(t *testing.T) {
+	var testCases = []struct {
+		name            string
+		whenContentType string
+		whenBody        string
+		expect          string
+	}{
+		{
+			name:            "json nested fields",
+			whenContentType: echo.MIMEApplicationJSONCharsetUTF8,
+			whenBody:        `{"user":"jon","Password":"secret","cards":[{"number":"4111","id":1}]}`,
+			expect:          `{"Password":"[REDACTED]","cards":[{"id":1,"number":"[REDACTED]"}],"user":"jon"}`,
+		},
+		{
+			name:            "json suffix media type",
+			whenContentType: "application/problem+json",
+			whenBody:        `{"password":"secret"}`,
+			expect:          `{"password":"[REDACTED]"}`,
+		},
+		{
+			name:            "invalid json is redacted entirely",
+			whenContentType: echo.MIMEApplicationJSON,
+			whenBody:        `{"password":"sec`,
+			expect:          RedactedValue,
+		},
+		{
+			name:            "form",
+			whenContentType: echo.MIMEApplicationForm,
+			whenBody:        `user=jon&password=secret`,
+			expect:          `password=%5BREDACTED%5D&user=jon`,
+		},
+		{
+			name:            "other content type unchanged",
+			whenContentType: echo.MIMETextPlain,
+			whenBody:        `password=secret`,
+			expect:          `password=secret`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sanitizer := RedactFields("password", "number")
+			result := string(sanitizer(tc.whenContentType, []byte(tc.whenBody)))
+			assert.Equal(t, tc.expect, result)
+		})
+	}
+}
@@ -0,0 +1,68 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package jaegertracing
+
+import (
+	"context"
+
+	"github.com/labstack/echo/v4"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+)
+
+// SpanFromContext returns span of the request stored in request context by Trace middleware. When request has no span
+// (middleware skipped the request or is not used) noop span is returned so result can be used without nil checks.
+func SpanFromContext(c echo.Context) opentracing.Span {
+	if sp := opentracing.SpanFromContext(c.Request().Context()); sp != nil {
+		return sp
+	}
+	return opentracing.NoopTracer{}.StartSpan("")
+}
+
+// StartChildSpanWithTags starts child span of the request span with given tags. Span is started with tracer of the
+// request span so it works with tracers not set as global tracer. User must call `defer sp.Finish()`.
+//
+// Example:
+//
+//	sp := jaegertracing.StartChildSpanWithTags(c, "db.query", opentracing.Tags{"db.statement": query})
+//	defer sp.Finish()
+func StartChildSpanWithTags(c echo.Context, name string, tags opentracing.Tags) opentracing.Span {
+	return startChildSpan(c.Request().Context(), name, tags)
+}
+
+// TraceFunctionWithContext runs fn within child span of the span found in ctx. Context passed to fn contains the child
+// span so nested calls create nested spans. Error returned by fn is logged to span and span is marked with
+// `error=true` tag.
+//
+// Example:
+//
+//	err := jaegertracing.TraceFunctionWithContext(c.Request().Context(), "queue.publish", func(ctx context.Context) error {
+//		return queue.Publish(ctx, msg)
+//	})
+func TraceFunctionWithContext(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	sp := startChildSpan(ctx, name, nil)
+	defer sp.Finish()
+
+	if err := fn(opentracing.ContextWithSpan(ctx, sp)); err != nil {
+		logError(sp, err)
+		ext.Error.Set(sp, true)
+		return err
+	}
+	return nil
+}
+
+// startChildSpan starts child span of the span found in ctx with tracer of that span. Span without parent is started
+// with global tracer.
+func startChildSpan(ctx context.Context, name string, tags opentracing.Tags) opentracing.Span {
+	tracer := opentracing.GlobalTracer()
+	opts := make([]opentracing.StartSpanOption, 0, 2)
+	if parent := opentracing.SpanFromContext(ctx); parent != nil {
+		tracer = parent.Tracer()
+		opts = append(opts, opentracing.ChildOf(parent.Context()))
+	}
+	if len(tags) > 0 {
+		opts = append(opts, tags)
+	}
+	return tracer.StartSpan(name, opts...)
+}
@@ -0,0 +1,89 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package jaegertracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpanFromContext(t *testing.T) {
+	tracer := mocktracer.New()
+	e := echo.New()
+	e.Use(TraceWithConfig(TraceConfig{
+		Tracer: tracer,
+		Skipper: func(c echo.Context) bool {
+			return c.Path() == "/skipped"
+		},
+	}))
+
+	var spans []opentracing.Span
+	handler := func(c echo.Context) error {
+		spans = append(spans, SpanFromContext(c))
+		return c.NoContent(http.StatusOK)
+	}
+	e.GET("/traced", handler)
+	e.GET("/skipped", handler)
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/traced", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/skipped", nil))
+
+	if assert.Len(t, spans, 2) {
+		assert.IsType(t, &mocktracer.MockSpan{}, spans[0])
+		assert.NotNil(t, spans[1]) // noop span
+		assert.NotPanics(t, func() { spans[1].SetTag("key", "value").Finish() })
+	}
+}
+
+func TestStartChildSpanWithTags(t *testing.T) {
+	tracer := mocktracer.New()
+	e := echo.New()
+	e.Use(TraceWithConfig(TraceConfig{Tracer: tracer}))
+	e.GET("/", func(c echo.Context) error {
+		sp := StartChildSpanWithTags(c, "db.query", opentracing.Tags{"db.statement": "SELECT 1"})
+		sp.Finish()
+		return c.NoContent(http.StatusOK)
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	finished := tracer.FinishedSpans()
+	if assert.Len(t, finished, 2) {
+		child, server := finished[0], finished[1]
+		assert.Equal(t, "db.query", child.OperationName)
+		assert.Equal(t, "SELECT 1", child.Tag("db.statement"))
+		assert.Equal(t, server.SpanContext.SpanID, child.ParentID)
+	}
+}
+
+func TestTraceFunctionWithContext(t *testing.T) {
+	tracer := mocktracer.New()
+	parent := tracer.StartSpan("parent")
+	ctx := opentracing.ContextWithSpan(context.Background(), parent)
+
+	err := TraceFunctionWithContext(ctx, "outer", func(ctx context.Context) error {
+		return TraceFunctionWithContext(ctx, "inner", func(ctx context.Context) error {
+			return errors.New("queue unavailable")
+		})
+	})
+	assert.EqualError(t, err, "queue unavailable")
+
+	finished := tracer.FinishedSpans()
+	if assert.Len(t, finished, 2) {
+		inner, outer := finished[0], finished[1]
+		assert.Equal(t, "inner", inner.OperationName)
+		assert.Equal(t, outer.SpanContext.SpanID, inner.ParentID)
+		assert.Equal(t, parent.Context().(mocktracer.MockSpanContext).SpanID, outer.ParentID)
+		assert.Equal(t, true, inner.Tag("error"))
+		assert.Equal(t, "queue unavailable", inner.Logs()[0].Fields[0].ValueString)
+	}
+}
@@ -0,0 +1,94 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package jaegertracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Canonical error codes (as used by gRPC and google.rpc.Code) set as `error.code` span tag.
+const (
+	CodeCancelled          = "CANCELLED"
+	CodeUnknown            = "UNKNOWN"
+	CodeInvalidArgument    = "INVALID_ARGUMENT"
+	CodeDeadlineExceeded   = "DEADLINE_EXCEEDED"
+	CodeNotFound           = "NOT_FOUND"
+	CodeAlreadyExists      = "ALREADY_EXISTS"
+	CodePermissionDenied   = "PERMISSION_DENIED"
+	CodeResourceExhausted  = "RESOURCE_EXHAUSTED"
+	CodeFailedPrecondition = "FAILED_PRECONDITION"
+	CodeAborted            = "ABORTED"
+	CodeOutOfRange         = "OUT_OF_RANGE"
+	CodeUnimplemented      = "UNIMPLEMENTED"
+	CodeInternal           = "INTERNAL"
+	CodeUnavailable        = "UNAVAILABLE"
+	CodeDataLoss           = "DATA_LOSS"
+	CodeUnauthenticated    = "UNAUTHENTICATED"
+)
+
+// DefaultStatusErrorCodes maps HTTP response status codes to canonical error codes following the mapping used by
+// gRPC-gateway and Google APIs.
+var DefaultStatusErrorCodes = map[int]string{
+	http.StatusBadRequest:                   CodeInvalidArgument,
+	http.StatusUnauthorized:                 CodeUnauthenticated,
+	http.StatusForbidden:                    CodePermissionDenied,
+	http.StatusNotFound:                     CodeNotFound,
+	http.StatusMethodNotAllowed:             CodeUnimplemented,
+	http.StatusRequestTimeout:               CodeDeadlineExceeded,
+	http.StatusConflict:                     CodeAborted,
+	http.StatusPreconditionFailed:           CodeFailedPrecondition,
+	http.StatusRequestEntityTooLarge:        CodeOutOfRange,
+	http.StatusRequestedRangeNotSatisfiable: CodeOutOfRange,
+	http.StatusTooManyRequests:              CodeResourceExhausted,
+	499:                                     CodeCancelled, // client closed request
+	http.StatusInternalServerError:          CodeInternal,
+	http.StatusNotImplemented:               CodeUnimplemented,
+	http.StatusBadGateway:                   CodeUnavailable,
+	http.StatusServiceUnavailable:           CodeUnavailable,
+	http.StatusGatewayTimeout:               CodeDeadlineExceeded,
+}
+
+// ErrorCoder is implemented by errors that carry their own canonical error code.
+type ErrorCoder interface {
+	ErrorCode() string
+}
+
+// DefaultErrorCode returns canonical error code for the request. Codes of errors implementing ErrorCoder take
+// precedence, then context cancellation and deadline errors, and finally response status code is mapped with
+// DefaultStatusErrorCodes. Unmapped 4xx and 5xx statuses result in `UNKNOWN`. Returns empty string for successful
+// requests. Can be used as TraceConfig.ErrorCodeFunc.
+func DefaultErrorCode(c echo.Context, err error) string {
+	return StatusErrorCodeFunc(DefaultStatusErrorCodes)(c, err)
+}
+
+// StatusErrorCodeFunc returns function resolving canonical error codes like DefaultErrorCode but with custom status
+// code mapping.
+func StatusErrorCodeFunc(statusCodes map[int]string) func(c echo.Context, err error) string {
+	return func(c echo.Context, err error) string {
+		if err != nil {
+			var coder ErrorCoder
+			if errors.As(err, &coder) {
+				return coder.ErrorCode()
+			}
+			if errors.Is(err, context.DeadlineExceeded) {
+				return CodeDeadlineExceeded
+			}
+			if errors.Is(err, context.Canceled) {
+				return CodeCancelled
+			}
+		}
+		status := c.Response().Status
+		if code, ok := statusCodes[status]; ok {
+			return code
+		}
+		if status >= http.StatusBadRequest {
+			return CodeUnknown
+		}
+		return ""
+	}
+}
@@ -0,0 +1,102 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package jaegertracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type codedError struct{ code string }
+
+func (e codedError) Error() string     { return "coded error" }
+func (e codedError) ErrorCode() string { return e.code }
+
+func TestDefaultErrorCode(t *testing.T) {
+	var testCases = []struct {
+		name       string
+		whenStatus int
+		whenErr    error
+		expect     string
+	}{
+		{name: "success", whenStatus: http.StatusOK, expect: ""},
+		{name: "redirect", whenStatus: http.StatusFound, expect: ""},
+		{name: "bad request", whenStatus: http.StatusBadRequest, whenErr: echo.ErrBadRequest, expect: CodeInvalidArgument},
+		{name: "not found", whenStatus: http.StatusNotFound, expect: CodeNotFound},
+		{name: "unavailable", whenStatus: http.StatusServiceUnavailable, expect: CodeUnavailable},
+		{name: "unmapped 4xx", whenStatus: http.StatusTeapot, expect: CodeUnknown},
+		{name: "deadline exceeded", whenStatus: http.StatusInternalServerError, whenErr: fmt.Errorf("query: %w", context.DeadlineExceeded), expect: CodeDeadlineExceeded},
+		{name: "canceled", whenStatus: http.StatusInternalServerError, whenErr: context.Canceled, expect: CodeCancelled},
+		{name: "error with code", whenStatus: http.StatusInternalServerError, whenErr: fmt.Errorf("wrap: %w", codedError{code: CodeDataLoss}), expect: CodeDataLoss},
+		{name: "plain error", whenStatus: http.StatusInternalServerError, whenErr: errors.New("boom"), expect: CodeInternal},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+			c.Response().Status = tc.whenStatus
+
+			assert.Equal(t, tc.expect, DefaultErrorCode(c, tc.whenErr))
+		})
+	}
+}
+
+func TestStatusErrorCodeFunc(t *testing.T) {
+	f := StatusErrorCodeFunc(map[int]string{http.StatusConflict: CodeAlreadyExists})
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+
+	c.Response().Status = http.StatusConflict
+	assert.Equal(t, CodeAlreadyExists, f(c, nil))
+
+	c.Response().Status = http.StatusBadGateway
+	assert.Equal(t, CodeUnknown, f(c, nil))
+}
+
+func TestTraceWithErrorCodeFunc(t *testing.T) {
+	var testCases = []struct {
+		name         string
+		whenHandler  echo.HandlerFunc
+		expectStatus uint16
+		expectCode   interface{}
+	}{
+		{
+			name: "deadline exceeded",
+			whenHandler: func(c echo.Context) error {
+				return fmt.Errorf("upstream call: %w", context.DeadlineExceeded)
+			},
+			expectStatus: 500,
+			expectCode:   CodeDeadlineExceeded,
+		},
+		{
+			name: "success is not tagged",
+			whenHandler: func(c echo.Context) error {
+				return c.String(http.StatusOK, "ok")
+			},
+			expectStatus: 200,
+			expectCode:   nil,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tracer := createMockTracer()
+			e := echo.New()
+			e.Use(TraceWithConfig(TraceConfig{
+				Tracer:        tracer,
+				ErrorCodeFunc: DefaultErrorCode,
+			}))
+			e.GET("/", tc.whenHandler)
+
+			e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+			assert.Equal(t, tc.expectStatus, tracer.currentSpan().getTag("http.status_code"))
+			assert.Equal(t, tc.expectCode, tracer.currentSpan().getTag("error.code"))
+		})
+	}
+}
@@ -4,6 +4,8 @@
 /*
 Package jaegertracing provides middleware to Opentracing using Jaeger.
 
+OpenTracing is deprecated. For OpenTelemetry native middleware see oteltracing package.
+
 Example:
 ```
 package main
@@ -29,13 +31,16 @@ package jaegertracing
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
 	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"reflect"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -71,6 +76,50 @@ type (
 
 		// OperationNameFunc composes operation name based on context. Can be used to override default naming
 		OperationNameFunc func(c echo.Context) string
+
+		// HeadersToTags lists request headers that are added to span as `http.request.header.<name>` tags.
+		// Header names are case-insensitive and tag names use lower case.
+		HeadersToTags []string
+
+		// ResponseHeadersToTags lists response headers that are added to span as `http.response.header.<name>` tags.
+		ResponseHeadersToTags []string
+
+		// HeaderTagRedactor is called for each captured header value and returns value that is used as tag value.
+		// Can be used to mask sensitive values (tokens, cookies).
+		// Optional.
+		HeaderTagRedactor func(name string, value string) string
+
+		// BodySanitizer is called with content type and body before request and response bodies are dumped to span logs.
+		// Returned value is logged instead of original body. Can be used to mask passwords and PII, see `RedactFields`.
+		// Optional.
+		BodySanitizer func(contentType string, body []byte) []byte
+
+		// BodyDumpRoutes limits body dump to given routes (route paths as registered, e.g. `/users/:id`).
+		// Optional. When empty bodies of all routes are dumped.
+		BodyDumpRoutes []string
+
+		// BodyDumpContentTypes limits body dump to request and response bodies of given media types (e.g. `application/json`).
+		// Optional. When empty bodies of all content types are dumped.
+		BodyDumpContentTypes []string
+
+		// IsError decides if span is marked with `error=true` tag. It is called after handler chain has returned and
+		// error (if any) was handled, so response status is already known. Error message is logged to span regardless.
+		// Can be used to not flag 4xx responses as failed spans.
+		// Defaults to: any error returned from handler chain marks span as failed.
+		IsError func(c echo.Context, err error) bool
+
+		// ErrorCodeFunc returns canonical error code (i.e. `DEADLINE_EXCEEDED`, `UNAVAILABLE`) that is added to span as
+		// `error.code` tag so traces can be aggregated by failure class. Empty code is not tagged. Like IsError it is
+		// called after error was handled. See DefaultErrorCode.
+		// Optional.
+		ErrorCodeFunc func(c echo.Context, err error) string
+
+		// Sampler decides if span is created for the request. When it returns false incoming trace context is still
+		// extracted and put to request context as non-recording span, so spans created by handler and outgoing requests
+		// remain part of the caller's trace, but no span is recorded for the request itself. Unlike Skipper it does not
+		// break trace propagation. Useful for high-volume routes like health checks and metrics scraping.
+		// Optional. Defaults to: span is created for every request.
+		Sampler func(c echo.Context) bool
 	}
 )
 
@@ -84,6 +133,7 @@ var (
 		LimitHTTPBody:     true,
 		LimitSize:         60_000,
 		OperationNameFunc: defaultOperationName,
+		IsError:           defaultIsError,
 	}
 )
 
@@ -119,6 +169,31 @@ func New(e *echo.Echo, skipper middleware.Skipper) io.Closer {
 	return closer
 }
 
+// NewWithConfig creates an Opentracing tracer from given Jaeger configuration and attaches it to Echo middleware
+// configured with traceConfig. Returned Closer flushes spans buffered by reporter. Stop the server with `Shutdown` so
+// spans of requests finished during graceful shutdown are flushed before process exits.
+func NewWithConfig(e *echo.Echo, jaegerConfig config.Configuration, traceConfig TraceConfig, options ...config.Option) (io.Closer, error) {
+	tracer, closer, err := jaegerConfig.NewTracer(options...)
+	if err != nil {
+		return nil, fmt.Errorf("could not initialize jaeger tracer: %w", err)
+	}
+
+	opentracing.SetGlobalTracer(tracer)
+	traceConfig.Tracer = tracer
+	e.Use(TraceWithConfig(traceConfig))
+	return closer, nil
+}
+
+// Shutdown gracefully shuts down the server (see `echo.Echo.Shutdown`) and then closes tracer closer, flushing spans
+// buffered by reporter. Closer is closed even when server shutdown fails, errors of both are returned.
+func Shutdown(ctx context.Context, e *echo.Echo, closer io.Closer) error {
+	err := e.Shutdown(ctx)
+	if cErr := closer.Close(); cErr != nil {
+		err = errors.Join(err, fmt.Errorf("failed to close jaeger tracer: %w", cErr))
+	}
+	return err
+}
+
 // Trace returns a Trace middleware.
 // Trace middleware traces http requests and reporting errors.
 func Trace(tracer opentracing.Tracer) echo.MiddlewareFunc {
@@ -143,6 +218,9 @@ func TraceWithConfig(config TraceConfig) echo.MiddlewareFunc {
 	if config.OperationNameFunc == nil {
 		config.OperationNameFunc = defaultOperationName
 	}
+	if config.IsError == nil {
+		config.IsError = defaultIsError
+	}
 
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
@@ -151,6 +229,10 @@ func TraceWithConfig(config TraceConfig) echo.MiddlewareFunc {
 			}
 
 			req := c.Request()
+			if config.Sampler != nil && !config.Sampler(c) {
+				return nextWithoutSpan(c, config.Tracer, next)
+			}
+
 			opname := config.OperationNameFunc(c)
 			realIP := c.RealIP()
 			requestID := getRequestID(c) // request-id generated by reverse-proxy
@@ -175,19 +257,20 @@ func TraceWithConfig(config TraceConfig) echo.MiddlewareFunc {
 			ext.Component.Set(sp, config.ComponentName)
 			sp.SetTag("client_ip", realIP)
 			sp.SetTag("request_id", requestID)
+			setHeaderTags(sp, "http.request.header.", req.Header, config.HeadersToTags, config.HeaderTagRedactor)
 
 			// Dump request & response body
 			var respDumper *responseDumper
-			if config.IsBodyDump {
+			isBodyDump := config.IsBodyDump && isAllowed(config.BodyDumpRoutes, c.Path())
+			if isBodyDump {
 				// request
 				reqBody := []byte{}
 				if c.Request().Body != nil {
 					reqBody, _ = io.ReadAll(c.Request().Body)
 
-					if config.LimitHTTPBody {
-						sp.LogKV("http.req.body", limitString(string(reqBody), config.LimitSize))
-					} else {
-						sp.LogKV("http.req.body", string(reqBody))
+					contentType := req.Header.Get(echo.HeaderContentType)
+					if isAllowedContentType(config.BodyDumpContentTypes, contentType) {
+						sp.LogKV("http.req.body", config.dumpBody(contentType, reqBody))
 					}
 				}
 
@@ -224,17 +307,25 @@ func TraceWithConfig(config TraceConfig) echo.MiddlewareFunc {
 
 			status := c.Response().Status
 			ext.HTTPStatusCode.Set(sp, uint16(status))
+			setHeaderTags(sp, "http.response.header.", c.Response().Header(), config.ResponseHeadersToTags, config.HeaderTagRedactor)
 
 			if err != nil {
 				logError(sp, err)
 			}
+			if config.IsError(c, err) {
+				sp.SetTag("error", true)
+			}
+			if config.ErrorCodeFunc != nil {
+				if code := config.ErrorCodeFunc(c, err); code != "" {
+					sp.SetTag("error.code", code)
+				}
+			}
 
 			// Dump response body
-			if config.IsBodyDump {
-				if config.LimitHTTPBody {
-					sp.LogKV("http.resp.body", limitString(respDumper.GetResponse(), config.LimitSize))
-				} else {
-					sp.LogKV("http.resp.body", respDumper.GetResponse())
+			if isBodyDump {
+				contentType := c.Response().Header().Get(echo.HeaderContentType)
+				if isAllowedContentType(config.BodyDumpContentTypes, contentType) {
+					sp.LogKV("http.resp.body", config.dumpBody(contentType, respDumper.GetResponseBytes()))
 				}
 			}
 
@@ -243,6 +334,56 @@ func TraceWithConfig(config TraceConfig) echo.MiddlewareFunc {
 	}
 }
 
+func setHeaderTags(sp opentracing.Span, prefix string, header http.Header, names []string, redactor func(name string, value string) string) {
+	for _, name := range names {
+		values := header.Values(name)
+		if len(values) == 0 {
+			continue
+		}
+		value := strings.Join(values, ",")
+		if redactor != nil {
+			value = redactor(name, value)
+		}
+		sp.SetTag(prefix+strings.ToLower(name), value)
+	}
+}
+
+// dumpBody sanitizes and limits body before it is logged to span.
+func (config TraceConfig) dumpBody(contentType string, body []byte) string {
+	if config.BodySanitizer != nil {
+		body = config.BodySanitizer(contentType, body)
+	}
+	if config.LimitHTTPBody {
+		return limitString(string(body), config.LimitSize)
+	}
+	return string(body)
+}
+
+func isAllowed(allowlist []string, value string) bool {
+	if len(allowlist) == 0 {
+		return true
+	}
+	for _, v := range allowlist {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func isAllowedContentType(allowlist []string, contentType string) bool {
+	if len(allowlist) == 0 {
+		return true
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	for _, v := range allowlist {
+		if strings.EqualFold(v, mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
 func limitString(str string, size int) string {
 	if len(str) > size {
 		return str[:size/2] + "\n---- skipped ----\n" + str[len(str)-size/2:]
@@ -251,6 +392,10 @@ func limitString(str string, size int) string {
 	return str
 }
 
+func defaultIsError(c echo.Context, err error) bool {
+	return err != nil
+}
+
 func logError(span opentracing.Span, err error) {
 	var httpError *echo.HTTPError
 	if errors.As(err, &httpError) {
@@ -258,7 +403,6 @@ func logError(span opentracing.Span, err error) {
 	} else {
 		span.LogKV("error.message", err.Error())
 	}
-	span.SetTag("error", true)
 }
 
 func getRequestID(ctx echo.Context) string {
@@ -350,3 +494,65 @@ func NewTracedRequest(method string, url string, body io.Reader, span opentracin
 
 	return req, err
 }
+
+// WrapHTTPClient returns copy of the client (http.DefaultClient when nil) that creates child span of the request span
+// and injects tracing headers for every outgoing request. Use it for requests made during handler execution.
+func WrapHTTPClient(c echo.Context, client *http.Client) *http.Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	wrapped := *client
+	wrapped.Transport = NewRoundTripper(c, client.Transport)
+	return &wrapped
+}
+
+// NewRoundTripper returns http.RoundTripper that creates child span of the request span and injects tracing headers
+// for every outgoing request before passing it to base (http.DefaultTransport when nil). Useful for clients that can
+// not be replaced, i.e. generated SDKs accepting only http.RoundTripper or already configured http.Client.
+func NewRoundTripper(c echo.Context, base http.RoundTripper) http.RoundTripper {
+	return &tracingRoundTripper{
+		parent: opentracing.SpanFromContext(c.Request().Context()),
+		base:   base,
+	}
+}
+
+type tracingRoundTripper struct {
+	parent opentracing.Span
+	base   http.RoundTripper
+}
+
+func (t *tracingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	tracer := opentracing.GlobalTracer()
+	var opts []opentracing.StartSpanOption
+	if t.parent != nil {
+		tracer = t.parent.Tracer()
+		opts = append(opts, opentracing.ChildOf(t.parent.Context()))
+	}
+	sp := tracer.StartSpan("HTTP "+req.Method, opts...)
+	defer sp.Finish()
+
+	ext.SpanKindRPCClient.Set(sp)
+	ext.HTTPUrl.Set(sp, req.URL.String())
+	ext.HTTPMethod.Set(sp, req.Method)
+
+	// RoundTripper must not modify the request, headers are injected into a clone
+	outReq := req.Clone(req.Context())
+	tracer.Inject(sp.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(outReq.Header))
+
+	resp, err := base.RoundTrip(outReq)
+	if err != nil {
+		logError(sp, err)
+		sp.SetTag("error", true)
+		return resp, err
+	}
+	ext.HTTPStatusCode.Set(sp, uint16(resp.StatusCode))
+	if resp.StatusCode >= http.StatusInternalServerError {
+		sp.SetTag("error", true)
+	}
+	return resp, nil
+}
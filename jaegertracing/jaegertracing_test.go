@@ -5,17 +5,25 @@ package jaegertracing
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
 	"github.com/opentracing/opentracing-go/log"
+	"github.com/opentracing/opentracing-go/mocktracer"
 	"github.com/stretchr/testify/assert"
+	"github.com/uber/jaeger-client-go"
+	"github.com/uber/jaeger-client-go/config"
 )
 
 // Mock opentracing.Span
@@ -387,3 +395,298 @@ func TestTraceWithCustomOperationName(t *testing.T) {
 	assert.Equal(t, true, tracer.currentSpan().isFinished())
 	assert.Equal(t, "HTTP GET /trace/{traceID}/spans/{spanID}", tracer.currentSpan().getOpName())
 }
+
+func TestTraceWithHeadersToTags(t *testing.T) {
+	tracer := createMockTracer()
+
+	e := echo.New()
+	e.Use(TraceWithConfig(TraceConfig{
+		Tracer:                tracer,
+		HeadersToTags:         []string{"X-Request-ID", "User-Agent", "Authorization", "X-Missing"},
+		ResponseHeadersToTags: []string{"Content-Type", "Set-Cookie"},
+		HeaderTagRedactor: func(name string, value string) string {
+			if name == "Authorization" || name == "Set-Cookie" {
+				return "[REDACTED]"
+			}
+			return value
+		},
+	}))
+
+	e.GET("/hello", func(c echo.Context) error {
+		c.Response().Header().Add("Set-Cookie", "session=secret")
+		return c.String(http.StatusOK, "world")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/hello", nil)
+	req.Header.Set("X-Request-ID", "abc")
+	req.Header.Add("User-Agent", "agent/1")
+	req.Header.Set("Authorization", "Bearer token")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	span := tracer.currentSpan()
+	assert.Equal(t, "abc", span.getTag("http.request.header.x-request-id"))
+	assert.Equal(t, "agent/1", span.getTag("http.request.header.user-agent"))
+	assert.Equal(t, "[REDACTED]", span.getTag("http.request.header.authorization"))
+	assert.Nil(t, span.getTag("http.request.header.x-missing"))
+	assert.Equal(t, echo.MIMETextPlainCharsetUTF8, span.getTag("http.response.header.content-type"))
+	assert.Equal(t, "[REDACTED]", span.getTag("http.response.header.set-cookie"))
+}
+
+// bufferingReporter buffers reported spans and flushes them on Close like remote reporter does.
+type bufferingReporter struct {
+	mu       sync.Mutex
+	buffered []string
+	flushed  []string
+}
+
+func (r *bufferingReporter) Report(span *jaeger.Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.buffered = append(r.buffered, span.OperationName())
+}
+
+func (r *bufferingReporter) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.flushed = append(r.flushed, r.buffered...)
+	r.buffered = nil
+}
+
+func TestShutdown_flushesSpans(t *testing.T) {
+	e := echo.New()
+	reporter := &bufferingReporter{}
+
+	closer, err := NewWithConfig(
+		e,
+		config.Configuration{ServiceName: "test", Sampler: &config.SamplerConfig{Type: "const", Param: 1}},
+		TraceConfig{},
+		config.Reporter(reporter),
+	)
+	assert.NoError(t, err)
+	defer opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+	e.GET("/users", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+	assert.Empty(t, reporter.flushed)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, Shutdown(ctx, e, closer))
+
+	reporter.mu.Lock()
+	defer reporter.mu.Unlock()
+	assert.Equal(t, []string{"HTTP GET URL: /users"}, reporter.flushed)
+}
+
+func TestNewWithConfig_invalidConfig(t *testing.T) {
+	closer, err := NewWithConfig(echo.New(), config.Configuration{}, TraceConfig{})
+
+	assert.Nil(t, closer)
+	assert.EqualError(t, err, "could not initialize jaeger tracer: no service name provided")
+}
+
+func TestTraceWithIsError(t *testing.T) {
+	tracer := createMockTracer()
+
+	e := echo.New()
+	e.Use(TraceWithConfig(TraceConfig{
+		Tracer: tracer,
+		IsError: func(c echo.Context, err error) bool {
+			return c.Response().Status >= http.StatusInternalServerError
+		},
+	}))
+
+	e.GET("/giveme404", func(c echo.Context) error {
+		return echo.ErrNotFound
+	})
+	e.GET("/giveme503", func(c echo.Context) error {
+		return c.String(http.StatusServiceUnavailable, "unavailable")
+	})
+
+	t.Run("4xx is not error", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/giveme404", nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		assert.Equal(t, uint16(404), tracer.currentSpan().getTag("http.status_code"))
+		assert.Nil(t, tracer.currentSpan().getTag("error"))
+		assert.Equal(t, "Not Found", tracer.currentSpan().getLog("error.message"))
+	})
+
+	t.Run("5xx without returned error is error", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/giveme503", nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		assert.Equal(t, uint16(503), tracer.currentSpan().getTag("http.status_code"))
+		assert.Equal(t, true, tracer.currentSpan().getTag("error"))
+	})
+}
+
+func TestTraceWithBodyDumpSanitizer(t *testing.T) {
+	var testCases = []struct {
+		name           string
+		givenConfig    TraceConfig
+		whenPath       string
+		expectReqBody  interface{}
+		expectRespBody interface{}
+	}{
+		{
+			name: "redacted",
+			givenConfig: TraceConfig{
+				BodySanitizer: RedactFields("password", "token"),
+			},
+			whenPath:       "/login",
+			expectReqBody:  `{"password":"[REDACTED]","user":"jon"}`,
+			expectRespBody: `{"token":"[REDACTED]"}`,
+		},
+		{
+			name: "route not in allowlist",
+			givenConfig: TraceConfig{
+				BodyDumpRoutes: []string{"/users/:id"},
+			},
+			whenPath:       "/login",
+			expectReqBody:  nil,
+			expectRespBody: nil,
+		},
+		{
+			name: "route in allowlist",
+			givenConfig: TraceConfig{
+				BodyDumpRoutes: []string{"/users/:id"},
+			},
+			whenPath:       "/users/1",
+			expectReqBody:  `{"user":"jon","password":"secret"}`,
+			expectRespBody: "{\"token\":\"abc\"}\n",
+		},
+		{
+			name: "content type not in allowlist",
+			givenConfig: TraceConfig{
+				BodyDumpContentTypes: []string{echo.MIMEApplicationXML},
+			},
+			whenPath:       "/login",
+			expectReqBody:  nil,
+			expectRespBody: nil,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tracer := createMockTracer()
+			config := tc.givenConfig
+			config.Tracer = tracer
+			config.IsBodyDump = true
+
+			e := echo.New()
+			e.Use(TraceWithConfig(config))
+			handler := func(c echo.Context) error {
+				return c.JSON(http.StatusOK, map[string]string{"token": "abc"})
+			}
+			e.POST("/login", handler)
+			e.POST("/users/:id", handler)
+
+			req := httptest.NewRequest(http.MethodPost, tc.whenPath, bytes.NewBufferString(`{"user":"jon","password":"secret"}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tc.expectReqBody, tracer.currentSpan().getLog("http.req.body"))
+			assert.Equal(t, tc.expectRespBody, tracer.currentSpan().getLog("http.resp.body"))
+		})
+	}
+}
+
+func TestWrapHTTPClient(t *testing.T) {
+	var receivedHeaders http.Header
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedHeaders = r.Header.Clone()
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer upstream.Close()
+
+	tracer := mocktracer.New()
+	parent := tracer.StartSpan("parent")
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(opentracing.ContextWithSpan(req.Context(), parent))
+	c := e.NewContext(req, httptest.NewRecorder())
+
+	client := WrapHTTPClient(c, nil)
+	assert.Nil(t, http.DefaultClient.Transport) // original client is not modified
+
+	resp, err := client.Get(upstream.URL + "/ok")
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	resp, err = client.Get(upstream.URL + "/fail")
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	spans := tracer.FinishedSpans()
+	assert.Len(t, spans, 2)
+	parentCtx := parent.Context().(mocktracer.MockSpanContext)
+
+	assert.Equal(t, "HTTP GET", spans[0].OperationName)
+	assert.Equal(t, parentCtx.SpanID, spans[0].ParentID)
+	assert.Equal(t, ext.SpanKindRPCClientEnum, spans[0].Tag(string(ext.SpanKind)))
+	assert.Equal(t, upstream.URL+"/ok", spans[0].Tag(string(ext.HTTPUrl)))
+	assert.Equal(t, uint16(http.StatusOK), spans[0].Tag(string(ext.HTTPStatusCode)))
+	assert.Nil(t, spans[0].Tag("error"))
+
+	assert.Equal(t, uint16(http.StatusBadGateway), spans[1].Tag(string(ext.HTTPStatusCode)))
+	assert.Equal(t, true, spans[1].Tag("error"))
+
+	assert.Equal(t, strconv.Itoa(parentCtx.TraceID), receivedHeaders.Get("Mockpfx-Ids-Traceid"))
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestNewRoundTripper(t *testing.T) {
+	tracer := mocktracer.New()
+	parent := tracer.StartSpan("parent")
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(opentracing.ContextWithSpan(req.Context(), parent))
+	c := e.NewContext(req, httptest.NewRecorder())
+
+	var sent *http.Request
+	base := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		sent = req
+		if req.URL.Path == "/down" {
+			return nil, errors.New("connection refused")
+		}
+		return &http.Response{StatusCode: http.StatusNoContent, Body: http.NoBody}, nil
+	})
+	client := &http.Client{Transport: NewRoundTripper(c, base)}
+
+	outReq, _ := http.NewRequest(http.MethodPost, "http://sdk.example.com/items", nil)
+	resp, err := client.Do(outReq)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Empty(t, outReq.Header.Get("Mockpfx-Ids-Traceid")) // original request is not modified
+	parentCtx := parent.Context().(mocktracer.MockSpanContext)
+	assert.Equal(t, strconv.Itoa(parentCtx.TraceID), sent.Header.Get("Mockpfx-Ids-Traceid"))
+
+	_, err = client.Get("http://sdk.example.com/down")
+	assert.Error(t, err)
+
+	spans := tracer.FinishedSpans()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "HTTP POST", spans[0].OperationName)
+	assert.Equal(t, parentCtx.SpanID, spans[0].ParentID)
+	assert.Equal(t, uint16(http.StatusNoContent), spans[0].Tag(string(ext.HTTPStatusCode)))
+	assert.Equal(t, "HTTP GET", spans[1].OperationName)
+	assert.Equal(t, true, spans[1].Tag("error"))
+}
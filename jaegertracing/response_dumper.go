@@ -32,6 +32,6 @@ func (d *responseDumper) Write(b []byte) (int, error) {
 	return d.mw.Write(b)
 }
 
-func (d *responseDumper) GetResponse() string {
-	return d.buf.String()
+func (d *responseDumper) GetResponseBytes() []byte {
+	return d.buf.Bytes()
 }
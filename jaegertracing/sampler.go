@@ -0,0 +1,61 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package jaegertracing
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// nextWithoutSpan calls next without creating span for the request. Trace context extracted from request headers is
+// put to request context as nonRecordingSpan so it is propagated to child spans and outgoing requests.
+func nextWithoutSpan(c echo.Context, tracer opentracing.Tracer, next echo.HandlerFunc) error {
+	req := c.Request()
+	spanCtx, err := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(req.Header))
+	if err != nil || spanCtx == nil {
+		return next(c)
+	}
+	sp := &nonRecordingSpan{tracer: tracer, spanCtx: spanCtx}
+	reqSpan := req.WithContext(opentracing.ContextWithSpan(req.Context(), sp))
+	c.SetRequest(reqSpan)
+	defer func() {
+		// http.Server cleans up MultipartForm temporary files only of the original request, see TraceWithConfig.
+		if reqSpan.MultipartForm != nil {
+			reqSpan.MultipartForm.RemoveAll()
+		}
+	}()
+	return next(c)
+}
+
+// nonRecordingSpan is opentracing.Span carrying only extracted span context. All recording methods are no-op.
+type nonRecordingSpan struct {
+	tracer  opentracing.Tracer
+	spanCtx opentracing.SpanContext
+}
+
+func (s *nonRecordingSpan) Finish()                                        {}
+func (s *nonRecordingSpan) FinishWithOptions(opentracing.FinishOptions)    {}
+func (s *nonRecordingSpan) Context() opentracing.SpanContext               { return s.spanCtx }
+func (s *nonRecordingSpan) SetOperationName(string) opentracing.Span       { return s }
+func (s *nonRecordingSpan) SetTag(string, interface{}) opentracing.Span    { return s }
+func (s *nonRecordingSpan) LogFields(...log.Field)                         {}
+func (s *nonRecordingSpan) LogKV(...interface{})                           {}
+func (s *nonRecordingSpan) SetBaggageItem(string, string) opentracing.Span { return s }
+func (s *nonRecordingSpan) Tracer() opentracing.Tracer                     { return s.tracer }
+func (s *nonRecordingSpan) LogEvent(string)                                {}
+func (s *nonRecordingSpan) LogEventWithPayload(string, interface{})        {}
+func (s *nonRecordingSpan) Log(opentracing.LogData)                        {}
+
+func (s *nonRecordingSpan) BaggageItem(key string) string {
+	value := ""
+	s.spanCtx.ForeachBaggageItem(func(k, v string) bool {
+		if k == key {
+			value = v
+			return false
+		}
+		return true
+	})
+	return value
+}
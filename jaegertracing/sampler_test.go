@@ -0,0 +1,119 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package jaegertracing
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraceWithSampler(t *testing.T) {
+	tracer := mocktracer.New()
+	e := echo.New()
+	e.Use(TraceWithConfig(TraceConfig{
+		Tracer: tracer,
+		Sampler: func(c echo.Context) bool {
+			return c.Path() != "/health"
+		},
+	}))
+
+	var handlerSpan opentracing.Span
+	e.GET("/health", func(c echo.Context) error {
+		handlerSpan = opentracing.SpanFromContext(c.Request().Context())
+		if handlerSpan != nil {
+			child := tracer.StartSpan("db ping", opentracing.ChildOf(handlerSpan.Context()))
+			child.Finish()
+		}
+		return c.NoContent(http.StatusOK)
+	})
+	e.GET("/users", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	upstream := tracer.StartSpan("upstream")
+	upstream.SetBaggageItem("tenant", "acme")
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	assert.NoError(t, tracer.Inject(upstream.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(req.Header)))
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	if assert.NotNil(t, handlerSpan) {
+		assert.Equal(t, "acme", handlerSpan.BaggageItem("tenant"))
+	}
+	finished := tracer.FinishedSpans()
+	if assert.Len(t, finished, 1) { // only the child span, no span for the request itself
+		upstreamCtx := upstream.Context().(mocktracer.MockSpanContext)
+		assert.Equal(t, "db ping", finished[0].OperationName)
+		assert.Equal(t, upstreamCtx.TraceID, finished[0].SpanContext.TraceID)
+		assert.Equal(t, upstreamCtx.SpanID, finished[0].ParentID)
+	}
+
+	tracer.Reset()
+	handlerSpan = nil
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Nil(t, handlerSpan)
+	assert.Empty(t, tracer.FinishedSpans())
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users", nil))
+	assert.Len(t, tracer.FinishedSpans(), 1)
+}
+
+func TestTraceWithSampler_removesMultipartFiles(t *testing.T) {
+	tracer := mocktracer.New()
+	e := echo.New()
+	e.Use(TraceWithConfig(TraceConfig{
+		Tracer:  tracer,
+		Sampler: func(c echo.Context) bool { return false },
+	}))
+
+	var tmpFile string
+	e.POST("/upload", func(c echo.Context) error {
+		if err := c.Request().ParseMultipartForm(1); err != nil {
+			return err
+		}
+		f, err := c.Request().MultipartForm.File["file"][0].Open()
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if osFile, ok := f.(*os.File); ok {
+			tmpFile = osFile.Name()
+		}
+		return c.NoContent(http.StatusOK)
+	})
+
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	part, err := mw.CreateFormFile("file", "test.txt")
+	assert.NoError(t, err)
+	_, err = part.Write(bytes.Repeat([]byte("a"), 1024))
+	assert.NoError(t, err)
+	assert.NoError(t, mw.Close())
+
+	upstream := tracer.StartSpan("upstream")
+	req := httptest.NewRequest(http.MethodPost, "/upload", body)
+	req.Header.Set(echo.HeaderContentType, mw.FormDataContentType())
+	assert.NoError(t, tracer.Inject(upstream.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(req.Header)))
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	if assert.NotEmpty(t, tmpFile) {
+		_, err = os.Stat(tmpFile)
+		assert.True(t, os.IsNotExist(err))
+	}
+}
@@ -0,0 +1,271 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+/*
+Package oteltracing provides middleware to trace requests using OpenTelemetry.
+
+It is OpenTelemetry native alternative to jaegertracing package which is based on deprecated OpenTracing API.
+
+Example:
+```
+package main
+import (
+
+	"github.com/labstack/echo-contrib/oteltracing"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+
+)
+
+	func main() {
+	    e := echo.New()
+	    // tracer provider is configured with exporter of your choice, i.e. OTLP
+	    e.Use(oteltracing.Trace(otel.GetTracerProvider()))
+
+	    e.Logger.Fatal(e.Start(":1323"))
+	}
+
+```
+*/
+package oteltracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// ScopeName is instrumentation scope name used to create tracers.
+	ScopeName = "github.com/labstack/echo-contrib/oteltracing"
+
+	defaultComponentName = "echo/v4"
+)
+
+type (
+	// TraceConfig defines the config for Trace middleware.
+	TraceConfig struct {
+		// Skipper defines a function to skip middleware.
+		Skipper middleware.Skipper
+
+		// TracerProvider is used to create tracer.
+		// Defaults to: otel.GetTracerProvider()
+		TracerProvider trace.TracerProvider
+
+		// Propagators are used to extract trace context and baggage from request headers.
+		// Defaults to: W3C Trace Context and Baggage propagators
+		Propagators propagation.TextMapPropagator
+
+		// ComponentName used for describing the tracing component name
+		ComponentName string
+
+		// add req body & resp body to span events
+		IsBodyDump bool
+
+		// prevent logging long http request bodies
+		LimitHTTPBody bool
+
+		// http body limit size (in bytes)
+		LimitSize int
+
+		// OperationNameFunc composes span name based on context. Can be used to override default naming
+		OperationNameFunc func(c echo.Context) string
+
+		// BodySanitizer is called with content type and body before request and response bodies are added to span
+		// events. Returned value is recorded instead of original body. Can be used to mask passwords and PII, see
+		// `echosecretsredact.Redactor.Body`.
+		// Optional.
+		BodySanitizer func(contentType string, body []byte) []byte
+	}
+)
+
+var (
+	// DefaultTraceConfig is the default Trace middleware config.
+	DefaultTraceConfig = TraceConfig{
+		Skipper:       middleware.DefaultSkipper,
+		ComponentName: defaultComponentName,
+		IsBodyDump:    false,
+
+		LimitHTTPBody:     true,
+		LimitSize:         60_000,
+		OperationNameFunc: defaultOperationName,
+	}
+)
+
+// Trace returns a Trace middleware.
+// Trace middleware traces http requests and reporting errors.
+func Trace(tp trace.TracerProvider) echo.MiddlewareFunc {
+	c := DefaultTraceConfig
+	c.TracerProvider = tp
+	return TraceWithConfig(c)
+}
+
+// TraceWithConfig returns a Trace middleware with config.
+// See: `Trace()`.
+func TraceWithConfig(config TraceConfig) echo.MiddlewareFunc {
+	if config.Skipper == nil {
+		config.Skipper = middleware.DefaultSkipper
+	}
+	if config.TracerProvider == nil {
+		config.TracerProvider = otel.GetTracerProvider()
+	}
+	if config.Propagators == nil {
+		config.Propagators = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+	}
+	if config.ComponentName == "" {
+		config.ComponentName = defaultComponentName
+	}
+	if config.OperationNameFunc == nil {
+		config.OperationNameFunc = defaultOperationName
+	}
+	tracer := config.TracerProvider.Tracer(ScopeName)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			req := c.Request()
+			ctx := config.Propagators.Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+
+			attrs := []attribute.KeyValue{
+				semconv.HTTPRequestMethodKey.String(req.Method),
+				semconv.URLFull(req.URL.String()),
+				semconv.URLPath(req.URL.Path),
+				semconv.URLScheme(c.Scheme()),
+				semconv.ServerAddress(req.Host),
+				semconv.ClientAddress(c.RealIP()),
+				attribute.String("component", config.ComponentName),
+			}
+			if ua := req.UserAgent(); ua != "" {
+				attrs = append(attrs, semconv.UserAgentOriginal(ua))
+			}
+			if route := c.Path(); route != "" {
+				attrs = append(attrs, semconv.HTTPRoute(route))
+			}
+
+			ctx, sp := tracer.Start(
+				ctx,
+				config.OperationNameFunc(c),
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(attrs...),
+			)
+			defer sp.End()
+
+			// Dump request & response body
+			var respDumper *responseDumper
+			if config.IsBodyDump {
+				// request
+				reqBody := []byte{}
+				if req.Body != nil {
+					reqBody, _ = io.ReadAll(req.Body)
+					sp.AddEvent("http.req.body", trace.WithAttributes(attribute.String("body", config.dumpBody(req.Header.Get(echo.HeaderContentType), reqBody))))
+				}
+
+				req.Body = io.NopCloser(bytes.NewBuffer(reqBody)) // reset original request body
+
+				// response
+				respDumper = newResponseDumper(c.Response())
+				c.Response().Writer = respDumper
+			}
+
+			// setup request context - add span and extracted baggage
+			reqSpan := req.WithContext(ctx)
+			c.SetRequest(reqSpan)
+			defer func() {
+				// as we have created new http.Request object we need to make sure that temporary files created to hold MultipartForm
+				// files are cleaned up. This is done by http.Server at the end of request lifecycle but Server does not
+				// have reference to our new Request instance therefore it is our responsibility to fix the mess we caused.
+				if reqSpan.MultipartForm != nil {
+					reqSpan.MultipartForm.RemoveAll()
+				}
+			}()
+
+			// call next middleware / controller
+			err := next(c)
+			if err != nil {
+				c.Error(err) // call custom registered error handler
+			}
+
+			status := c.Response().Status
+			sp.SetAttributes(semconv.HTTPResponseStatusCode(status))
+			if err != nil {
+				logError(sp, err, status)
+			} else if status >= http.StatusInternalServerError {
+				sp.SetStatus(codes.Error, http.StatusText(status))
+			}
+
+			// Dump response body
+			if config.IsBodyDump {
+				body := config.dumpBody(c.Response().Header().Get(echo.HeaderContentType), []byte(respDumper.GetResponse()))
+				sp.AddEvent("http.resp.body", trace.WithAttributes(attribute.String("body", body)))
+			}
+
+			return nil // error was already processed with ctx.Error(err)
+		}
+	}
+}
+
+// CreateChildSpan starts new span as child of the request span and returns context containing the span. Request is
+// not modified so spans created later are children of the request span too. User must call `defer sp.End()`.
+func CreateChildSpan(c echo.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	ctx := c.Request().Context()
+	tracer := trace.SpanFromContext(ctx).TracerProvider().Tracer(ScopeName)
+	return tracer.Start(ctx, name, opts...)
+}
+
+// dumpBody sanitizes and limits body before it is added to span event.
+func (config TraceConfig) dumpBody(contentType string, body []byte) string {
+	if config.BodySanitizer != nil {
+		body = config.BodySanitizer(contentType, body)
+	}
+	if config.LimitHTTPBody {
+		return limitString(string(body), config.LimitSize)
+	}
+	return string(body)
+}
+
+func limitString(str string, size int) string {
+	if len(str) > size {
+		return str[:size/2] + "\n---- skipped ----\n" + str[len(str)-size/2:]
+	}
+
+	return str
+}
+
+// logError records error to the span. Span status is set to error only for server errors, client errors (4xx) are left
+// unset as required by HTTP semantic conventions for server spans.
+func logError(span trace.Span, err error, status int) {
+	span.RecordError(err)
+	if status < http.StatusInternalServerError {
+		return
+	}
+	var httpError *echo.HTTPError
+	message := err.Error()
+	if errors.As(err, &httpError) {
+		if m, ok := httpError.Message.(string); ok {
+			message = m
+		}
+	}
+	span.SetStatus(codes.Error, message)
+}
+
+func defaultOperationName(c echo.Context) string {
+	req := c.Request()
+	if path := c.Path(); path != "" {
+		return req.Method + " " + path
+	}
+	return req.Method
+}
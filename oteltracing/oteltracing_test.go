@@ -0,0 +1,192 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package oteltracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTestProvider() (*sdktrace.TracerProvider, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	return sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)), recorder
+}
+
+func attributeValue(attrs []attribute.KeyValue, key string) attribute.Value {
+	for _, a := range attrs {
+		if string(a.Key) == key {
+			return a.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTrace(t *testing.T) {
+	tp, recorder := newTestProvider()
+
+	e := echo.New()
+	e.Use(Trace(tp))
+	e.GET("/users/:id", func(c echo.Context) error {
+		return c.String(http.StatusOK, "OK")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set("User-Agent", "test-agent")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 1) {
+		sp := spans[0]
+		assert.Equal(t, "GET /users/:id", sp.Name())
+		assert.Equal(t, trace.SpanKindServer, sp.SpanKind())
+		assert.Equal(t, "GET", attributeValue(sp.Attributes(), "http.request.method").AsString())
+		assert.Equal(t, "/users/:id", attributeValue(sp.Attributes(), "http.route").AsString())
+		assert.Equal(t, "test-agent", attributeValue(sp.Attributes(), "user_agent.original").AsString())
+		assert.Equal(t, int64(200), attributeValue(sp.Attributes(), "http.response.status_code").AsInt64())
+		assert.Equal(t, codes.Unset, sp.Status().Code)
+	}
+}
+
+func TestTraceWithConfig_error(t *testing.T) {
+	tp, recorder := newTestProvider()
+
+	e := echo.New()
+	e.Use(TraceWithConfig(TraceConfig{TracerProvider: tp}))
+	e.GET("/", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusBadGateway, "upstream failed")
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+		assert.Equal(t, "upstream failed", spans[0].Status().Description)
+		assert.Equal(t, int64(502), attributeValue(spans[0].Attributes(), "http.response.status_code").AsInt64())
+	}
+}
+
+func TestTraceWithConfig_clientError(t *testing.T) {
+	tp, recorder := newTestProvider()
+
+	e := echo.New()
+	e.Use(TraceWithConfig(TraceConfig{TracerProvider: tp}))
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/unknown", nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, codes.Unset, spans[0].Status().Code)
+		assert.Equal(t, int64(404), attributeValue(spans[0].Attributes(), "http.response.status_code").AsInt64())
+		if assert.Len(t, spans[0].Events(), 1) {
+			assert.Equal(t, "exception", spans[0].Events()[0].Name)
+		}
+	}
+}
+
+func TestTraceWithConfig_propagation(t *testing.T) {
+	tp, recorder := newTestProvider()
+
+	e := echo.New()
+	e.Use(TraceWithConfig(TraceConfig{TracerProvider: tp})) // default propagators
+	var member string
+	e.GET("/", func(c echo.Context) error {
+		member = baggage.FromContext(c.Request().Context()).Member("tenant").Value()
+		_, sp := CreateChildSpan(c, "child1")
+		sp.End()
+		_, sp = CreateChildSpan(c, "child2")
+		sp.End()
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set("baggage", "tenant=acme")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, "acme", member)
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 3) {
+		child1, child2, server := spans[0], spans[1], spans[2]
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+		assert.Equal(t, server.SpanContext().SpanID(), child1.Parent().SpanID())
+		assert.Equal(t, server.SpanContext().SpanID(), child2.Parent().SpanID())
+	}
+}
+
+func TestTraceWithConfig_bodyDump(t *testing.T) {
+	tp, recorder := newTestProvider()
+
+	e := echo.New()
+	e.Use(TraceWithConfig(TraceConfig{
+		TracerProvider: tp,
+		IsBodyDump:     true,
+		LimitHTTPBody:  true,
+		LimitSize:      10,
+	}))
+	e.POST("/", func(c echo.Context) error {
+		return c.String(http.StatusOK, "response body")
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("0123456789abcdef"))
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 1) {
+		events := spans[0].Events()
+		if assert.Len(t, events, 2) {
+			assert.Equal(t, "http.req.body", events[0].Name)
+			assert.Equal(t, "01234\n---- skipped ----\nbcdef", attributeValue(events[0].Attributes, "body").AsString())
+			assert.Equal(t, "http.resp.body", events[1].Name)
+			assert.Equal(t, "respo\n---- skipped ----\n body", attributeValue(events[1].Attributes, "body").AsString())
+		}
+	}
+}
+
+func TestTraceWithConfig_bodySanitizer(t *testing.T) {
+	tp, recorder := newTestProvider()
+
+	e := echo.New()
+	e.Use(TraceWithConfig(TraceConfig{
+		TracerProvider: tp,
+		IsBodyDump:     true,
+		BodySanitizer: func(contentType string, body []byte) []byte {
+			return []byte(contentType + ":" + strings.ToUpper(string(body)))
+		},
+	}))
+	e.POST("/", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, "secret")
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("password=x"))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 1) {
+		events := spans[0].Events()
+		if assert.Len(t, events, 2) {
+			assert.Equal(t, "application/x-www-form-urlencoded:PASSWORD=X", attributeValue(events[0].Attributes, "body").AsString())
+			assert.Equal(t, "application/json:\"SECRET\"\n", attributeValue(events[1].Attributes, "body").AsString())
+		}
+	}
+}
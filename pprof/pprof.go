@@ -22,11 +22,40 @@ func getPrefix(prefixOptions ...string) string {
 	return DefaultPrefix
 }
 
+// Config defines the config for pprof endpoints.
+type Config struct {
+	// Allow decides whether pprof endpoints are served for the request. Requests that are not allowed are responded
+	// with `404 Not Found` (i.e. to serve profiles only on internal listener, see `http.LocalAddrContextKey`).
+	// Optional. Default value nil (all requests are allowed).
+	Allow func(c echo.Context) bool
+
+	// Prefix is url prefix of pprof endpoints.
+	// Optional. Default value "/debug/pprof".
+	Prefix string
+
+	// Middlewares are added to pprof endpoints group (i.e. `middleware.BasicAuth`).
+	// Optional.
+	Middlewares []echo.MiddlewareFunc
+}
+
 // Register middleware for net/http/pprof
 func Register(e *echo.Echo, prefixOptions ...string) {
-	prefix := getPrefix(prefixOptions...)
+	RegisterWithConfig(e, Config{Prefix: getPrefix(prefixOptions...)})
+}
 
-	prefixRouter := e.Group(prefix)
+// RegisterWithConfig registers net/http/pprof endpoints with config.
+// See `Register()`.
+func RegisterWithConfig(e *echo.Echo, config Config) {
+	if config.Prefix == "" {
+		config.Prefix = DefaultPrefix
+	}
+	middlewares := make([]echo.MiddlewareFunc, 0, len(config.Middlewares)+1)
+	if config.Allow != nil {
+		middlewares = append(middlewares, allowMiddleware(config.Allow))
+	}
+	middlewares = append(middlewares, config.Middlewares...)
+
+	prefixRouter := e.Group(config.Prefix, middlewares...)
 	{
 		prefixRouter.GET("/", handler(pprof.Index))
 		prefixRouter.GET("/allocs", handler(pprof.Handler("allocs").ServeHTTP))
@@ -49,3 +78,14 @@ func handler(h http.HandlerFunc) echo.HandlerFunc {
 		return nil
 	}
 }
+
+func allowMiddleware(allow func(c echo.Context) bool) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !allow(c) {
+				return echo.ErrNotFound
+			}
+			return next(c)
+		}
+	}
+}
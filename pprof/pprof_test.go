@@ -4,12 +4,15 @@
 package pprof
 
 import (
+	"context"
 	"github.com/stretchr/testify/assert"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
 )
 
 func TestPProfRegisterDefaualtPrefix(t *testing.T) {
@@ -70,3 +73,39 @@ func TestPProfRegisterCustomPrefix(t *testing.T) {
 		})
 	}
 }
+
+func TestPProfRegisterWithConfig(t *testing.T) {
+	e := echo.New()
+	RegisterWithConfig(e, Config{
+		Allow: func(c echo.Context) bool {
+			addr, ok := c.Request().Context().Value(http.LocalAddrContextKey).(net.Addr)
+			return ok && addr.String() == "127.0.0.1:6060"
+		},
+		Middlewares: []echo.MiddlewareFunc{
+			middleware.BasicAuth(func(user, password string, c echo.Context) (bool, error) {
+				return user == "admin" && password == "secret", nil
+			}),
+		},
+	})
+
+	internal := context.WithValue(context.Background(), http.LocalAddrContextKey, &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 6060})
+
+	req := httptest.NewRequest(http.MethodGet, DefaultPrefix+"/heap", nil).WithContext(internal)
+	req.SetBasicAuth("admin", "secret")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, DefaultPrefix+"/heap", nil).WithContext(internal)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// Host header is controlled by the client and does not matter
+	req = httptest.NewRequest(http.MethodGet, DefaultPrefix+"/heap", nil)
+	req.Host = "internal:6060"
+	req.SetBasicAuth("admin", "secret")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,101 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+/*
+Package redisstore provides Redis backed session store for session middleware.
+
+Sessions expire using Redis key TTL so no garbage collection is needed. Package does not depend on any Redis client
+library, client is adapted with Client interface. For example with github.com/redis/go-redis/v9:
+```
+type goRedisClient struct{ rdb *redis.Client }
+
+	func (c goRedisClient) Get(ctx context.Context, key string) ([]byte, error) {
+		b, err := c.rdb.Get(ctx, key).Bytes()
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return b, err
+	}
+
+	func (c goRedisClient) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+		return c.rdb.Set(ctx, key, value, ttl).Err()
+	}
+
+	func (c goRedisClient) Expire(ctx context.Context, key string, ttl time.Duration) error {
+		return c.rdb.Expire(ctx, key, ttl).Err()
+	}
+
+	func (c goRedisClient) Del(ctx context.Context, key string) error {
+		return c.rdb.Del(ctx, key).Err()
+	}
+
+	func main() {
+		e := echo.New()
+		store := redisstore.New(goRedisClient{rdb: redis.NewClient(&redis.Options{Addr: "localhost:6379"})}, []byte("secret"))
+		store.SlidingExpiration = true
+		e.Use(session.Middleware(store))
+
+		e.Logger.Fatal(e.Start(":1323"))
+	}
+
+```
+*/
+package redisstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/labstack/echo-contrib/session"
+)
+
+// DefaultKeyPrefix is prefix of Redis keys sessions are stored under.
+const DefaultKeyPrefix = "session_"
+
+// Client is minimal Redis client used by Backend.
+type Client interface {
+	// Get returns value of the key or nil value when key does not exist.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set sets value of the key with expiry.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Expire sets expiry of the key.
+	Expire(ctx context.Context, key string, ttl time.Duration) error
+	// Del deletes the key.
+	Del(ctx context.Context, key string) error
+}
+
+// Backend is session.Backend storing sessions in Redis.
+type Backend struct {
+	client Client
+	prefix string
+}
+
+// New returns session store keeping sessions in Redis under keys with DefaultKeyPrefix.
+func New(client Client, keyPairs ...[]byte) *session.ServerStore {
+	return session.NewServerStore(NewBackend(client, DefaultKeyPrefix), keyPairs...)
+}
+
+// NewBackend returns Redis backend storing sessions under keys with given prefix.
+func NewBackend(client Client, prefix string) *Backend {
+	return &Backend{client: client, prefix: prefix}
+}
+
+// Load returns session data or nil data when session does not exist.
+func (b *Backend) Load(ctx context.Context, id string) ([]byte, error) {
+	return b.client.Get(ctx, b.prefix+id)
+}
+
+// Save stores session data with ttl.
+func (b *Backend) Save(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	return b.client.Set(ctx, b.prefix+id, data, ttl)
+}
+
+// Touch extends expiry of the session.
+func (b *Backend) Touch(ctx context.Context, id string, ttl time.Duration) error {
+	return b.client.Expire(ctx, b.prefix+id, ttl)
+}
+
+// Delete removes the session.
+func (b *Backend) Delete(ctx context.Context, id string) error {
+	return b.client.Del(ctx, b.prefix+id)
+}
@@ -0,0 +1,79 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package redisstore
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClient struct {
+	values map[string][]byte
+	ttls   map[string]time.Duration
+}
+
+func (c *fakeClient) Get(ctx context.Context, key string) ([]byte, error) {
+	return c.values[key], nil
+}
+
+func (c *fakeClient) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.values[key] = value
+	c.ttls[key] = ttl
+	return nil
+}
+
+func (c *fakeClient) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	c.ttls[key] = ttl
+	return nil
+}
+
+func (c *fakeClient) Del(ctx context.Context, key string) error {
+	delete(c.values, key)
+	return nil
+}
+
+func TestNew(t *testing.T) {
+	client := &fakeClient{values: map[string][]byte{}, ttls: map[string]time.Duration{}}
+	store := New(client, []byte("secret"))
+	store.MaxAge(3600)
+
+	e := echo.New()
+	e.Use(session.Middleware(store))
+	e.GET("/", func(c echo.Context) error {
+		sess, err := session.Get("app", c)
+		if err != nil {
+			return err
+		}
+		n, _ := sess.Values["n"].(int)
+		sess.Values["n"] = n + 1
+		if err := sess.Save(c.Request(), c.Response()); err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, n+1)
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "1\n", rec.Body.String())
+	cookie := rec.Result().Cookies()[0]
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, "2\n", rec.Body.String())
+
+	assert.Len(t, client.values, 1)
+	for key, ttl := range client.ttls {
+		assert.Contains(t, key, DefaultKeyPrefix)
+		assert.Equal(t, time.Hour, ttl)
+	}
+}
@@ -5,6 +5,8 @@ package session
 
 import (
 	"fmt"
+	"net/http"
+	"strings"
 
 	"github.com/gorilla/context"
 	"github.com/gorilla/sessions"
@@ -21,17 +23,34 @@ type (
 		// Session store.
 		// Required.
 		Store sessions.Store
+
+		// Name is name of the session returned by `Default()`.
+		// Optional. Default value "session".
+		Name string
+
+		// Options overrides cookie options of the store for sessions returned by `Get()` and `Default()`.
+		// Optional.
+		Options *sessions.Options
+
+		// RouteOptions overrides cookie options per route path (i.e. `/admin/*`). Takes precedence over Options.
+		// Optional.
+		RouteOptions map[string]*sessions.Options
 	}
 )
 
 const (
-	key = "_session_store"
+	key        = "_session_store"
+	nameKey    = "_session_name"
+	optionsKey = "_session_options"
+	appliedKey = "_session_options_applied"
+	loadedKey  = "_session_loaded"
 )
 
 var (
 	// DefaultConfig is the default Session middleware config.
 	DefaultConfig = Config{
 		Skipper: middleware.DefaultSkipper,
+		Name:    "session",
 	}
 )
 
@@ -42,7 +61,48 @@ func Get(name string, c echo.Context) (*sessions.Session, error) {
 		return nil, fmt.Errorf("%q session store not found", key)
 	}
 	store := s.(sessions.Store)
-	return store.Get(c.Request(), name)
+	sess, err := store.Get(c.Request(), name)
+	if sess != nil {
+		applyOptions(c, name, sess)
+		if loaded, ok := c.Get(loadedKey).(map[string]struct{}); ok {
+			loaded[name] = struct{}{}
+		}
+	}
+	return sess, err
+}
+
+// applyOptions copies configured options to the session once per request. Session is cached in request registry so
+// changes done by handler (i.e. `sess.Options.MaxAge = -1` on logout) must not be overwritten by later Get calls.
+func applyOptions(c echo.Context, name string, sess *sessions.Session) {
+	opts, ok := c.Get(optionsKey).(*sessions.Options)
+	if !ok {
+		return
+	}
+	applied, _ := c.Get(appliedKey).(map[string]struct{})
+	if _, ok := applied[name]; ok {
+		return
+	}
+	if applied == nil {
+		applied = make(map[string]struct{}, 1)
+		c.Set(appliedKey, applied)
+	}
+	applied[name] = struct{}{}
+	o := *opts
+	sess.Options = &o
+}
+
+// Default returns the session with name configured for the middleware.
+func Default(c echo.Context) (*sessions.Session, error) {
+	name, ok := c.Get(nameKey).(string)
+	if !ok {
+		name = DefaultConfig.Name
+	}
+	return Get(name, c)
+}
+
+// Save saves all sessions used during the request.
+func Save(c echo.Context) error {
+	return sessions.Save(c.Request(), c.Response())
 }
 
 // Middleware returns a Session middleware.
@@ -62,6 +122,9 @@ func MiddlewareWithConfig(config Config) echo.MiddlewareFunc {
 	if config.Store == nil {
 		panic("echo: session middleware requires store")
 	}
+	if config.Name == "" {
+		config.Name = DefaultConfig.Name
+	}
 
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
@@ -70,7 +133,46 @@ func MiddlewareWithConfig(config Config) echo.MiddlewareFunc {
 			}
 			defer context.Clear(c.Request())
 			c.Set(key, config.Store)
+			c.Set(nameKey, config.Name)
+			if opts, ok := config.RouteOptions[c.Path()]; ok {
+				c.Set(optionsKey, opts)
+			} else if config.Options != nil {
+				c.Set(optionsKey, config.Options)
+			}
+			if store, ok := config.Store.(*ServerStore); ok && store.SlidingExpiration {
+				loaded := make(map[string]struct{}, 1)
+				c.Set(loadedKey, loaded)
+				c.Response().Before(func() {
+					refreshSessions(c, store, loaded)
+				})
+			}
 			return next(c)
 		}
 	}
 }
+
+// refreshSessions re-saves existing sessions loaded during the request so the signed timestamps in the cookie and in
+// the stored data, and cookie expiry, slide forward. Sessions already saved by the handler are left as they are.
+func refreshSessions(c echo.Context, store *ServerStore, loaded map[string]struct{}) {
+	for name := range loaded {
+		if hasSetCookie(c.Response().Header(), name) {
+			continue
+		}
+		sess, err := store.Get(c.Request(), name)
+		if err != nil || sess.IsNew || sess.Options.MaxAge <= 0 {
+			continue
+		}
+		if err := store.Save(c.Request(), c.Response(), sess); err != nil {
+			c.Logger().Errorf("session: failed to refresh session %q: %v", name, err)
+		}
+	}
+}
+
+func hasSetCookie(h http.Header, name string) bool {
+	for _, v := range h.Values(echo.HeaderSetCookie) {
+		if strings.HasPrefix(v, name+"=") {
+			return true
+		}
+	}
+	return false
+}
@@ -66,3 +66,68 @@ func TestGetSessionMissingStore(t *testing.T) {
 
 	assert.EqualError(t, err, fmt.Sprintf("%q session store not found", key))
 }
+
+func TestMiddlewareWithConfigOptions(t *testing.T) {
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(Config{
+		Store:   sessions.NewCookieStore([]byte("secret")),
+		Name:    "app",
+		Options: &sessions.Options{Path: "/", MaxAge: 3600, HttpOnly: true},
+		RouteOptions: map[string]*sessions.Options{
+			"/admin": {Path: "/admin", MaxAge: 600, Secure: true},
+		},
+	}))
+	handler := func(c echo.Context) error {
+		sess, err := Default(c)
+		if err != nil {
+			return err
+		}
+		sess.Values["foo"] = "bar"
+		if err := Save(c); err != nil {
+			return err
+		}
+		return c.NoContent(http.StatusOK)
+	}
+	e.GET("/", handler)
+	e.GET("/admin", handler)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	cookie := rec.Header().Get(echo.HeaderSetCookie)
+	assert.Contains(t, cookie, "app=")
+	assert.Contains(t, cookie, "Max-Age=3600")
+	assert.Contains(t, cookie, "HttpOnly")
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin", nil))
+	cookie = rec.Header().Get(echo.HeaderSetCookie)
+	assert.Contains(t, cookie, "Path=/admin")
+	assert.Contains(t, cookie, "Max-Age=600")
+	assert.Contains(t, cookie, "Secure")
+}
+
+func TestGet_optionsAppliedOnce(t *testing.T) {
+	e := echo.New()
+	e.Use(MiddlewareWithConfig(Config{
+		Store:   sessions.NewCookieStore([]byte("secret")),
+		Options: &sessions.Options{Path: "/", MaxAge: 3600},
+	}))
+	e.POST("/logout", func(c echo.Context) error {
+		sess, err := Default(c)
+		if err != nil {
+			return err
+		}
+		sess.Options.MaxAge = -1
+		if _, err := Default(c); err != nil { // i.e. called by another middleware
+			return err
+		}
+		if err := Save(c); err != nil {
+			return err
+		}
+		return c.NoContent(http.StatusOK)
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/logout", nil))
+	assert.Contains(t, rec.Header().Get(echo.HeaderSetCookie), "Max-Age=0")
+}
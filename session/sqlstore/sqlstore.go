@@ -0,0 +1,180 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+/*
+Package sqlstore provides database/sql backed session store for session middleware.
+
+Sessions are stored in table with following schema (adjust types to your database):
+```
+
+	CREATE TABLE sessions (
+		id         VARCHAR(64) NOT NULL PRIMARY KEY,
+		data       TEXT        NOT NULL,
+		expires_at BIGINT      NOT NULL
+	);
+	CREATE INDEX sessions_expires_at_idx ON sessions (expires_at);
+
+```
+`expires_at` is Unix time in seconds. Expired sessions are not loaded and are removed by garbage collector started
+with ServerStore.StartGC. Sessions are upserted with UPDATE followed by INSERT when no row was affected, so MySQL
+connections must be opened with `clientFoundRows=true` DSN parameter.
+
+Example:
+```
+
+	func main() {
+		e := echo.New()
+		db, _ := sql.Open("pgx", "postgres://localhost/app")
+
+		store, err := sqlstore.New(db, sqlstore.Config{Placeholder: sqlstore.PlaceholderDollar}, []byte("secret"))
+		if err != nil {
+			e.Logger.Fatal(err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		store.StartGC(ctx, 10*time.Minute, func(err error) { e.Logger.Error(err) })
+		e.Use(session.Middleware(store))
+
+		e.Logger.Fatal(e.Start(":1323"))
+	}
+
+```
+*/
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo-contrib/session"
+)
+
+// Placeholder returns bind parameter placeholder for n-th (starting from 1) query argument.
+type Placeholder func(n int) string
+
+// PlaceholderQuestion is placeholder used by MySQL and SQLite (`?`).
+func PlaceholderQuestion(n int) string {
+	return "?"
+}
+
+// PlaceholderDollar is placeholder used by PostgreSQL (`$1`).
+func PlaceholderDollar(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+var tableNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// Config defines the config for SQL backend.
+type Config struct {
+	// Table is name of the sessions table.
+	// Defaults to: "sessions"
+	Table string
+
+	// Placeholder returns bind parameter placeholders of the database driver.
+	// Defaults to: PlaceholderQuestion
+	Placeholder Placeholder
+}
+
+// Backend is session.Backend storing sessions in SQL database.
+type Backend struct {
+	db  *sql.DB
+	now func() time.Time
+
+	loadQuery          string
+	updateQuery        string
+	insertQuery        string
+	touchQuery         string
+	deleteQuery        string
+	deleteExpiredQuery string
+}
+
+// New returns session store keeping sessions in SQL database.
+func New(db *sql.DB, config Config, keyPairs ...[]byte) (*session.ServerStore, error) {
+	b, err := NewBackend(db, config)
+	if err != nil {
+		return nil, err
+	}
+	return session.NewServerStore(b, keyPairs...), nil
+}
+
+// NewBackend returns SQL backend or an error on invalid configuration.
+func NewBackend(db *sql.DB, config Config) (*Backend, error) {
+	if db == nil {
+		return nil, errors.New("sqlstore: db is required")
+	}
+	if config.Table == "" {
+		config.Table = "sessions"
+	}
+	if !tableNameRe.MatchString(config.Table) {
+		return nil, errors.New("sqlstore: invalid table name")
+	}
+	if config.Placeholder == nil {
+		config.Placeholder = PlaceholderQuestion
+	}
+	p := config.Placeholder
+	t := config.Table
+
+	return &Backend{
+		db:                 db,
+		now:                time.Now,
+		loadQuery:          fmt.Sprintf("SELECT data FROM %s WHERE id = %s AND expires_at > %s", t, p(1), p(2)),
+		updateQuery:        fmt.Sprintf("UPDATE %s SET data = %s, expires_at = %s WHERE id = %s", t, p(1), p(2), p(3)),
+		insertQuery:        fmt.Sprintf("INSERT INTO %s (id, data, expires_at) VALUES (%s, %s, %s)", t, p(1), p(2), p(3)),
+		touchQuery:         fmt.Sprintf("UPDATE %s SET expires_at = %s WHERE id = %s", t, p(1), p(2)),
+		deleteQuery:        fmt.Sprintf("DELETE FROM %s WHERE id = %s", t, p(1)),
+		deleteExpiredQuery: fmt.Sprintf("DELETE FROM %s WHERE expires_at <= %s", t, p(1)),
+	}, nil
+}
+
+// Load returns session data or nil data when session does not exist or has expired.
+func (b *Backend) Load(ctx context.Context, id string) ([]byte, error) {
+	var data string
+	err := b.db.QueryRowContext(ctx, b.loadQuery, id, b.now().Unix()).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []byte(data), nil
+}
+
+// Save stores session data with ttl.
+func (b *Backend) Save(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	expiresAt := b.now().Add(ttl).Unix()
+	res, err := b.db.ExecContext(ctx, b.updateQuery, string(data), expiresAt, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+	_, err = b.db.ExecContext(ctx, b.insertQuery, id, string(data), expiresAt)
+	return err
+}
+
+// Touch extends expiry of the session.
+func (b *Backend) Touch(ctx context.Context, id string, ttl time.Duration) error {
+	_, err := b.db.ExecContext(ctx, b.touchQuery, b.now().Add(ttl).Unix(), id)
+	return err
+}
+
+// Delete removes the session.
+func (b *Backend) Delete(ctx context.Context, id string) error {
+	_, err := b.db.ExecContext(ctx, b.deleteQuery, id)
+	return err
+}
+
+// DeleteExpired removes expired sessions and returns number of removed sessions.
+func (b *Backend) DeleteExpired(ctx context.Context) (int64, error) {
+	res, err := b.db.ExecContext(ctx, b.deleteExpiredQuery, b.now().Unix())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
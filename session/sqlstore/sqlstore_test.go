@@ -0,0 +1,168 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeRow struct {
+	data      string
+	expiresAt int64
+}
+
+// fakeDB is minimal database/sql driver understanding queries generated by Backend.
+type fakeDB struct {
+	mu      sync.Mutex
+	rows    map[string]fakeRow
+	queries []string
+}
+
+func (db *fakeDB) Connect(ctx context.Context) (driver.Conn, error) { return &fakeConn{db: db}, nil }
+func (db *fakeDB) Driver() driver.Driver                            { return nil }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{db: c.db, query: query}, nil
+}
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	db := s.db
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.queries = append(db.queries, s.query)
+
+	var n int64
+	switch {
+	case strings.HasPrefix(s.query, "UPDATE sessions SET data"):
+		id := args[2].(string)
+		if _, ok := db.rows[id]; ok {
+			db.rows[id] = fakeRow{data: args[0].(string), expiresAt: args[1].(int64)}
+			n = 1
+		}
+	case strings.HasPrefix(s.query, "INSERT INTO"):
+		db.rows[args[0].(string)] = fakeRow{data: args[1].(string), expiresAt: args[2].(int64)}
+		n = 1
+	case strings.HasPrefix(s.query, "UPDATE sessions SET expires_at"):
+		id := args[1].(string)
+		if r, ok := db.rows[id]; ok {
+			r.expiresAt = args[0].(int64)
+			db.rows[id] = r
+			n = 1
+		}
+	case strings.HasPrefix(s.query, "DELETE FROM sessions WHERE id"):
+		delete(db.rows, args[0].(string))
+		n = 1
+	case strings.HasPrefix(s.query, "DELETE FROM sessions WHERE expires_at"):
+		for id, r := range db.rows {
+			if r.expiresAt <= args[0].(int64) {
+				delete(db.rows, id)
+				n++
+			}
+		}
+	default:
+		return nil, errors.New("unexpected query: " + s.query)
+	}
+	return driver.RowsAffected(n), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	db := s.db
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.queries = append(db.queries, s.query)
+
+	r, ok := db.rows[args[0].(string)]
+	if !ok || r.expiresAt <= args[1].(int64) {
+		return &fakeRows{}, nil
+	}
+	return &fakeRows{values: []string{r.data}}, nil
+}
+
+type fakeRows struct{ values []string }
+
+func (r *fakeRows) Columns() []string { return []string{"data"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	dest[0] = r.values[0]
+	r.values = r.values[1:]
+	return nil
+}
+
+func TestNewBackend_invalidConfig(t *testing.T) {
+	_, err := NewBackend(nil, Config{})
+	assert.EqualError(t, err, "sqlstore: db is required")
+
+	db := sql.OpenDB(&fakeDB{})
+	_, err = NewBackend(db, Config{Table: "sessions; DROP TABLE users"})
+	assert.EqualError(t, err, "sqlstore: invalid table name")
+}
+
+func TestNewBackend_placeholders(t *testing.T) {
+	b, err := NewBackend(sql.OpenDB(&fakeDB{}), Config{Table: "app.sessions", Placeholder: PlaceholderDollar})
+	assert.NoError(t, err)
+	assert.Equal(t, "UPDATE app.sessions SET data = $1, expires_at = $2 WHERE id = $3", b.updateQuery)
+}
+
+func TestBackend(t *testing.T) {
+	fake := &fakeDB{rows: map[string]fakeRow{}}
+	b, err := NewBackend(sql.OpenDB(fake), Config{})
+	assert.NoError(t, err)
+	now := time.Unix(1_700_000_000, 0)
+	b.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	assert.NoError(t, b.Save(ctx, "a", []byte("data-a"), time.Minute))
+	assert.NoError(t, b.Save(ctx, "a", []byte("data-a2"), time.Minute)) // update
+	assert.NoError(t, b.Save(ctx, "b", []byte("data-b"), time.Hour))
+	assert.Equal(t, fakeRow{data: "data-a2", expiresAt: now.Add(time.Minute).Unix()}, fake.rows["a"])
+
+	data, err := b.Load(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("data-a2"), data)
+
+	data, err = b.Load(ctx, "missing")
+	assert.NoError(t, err)
+	assert.Nil(t, data)
+
+	now = now.Add(2 * time.Minute)
+	data, err = b.Load(ctx, "a") // expired
+	assert.NoError(t, err)
+	assert.Nil(t, data)
+
+	assert.NoError(t, b.Touch(ctx, "b", time.Hour))
+	assert.Equal(t, now.Add(time.Hour).Unix(), fake.rows["b"].expiresAt)
+
+	n, err := b.DeleteExpired(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	assert.NotContains(t, fake.rows, "a")
+
+	assert.NoError(t, b.Delete(ctx, "b"))
+	assert.Empty(t, fake.rows)
+}
@@ -0,0 +1,171 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package session
+
+import (
+	"context"
+	"encoding/base32"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+// Backend persists encoded session data by session ID. See `redisstore` and `sqlstore` packages for implementations.
+type Backend interface {
+	// Load returns session data or nil data when session does not exist or has expired.
+	Load(ctx context.Context, id string) ([]byte, error)
+	// Save stores session data that expires after ttl.
+	Save(ctx context.Context, id string, data []byte, ttl time.Duration) error
+	// Touch extends expiry of existing session to ttl from now.
+	Touch(ctx context.Context, id string, ttl time.Duration) error
+	// Delete removes session data.
+	Delete(ctx context.Context, id string) error
+}
+
+// ExpiredDeleter is implemented by backends that do not expire sessions on their own and need garbage collection.
+type ExpiredDeleter interface {
+	// DeleteExpired removes expired sessions and returns number of removed sessions.
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+var base32RawStdEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// ServerStore is sessions.Store keeping session values in Backend and only signed session ID in the cookie.
+type ServerStore struct {
+	Codecs  []securecookie.Codec
+	Options *sessions.Options
+
+	// SlidingExpiration extends expiry of existing session in backend every time it is loaded so sessions expire
+	// only after MaxAge of inactivity. When session is loaded through the session middleware, the middleware also
+	// re-saves the session and re-issues the cookie before the response is written unless the handler saved it.
+	SlidingExpiration bool
+
+	backend Backend
+}
+
+// NewServerStore returns a new ServerStore storing sessions in backend.
+//
+// Keys are defined in pairs to allow key rotation, but the common case is to set a single authentication key and
+// optionally an encryption key. See sessions.NewCookieStore.
+func NewServerStore(backend Backend, keyPairs ...[]byte) *ServerStore {
+	s := &ServerStore{
+		Codecs: securecookie.CodecsFromPairs(keyPairs...),
+		Options: &sessions.Options{
+			Path:   "/",
+			MaxAge: 86400 * 30,
+		},
+		backend: backend,
+	}
+	s.MaxAge(s.Options.MaxAge)
+	return s
+}
+
+// MaxAge sets the maximum age for the store and the underlying cookie implementation. Individual sessions can be
+// deleted by setting Options.MaxAge = -1 for that session.
+func (s *ServerStore) MaxAge(age int) {
+	s.Options.MaxAge = age
+	for _, codec := range s.Codecs {
+		if sc, ok := codec.(*securecookie.SecureCookie); ok {
+			sc.MaxAge(age)
+		}
+	}
+}
+
+// Get returns a session for the given name after adding it to the registry.
+func (s *ServerStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New returns a session for the given name without adding it to the registry. Session that does not exist in backend
+// (i.e. has expired) is returned as new session with new ID.
+func (s *ServerStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	opts := *s.Options
+	session.Options = &opts
+	session.IsNew = true
+
+	c, errCookie := r.Cookie(name)
+	if errCookie != nil {
+		return session, nil
+	}
+	var id string
+	if err := securecookie.DecodeMulti(name, c.Value, &id, s.Codecs...); err != nil {
+		return session, err
+	}
+	data, err := s.backend.Load(r.Context(), id)
+	if err != nil || data == nil {
+		return session, err
+	}
+	if err := securecookie.DecodeMulti(name, string(data), &session.Values, s.Codecs...); err != nil {
+		return session, err
+	}
+	session.ID = id
+	session.IsNew = false
+	if s.SlidingExpiration && session.Options.MaxAge > 0 {
+		if err := s.backend.Touch(r.Context(), id, maxAgeTTL(session.Options.MaxAge)); err != nil {
+			return session, err
+		}
+	}
+	return session, nil
+}
+
+// Save stores session values in backend and adds cookie with session ID to the response. Session with
+// Options.MaxAge <= 0 is deleted from backend.
+func (s *ServerStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if session.Options.MaxAge <= 0 {
+		if session.ID != "" {
+			if err := s.backend.Delete(r.Context(), session.ID); err != nil {
+				return err
+			}
+		}
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	if session.ID == "" {
+		session.ID = base32RawStdEncoding.EncodeToString(securecookie.GenerateRandomKey(32))
+	}
+	data, err := securecookie.EncodeMulti(session.Name(), session.Values, s.Codecs...)
+	if err != nil {
+		return err
+	}
+	if err := s.backend.Save(r.Context(), session.ID, []byte(data), maxAgeTTL(session.Options.MaxAge)); err != nil {
+		return err
+	}
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.Codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+// StartGC periodically deletes expired sessions from backend until ctx is cancelled. Does nothing when backend
+// expires sessions on its own (does not implement ExpiredDeleter). Errors are passed to onError when not nil.
+func (s *ServerStore) StartGC(ctx context.Context, interval time.Duration, onError func(err error)) {
+	deleter, ok := s.backend.(ExpiredDeleter)
+	if !ok {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := deleter.DeleteExpired(ctx); err != nil && onError != nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+}
+
+func maxAgeTTL(maxAge int) time.Duration {
+	return time.Duration(maxAge) * time.Second
+}
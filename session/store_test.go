@@ -0,0 +1,191 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package session
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryBackend struct {
+	mu      sync.Mutex
+	data    map[string][]byte
+	ttls    map[string]time.Duration
+	touches int
+	gcRuns  int
+}
+
+func newMemoryBackend() *memoryBackend {
+	return &memoryBackend{data: map[string][]byte{}, ttls: map[string]time.Duration{}}
+}
+
+func (b *memoryBackend) Load(ctx context.Context, id string) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.data[id], nil
+}
+
+func (b *memoryBackend) Save(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.data[id] = data
+	b.ttls[id] = ttl
+	return nil
+}
+
+func (b *memoryBackend) Touch(ctx context.Context, id string, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.touches++
+	b.ttls[id] = ttl
+	return nil
+}
+
+func (b *memoryBackend) Delete(ctx context.Context, id string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.data, id)
+	return nil
+}
+
+func (b *memoryBackend) DeleteExpired(ctx context.Context) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.gcRuns++
+	return 0, nil
+}
+
+func TestServerStore(t *testing.T) {
+	backend := newMemoryBackend()
+	store := NewServerStore(backend, []byte("secret"))
+	store.SlidingExpiration = true
+
+	e := echo.New()
+	e.Use(Middleware(store))
+	e.GET("/set", func(c echo.Context) error {
+		sess, _ := Get("app", c)
+		sess.Values["user"] = "jon"
+		if err := sess.Save(c.Request(), c.Response()); err != nil {
+			return err
+		}
+		return c.NoContent(http.StatusOK)
+	})
+	e.GET("/get", func(c echo.Context) error {
+		sess, err := Get("app", c)
+		if err != nil {
+			return err
+		}
+		user, _ := sess.Values["user"].(string)
+		return c.String(http.StatusOK, user)
+	})
+	e.GET("/logout", func(c echo.Context) error {
+		sess, _ := Get("app", c)
+		sess.Options.MaxAge = -1
+		if err := sess.Save(c.Request(), c.Response()); err != nil {
+			return err
+		}
+		return c.NoContent(http.StatusOK)
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/set", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	cookie := rec.Result().Cookies()[0]
+	assert.Len(t, backend.data, 1)
+	for _, ttl := range backend.ttls {
+		assert.Equal(t, 30*24*time.Hour, ttl)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/get", nil)
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, "jon", rec.Body.String())
+	assert.Equal(t, 1, backend.touches)
+
+	req = httptest.NewRequest(http.MethodGet, "/logout", nil)
+	req.AddCookie(cookie)
+	e.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Empty(t, backend.data)
+
+	// deleted or expired session is returned as new session
+	req = httptest.NewRequest(http.MethodGet, "/get", nil)
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "", rec.Body.String())
+}
+
+func TestServerStore_StartGC(t *testing.T) {
+	backend := newMemoryBackend()
+	store := NewServerStore(backend, []byte("secret"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store.StartGC(ctx, time.Millisecond, nil)
+
+	assert.Eventually(t, func() bool {
+		backend.mu.Lock()
+		defer backend.mu.Unlock()
+		return backend.gcRuns >= 2
+	}, time.Second, time.Millisecond)
+}
+
+func TestServerStore_slidingExpirationRefreshesCookie(t *testing.T) {
+	backend := newMemoryBackend()
+	store := NewServerStore(backend, []byte("secret"))
+	store.MaxAge(2)
+	store.SlidingExpiration = true
+
+	e := echo.New()
+	e.Use(Middleware(store))
+	e.GET("/set", func(c echo.Context) error {
+		sess, _ := Get("app", c)
+		sess.Values["user"] = "jon"
+		if err := sess.Save(c.Request(), c.Response()); err != nil {
+			return err
+		}
+		return c.NoContent(http.StatusOK)
+	})
+	e.GET("/get", func(c echo.Context) error {
+		sess, err := Get("app", c)
+		if err != nil {
+			return err
+		}
+		user, _ := sess.Values["user"].(string)
+		return c.String(http.StatusOK, user)
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/set", nil))
+	cookies := rec.Result().Cookies()
+	assert.Len(t, cookies, 1)
+	cookie := cookies[0]
+
+	// total time exceeds MaxAge since /set but every request happens within MaxAge of the previous one
+	for i := 0; i < 3; i++ {
+		time.Sleep(1200 * time.Millisecond)
+
+		req := httptest.NewRequest(http.MethodGet, "/get", nil)
+		req.AddCookie(cookie)
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "jon", rec.Body.String())
+
+		cookies = rec.Result().Cookies()
+		if assert.Len(t, cookies, 1) {
+			assert.Equal(t, 2, cookies[0].MaxAge)
+			cookie = cookies[0]
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package zipkintracing
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/openzipkin/zipkin-go/model"
+	"github.com/openzipkin/zipkin-go/propagation"
+	"github.com/openzipkin/zipkin-go/propagation/b3"
+)
+
+// Propagation is set of header formats span context is extracted from and injected into. Formats can be combined
+// with `|` operator. Zero value means PropagationB3Multi.
+type Propagation uint8
+
+const (
+	// PropagationB3Multi is multi-header B3 format (`X-B3-TraceId`, `X-B3-SpanId`, ...).
+	PropagationB3Multi Propagation = 1 << iota
+	// PropagationB3Single is single-header B3 format (`b3`).
+	PropagationB3Single
+	// PropagationW3C is W3C Trace Context format (`traceparent`).
+	PropagationW3C
+)
+
+// HeaderTraceparent is W3C Trace Context header name.
+const HeaderTraceparent = "traceparent"
+
+var (
+	// ErrInvalidTraceparent is returned when `traceparent` header value is malformed.
+	ErrInvalidTraceparent = errors.New("invalid traceparent header")
+	// ErrEmptyTraceparent is returned when request does not contain `traceparent` header.
+	ErrEmptyTraceparent = errors.New("empty traceparent header")
+)
+
+func (p Propagation) orDefault() Propagation {
+	if p == 0 {
+		return PropagationB3Multi
+	}
+	return p
+}
+
+// Extract returns extractor that reads span context from request headers. W3C format is tried first (when enabled)
+// then B3 single and multi header formats.
+func (p Propagation) Extract(r *http.Request) propagation.Extractor {
+	p = p.orDefault()
+	return func() (*model.SpanContext, error) {
+		var err error
+		if p&PropagationW3C != 0 {
+			var sc *model.SpanContext
+			if sc, err = ExtractW3C(r)(); err == nil {
+				return sc, nil
+			}
+		}
+		if p&(PropagationB3Multi|PropagationB3Single) != 0 {
+			return b3.ExtractHTTP(r)()
+		}
+		return nil, err
+	}
+}
+
+// Inject returns injector that writes span context to request headers in all enabled formats.
+func (p Propagation) Inject(r *http.Request) propagation.Injector {
+	p = p.orDefault()
+	var b3Opts []b3.InjectOption
+	switch {
+	case p&PropagationB3Multi != 0 && p&PropagationB3Single != 0:
+		b3Opts = append(b3Opts, b3.WithSingleAndMultiHeader())
+	case p&PropagationB3Single != 0:
+		b3Opts = append(b3Opts, b3.WithSingleHeaderOnly())
+	}
+	return func(sc model.SpanContext) error {
+		if p&PropagationW3C != 0 {
+			if err := InjectW3C(r)(sc); err != nil {
+				return err
+			}
+		}
+		if p&(PropagationB3Multi|PropagationB3Single) != 0 {
+			return b3.InjectHTTP(r, b3Opts...)(sc)
+		}
+		return nil
+	}
+}
+
+// ExtractW3C returns extractor that reads span context from W3C `traceparent` header.
+func ExtractW3C(r *http.Request) propagation.Extractor {
+	return func() (*model.SpanContext, error) {
+		value := strings.TrimSpace(r.Header.Get(HeaderTraceparent))
+		if value == "" {
+			return nil, ErrEmptyTraceparent
+		}
+		return parseTraceparent(value)
+	}
+}
+
+// InjectW3C returns injector that writes span context to W3C `traceparent` header.
+func InjectW3C(r *http.Request) propagation.Injector {
+	return func(sc model.SpanContext) error {
+		if sc.TraceID.Empty() || sc.ID == 0 {
+			return b3.ErrEmptyContext
+		}
+		flags := "00"
+		if sc.Debug || (sc.Sampled != nil && *sc.Sampled) {
+			flags = "01"
+		}
+		r.Header.Set(HeaderTraceparent, fmt.Sprintf("00-%016x%016x-%016x-%s", sc.TraceID.High, sc.TraceID.Low, uint64(sc.ID), flags))
+		return nil
+	}
+}
+
+// parseTraceparent parses `version-traceid-parentid-flags` value.
+// See: https://www.w3.org/TR/trace-context/#traceparent-header
+func parseTraceparent(value string) (*model.SpanContext, error) {
+	parts := strings.Split(value, "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return nil, ErrInvalidTraceparent
+	}
+	if parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return nil, ErrInvalidTraceparent
+	}
+	if strings.ToLower(value) != value {
+		return nil, ErrInvalidTraceparent
+	}
+
+	traceID, err := model.TraceIDFromHex(parts[1])
+	if err != nil || traceID.Empty() {
+		return nil, ErrInvalidTraceparent
+	}
+	spanID, err := strconv.ParseUint(parts[2], 16, 64)
+	if err != nil || spanID == 0 {
+		return nil, ErrInvalidTraceparent
+	}
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil {
+		return nil, ErrInvalidTraceparent
+	}
+	sampled := flags&0x01 == 1
+
+	return &model.SpanContext{
+		TraceID: traceID,
+		ID:      model.ID(spanID),
+		Sampled: &sampled,
+	}, nil
+}
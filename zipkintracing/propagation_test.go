@@ -0,0 +1,216 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package zipkintracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/model"
+	"github.com/openzipkin/zipkin-go/propagation/b3"
+	"github.com/openzipkin/zipkin-go/propagation/baggage"
+	"github.com/openzipkin/zipkin-go/reporter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractW3C(t *testing.T) {
+	sampled := true
+	notSampled := false
+
+	var testCases = []struct {
+		name        string
+		whenHeader  string
+		expect      *model.SpanContext
+		expectError error
+	}{
+		{
+			name:       "ok",
+			whenHeader: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			expect: &model.SpanContext{
+				TraceID: model.TraceID{High: 0x4bf92f3577b34da6, Low: 0xa3ce929d0e0e4736},
+				ID:      model.ID(0x00f067aa0ba902b7),
+				Sampled: &sampled,
+			},
+		},
+		{
+			name:       "ok, not sampled",
+			whenHeader: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+			expect: &model.SpanContext{
+				TraceID: model.TraceID{High: 0x4bf92f3577b34da6, Low: 0xa3ce929d0e0e4736},
+				ID:      model.ID(0x00f067aa0ba902b7),
+				Sampled: &notSampled,
+			},
+		},
+		{
+			name:       "ok, future version with extra fields",
+			whenHeader: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+			expect: &model.SpanContext{
+				TraceID: model.TraceID{High: 0x4bf92f3577b34da6, Low: 0xa3ce929d0e0e4736},
+				ID:      model.ID(0x00f067aa0ba902b7),
+				Sampled: &sampled,
+			},
+		},
+		{
+			name:        "nok, empty",
+			whenHeader:  "",
+			expectError: ErrEmptyTraceparent,
+		},
+		{
+			name:        "nok, zero trace id",
+			whenHeader:  "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+			expectError: ErrInvalidTraceparent,
+		},
+		{
+			name:        "nok, zero span id",
+			whenHeader:  "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+			expectError: ErrInvalidTraceparent,
+		},
+		{
+			name:        "nok, invalid version",
+			whenHeader:  "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			expectError: ErrInvalidTraceparent,
+		},
+		{
+			name:        "nok, upper case",
+			whenHeader:  "00-4BF92F3577B34DA6A3CE929D0E0E4736-00F067AA0BA902B7-01",
+			expectError: ErrInvalidTraceparent,
+		},
+		{
+			name:        "nok, short trace id",
+			whenHeader:  "00-a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			expectError: ErrInvalidTraceparent,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.whenHeader != "" {
+				req.Header.Set(HeaderTraceparent, tc.whenHeader)
+			}
+			sc, err := ExtractW3C(req)()
+			assert.Equal(t, tc.expectError, err)
+			assert.Equal(t, tc.expect, sc)
+		})
+	}
+}
+
+func TestInjectW3C(t *testing.T) {
+	sampled := true
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	err := InjectW3C(req)(model.SpanContext{
+		TraceID: model.TraceID{Low: 0xa3ce929d0e0e4736},
+		ID:      model.ID(0xf067aa0ba902b7),
+		Sampled: &sampled,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "00-0000000000000000a3ce929d0e0e4736-00f067aa0ba902b7-01", req.Header.Get(HeaderTraceparent))
+
+	assert.Equal(t, b3.ErrEmptyContext, InjectW3C(req)(model.SpanContext{}))
+}
+
+func TestPropagation_Inject(t *testing.T) {
+	sc := model.SpanContext{
+		TraceID: model.TraceID{Low: 1},
+		ID:      model.ID(2),
+	}
+
+	var testCases = []struct {
+		name          string
+		givenFormat   Propagation
+		expectHeaders []string
+	}{
+		{
+			name:          "default is multi-header b3",
+			givenFormat:   0,
+			expectHeaders: []string{"X-B3-Spanid", "X-B3-Traceid"},
+		},
+		{
+			name:          "single header b3",
+			givenFormat:   PropagationB3Single,
+			expectHeaders: []string{"B3"},
+		},
+		{
+			name:          "w3c",
+			givenFormat:   PropagationW3C,
+			expectHeaders: []string{"Traceparent"},
+		},
+		{
+			name:          "all",
+			givenFormat:   PropagationW3C | PropagationB3Single | PropagationB3Multi,
+			expectHeaders: []string{"B3", "Traceparent", "X-B3-Spanid", "X-B3-Traceid"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "/", nil)
+			assert.NoError(t, tc.givenFormat.Inject(req)(sc))
+
+			headers := make([]string, 0, len(req.Header))
+			for k := range req.Header {
+				headers = append(headers, http.CanonicalHeaderKey(k))
+			}
+			assert.ElementsMatch(t, tc.expectHeaders, headers)
+		})
+	}
+}
+
+func TestPropagation_Extract(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(b3.Context, "0000000000000003-0000000000000004-1")
+
+	sc, err := PropagationW3C.Extract(req)()
+	assert.Equal(t, ErrEmptyTraceparent, err)
+	assert.Nil(t, sc)
+
+	sc, err = (PropagationW3C | PropagationB3Single).Extract(req)()
+	assert.NoError(t, err)
+	assert.Equal(t, model.TraceID{Low: 3}, sc.TraceID)
+
+	req.Header.Set(HeaderTraceparent, "00-00000000000000000000000000000005-0000000000000006-01")
+	sc, err = (PropagationW3C | PropagationB3Single).Extract(req)()
+	assert.NoError(t, err)
+	assert.Equal(t, model.TraceID{Low: 5}, sc.TraceID) // w3c takes precedence
+}
+
+func TestTraceServerAndProxy_w3cAndBaggage(t *testing.T) {
+	tracer, err := zipkin.NewTracer(reporter.NewNoopReporter())
+	assert.NoError(t, err)
+
+	var proxied *http.Request
+	e := echo.New()
+	e.Use(TraceServerWithConfig(TraceServerConfig{
+		Skipper:     DefaultTraceServerConfig.Skipper,
+		SpanTags:    DefaultSpanTags,
+		Tracer:      tracer,
+		Propagation: PropagationW3C,
+		Baggage:     baggage.New("X-Tenant-Id"),
+	}))
+	e.GET("/", func(c echo.Context) error {
+		proxied = c.Request()
+		return c.NoContent(http.StatusOK)
+	}, TraceProxyWithConfig(TraceProxyConfig{
+		Skipper:     DefaultTraceProxyConfig.Skipper,
+		SpanTags:    DefaultSpanTags,
+		Tracer:      tracer,
+		Propagation: PropagationW3C | PropagationB3Single,
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(HeaderTraceparent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set("X-Tenant-Id", "acme")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	sc, err := ExtractW3C(proxied)()
+	assert.NoError(t, err)
+	assert.Equal(t, model.TraceID{High: 0x4bf92f3577b34da6, Low: 0xa3ce929d0e0e4736}, sc.TraceID)
+	assert.NotEqual(t, model.ID(0x00f067aa0ba902b7), sc.ID)
+	assert.NotEmpty(t, proxied.Header.Get(b3.Context))
+	assert.Equal(t, []string{"acme"}, zipkin.SpanFromContext(proxied.Context()).Context().Baggage.Get("X-Tenant-Id"))
+}
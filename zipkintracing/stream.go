@@ -0,0 +1,129 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package zipkintracing
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/openzipkin/zipkin-go"
+)
+
+const (
+	// AnnotationHeadersSent is annotation added to streaming span when response headers are written.
+	AnnotationHeadersSent = "headers sent"
+	// AnnotationStreamClosed is annotation added to streaming span when response stream or hijacked connection is closed.
+	AnnotationStreamClosed = "stream closed"
+
+	// TagStreamBytes is tag with number of bytes written to streaming response.
+	TagStreamBytes = "http.stream.bytes"
+	// TagStreamDuration is tag with duration from headers sent to stream close.
+	TagStreamDuration = "http.stream.duration"
+)
+
+// DefaultStreamingFunc marks Server-Sent Events (`Accept: text/event-stream`) and connection upgrade (i.e. WebSocket)
+// requests as streaming.
+func DefaultStreamingFunc(c echo.Context) bool {
+	req := c.Request()
+	if strings.Contains(req.Header.Get(echo.HeaderAccept), "text/event-stream") {
+		return true
+	}
+	return req.Header.Get(echo.HeaderUpgrade) != ""
+}
+
+// streamWriter wraps http.ResponseWriter of streaming request to annotate span when headers are sent and to finish
+// span when hijacked connection is closed.
+type streamWriter struct {
+	http.ResponseWriter
+
+	span        zipkin.Span
+	headersSent time.Time
+	written     atomic.Int64
+	hijacked    bool
+	finishOnce  sync.Once
+}
+
+func newStreamWriter(rw http.ResponseWriter, span zipkin.Span) *streamWriter {
+	return &streamWriter{ResponseWriter: rw, span: span}
+}
+
+func (w *streamWriter) WriteHeader(code int) {
+	w.markHeadersSent()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *streamWriter) Write(b []byte) (int, error) {
+	w.markHeadersSent()
+	n, err := w.ResponseWriter.Write(b)
+	w.written.Add(int64(n))
+	return n, err
+}
+
+func (w *streamWriter) Flush() {
+	w.markHeadersSent()
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *streamWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err != nil {
+		return conn, rw, err
+	}
+	w.hijacked = true
+	w.markHeadersSent()
+	sc := &streamConn{Conn: conn, w: w}
+	// writer returned by Hijack has empty buffer so it is safe to replace it with one writing through our conn
+	rw.Writer = bufio.NewWriterSize(sc, rw.Writer.Size())
+	return sc, rw, nil
+}
+
+func (w *streamWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *streamWriter) markHeadersSent() {
+	if !w.headersSent.IsZero() {
+		return
+	}
+	w.headersSent = time.Now()
+	w.span.Annotate(w.headersSent, AnnotationHeadersSent)
+}
+
+// finish annotates stream close and finishes span. It is safe to call multiple times.
+func (w *streamWriter) finish() {
+	w.finishOnce.Do(func() {
+		now := time.Now()
+		w.span.Annotate(now, AnnotationStreamClosed)
+		w.span.Tag(TagStreamBytes, strconv.FormatInt(w.written.Load(), 10))
+		if !w.headersSent.IsZero() {
+			w.span.Tag(TagStreamDuration, now.Sub(w.headersSent).String())
+		}
+		w.span.Finish()
+	})
+}
+
+// streamConn counts bytes written to hijacked connection and finishes span when connection is closed.
+type streamConn struct {
+	net.Conn
+	w *streamWriter
+}
+
+func (c *streamConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.w.written.Add(int64(n))
+	return n, err
+}
+
+func (c *streamConn) Close() error {
+	err := c.Conn.Close()
+	c.w.finish()
+	return err
+}
@@ -0,0 +1,120 @@
+// SPDX-License-Identifier: MIT
+// SPDX-FileCopyrightText: © 2017 LabStack and Echo contributors
+
+package zipkintracing
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/openzipkin/zipkin-go"
+	"github.com/openzipkin/zipkin-go/model"
+	"github.com/openzipkin/zipkin-go/reporter/recorder"
+	"github.com/stretchr/testify/assert"
+)
+
+func annotationValues(span model.SpanModel) []string {
+	values := make([]string, 0, len(span.Annotations))
+	for _, a := range span.Annotations {
+		values = append(values, a.Value)
+	}
+	return values
+}
+
+func TestTraceServerWithConfigStreaming(t *testing.T) {
+	rec := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(rec, zipkin.WithSampler(zipkin.AlwaysSample))
+	assert.NoError(t, err)
+
+	e := echo.New()
+	e.Use(TraceServerWithConfig(TraceServerConfig{
+		Skipper:       middleware.DefaultSkipper,
+		SpanTags:      DefaultSpanTags,
+		Tracer:        tracer,
+		StreamingFunc: DefaultStreamingFunc,
+	}))
+	e.GET("/events", func(c echo.Context) error {
+		c.Response().Header().Set(echo.HeaderContentType, "text/event-stream")
+		c.Response().WriteHeader(http.StatusOK)
+		for i := 0; i < 3; i++ {
+			_, _ = c.Response().Write([]byte("data: ping\n\n"))
+			c.Response().Flush()
+		}
+		return nil
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.Header.Set(echo.HeaderAccept, "text/event-stream")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := rec.Flush()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, []string{AnnotationHeadersSent, AnnotationStreamClosed}, annotationValues(spans[0]))
+	assert.Equal(t, "36", spans[0].Tags[TagStreamBytes])
+	assert.NotEmpty(t, spans[0].Tags[TagStreamDuration])
+
+	// not streaming request gets no annotations
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/events", nil))
+	spans = rec.Flush()
+	assert.Len(t, spans, 1)
+	assert.Empty(t, spans[0].Annotations)
+}
+
+func TestTraceServerWithConfigStreamingHijacked(t *testing.T) {
+	rec := recorder.NewReporter()
+	tracer, err := zipkin.NewTracer(rec, zipkin.WithSampler(zipkin.AlwaysSample))
+	assert.NoError(t, err)
+
+	closeConn := make(chan struct{})
+	handlerDone := make(chan struct{})
+	e := echo.New()
+	e.Use(TraceServerWithConfig(TraceServerConfig{
+		Skipper:       middleware.DefaultSkipper,
+		SpanTags:      DefaultSpanTags,
+		Tracer:        tracer,
+		StreamingFunc: DefaultStreamingFunc,
+	}))
+	e.GET("/ws", func(c echo.Context) error {
+		conn, rw, err := c.Response().Hijack()
+		if err != nil {
+			return err
+		}
+		_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n\r\n")
+		_ = rw.Flush()
+		go func() {
+			<-closeConn
+			_ = conn.Close()
+		}()
+		close(handlerDone)
+		return nil
+	})
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/ws", nil)
+	req.Header.Set(echo.HeaderUpgrade, "websocket")
+	req.Header.Set(echo.HeaderConnection, "Upgrade")
+	res, err := http.DefaultTransport.RoundTrip(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, res.StatusCode)
+
+	<-handlerDone
+	assert.Empty(t, rec.Flush()) // span is not finished before connection is closed
+
+	close(closeConn)
+	_, err = bufio.NewReader(res.Body).ReadByte()
+	assert.Error(t, err)
+	res.Body.Close()
+
+	var spans []model.SpanModel
+	assert.Eventually(t, func() bool {
+		spans = append(spans, rec.Flush()...)
+		return len(spans) == 1
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{AnnotationHeadersSent, AnnotationStreamClosed}, annotationValues(spans[0]))
+}
@@ -6,14 +6,15 @@ package zipkintracing
 import (
 	"fmt"
 	"github.com/labstack/echo/v4/middleware"
+	"math/rand"
 	"net/http"
 	"strconv"
 
 	"github.com/labstack/echo/v4"
 	"github.com/openzipkin/zipkin-go"
+	zipkinmiddleware "github.com/openzipkin/zipkin-go/middleware"
 	zipkinhttp "github.com/openzipkin/zipkin-go/middleware/http"
 	"github.com/openzipkin/zipkin-go/model"
-	"github.com/openzipkin/zipkin-go/propagation/b3"
 )
 
 type (
@@ -26,6 +27,8 @@ type (
 		Skipper  middleware.Skipper
 		Tracer   *zipkin.Tracer
 		SpanTags Tags
+		// Propagation sets header formats span context is injected into proxied request. Defaults to multi-header B3.
+		Propagation Propagation
 	}
 
 	//TraceServerConfig config for TraceServerWithConfig
@@ -33,6 +36,22 @@ type (
 		Skipper  middleware.Skipper
 		Tracer   *zipkin.Tracer
 		SpanTags Tags
+		// Propagation sets header formats span context is extracted from. Defaults to multi-header B3.
+		Propagation Propagation
+		// Baggage enables propagation of registered request headers as span context baggage (see `baggage.New`).
+		// Baggage is injected into outgoing requests by TraceProxy and zipkin-go HTTP client.
+		Baggage zipkinmiddleware.BaggageHandler
+		// RouteSamplingRates sets sampling rate (0.0 - 1.0) of root spans per route path (e.g. `/users/:id`). Routes not in
+		// the map are sampled by tracer sampler. Sampling decision received from upstream is always respected.
+		RouteSamplingRates map[string]float64
+		// SamplingRateFunc returns sampling rate (0.0 - 1.0) of root span for the request. When ok is false
+		// RouteSamplingRates and tracer sampler are used. Takes precedence over RouteSamplingRates.
+		SamplingRateFunc func(c echo.Context) (rate float64, ok bool)
+		// StreamingFunc marks request as streaming (i.e. Server-Sent Events or WebSocket). Span of streaming request is
+		// annotated when headers are sent and when stream is closed, is tagged with number of bytes streamed, and for
+		// hijacked connections is finished only when connection is closed. See DefaultStreamingFunc. Defaults to nil
+		// (no request is streaming).
+		StreamingFunc func(c echo.Context) bool
 	}
 )
 
@@ -94,7 +113,16 @@ func TraceProxyWithConfig(config TraceProxyConfig) echo.MiddlewareFunc {
 			defer span.Finish()
 			ctx := zipkin.NewContext(c.Request().Context(), span)
 			c.SetRequest(c.Request().WithContext(ctx))
-			b3.InjectHTTP(c.Request())(span.Context())
+			req := c.Request()
+			config.Propagation.Inject(req)(span.Context())
+			if baggage := span.Context().Baggage; baggage != nil {
+				baggage.Iterate(func(key string, values []string) {
+					req.Header.Del(key)
+					for _, v := range values {
+						req.Header.Add(key, v)
+					}
+				})
+			}
 			nrw := NewResponseWriter(c.Response().Writer)
 			if err := next(c); err != nil {
 				c.Error(err)
@@ -128,12 +156,33 @@ func TraceServerWithConfig(config TraceServerConfig) echo.MiddlewareFunc {
 			if config.Skipper(c) {
 				return next(c)
 			}
-			sc := config.Tracer.Extract(b3.ExtractHTTP(c.Request()))
+			sc := config.Tracer.Extract(config.Propagation.Extract(c.Request()))
+			if config.Baggage != nil {
+				sc.Baggage = config.Baggage.New()
+				for key, values := range c.Request().Header {
+					sc.Baggage.Add(key, values...)
+				}
+			}
+			if sc.Sampled == nil && !sc.Debug {
+				sc.Sampled = config.sample(c)
+			}
 			span := config.Tracer.StartSpan(fmt.Sprintf("S %s %s", c.Request().Method, c.Request().URL.Path), zipkin.Parent(sc))
 			for key, value := range config.SpanTags(c) {
 				span.Tag(key, value)
 			}
-			defer span.Finish()
+			var sw *streamWriter
+			if config.StreamingFunc != nil && config.StreamingFunc(c) {
+				sw = newStreamWriter(c.Response().Writer, span)
+				c.Response().Writer = sw
+				defer func() {
+					// span of hijacked connection is finished when connection is closed
+					if !sw.hijacked {
+						sw.finish()
+					}
+				}()
+			} else {
+				defer span.Finish()
+			}
 			ctx := zipkin.NewContext(c.Request().Context(), span)
 			c.SetRequest(c.Request().WithContext(ctx))
 			nrw := NewResponseWriter(c.Response().Writer)
@@ -156,6 +205,23 @@ func TraceServerWithConfig(config TraceServerConfig) echo.MiddlewareFunc {
 	}
 }
 
+// sample returns sampling decision for root span based on configured sampling rates or nil when tracer sampler
+// should decide.
+func (config TraceServerConfig) sample(c echo.Context) *bool {
+	rate, ok := 0.0, false
+	if config.SamplingRateFunc != nil {
+		rate, ok = config.SamplingRateFunc(c)
+	}
+	if !ok && config.RouteSamplingRates != nil {
+		rate, ok = config.RouteSamplingRates[c.Path()]
+	}
+	if !ok {
+		return nil
+	}
+	sampled := rand.Float64() < rate
+	return &sampled
+}
+
 // StartChildSpan starts a new child span as child of parent span from context
 // user must call defer childSpan.Finish()
 func StartChildSpan(c echo.Context, spanName string, tracer *zipkin.Tracer) (childSpan zipkin.Span) {
@@ -354,3 +354,49 @@ func TestStartChildSpan(t *testing.T) {
 		t.Fatalf("Test server did not receive spans")
 	}
 }
+
+func TestTraceServerWithConfigSamplingRates(t *testing.T) {
+	tracer, err := zipkin.NewTracer(reporter.NewNoopReporter(), zipkin.WithSampler(zipkin.AlwaysSample))
+	assert.NoError(t, err)
+
+	e := echo.New()
+	e.Use(TraceServerWithConfig(TraceServerConfig{
+		Skipper:  middleware.DefaultSkipper,
+		SpanTags: DefaultSpanTags,
+		Tracer:   tracer,
+		RouteSamplingRates: map[string]float64{
+			"/health":    0,
+			"/users/:id": 1,
+		},
+		SamplingRateFunc: func(c echo.Context) (float64, bool) {
+			if c.QueryParam("debug") != "" {
+				return 1, true
+			}
+			return 0, false
+		},
+	}))
+	sampled := map[string]*bool{}
+	handler := func(c echo.Context) error {
+		sampled[c.Request().URL.String()] = zipkin.SpanFromContext(c.Request().Context()).Context().Sampled
+		return c.NoContent(http.StatusOK)
+	}
+	e.GET("/health", handler)
+	e.GET("/users/:id", handler)
+	e.GET("/other", handler)
+
+	for _, target := range []string{"/health", "/health?debug=1", "/users/1", "/other"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.Header.Set(b3.Sampled, "1") // upstream decision is respected
+	req.Header.Set(b3.TraceID, "0000000000000001")
+	req.Header.Set(b3.SpanID, "0000000000000002")
+	req.URL.RawQuery = "upstream=1"
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.False(t, *sampled["/health"])
+	assert.True(t, *sampled["/health?debug=1"])
+	assert.True(t, *sampled["/users/1"])
+	assert.True(t, *sampled["/other"]) // tracer sampler
+	assert.True(t, *sampled["/health?upstream=1"])
+}